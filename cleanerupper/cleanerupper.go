@@ -44,6 +44,9 @@ type Clients struct {
 	Daisy         daisyCompute.Client
 	OSConfig      osconfigInterface
 	OSConfigZonal osconfigZonalInterface
+	// Compute is used for resources the daisy client doesn't support, such as
+	// managed instance groups. Those resources are skipped when it is nil.
+	Compute *compute.Service
 }
 
 type osconfigInterface interface {
@@ -72,6 +75,10 @@ func NewClients(ctx context.Context, opts ...option.ClientOption) (*Clients, err
 	if err != nil {
 		return nil, err
 	}
+	c.Compute, err = compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
 	return &c, nil
 }
 
@@ -82,6 +89,9 @@ type PolicyFunc func(any) bool
 // AgePolicy takes a time.Time and returns a PolicyFunc which indicates to
 // delete anything older than the given time. Also contains safeguards such as
 // refusing to delete default networks or resources with a "do-not-delete" label.
+// Managed instance groups and instance templates are never deleted, since
+// their names don't tell those created by tests from others; use
+// WorkflowPolicy for them.
 func AgePolicy(t time.Time) PolicyFunc {
 	return func(resource any) bool {
 		var labels map[string]string
@@ -233,6 +243,15 @@ func WorkflowPolicy(id string) PolicyFunc {
 		case *compute.NetworkEndpointGroup:
 			desc = r.Description
 			name = r.Name
+		case *compute.Firewall:
+			desc = r.Description
+			name = r.Name
+		case *compute.InstanceGroupManager:
+			desc = r.Description
+			name = r.Name
+		case *compute.InstanceTemplate:
+			desc = r.Description
+			name = r.Name
 		default:
 			return false
 		}
@@ -690,6 +709,217 @@ func CleanLoadBalancerResources(clients Clients, project string, delete PolicyFu
 		deleted = append(deleted, deletedNEGs...)
 	}
 	wg.Wait()
+
+	// Instance groups might be backends of the backend services, so they are
+	// deleted last.
+	if clients.Compute != nil {
+		deletedIGs, igErrs := CleanInstanceGroups(clients, project, delete, dryRun)
+		deleted = append(deleted, deletedIGs...)
+		errs = append(errs, igErrs...)
+	}
+	return deleted, errs
+}
+
+// CleanInstanceGroups deletes managed instance groups and instance templates
+// indicated by the policy func, and the global health checks indicated by the
+// policy func which autoheal the deleted groups, returning a slice of deleted
+// partial urls and a slice of encountered errors. Managed instance groups are
+// deleted first, as they use the templates and health checks. This requires
+// clients.Compute. On dry run, returns what would have been deleted.
+func CleanInstanceGroups(clients Clients, project string, delete PolicyFunc, dryRun bool) ([]string, []error) {
+	ctx := context.Background()
+	var igms []*compute.InstanceGroupManager
+	err := clients.Compute.InstanceGroupManagers.AggregatedList(project).Pages(ctx, func(list *compute.InstanceGroupManagerAggregatedList) error {
+		for _, scoped := range list.Items {
+			igms = append(igms, scoped.InstanceGroupManagers...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, []error{fmt.Errorf("error listing managed instance groups in project %q: %v", project, err)}
+	}
+
+	var deletedMu sync.Mutex
+	var deleted []string
+	var errsMu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	// Only the health checks of the deleted groups are deleted, so that those
+	// of other groups and load balancers are kept.
+	groupHealthChecks := make(map[string]bool)
+	for _, igm := range igms {
+		if !delete(igm) {
+			continue
+		}
+		for _, policy := range igm.AutoHealingPolicies {
+			groupHealthChecks[path.Base(policy.HealthCheck)] = true
+		}
+
+		var partial string
+		if igm.Region != "" {
+			partial = fmt.Sprintf("projects/%s/regions/%s/instanceGroupManagers/%s", project, path.Base(igm.Region), igm.Name)
+		} else {
+			partial = fmt.Sprintf("projects/%s/zones/%s/instanceGroupManagers/%s", project, path.Base(igm.Zone), igm.Name)
+		}
+		wg.Add(1)
+		go func(igm *compute.InstanceGroupManager) {
+			defer wg.Done()
+			if !dryRun {
+				var op *compute.Operation
+				var err error
+				if igm.Region != "" {
+					op, err = clients.Compute.RegionInstanceGroupManagers.Delete(project, path.Base(igm.Region), igm.Name).Context(ctx).Do()
+				} else {
+					op, err = clients.Compute.InstanceGroupManagers.Delete(project, path.Base(igm.Zone), igm.Name).Context(ctx).Do()
+				}
+				if err == nil {
+					err = waitForOperation(ctx, clients.Compute, project, op)
+				}
+				if err != nil {
+					errsMu.Lock()
+					defer errsMu.Unlock()
+					errs = append(errs, err)
+					return
+				}
+			}
+			deletedMu.Lock()
+			defer deletedMu.Unlock()
+			deleted = append(deleted, partial)
+		}(igm)
+	}
+	// Templates and health checks can't be deleted while in use by the groups.
+	wg.Wait()
+
+	var templates []*compute.InstanceTemplate
+	err = clients.Compute.InstanceTemplates.List(project).Pages(ctx, func(list *compute.InstanceTemplateList) error {
+		templates = append(templates, list.Items...)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing instance templates in project %q: %v", project, err))
+	}
+	for _, template := range templates {
+		if !delete(template) {
+			continue
+		}
+
+		name := template.Name
+		partial := fmt.Sprintf("projects/%s/global/instanceTemplates/%s", project, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !dryRun {
+				if _, err := clients.Compute.InstanceTemplates.Delete(project, name).Context(ctx).Do(); err != nil {
+					errsMu.Lock()
+					defer errsMu.Unlock()
+					errs = append(errs, err)
+					return
+				}
+			}
+			deletedMu.Lock()
+			defer deletedMu.Unlock()
+			deleted = append(deleted, partial)
+		}()
+	}
+
+	var healthChecks []*compute.HealthCheck
+	err = clients.Compute.HealthChecks.List(project).Pages(ctx, func(list *compute.HealthCheckList) error {
+		healthChecks = append(healthChecks, list.Items...)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing health checks in project %q: %v", project, err))
+	}
+	for _, hc := range healthChecks {
+		if !groupHealthChecks[hc.Name] || !delete(hc) {
+			continue
+		}
+
+		name := hc.Name
+		partial := fmt.Sprintf("projects/%s/global/healthChecks/%s", project, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !dryRun {
+				if _, err := clients.Compute.HealthChecks.Delete(project, name).Context(ctx).Do(); err != nil {
+					errsMu.Lock()
+					defer errsMu.Unlock()
+					errs = append(errs, err)
+					return
+				}
+			}
+			deletedMu.Lock()
+			defer deletedMu.Unlock()
+			deleted = append(deleted, partial)
+		}()
+	}
+	wg.Wait()
+	return deleted, errs
+}
+
+// waitForOperation waits for a zonal, regional or global operation to be done
+// and returns its error, if any.
+func waitForOperation(ctx context.Context, service *compute.Service, project string, op *compute.Operation) error {
+	var err error
+	for op.Status != "DONE" {
+		switch {
+		case op.Zone != "":
+			op, err = service.ZoneOperations.Wait(project, path.Base(op.Zone), op.Name).Context(ctx).Do()
+		case op.Region != "":
+			op, err = service.RegionOperations.Wait(project, path.Base(op.Region), op.Name).Context(ctx).Do()
+		default:
+			op, err = service.GlobalOperations.Wait(project, op.Name).Context(ctx).Do()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("operation %s failed: %s", op.Name, op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+// CleanFirewallRules deletes all firewall rules indicated, regardless of the
+// network they belong to, returning a slice of deleted partial urls and a slice
+// of encountered errors. This is used for projects where the networks
+// themselves must be left alone, such as a Shared VPC host project. On dry run,
+// returns what would have been deleted.
+func CleanFirewallRules(clients Clients, project string, delete PolicyFunc, dryRun bool) ([]string, []error) {
+	firewalls, err := clients.Daisy.ListFirewallRules(project)
+	if err != nil {
+		return nil, []error{fmt.Errorf("error listing firewalls in project %q: %v", project, err)}
+	}
+
+	var deletedMu sync.Mutex
+	var deleted []string
+	var errsMu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, f := range firewalls {
+		if !delete(f) {
+			continue
+		}
+
+		name := path.Base(f.SelfLink)
+		partial := fmt.Sprintf("projects/%s/global/firewalls/%s", project, name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !dryRun {
+				if err := clients.Daisy.DeleteFirewallRule(project, name); err != nil {
+					errsMu.Lock()
+					defer errsMu.Unlock()
+					errs = append(errs, err)
+					return
+				}
+			}
+			deletedMu.Lock()
+			defer deletedMu.Unlock()
+			deleted = append(deleted, partial)
+		}()
+	}
+	wg.Wait()
 	return deleted, errs
 }
 
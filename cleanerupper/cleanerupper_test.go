@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	"sort"
 	"strings"
@@ -31,6 +32,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
 	osconfigv1alphapb "google.golang.org/genproto/googleapis/cloud/osconfig/v1alpha"
 	osconfigpb "google.golang.org/genproto/googleapis/cloud/osconfig/v1beta"
 )
@@ -136,6 +138,18 @@ func TestAgePolicy(t *testing.T) {
 			resource: &compute.Instance{CreationTimestamp: "1970-01-01"},
 			output:   false,
 		},
+		{
+			name:     "Old InstanceGroupManager",
+			time:     time.Now(),
+			resource: &compute.InstanceGroupManager{CreationTimestamp: "1970-01-01T00:00:01+00:00"},
+			output:   false,
+		},
+		{
+			name:     "Old InstanceTemplate",
+			time:     time.Now(),
+			resource: &compute.InstanceTemplate{CreationTimestamp: "1970-01-01T00:00:01+00:00"},
+			output:   false,
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -214,6 +228,18 @@ func TestWorkflowPolicy(t *testing.T) {
 			resource: &compute.Instance{Name: "instance-asdf", Description: "created by Daisy in workflow \"asdf\" on behalf of root"},
 			output:   true,
 		},
+		{
+			name:     "Workflow Firewall",
+			wfID:     "asdf",
+			resource: &compute.Firewall{Name: "firewall-asdf", Description: "created by Daisy in workflow \"asdf\" on behalf of root"},
+			output:   true,
+		},
+		{
+			name:     "Other Firewall",
+			wfID:     "asdf",
+			resource: &compute.Firewall{Name: "allow-ssh", Description: "shared vpc host rule"},
+			output:   false,
+		},
 		{
 			name:     "Keep label in labels",
 			wfID:     "asdf",
@@ -452,6 +478,75 @@ func TestCleanImages(t *testing.T) {
 	}
 }
 
+func TestCleanFirewallRules(t *testing.T) {
+	_, daisyFake, err := computeDaisy.NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.String() == fmt.Sprintf("/projects/%s/global/firewalls?alt=json&pageToken=&prettyPrint=false", "host-project") {
+			fmt.Fprint(w, `{"items":[{"SelfLink": "projects/host-project/global/firewalls/test-firewall"}]}`)
+		} else if r.Method == "DELETE" && r.URL.String() == fmt.Sprintf("/projects/%s/global/firewalls/test-firewall?alt=json&prettyPrint=false", "host-project") {
+			w.WriteHeader(200)
+			w.Write([]byte(`{"status":"DONE"}`))
+		} else if r.Method == "POST" && r.URL.String() == fmt.Sprintf("/projects/%s/global/operations//wait?alt=json&prettyPrint=false", "host-project") {
+			w.WriteHeader(200)
+			w.Write([]byte(`{"status":"DONE"}`))
+		} else {
+			w.WriteHeader(555)
+			fmt.Fprintln(w, "URL and Method not recognized:", r.Method, r.URL)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		name    string
+		clients Clients
+		project string
+		policy  PolicyFunc
+		output  []string
+		dryRun  bool
+	}{
+		{
+			name:    "delete everything",
+			clients: Clients{Daisy: daisyFake},
+			project: "host-project",
+			policy:  deleteEverything,
+			output:  []string{"projects/host-project/global/firewalls/test-firewall"},
+		},
+		{
+			name:    "delete everything dry run",
+			clients: Clients{Daisy: daisyFake},
+			project: "host-project",
+			policy:  deleteEverything,
+			output:  []string{"projects/host-project/global/firewalls/test-firewall"},
+			dryRun:  true,
+		},
+		{
+			name:    "delete nothing",
+			clients: Clients{Daisy: daisyFake},
+			project: "host-project",
+			policy:  deleteNothing,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, errs := CleanFirewallRules(tc.clients, tc.project, tc.policy, tc.dryRun)
+			if len(errs) > 0 {
+				for _, e := range errs {
+					t.Errorf("error from CleanFirewallRules: %v", e)
+				}
+			}
+			if len(o) != len(tc.output) {
+				t.Fatalf("unexpected output length from CleanFirewallRules, want %d but got %d", len(tc.output), len(o))
+			}
+			sort.Strings(o)
+			for i := range o {
+				if o[i] != tc.output[i] {
+					t.Errorf("unexpected output from CleanFirewallRules at position %d, want %s but got %s", i, tc.output[i], o[i])
+				}
+			}
+		})
+	}
+}
+
 func TestCleanMachineImages(t *testing.T) {
 	_, daisyFake, err := computeDaisy.NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" && r.URL.String() == fmt.Sprintf("/projects/%s/global/machineImages?alt=json&pageToken=&prettyPrint=false", "test-project") {
@@ -986,3 +1081,112 @@ func TestDeleteOSPolicies(t *testing.T) {
 		})
 	}
 }
+
+func TestCleanInstanceGroups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/projects/test-project/aggregated/instanceGroupManagers" {
+			fmt.Fprint(w, `{"items":{"zones/test-zone":{"instanceGroupManagers":[`+
+				`{"name":"test-zonal-mig-abc","zone":"projects/test-project/zones/test-zone","creationTimestamp":"1970-01-01T00:00:01+00:00","autoHealingPolicies":[{"healthCheck":"projects/test-project/global/healthChecks/test-healthcheck-abc"}]},`+
+				`{"name":"foreign-mig","zone":"projects/test-project/zones/test-zone","creationTimestamp":"1970-01-01T00:00:01+00:00","autoHealingPolicies":[{"healthCheck":"projects/test-project/global/healthChecks/foreign-healthcheck"}]}]},`+
+				`"regions/test-region":{"instanceGroupManagers":[{"name":"test-regional-mig-abc","region":"projects/test-project/regions/test-region","creationTimestamp":"1970-01-01T00:00:01+00:00"}]}}}`)
+		} else if r.Method == "DELETE" && r.URL.Path == "/projects/test-project/zones/test-zone/instanceGroupManagers/test-zonal-mig-abc" {
+			fmt.Fprint(w, `{"name":"zonal-op","zone":"projects/test-project/zones/test-zone","status":"RUNNING"}`)
+		} else if r.Method == "POST" && r.URL.Path == "/projects/test-project/zones/test-zone/operations/zonal-op/wait" {
+			fmt.Fprint(w, `{"name":"zonal-op","status":"DONE"}`)
+		} else if r.Method == "DELETE" && r.URL.Path == "/projects/test-project/zones/test-zone/instanceGroupManagers/foreign-mig" {
+			fmt.Fprint(w, `{"name":"foreign-op","status":"DONE"}`)
+		} else if r.Method == "DELETE" && r.URL.Path == "/projects/test-project/regions/test-region/instanceGroupManagers/test-regional-mig-abc" {
+			fmt.Fprint(w, `{"name":"regional-op","status":"DONE"}`)
+		} else if r.Method == "GET" && r.URL.Path == "/projects/test-project/global/instanceTemplates" {
+			fmt.Fprint(w, `{"items":[{"name":"test-template-abc","creationTimestamp":"1970-01-01T00:00:01+00:00"},{"name":"foreign-template","creationTimestamp":"1970-01-01T00:00:01+00:00"}]}`)
+		} else if r.Method == "DELETE" && (r.URL.Path == "/projects/test-project/global/instanceTemplates/test-template-abc" || r.URL.Path == "/projects/test-project/global/instanceTemplates/foreign-template") {
+			fmt.Fprint(w, `{"status":"DONE"}`)
+		} else if r.Method == "GET" && r.URL.Path == "/projects/test-project/global/healthChecks" {
+			fmt.Fprint(w, `{"items":[{"name":"test-healthcheck-abc","creationTimestamp":"1970-01-01T00:00:01+00:00"},{"name":"foreign-healthcheck","creationTimestamp":"1970-01-01T00:00:01+00:00"},{"name":"load-balancer-healthcheck-abc","creationTimestamp":"1970-01-01T00:00:01+00:00"}]}`)
+		} else if r.Method == "DELETE" && (r.URL.Path == "/projects/test-project/global/healthChecks/test-healthcheck-abc" || r.URL.Path == "/projects/test-project/global/healthChecks/foreign-healthcheck") {
+			fmt.Fprint(w, `{"status":"DONE"}`)
+		} else {
+			w.WriteHeader(555)
+			fmt.Fprintln(w, "URL and Method not recognized:", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+	computeFake, err := compute.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	workflow := []string{
+		"projects/test-project/global/healthChecks/test-healthcheck-abc",
+		"projects/test-project/global/instanceTemplates/test-template-abc",
+		"projects/test-project/regions/test-region/instanceGroupManagers/test-regional-mig-abc",
+		"projects/test-project/zones/test-zone/instanceGroupManagers/test-zonal-mig-abc",
+	}
+	// Health checks which autoheal no group, like those of load balancers,
+	// are kept.
+	all := []string{
+		"projects/test-project/global/healthChecks/foreign-healthcheck",
+		"projects/test-project/global/healthChecks/test-healthcheck-abc",
+		"projects/test-project/global/instanceTemplates/foreign-template",
+		"projects/test-project/global/instanceTemplates/test-template-abc",
+		"projects/test-project/regions/test-region/instanceGroupManagers/test-regional-mig-abc",
+		"projects/test-project/zones/test-zone/instanceGroupManagers/foreign-mig",
+		"projects/test-project/zones/test-zone/instanceGroupManagers/test-zonal-mig-abc",
+	}
+	testcases := []struct {
+		name    string
+		clients Clients
+		project string
+		policy  PolicyFunc
+		output  []string
+		dryRun  bool
+	}{
+		{
+			name:    "delete everything",
+			clients: Clients{Compute: computeFake},
+			project: "test-project",
+			policy:  deleteEverything,
+			output:  all,
+		},
+		{
+			name:    "delete everything dry run",
+			clients: Clients{Compute: computeFake},
+			project: "test-project",
+			policy:  deleteEverything,
+			output:  all,
+			dryRun:  true,
+		},
+		{
+			name:    "delete nothing",
+			clients: Clients{Compute: computeFake},
+			project: "test-project",
+			policy:  deleteNothing,
+		},
+		{
+			name:    "workflow policy keeps foreign groups",
+			clients: Clients{Compute: computeFake},
+			project: "test-project",
+			policy:  WorkflowPolicy("abc"),
+			output:  workflow,
+		},
+		{
+			name:    "age policy keeps foreign groups",
+			clients: Clients{Compute: computeFake},
+			project: "test-project",
+			policy:  AgePolicy(time.Now()),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			o, errs := CleanInstanceGroups(tc.clients, tc.project, tc.policy, tc.dryRun)
+			if len(errs) > 0 {
+				for _, e := range errs {
+					t.Errorf("error from CleanInstanceGroups: %v", e)
+				}
+			}
+			sort.Strings(o)
+			if diff := cmp.Diff(tc.output, o); diff != "" {
+				t.Errorf("unexpected output from CleanInstanceGroups (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
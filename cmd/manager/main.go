@@ -18,783 +18,10 @@
 package main
 
 import (
-	"context"
-	"encoding/xml"
-	"flag"
-	"fmt"
-	"io"
-	"log"
-	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
-	"sync"
-
-	"cloud.google.com/go/storage"
-	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorconfig"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratornccl"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdma"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmabandwidth"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmanetwork"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmawriteimmediate"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/compatmanager"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/cvm"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/disk"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/guestagent"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/hostnamevalidation"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/hotattach"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/imageboot"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/licensevalidation"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/livemigrate"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/loadbalancer"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/lssd"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/lvmvalidation"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/mdsmtls"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/mdsroutes"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/metadata"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/network"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkconfig"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkinterfacenaming"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkperf"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/nicsetup"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/oslogin"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packagemanager"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packageupgrade"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packagevalidation"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/pluginmanager"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/rhel"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/security"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/shapevalidation"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/sql"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/ssh"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/storageperf"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/suspendresume"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/vmspec"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/windowscontainers"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/winrm"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/wsfc"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
-	"github.com/GoogleCloudPlatform/compute-daisy/compute"
-	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
-
-	computev1 "google.golang.org/api/compute/v1"
-)
-
-var (
-	// zones is a flag.Value that represents a comma-separated list of zones. Initialized in init().
-	zones                   StringSlice
-	project                 = flag.String("project", "", "project to use for test runner")
-	testProjects            = flag.String("test_projects", "", "comma separated list of projects to be used for tests. defaults to the test runner project")
-	zone                    = flag.String("zone", "us-central1-a", "zone to be used for tests")
-	printwf                 = flag.Bool("print", false, "print out the parsed test workflows and exit")
-	validate                = flag.Bool("validate", false, "validate all the test workflows and exit")
-	argZoneOverride         = flag.Bool("zone_override", true, "argument provided zones (via -zone or -zones flags) will override tests hardcoded zones")
-	outPath                 = flag.String("out_path", "junit.xml", "junit xml path")
-	gcsPath                 = flag.String("gcs_path", "", "GCS Path for Daisy working directory")
-	writeLocalArtifacts     = flag.String("write_local_artifacts", "", "Local path to download test artifacts from gcs.")
-	localPath               = flag.String("local_path", "", "path where test output files are stored, can be modified for local testing")
-	images                  = flag.String("images", "", "comma separated list of images to test")
-	timeout                 = flag.String("timeout", "30m", "timeout for the test suite")
-	computeEndpointOverride = flag.String("compute_endpoint_override", "", "compute client endpoint override")
-	parallelCount           = flag.Int("parallel_count", 5, "TestParallelCount")
-	parallelStagger         = flag.String("parallel_stagger", "60s", "parseable time.Duration to stagger each parallel test")
-	filter                  = flag.String("filter", "", "only run test suites matching filter")
-	exclude                 = flag.String("exclude", "", "skip test suites matching filter")
-	testExcludeFilter       = flag.String("exclude_discrete_tests", "", "skip individual tests within suites that match the regexp filter")
-	machineType             = flag.String("machine_type", "", "deprecated, use -x86_shape and/or -arm64_shape instead")
-	x86Shape                = flag.String("x86_shape", "n1-standard-1", "default x86(-32 and -64) vm shape for tests not requiring a specific shape")
-	arm64Shape              = flag.String("arm64_shape", "t2a-standard-1", "default arm64 vm shape for tests not requiring a specific shape")
-	setExitStatus           = flag.Bool("set_exit_status", true, "Exit with non-zero exit code if test suites are failing")
-	useReservations         = flag.Bool("use_reservations", false, "Whether to consume reservations when creating VMs. Will consume any reservation if reservation_urls is unspecified.")
-	reservationURLs         = flag.String("reservation_urls", "", "Comma separated list of partial URLs for reservations to consume.")
-	acceleratorType         = flag.String("accelerator_type", "", "Accelerator type to be used for accelerator tests")
-	allImageFamilies        = flag.String("all_image_families", "", "Single image project to test all image families in.")
-	architectureType        = flag.String("architecture_type", "", "Specific architecture to test on. Accepts one of x86 or arm64.")
-
-	// zonesRoundRobinIdx points to an index in the list of zones.
-	// This is used to distribute tests across the list of zones in a round robin fashion,
-	// when the zones flag is set.
-	zonesRoundRobinIdx = -1
+	"github.com/GoogleCloudPlatform/cloud-image-tests/manager"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/all"
 )
 
-var (
-	// Maps an image family prefix to the project that hosts the image family.
-	// When modifying this map, please make sure that the image family prefix is
-	// also added to the imageKeys list.
-	projectMap = map[string]string{
-		"almalinux":          "almalinux-cloud",
-		"centos":             "centos-cloud",
-		"cos":                "cos-cloud",
-		"debian":             "debian-cloud",
-		"fedora-cloud":       "fedora-cloud",
-		"fedora-coreos":      "fedora-coreos-cloud",
-		"opensuse":           "opensuse-cloud",
-		"oracle-linux":       "oracle-linux-cloud",
-		"rhel-(.*-)?byos":    "rhel-byos-cloud",
-		"rhel-(.*-)?eus":     "rhel-cloud",
-		"rhel-(.*-)?lvm":     "rhel-cloud",
-		"rhel-(.*-)?sap":     "rhel-sap-cloud",
-		"rhel":               "rhel-cloud",
-		"rocky-linux":        "rocky-linux-cloud",
-		"sles-(.*-)?sap":     "suse-sap-cloud",
-		"sles":               "suse-cloud",
-		"sql-":               "windows-sql-cloud",
-		"ubuntu-(.*-)?pro":   "ubuntu-os-pro-cloud",
-		"ubuntu-accelerator": "ubuntu-os-accelerator-images",
-		"ubuntu":             "ubuntu-os-cloud",
-		"windows":            "windows-cloud",
-	}
-
-	// An ordered list is required because some image names are substrings of
-	// other image names. Map keys are returned in random order, so this ensures
-	// that we can set an order that always works.
-	imageKeys = []string{
-		"almalinux",
-		"centos",
-		"cos",
-		"debian",
-		"fedora-cloud",
-		"fedora-coreos",
-		"opensuse",
-		"oracle-linux",
-		"rhel-(.*-)?byos",
-		"rhel-(.*-)?eus",
-		"rhel-(.*-)?lvm",
-		"rhel-(.*-)?sap",
-		"rhel",
-		"rocky-linux",
-		"sles-(.*-)?sap",
-		"sles",
-		"sql-",
-		"ubuntu-(.*-)?pro",
-		"ubuntu-accelerator",
-		"ubuntu",
-		"windows",
-	}
-)
-
-var extraTestPackages []struct {
-	name      string
-	setupFunc func(*imagetest.TestWorkflow) error
-}
-
-// StringSlice is a flag.Value that represents a comma-separated list of strings.
-type StringSlice []string
-
-// Set implements flag.Value.Set.
-func (s *StringSlice) Set(value string) error {
-	*s = append(*s, strings.Split(strings.ReplaceAll(value, " ", ""), ",")...)
-	return nil
-}
-
-// String implements flag.Value.String.
-func (s *StringSlice) String() string {
-	return strings.Join(*s, ",")
-}
-
-type logWriter struct {
-	log *log.Logger
-}
-
-func (l *logWriter) Write(b []byte) (int, error) {
-	l.log.Print(string(b))
-	return len(b), nil
-}
-
-func init() {
-	flag.Var(&zones, "zones", "A comma-separated list of zones (e.g., --zones=\"us-east4, us-west1, us-west4\")")
-}
-
-// rotatedZones returns a slice of zones rotated such that the zone at
-// zonesRoundRobinIdx is first.
-func rotatedZones() []string {
-	if len(zones) == 0 {
-		return []string{*zone}
-	}
-	zonesRoundRobinIdx++
-	if zonesRoundRobinIdx >= len(zones) {
-		zonesRoundRobinIdx = 0
-	}
-	rotated := make([]string, len(zones))
-	for i := 0; i < len(zones); i++ {
-		idx := (zonesRoundRobinIdx + i) % len(zones)
-		rotated[i] = zones[idx]
-	}
-	return rotated
-}
-
-// displayZone returns the zone to be displayed in the log. If the zones
-// flag is set, its serialized string is returned. Otherwise, the zone flag is returned.
-func displayZone() string {
-	if len(zones) > 0 {
-		return zones.String()
-	}
-	return *zone
-}
-
 func main() {
-	flag.Parse()
-	if *project == "" || (*zone == "" && len(zones) == 0) || (*images == "" && *allImageFamilies == "") {
-		log.Fatal("Must provide project, zone(s), and one of images or all_image_families arguments")
-		return
-	}
-	if *images != "" && *allImageFamilies != "" {
-		log.Fatal("Must provide one of images or all_image_families arguments, not both")
-		return
-	}
-
-	if *architectureType != "" && (*architectureType != "x86" && *architectureType != "arm64") {
-		log.Fatal("architecture_type must be blank, x86, or arm64")
-		return
-	}
-
-	var testProjectsReal []string
-	if *testProjects == "" {
-		testProjectsReal = append(testProjectsReal, *project)
-	} else {
-		testProjectsReal = strings.Split(*testProjects, ",")
-	}
-
-	log.Printf("Running in project %s zone(s) %s. Tests will run in projects: %s", *project, displayZone(), testProjectsReal)
-	if *gcsPath != "" {
-		log.Printf("gcs_path set to %s", *gcsPath)
-	}
-
-	var filterRegex *regexp.Regexp
-	if *filter != "" {
-		var err error
-		filterRegex, err = regexp.Compile(*filter)
-		if err != nil {
-			log.Fatal("-filter flag not valid:", err)
-		}
-		log.Printf("using -filter %s", *filter)
-	}
-
-	var excludeRegex *regexp.Regexp
-	if *exclude != "" {
-		var err error
-		excludeRegex, err = regexp.Compile(*exclude)
-		if err != nil {
-			log.Fatal("-exclude flag not valid:", err)
-		}
-		log.Printf("using -exclude %s", *exclude)
-	}
-
-	if *testExcludeFilter != "" {
-		log.Printf("Using -exclude_discrete_tests %s", *testExcludeFilter)
-	}
-
-	if *machineType != "" {
-		log.Printf("The -machine_type flag is deprecated, please use -x86_shape and -arm64_shape instead. Retaining legacy behavior while this is set.")
-		*x86Shape = *machineType
-		*arm64Shape = *machineType
-	}
-
-	var reservationURLSlice []string
-	if *reservationURLs != "" {
-		reservationURLSlice = strings.Split(*reservationURLs, ",")
-	}
-
-	// Setup tests.
-	testPackages := []struct {
-		name      string
-		setupFunc func(*imagetest.TestWorkflow) error
-	}{
-		{
-			acceleratorconfig.Name,
-			acceleratorconfig.TestSetup,
-		},
-		{
-			acceleratornccl.Name,
-			acceleratornccl.TestSetup,
-		},
-		{
-			acceleratorrdma.Name,
-			acceleratorrdma.TestSetup,
-		},
-		{
-			acceleratorrdmabandwidth.Name,
-			acceleratorrdmabandwidth.TestSetup,
-		},
-		{
-			acceleratorrdmanetwork.Name,
-			acceleratorrdmanetwork.TestSetup,
-		},
-		{
-			acceleratorrdmawriteimmediate.Name,
-			acceleratorrdmawriteimmediate.TestSetup,
-		},
-		{
-			cvm.Name,
-			cvm.TestSetup,
-		},
-		{
-			livemigrate.Name,
-			livemigrate.TestSetup,
-		},
-		{
-			suspendresume.Name,
-			suspendresume.TestSetup,
-		},
-		{
-			networkperf.Name,
-			networkperf.TestSetup,
-		},
-		{
-			networkconfig.Name,
-			networkconfig.TestSetup,
-		},
-		{
-			networkinterfacenaming.Name,
-			networkinterfacenaming.TestSetup,
-		},
-		{
-			loadbalancer.Name,
-			loadbalancer.TestSetup,
-		},
-		{
-			guestagent.Name,
-			guestagent.TestSetup,
-		},
-		{
-			hostnamevalidation.Name,
-			hostnamevalidation.TestSetup,
-		},
-		{
-			imageboot.Name,
-			imageboot.TestSetup,
-		},
-		{
-			licensevalidation.Name,
-			licensevalidation.TestSetup,
-		},
-		{
-			network.Name,
-			network.TestSetup,
-		},
-		{
-			security.Name,
-			security.TestSetup,
-		},
-		{
-			hotattach.Name,
-			hotattach.TestSetup,
-		},
-		{
-			lssd.Name,
-			lssd.TestSetup,
-		},
-		{
-			lvmvalidation.Name,
-			lvmvalidation.TestSetup,
-		},
-		{
-			disk.Name,
-			disk.TestSetup,
-		},
-		{
-			shapevalidation.Name,
-			shapevalidation.TestSetup,
-		},
-		{
-			packagemanager.Name,
-			packagemanager.TestSetup,
-		},
-		{
-			packageupgrade.Name,
-			packageupgrade.TestSetup,
-		},
-		{
-			packagevalidation.Name,
-			packagevalidation.TestSetup,
-		},
-		{
-			storageperf.Name,
-			storageperf.TestSetup,
-		},
-		{
-			ssh.Name,
-			ssh.TestSetup,
-		},
-		{
-			winrm.Name,
-			winrm.TestSetup,
-		},
-		{
-			sql.Name,
-			sql.TestSetup,
-		},
-		{
-			metadata.Name,
-			metadata.TestSetup,
-		},
-		{
-			oslogin.Name,
-			oslogin.TestSetup,
-		},
-		{
-			mdsmtls.Name,
-			mdsmtls.TestSetup,
-		},
-		{
-			mdsroutes.Name,
-			mdsroutes.TestSetup,
-		},
-		{
-			windowscontainers.Name,
-			windowscontainers.TestSetup,
-		},
-		{
-			vmspec.Name,
-			vmspec.TestSetup,
-		},
-		{
-			pluginmanager.Name,
-			pluginmanager.TestSetup,
-		},
-		{
-			rhel.Name,
-			rhel.TestSetup,
-		},
-		{
-			compatmanager.Name,
-			compatmanager.TestSetup,
-		},
-		{
-			nicsetup.Name,
-			nicsetup.TestSetup,
-		},
-		{
-			wsfc.Name,
-			wsfc.TestSetup,
-		},
-	}
-
-	testPackages = append(testPackages, extraTestPackages...)
-
-	ctx := context.Background()
-	var computeclient compute.Client
-	var err error
-	if *computeEndpointOverride != "" {
-		log.Printf("Using compute endpoint %q", *computeEndpointOverride)
-		computeclient, err = compute.NewClient(ctx, option.WithEndpoint(*computeEndpointOverride))
-	} else {
-		computeclient, err = compute.NewClient(ctx)
-	}
-	if err != nil {
-		log.Fatalf("Could not create compute client:%v", err)
-	}
-
-	// Initialize the Compute API client
-	var computev1Client *computev1.Service
-	if *computeEndpointOverride != "" {
-		log.Printf("Using compute endpoint %q", *computeEndpointOverride)
-		computev1Client, err = computev1.NewService(ctx, option.WithEndpoint(*computeEndpointOverride))
-	} else {
-		computev1Client, err = computev1.NewService(ctx)
-	}
-	if err != nil {
-		log.Fatalf("Could not create compute v1 client: %v", err)
-	}
-
-	// Fetch active image families from the project
-	var imageList []string
-	if *allImageFamilies != "" {
-		projectName := *allImageFamilies
-		uniqueImageList := make(map[string]bool)
-
-		err := computev1Client.Images.List(projectName).Context(ctx).Pages(ctx, func(page *computev1.ImageList) error {
-			for _, projectImage := range page.Items {
-				if projectImage.Family != "" && !uniqueImageList[projectImage.Family] {
-					if projectImage.Deprecated == nil || (projectImage.Deprecated.State != "DEPRECATED" && projectImage.Deprecated.State != "OBSOLETE" && projectImage.Deprecated.State != "DELETED") {
-						uniqueImageList[projectImage.Family] = true
-						image := fmt.Sprintf("projects/%s/global/images/family/%s", projectName, projectImage.Family)
-						imageList = append(imageList, image)
-					}
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			log.Fatalf("Failed Compute API call: %v", err)
-		}
-
-		*images = strings.Join(imageList, ",")
-	}
-
-	// Filter by architecture type if applicable
-	if *architectureType != "" {
-		filteredImages, err := filterByArchitecture(computeclient, *images, *architectureType)
-		if err != nil {
-			log.Fatalf("Failed to filter images by architecture: %v", err)
-		}
-		*images = filteredImages
-	}
-
-	var testWorkflows []*imagetest.TestWorkflow
-	for _, testPackage := range testPackages {
-		if filterRegex != nil && !filterRegex.MatchString(testPackage.name) {
-			continue
-		}
-		if excludeRegex != nil && excludeRegex.MatchString(testPackage.name) {
-			continue
-		}
-		if testPackage.name == "" || testPackage.setupFunc == nil {
-			continue
-		}
-
-		for _, image := range strings.Split(*images, ",") {
-			// Ignore empty strings.
-			if image == "" {
-				log.Print("Skipping empty image")
-				continue
-			}
-
-			var err error
-			image, err = formatImageName(image)
-			if err != nil {
-				log.Fatalf("Failed to reformat image path: %v", err)
-			}
-
-			log.Printf("Add test workflow for test %s on image %s", testPackage.name, image)
-			rZones := rotatedZones()
-			test, err := imagetest.NewTestWorkflow(&imagetest.TestWorkflowOpts{
-				Client:                  computeclient,
-				ComputeEndpointOverride: *computeEndpointOverride,
-				Name:                    testPackage.name,
-				Image:                   image,
-				Timeout:                 *timeout,
-				Project:                 *project,
-				Zone:                    rZones[0],
-				Zones:                   rZones,
-				ExcludeFilter:           *testExcludeFilter,
-				X86Shape:                *x86Shape,
-				ARM64Shape:              *arm64Shape,
-				UseReservations:         *useReservations,
-				ReservationURLs:         reservationURLSlice,
-				AcceleratorType:         *acceleratorType,
-				ArgZoneOverride:         *argZoneOverride,
-			}, testPackage.setupFunc)
-			if err != nil {
-				log.Fatalf("Failed to create test workflow: %v", err)
-			}
-			testWorkflows = append(testWorkflows, test)
-			if err := test.SetupFunc(test); err != nil {
-				log.Fatalf("%s.TestSetup for %s failed: %v", testPackage.name, image, err)
-			}
-		}
-	}
-
-	if len(testWorkflows) == 0 {
-		log.Fatalf("No workflows to run!")
-	}
-
-	log.Println("Done with setup")
-
-	storageclient, err := storage.NewClient(ctx)
-	if err != nil {
-		log.Fatalf("failed to set up storage client: %v", err)
-	}
-
-	if *printwf {
-		imagetest.PrintTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath)
-		return
-	}
-
-	if *validate {
-		if err := imagetest.ValidateTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath); err != nil {
-			log.Printf("Validate failed: %v\n", err)
-		}
-		return
-	}
-
-	suites, err := imagetest.RunTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath, *parallelCount, *parallelStagger, testProjectsReal)
-	if err != nil {
-		log.Fatalf("Failed to run tests: %v", err)
-	}
-	if *writeLocalArtifacts != "" {
-		var wg sync.WaitGroup
-		for _, twf := range testWorkflows {
-			bkt := strings.TrimSuffix(strings.TrimPrefix(regexp.MustCompile(`gs://[a-z0-9][a-z0-9-_.]{2,62}[a-z0-9]/?`).FindString(twf.GCSPath), "gs://"), "/")
-			if bkt == "" {
-				log.Printf("could not find gcs bucket from %s for workflow %s", twf.GCSPath, twf.Name)
-				continue
-			}
-			gcsSubfolder := strings.TrimPrefix(twf.GCSPath, "gs://"+bkt+"/")
-			wg.Add(1)
-			go func(bucket, folder, dstDir string) {
-				defer wg.Done()
-				if err := downloadFolder(ctx, storageclient, bucket, folder, dstDir); err != nil {
-					log.Printf("failed to download test artifacts from folder %s in bucket %s to %s: %v\n", folder, bucket, dstDir, err)
-				}
-			}(bkt, gcsSubfolder, *writeLocalArtifacts)
-		}
-		wg.Wait()
-	}
-
-	bytes, err := xml.MarshalIndent(suites, "", "\t")
-	if err != nil {
-		log.Fatalf("failed to marshall result: %v", err)
-	}
-	bytes = []byte(fmt.Sprintf("%s%s", xml.Header, bytes))
-	var outFile *os.File
-	if artifacts := os.Getenv("ARTIFACTS"); artifacts != "" {
-		outFile, err = os.Create(artifacts + "/junit.xml")
-	} else {
-		outFile, err = os.Create(*outPath)
-	}
-	if err != nil {
-		log.Fatalf("failed to create output file: %v", err)
-	}
-	defer outFile.Close()
-
-	outFile.Write(bytes)
-	outFile.Write([]byte{'\n'})
-	fmt.Printf("%s\n", bytes)
-
-	if *setExitStatus && (suites.Errors != 0 || suites.Failures != 0) {
-		log.Fatalf("test suite has error or failure")
-	}
-}
-
-func downloadFolder(ctx context.Context, client *storage.Client, bucket, folder, dstDir string) error {
-	// Create the destination directory if it doesn't exist.
-	if err := os.MkdirAll(dstDir, 0755); err != nil {
-		return err
-	}
-
-	// List all objects in the folder.
-	query := &storage.Query{
-		Prefix: folder,
-	}
-	objs := client.Bucket(bucket).Objects(ctx, query)
-
-	// Download each object to the destination directory.
-	for {
-		obj, err := objs.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		dstFile := filepath.Join(dstDir, obj.Name)
-		if strings.Contains(dstFile, "/sources/") {
-			continue
-		}
-		// Remote path might contain subfolders, create them locally too.
-		fileDstDir := filepath.Dir(dstFile)
-		if err := os.MkdirAll(fileDstDir, 0755); err != nil {
-			log.Printf("failed to create %s: %v", fileDstDir, err)
-			continue
-		}
-		file, err := os.Create(dstFile)
-		if err != nil {
-			log.Printf("failed to write %s: %v", dstFile, err)
-			continue
-		}
-
-		objReader, err := client.Bucket(bucket).Object(obj.Name).NewReader(ctx)
-		if err != nil {
-			log.Printf("failed to make reader for %s: %v", obj.Name, err)
-			continue
-		}
-
-		if _, err := io.Copy(file, objReader); err != nil {
-			log.Printf("failed to copy %s to disk: %v", obj.Name, err)
-			continue
-		}
-
-		if err := objReader.Close(); err != nil {
-			log.Printf("failed to close %s reader: %v", obj.Name, err)
-			continue
-		}
-	}
-
-	return nil
-}
-
-// Reformat image path into valid URI
-func formatImageName(image string) (string, error) {
-	if strings.Contains(image, "/") {
-		return image, nil
-	}
-
-	// Find the project of the image.
-	project := ""
-	for _, k := range imageKeys {
-		if utils.IsSAP(k) {
-			// sap follows a slightly different naming convention.
-			imageName := strings.Split(k, "-")[0]
-			if strings.HasPrefix(image, imageName) && utils.IsSAP(image) {
-				project = projectMap[k]
-				break
-			}
-		}
-		imageRegex, err := regexp.Compile(k)
-		if err != nil {
-			log.Fatalf("failed regex: %v", err)
-		}
-		if imageRegex.MatchString(image) {
-			project = projectMap[k]
-			break
-		}
-	}
-	if project == "" {
-		log.Fatalf("unknown image %q", image)
-	}
-
-	// Check whether the image is an image family or a specific image version.
-	vRegex, err := regexp.Compile(".*v([0-9]+)")
-	if err != nil {
-		return "", fmt.Errorf("failed regex: %v", err)
-	}
-	if vRegex.MatchString(image) {
-		return fmt.Sprintf("projects/%s/global/images/%s", project, image), nil
-	}
-	return fmt.Sprintf("projects/%s/global/images/family/%s", project, image), nil
-}
-
-// Filter images list by architecture type
-func filterByArchitecture(computeclient compute.Client, images string, arch string) (string, error) {
-	if arch == "" {
-		return images, nil
-	}
-
-	var filteredImages []string
-	for _, image := range strings.Split(images, ",") {
-		if image == "" {
-			continue
-		}
-
-		fmtImage, err := formatImageName(image)
-		if err != nil {
-			return "", err
-		}
-
-		fmtImageComponents := strings.Split(fmtImage, "/")
-		var i *computev1.Image
-		if strings.Contains(fmtImage, "family") {
-			i, err = computeclient.GetImageFromFamily(fmtImageComponents[1], fmtImageComponents[len(fmtImageComponents)-1])
-		} else {
-			i, err = computeclient.GetImage(fmtImageComponents[1], fmtImageComponents[len(fmtImageComponents)-1])
-		}
-		if err != nil {
-			return "", fmt.Errorf("failed to get image %q: %w", fmtImage, err)
-		}
-
-		isArm := i.Architecture == "ARM64"
-		if arch == "x86" && isArm {
-			log.Printf("skipping over arm64 image: %s", fmtImage)
-			continue
-		}
-		if arch == "arm64" && !isArm {
-			log.Printf("skipping over x86 image: %s", fmtImage)
-			continue
-		}
-		filteredImages = append(filteredImages, fmtImage)
-	}
-	return strings.Join(filteredImages, ","), nil
+	manager.Main()
 }
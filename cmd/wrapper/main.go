@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -26,12 +27,14 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/migutils"
 	"google.golang.org/protobuf/proto"
 
 	vm_pb "github.com/GoogleCloudPlatform/cloud-image-tests/vm_test_info"
@@ -115,6 +118,20 @@ func main() {
 		log.Fatalf("failed to get metadata _test_properties_url: %v", err)
 	}
 
+	// Metrics are optional, members of managed instance groups don't record
+	// them.
+	metricsURL, err := utils.GetMetadata(ctx, "instance", "attributes", "_test_metrics_url")
+	if err != nil {
+		metricsURL = ""
+	}
+
+	// Artifacts are optional too, and uploaded to the objects of the directory
+	// of the URL named after their files.
+	artifactsURL, err := utils.GetMetadata(ctx, "instance", "attributes", "_test_artifacts_url")
+	if err != nil {
+		artifactsURL = ""
+	}
+
 	testArguments := []string{"-test.v", "-test.timeout", testTimeout}
 
 	testRun, err := utils.GetMetadata(ctx, "instance", "attributes", "_test_run")
@@ -167,12 +184,38 @@ func main() {
 	}
 	workDir = workDir + "/"
 
+	// Members of managed instance groups share their metadata, so they upload
+	// their results to objects named after them.
+	if strings.HasSuffix(resultsURL, "/") {
+		resultsURL = resultsURL + name + ".txt"
+	}
+	if strings.HasSuffix(propertiesURL, "/") {
+		propertiesURL = propertiesURL + name + ".txt"
+	}
+
 	if err = utils.DownloadGCSObjectToFile(ctx, client, testPackageURL, workDir+testPackage); err != nil {
 		log.Fatalf("failed to download object: %v", err)
 	}
-	client.Close()
 
-	out, err := executeCmd(workDir+testPackage, workDir, testArguments)
+	// The controller VM of a managed instance group creates the group before
+	// running its tests, which act on the group.
+	var group *migutils.Group
+	if spec, err := migutils.GetSpec(ctx); err == nil {
+		group, err = createGroup(ctx, spec)
+		if err != nil {
+			log.Printf("failed to create managed instance group %s: %v", spec.Name, err)
+		}
+	}
+	if group != nil {
+		defer deleteGroup(group)
+	}
+
+	metricsFile := workDir + "metrics.json"
+	artifactsDir := workDir + "artifacts"
+	if err := os.Mkdir(artifactsDir, 0755); err != nil {
+		log.Printf("failed to create artifacts dir: %v", err)
+	}
+	out, err := executeCmd(workDir+testPackage, workDir, testArguments, []string{utils.MetricsFileEnv + "=" + metricsFile, utils.ArtifactsDirEnv + "=" + artifactsDir})
 	if err != nil {
 		if ee, ok := err.(*exec.ExitError); ok {
 			log.Printf("test package exited with error: %v stderr: %q", ee, ee.Stderr)
@@ -183,6 +226,14 @@ func main() {
 
 	log.Printf("command output:\n%s\n", out)
 
+	if group != nil {
+		log.Printf("Waiting for members of managed instance group %s to upload results", group.Spec.Name)
+		if err := group.WaitForResults(ctx, client); err != nil {
+			log.Printf("failed to wait for managed instance group results: %v", err)
+		}
+	}
+	client.Close()
+
 	client, err = storage.NewClient(ctx)
 	if err != nil {
 		log.Fatalf("failed to create cloud storage client: %v", err)
@@ -192,6 +243,16 @@ func main() {
 		log.Fatalf("failed to upload test result: %v", err)
 	}
 
+	if metrics, err := os.ReadFile(metricsFile); err == nil && metricsURL != "" {
+		if err = uploadGCSObject(ctx, client, metricsURL, bytes.NewReader(metrics)); err != nil {
+			log.Printf("failed to upload test metrics: %v", err)
+		}
+	}
+
+	if artifactsURL != "" {
+		uploadArtifacts(ctx, client, artifactsDir, artifactsURL)
+	}
+
 	vmInfoProto := &vm_pb.Vm{
 		Test: &vm_pb.Vm_Test{
 			TestSuite: proto.String(testSuiteName),
@@ -210,11 +271,87 @@ func main() {
 	if err = uploadGCSObject(ctx, client, propertiesURL, bytes.NewReader(vmInfo)); err != nil {
 		log.Fatalf("failed to upload vm info: %v", err)
 	}
+
+	if flush, err := utils.GetMetadata(ctx, "instance", "attributes", utils.GuestFlushMetadataKey); err == nil && flush == "true" {
+		log.Printf("Flushing guest before image capture")
+		if err := flushGuest(workDir); err != nil {
+			log.Printf("failed to flush guest: %v", err)
+		}
+	}
+}
+
+// createGroup creates the managed instance group described by spec. If the
+// group can't be created, whatever was created is deleted.
+func createGroup(ctx context.Context, spec *migutils.Spec) (*migutils.Group, error) {
+	group, err := migutils.NewGroup(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Creating managed instance group %s", spec.Name)
+	if err := group.Create(ctx); err != nil {
+		deleteGroup(group)
+		return nil, err
+	}
+	return group, nil
 }
 
-func executeCmd(cmd, dir string, arg []string) ([]byte, error) {
+// deleteGroup deletes the managed instance group. It doesn't use the test
+// context, so that groups are deleted after tests time out too.
+func deleteGroup(group *migutils.Group) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+	log.Printf("Deleting managed instance group %s", group.Spec.Name)
+	if err := group.Delete(ctx); err != nil {
+		log.Printf("failed to delete managed instance group %s: %v", group.Spec.Name, err)
+	}
+}
+
+// flushGuest generalizes the guest so that an image captured from its boot disk
+// boots as a new instance, with new SSH host keys and machine ID.
+func flushGuest(workDir string) error {
+	if err := os.RemoveAll(workDir); err != nil {
+		log.Printf("failed to remove work dir %s: %v", workDir, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		out, err := exec.Command("powershell.exe", "-NonInteractive", "-NoProfile", "-Command", "GCESysprep -NoShutdown").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("GCESysprep failed: %v, output: %s", err, out)
+		}
+		return nil
+	}
+
+	var errs []error
+	hostKeys, err := filepath.Glob("/etc/ssh/ssh_host_*")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	// The guest agent regenerates host keys when the instance ID changes, so
+	// the saved instance ID is removed as well.
+	for _, f := range append(hostKeys, "/etc/google_instance_id", "/var/lib/dbus/machine-id") {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	// An empty machine-id is regenerated by systemd on the next boot.
+	if err := os.Truncate("/etc/machine-id", 0); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, err)
+	}
+	if _, err := exec.LookPath("cloud-init"); err == nil {
+		if out, err := exec.Command("cloud-init", "clean", "--logs").CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Errorf("cloud-init clean failed: %v, output: %s", err, out))
+		}
+	}
+	if err := exec.Command("sync").Run(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func executeCmd(cmd, dir string, arg, env []string) ([]byte, error) {
 	command := exec.Command(cmd, arg...)
 	command.Dir = dir
+	command.Env = append(os.Environ(), env...)
 	log.Printf("Going to execute: %q, pid: %d, ppid: %d", command.String(), os.Getpid(), os.Getppid())
 
 	output, err := command.Output()
@@ -224,6 +361,31 @@ func executeCmd(cmd, dir string, arg []string) ([]byte, error) {
 	return output, nil
 }
 
+// uploadArtifacts uploads the files of the artifacts dir written by the tests
+// with utils.WriteArtifact to the directory of the artifacts URL.
+func uploadArtifacts(ctx context.Context, client *storage.Client, artifactsDir, artifactsURL string) {
+	entries, err := os.ReadDir(artifactsDir)
+	if err != nil {
+		log.Printf("failed to read artifacts dir: %v", err)
+		return
+	}
+	artifactsURL = strings.TrimSuffix(artifactsURL, "/") + "/"
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		f, err := os.Open(filepath.Join(artifactsDir, entry.Name()))
+		if err != nil {
+			log.Printf("failed to open artifact %s: %v", entry.Name(), err)
+			continue
+		}
+		if err := uploadGCSObject(ctx, client, artifactsURL+entry.Name(), f); err != nil {
+			log.Printf("failed to upload artifact %s: %v", entry.Name(), err)
+		}
+		f.Close()
+	}
+}
+
 func uploadGCSObject(ctx context.Context, client *storage.Client, path string, data io.Reader) error {
 	u, err := url.Parse(path)
 	if err != nil {
@@ -22,7 +22,9 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 	daisy "github.com/GoogleCloudPlatform/compute-daisy"
 	"github.com/google/uuid"
 	computeBeta "google.golang.org/api/compute/v0.beta"
@@ -38,6 +40,7 @@ const (
 	createFirewallStepName    = "create-firewalls"
 	createSubnetworkStepName  = "create-sub-networks"
 	successMatch              = "FINISHED-TEST"
+	bootedMatch               = "FINISHED-BOOTING"
 	// ShouldRebootDuringTest is a local map key to indicate that the
 	// test will reboot and relies on results from the second boot.
 	ShouldRebootDuringTest = "shouldRebootDuringTest"
@@ -107,6 +110,27 @@ func (t *TestWorkflow) SkippedMessage() string {
 	return t.skippedMessage
 }
 
+// Fail marks a test workflow to fail without running, like when the exception
+// skipping it has expired.
+func (t *TestWorkflow) Fail(message string) {
+	t.failedMessage = message
+}
+
+// ScaleTimeout multiplies the timeout of the test workflow by factor, for
+// suites which repeat their tests. It must be called before the test VMs are
+// created, as they get the timeout in their metadata.
+func (t *TestWorkflow) ScaleTimeout(factor int) error {
+	if factor < 1 {
+		return fmt.Errorf("invalid timeout factor %d, want at least 1", factor)
+	}
+	timeout, err := time.ParseDuration(t.wf.DefaultTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid workflow timeout %q: %v", t.wf.DefaultTimeout, err)
+	}
+	t.wf.DefaultTimeout = (timeout * time.Duration(factor)).String()
+	return nil
+}
+
 // LockProject indicates this test modifies project-level data and must have
 // exclusive use of the project.
 func (t *TestWorkflow) LockProject() {
@@ -486,6 +510,238 @@ func (t *TestVM) CreateDerivativeVM(name string) (*TestVM, error) {
 	return &TestVM{name: vmname, testWorkflow: t.testWorkflow, instance: i}, nil
 }
 
+// DerivedImage is an image captured from the boot disk of a test VM by
+// CreateDerivedImage. Test VMs created from it boot after the image is created.
+type DerivedImage struct {
+	name            string
+	testWorkflow    *TestWorkflow
+	createImageStep *daisy.Step
+}
+
+// CreateDerivedImage captures an image with the given name from the boot disk
+// of the test VM once its tests have finished. Before the VM is stopped, the
+// guest is generalized: Windows runs GCESysprep, and other guests remove SSH
+// host keys, the machine ID and the guest agent instance ID. The flush runs at
+// the end of every test run of the VM, so the VM should not reboot during its
+// tests.
+func (t *TestVM) CreateDerivedImage(name string) (*DerivedImage, error) {
+	t.testWorkflow.counter++
+	stepSuffix := fmt.Sprintf("%s-%d", t.name, t.testWorkflow.counter)
+
+	t.AddMetadata(utils.GuestFlushMetadataKey, "true")
+
+	// Stop the source VM.
+	lastStep, err := t.testWorkflow.getLastStepForVM(t.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve last step")
+	}
+	stopStep, err := t.testWorkflow.addStopStep(stepSuffix, t.name)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.testWorkflow.wf.AddDependency(stopStep, lastStep); err != nil {
+		return nil, err
+	}
+
+	// Wait for the source VM to stop.
+	waitStopStep, err := t.testWorkflow.addWaitStoppedStep(stepSuffix, t.name)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.testWorkflow.wf.AddDependency(waitStopStep, stopStep); err != nil {
+		return nil, err
+	}
+
+	// Capture the image from the boot disk, which shares the VM name.
+	createImageStep, err := t.testWorkflow.appendCreateImageStep("create-image-"+stepSuffix, &compute.Image{Name: name, SourceDisk: t.name})
+	if err != nil {
+		return nil, err
+	}
+	if err := t.testWorkflow.wf.AddDependency(createImageStep, waitStopStep); err != nil {
+		return nil, err
+	}
+	t.testWorkflow.createsImages = true
+
+	return &DerivedImage{name: name, testWorkflow: t.testWorkflow, createImageStep: createImageStep}, nil
+}
+
+// CreateProbedVM creates a test VM from the image under test once the test VM
+// has booted, so that tests of the test VM probing the new VM, like measuring
+// the time it takes to accept SSH connections, observe it from its creation.
+func (t *TestVM) CreateProbedVM(name string) (*TestVM, error) {
+	waitBootedStep, err := t.testWorkflow.addWaitBootedStep(t.name, t.name)
+	if err != nil {
+		return nil, err
+	}
+	bootDisk := &daisy.Disk{}
+	bootDisk.SourceImage = t.testWorkflow.ImageURL
+	return t.testWorkflow.createTestVMFromBootDisk(name, bootDisk, waitBootedStep)
+}
+
+// CreateTestVM creates a test VM booting from the derived image.
+func (d *DerivedImage) CreateTestVM(name string) (*TestVM, error) {
+	bootDisk := &daisy.Disk{}
+	bootDisk.SourceImage = d.name
+	return d.testWorkflow.createTestVMFromBootDisk(name, bootDisk, d.createImageStep)
+}
+
+// createTestVMFromBootDisk creates a test VM with a new boot disk, after the
+// given step. The boot disk is named after the VM and must have its source set.
+func (t *TestWorkflow) createTestVMFromBootDisk(name string, bootDisk *daisy.Disk, after *daisy.Step) (*TestVM, error) {
+	t.counter++
+
+	bootDisk.Name = name
+	bootDisk.Type = DiskTypeNeeded(t.MachineType.Name)
+	createDisksStep, err := t.wf.NewStep(fmt.Sprintf("create-disks-%d", t.counter))
+	if err != nil {
+		return nil, err
+	}
+	createDisksStep.CreateDisks = &daisy.CreateDisks{bootDisk}
+	if err := t.wf.AddDependency(createDisksStep, after); err != nil {
+		return nil, err
+	}
+
+	createVMStep, i, err := t.addNewVMStep([]*compute.Disk{{Name: name}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	i.MachineType = t.MachineType.Name
+	if err := t.wf.AddDependency(createVMStep, createDisksStep); err != nil {
+		return nil, err
+	}
+
+	waitStep, err := t.addWaitStep(name, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.wf.AddDependency(waitStep, createVMStep); err != nil {
+		return nil, err
+	}
+
+	return &TestVM{name: name, testWorkflow: t, instance: i}, nil
+}
+
+// CreateTestVMs creates count test VMs booting from the derived image, named
+// with the given prefix and their index starting at 1.
+func (d *DerivedImage) CreateTestVMs(prefix string, count int) ([]*TestVM, error) {
+	var vms []*TestVM
+	for n := 1; n <= count; n++ {
+		vm, err := d.CreateTestVM(fmt.Sprintf("%s%d", prefix, n))
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+// Snapshot is a snapshot of the boot disk of a test VM created by
+// CreateSnapshot.
+type Snapshot struct {
+	name               string
+	createSnapshotStep *daisy.Step
+}
+
+// MachineImage is a machine image of a test VM created by CreateMachineImage.
+type MachineImage struct {
+	name                   string
+	createMachineImageStep *daisy.Step
+}
+
+// CreateSnapshot creates a snapshot with the given name of the boot disk of the
+// test VM once its tests have finished. The VM keeps running while the snapshot
+// is taken. If guestFlush is true, the guest agent runs the pre and post
+// snapshot scripts (VSS on Windows) for an application consistent snapshot.
+func (t *TestVM) CreateSnapshot(name string, guestFlush bool) (*Snapshot, error) {
+	t.testWorkflow.counter++
+	stepSuffix := fmt.Sprintf("%s-%d", t.name, t.testWorkflow.counter)
+
+	lastStep, err := t.testWorkflow.getLastStepForVM(t.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve last step")
+	}
+	snapshot := &daisy.Snapshot{}
+	snapshot.Name = name
+	snapshot.SourceDisk = t.name
+	snapshot.GuestFlush = guestFlush
+	createSnapshotStep, err := t.testWorkflow.wf.NewStep("create-snapshot-" + stepSuffix)
+	if err != nil {
+		return nil, err
+	}
+	createSnapshotStep.CreateSnapshots = &daisy.CreateSnapshots{snapshot}
+	if err := t.testWorkflow.wf.AddDependency(createSnapshotStep, lastStep); err != nil {
+		return nil, err
+	}
+	t.testWorkflow.createsSnapshots = true
+
+	return &Snapshot{name: name, createSnapshotStep: createSnapshotStep}, nil
+}
+
+// CreateMachineImage creates a machine image with the given name of the test
+// VM once its tests have finished. The VM keeps running while the machine image
+// is created. If guestFlush is true, the guest agent runs the pre and post
+// snapshot scripts (VSS on Windows) for an application consistent image.
+func (t *TestVM) CreateMachineImage(name string, guestFlush bool) (*MachineImage, error) {
+	t.testWorkflow.counter++
+	stepSuffix := fmt.Sprintf("%s-%d", t.name, t.testWorkflow.counter)
+
+	lastStep, err := t.testWorkflow.getLastStepForVM(t.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve last step")
+	}
+	machineImage := &daisy.MachineImage{}
+	machineImage.Name = name
+	machineImage.SourceInstance = t.name
+	machineImage.GuestFlush = guestFlush
+	createMachineImageStep, err := t.testWorkflow.wf.NewStep("create-machine-image-" + stepSuffix)
+	if err != nil {
+		return nil, err
+	}
+	createMachineImageStep.CreateMachineImages = &daisy.CreateMachineImages{machineImage}
+	if err := t.testWorkflow.wf.AddDependency(createMachineImageStep, lastStep); err != nil {
+		return nil, err
+	}
+	t.testWorkflow.createsMachineImages = true
+
+	return &MachineImage{name: name, createMachineImageStep: createMachineImageStep}, nil
+}
+
+// CreateTestVMFromSnapshot creates a test VM with a boot disk restored from the
+// snapshot.
+func (t *TestWorkflow) CreateTestVMFromSnapshot(snapshot *Snapshot, name string) (*TestVM, error) {
+	bootDisk := &daisy.Disk{}
+	bootDisk.SourceSnapshot = snapshot.name
+	return t.createTestVMFromBootDisk(name, bootDisk, snapshot.createSnapshotStep)
+}
+
+// CreateTestVMFromMachineImage creates a test VM restored from the machine
+// image. The disks of the VM come from the machine image, while the machine
+// type and test metadata are set by the test workflow as for any test VM.
+func (t *TestWorkflow) CreateTestVMFromMachineImage(machineImage *MachineImage, name string) (*TestVM, error) {
+	t.counter++
+
+	instance := &daisy.Instance{}
+	instance.SourceMachineImage = machineImage.name
+	instance.MachineType = t.MachineType.Name
+	createVMStep, i, err := t.addNewVMStepForInstance(name, instance)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.wf.AddDependency(createVMStep, machineImage.createMachineImageStep); err != nil {
+		return nil, err
+	}
+
+	waitStep, err := t.addWaitStep(name, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.wf.AddDependency(waitStep, createVMStep); err != nil {
+		return nil, err
+	}
+
+	return &TestVM{name: name, testWorkflow: t, instance: i}, nil
+}
+
 // AddDisk adds a given number of disks to the VM.
 func (t *TestVM) AddDisk(diskType string, initializeParams *compute.AttachedDiskInitializeParams) error {
 	// Empty string defaults to PERSISTENT.
@@ -963,6 +1219,9 @@ type Network struct {
 	name         string
 	testWorkflow *TestWorkflow
 	network      *daisy.Network
+	// project is the Shared VPC host project of the network. Empty for networks
+	// created by the workflow.
+	project string
 }
 
 // Subnetwork represent subnetwork used by vm in setup.go.
@@ -988,7 +1247,7 @@ func (t *TestWorkflow) CreateNetworkFromDaisyNetwork(net *daisy.Network) (*Netwo
 		}
 	}
 
-	return &Network{net.Name, t, network}, nil
+	return &Network{name: net.Name, testWorkflow: t, network: network}, nil
 }
 
 // CreateNetwork creates custom network. Using AddCustomNetwork method provided by
@@ -1013,7 +1272,7 @@ func (t *TestWorkflow) CreateNetwork(networkName string, autoCreateSubnetworks b
 		}
 	}
 
-	return &Network{networkName, t, network}, nil
+	return &Network{name: networkName, testWorkflow: t, network: network}, nil
 }
 
 // SetMTU sets the MTU of the network. The MTU must be between 1460 and 8896, inclusively.
@@ -1041,6 +1300,9 @@ func (n *Network) CreateSubnetwork(name string, ipRange string) (*Subnetwork, er
 // subnetwork. Callers that don't need to specify properties beyond ip ranges,
 // region, purpose, and role should call CreateSubnetwork instead.
 func (n *Network) CreateSubnetworkFromDaisySubnetwork(subnetwork *daisy.Subnetwork) (*Subnetwork, error) {
+	if n.project != "" {
+		return nil, fmt.Errorf("cannot create subnetwork %s in Shared VPC network %s", subnetwork.Name, n.name)
+	}
 	subnetwork.Network = n.name
 	createSubnetworksStep, subnetwork, err := n.testWorkflow.appendCreateSubnetworksStep(subnetwork)
 	if err != nil {
@@ -1143,12 +1405,16 @@ func (t *TestWorkflow) AddSSHKey(user string) (string, error) {
 
 // CreateFirewallRule create firewall rule. The firewall rule is created in the
 // given network as an ALLOW rule for the given protocol and ports. The ranges
-// are the source ranges from which the traffic is allowed.
+// are the source ranges from which the traffic is allowed. For Shared VPC
+// networks, the firewall rule is created in the host project.
 func (n *Network) CreateFirewallRule(firewallName, protocol string, ports, ranges []string) error {
-	createFirewallStep, _, err := n.testWorkflow.appendCreateFirewallStep(firewallName, n.name, protocol, ports, ranges)
+	createFirewallStep, firewall, err := n.testWorkflow.appendCreateFirewallStep(firewallName, n.name, protocol, ports, ranges)
 	if err != nil {
 		return err
 	}
+	if n.project != "" {
+		firewall.Project = n.project
+	}
 
 	createNetworkStep, ok := n.testWorkflow.wf.Steps[createNetworkStepName]
 	if ok {
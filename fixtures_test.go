@@ -15,6 +15,7 @@
 package imagetest
 
 import (
+	"fmt"
 	"slices"
 	"testing"
 
@@ -59,6 +60,25 @@ func TestAddMetadata(t *testing.T) {
 	}
 }
 
+// TestScaleTimeout tests that test VMs created after the workflow timeout is
+// scaled get the scaled timeout.
+func TestScaleTimeout(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	if err := twf.ScaleTimeout(0); err == nil {
+		t.Errorf("twf.ScaleTimeout(0) = nil, want error")
+	}
+	if err := twf.ScaleTimeout(3); err != nil {
+		t.Fatalf("twf.ScaleTimeout(3) = %v, want nil", err)
+	}
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	if got := tvm.instance.Metadata["_cit_timeout"]; got != "1h30m0s" {
+		t.Errorf("_cit_timeout = %q, want %q", got, "1h30m0s")
+	}
+}
+
 // TestReboot tests that *TestVM.Reboot succeeds and that the appropriate stop
 // and new final wait steps are created in the workflow.
 func TestReboot(t *testing.T) {
@@ -129,6 +149,138 @@ func TestCreateDerivativeVM(t *testing.T) {
 	}
 }
 
+// TestCreateDerivedImage tests that *TestVM.CreateDerivedImage captures an
+// image after the source VM stops, and that derived VMs boot from it.
+func TestCreateDerivedImage(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	image, err := tvm.CreateDerivedImage("derived")
+	if err != nil {
+		t.Fatalf("failed to create derived image: %v", err)
+	}
+	if tvm.instance.Metadata[utils.GuestFlushMetadataKey] != "true" {
+		t.Errorf("source vm metadata %s not set", utils.GuestFlushMetadataKey)
+	}
+	if !twf.createsImages {
+		t.Errorf("twf.createsImages = false, want true")
+	}
+	step, ok := twf.wf.Steps["create-image-vm-1"]
+	if !ok {
+		t.Fatalf("create-image-vm-1 step missing")
+	}
+	if !slices.Contains(twf.wf.Dependencies["create-image-vm-1"], "wait-stopped-vm-1") {
+		t.Errorf("create-image-vm-1 has deps %v, want a dependency on wait-stopped-vm-1", twf.wf.Dependencies["create-image-vm-1"])
+	}
+	if img := step.CreateImages.Images[0]; img.Name != "derived" || img.SourceDisk != "vm" {
+		t.Errorf("unexpected image: got %s from disk %s, want derived from disk vm", img.Name, img.SourceDisk)
+	}
+
+	vms, err := image.CreateTestVMs("derived", 2)
+	if err != nil {
+		t.Fatalf("failed to create derived vms: %v", err)
+	}
+	if len(vms) != 2 {
+		t.Fatalf("got %d derived vms, want 2", len(vms))
+	}
+	for i, vm := range vms {
+		counter := i + 2
+		disksStep := fmt.Sprintf("create-disks-%d", counter)
+		vmsStep := fmt.Sprintf("create-vms-%d", counter)
+		if want := fmt.Sprintf("derived%d", i+1); vm.name != want {
+			t.Errorf("unexpected derived vm name: got %s, want %s", vm.name, want)
+		}
+		disks, ok := twf.wf.Steps[disksStep]
+		if !ok {
+			t.Fatalf("%s step missing", disksStep)
+		}
+		if disk := (*disks.CreateDisks)[0]; disk.SourceImage != "derived" || disk.Name != vm.name {
+			t.Errorf("unexpected boot disk %s from image %s", disk.Name, disk.SourceImage)
+		}
+		if !slices.Contains(twf.wf.Dependencies[disksStep], "create-image-vm-1") {
+			t.Errorf("%s has deps %v, want a dependency on create-image-vm-1", disksStep, twf.wf.Dependencies[disksStep])
+		}
+		if !slices.Contains(twf.wf.Dependencies[vmsStep], disksStep) {
+			t.Errorf("%s has deps %v, want a dependency on %s", vmsStep, twf.wf.Dependencies[vmsStep], disksStep)
+		}
+		lastStep, err := twf.getLastStepForVM(vm.name)
+		if err != nil {
+			t.Errorf("failed to get last step for %s: %v", vm.name, err)
+		} else if lastStep.WaitForInstancesSignal == nil {
+			t.Errorf("last step for %s is not WaitForInstancesSignal", vm.name)
+		}
+	}
+}
+
+func TestCreateProbedVM(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	prober, err := twf.CreateTestVM("prober")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	probed, err := prober.CreateProbedVM("probed")
+	if err != nil {
+		t.Fatalf("failed to create probed vm: %v", err)
+	}
+	step, ok := twf.wf.Steps["wait-booted-prober"]
+	if !ok {
+		t.Fatalf("wait-booted-prober step missing")
+	}
+	if signal := (*step.WaitForInstancesSignal)[0]; signal.Name != "prober" || signal.SerialOutput.SuccessMatch != bootedMatch {
+		t.Errorf("unexpected wait-booted-prober signal: got instance %s matching %q, want prober matching %q", signal.Name, signal.SerialOutput.SuccessMatch, bootedMatch)
+	}
+	if !slices.Contains(twf.wf.Dependencies["wait-booted-prober"], createVMsStepName) {
+		t.Errorf("wait-booted-prober has deps %v, want a dependency on %s", twf.wf.Dependencies["wait-booted-prober"], createVMsStepName)
+	}
+	disks, ok := twf.wf.Steps["create-disks-1"]
+	if !ok {
+		t.Fatalf("create-disks-1 step missing")
+	}
+	if disk := (*disks.CreateDisks)[0]; disk.SourceImage != twf.ImageURL || disk.Name != probed.name {
+		t.Errorf("unexpected boot disk %s from image %s, want %s from image %s", disk.Name, disk.SourceImage, probed.name, twf.ImageURL)
+	}
+	if !slices.Contains(twf.wf.Dependencies["create-disks-1"], "wait-booted-prober") {
+		t.Errorf("create-disks-1 has deps %v, want a dependency on wait-booted-prober", twf.wf.Dependencies["create-disks-1"])
+	}
+	for _, vm := range []*TestVM{prober, probed} {
+		lastStep, err := twf.getLastStepForVM(vm.name)
+		if err != nil {
+			t.Errorf("failed to get last step for %s: %v", vm.name, err)
+		} else if lastStep != twf.wf.Steps["wait-"+vm.name] {
+			t.Errorf("last step for %s is not wait-%s", vm.name, vm.name)
+		}
+	}
+	if !slices.ContainsFunc(twf.testVMOutputs(), func(o testVMOutput) bool { return o.name == probed.name }) {
+		t.Errorf("testVMOutputs() = %+v, want the outputs of %s", twf.testVMOutputs(), probed.name)
+	}
+}
+
+// TestCreateProbedVMFromOwnCreateStep tests that a probed VM waits for a prober
+// VM created by its own create VM step, like a VM booting from a derived image.
+func TestCreateProbedVMFromOwnCreateStep(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	image, err := tvm.CreateDerivedImage("derived")
+	if err != nil {
+		t.Fatalf("failed to create derived image: %v", err)
+	}
+	prober, err := image.CreateTestVM("prober")
+	if err != nil {
+		t.Fatalf("failed to create derived vm: %v", err)
+	}
+	if _, err := prober.CreateProbedVM("probed"); err != nil {
+		t.Fatalf("failed to create probed vm: %v", err)
+	}
+	if deps := twf.wf.Dependencies["wait-booted-prober"]; !slices.Contains(deps, "create-vms-2") {
+		t.Errorf("wait-booted-prober has deps %v, want a dependency on create-vms-2", deps)
+	}
+}
+
 func TestResume(t *testing.T) {
 	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
 	tvm, err := twf.CreateTestVM("vm")
@@ -1026,3 +1178,95 @@ func TestMachineMaintenancePolicy(t *testing.T) {
 		})
 	}
 }
+
+// TestCreateSnapshotRoundTrip tests that a test VM can be restored from a
+// snapshot of another test VM.
+func TestCreateSnapshotRoundTrip(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	snapshot, err := tvm.CreateSnapshot("snap", true)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if !twf.createsSnapshots {
+		t.Errorf("twf.createsSnapshots = false, want true")
+	}
+	step, ok := twf.wf.Steps["create-snapshot-vm-1"]
+	if !ok {
+		t.Fatalf("create-snapshot-vm-1 step missing")
+	}
+	if !slices.Contains(twf.wf.Dependencies["create-snapshot-vm-1"], "wait-vm") {
+		t.Errorf("create-snapshot-vm-1 has deps %v, want a dependency on wait-vm", twf.wf.Dependencies["create-snapshot-vm-1"])
+	}
+	if s := (*step.CreateSnapshots)[0]; s.Name != "snap" || s.SourceDisk != "vm" || !s.GuestFlush {
+		t.Errorf("unexpected snapshot: got %s from disk %s with guest flush %t, want snap from disk vm with guest flush", s.Name, s.SourceDisk, s.GuestFlush)
+	}
+
+	restored, err := twf.CreateTestVMFromSnapshot(snapshot, "restored")
+	if err != nil {
+		t.Fatalf("failed to create vm from snapshot: %v", err)
+	}
+	disks, ok := twf.wf.Steps["create-disks-2"]
+	if !ok {
+		t.Fatalf("create-disks-2 step missing")
+	}
+	if disk := (*disks.CreateDisks)[0]; disk.SourceSnapshot != "snap" || disk.Name != restored.name {
+		t.Errorf("unexpected boot disk %s from snapshot %s", disk.Name, disk.SourceSnapshot)
+	}
+	if !slices.Contains(twf.wf.Dependencies["create-disks-2"], "create-snapshot-vm-1") {
+		t.Errorf("create-disks-2 has deps %v, want a dependency on create-snapshot-vm-1", twf.wf.Dependencies["create-disks-2"])
+	}
+	if _, err := twf.getLastStepForVM(restored.name); err != nil {
+		t.Errorf("failed to get last step for %s: %v", restored.name, err)
+	}
+	if !slices.ContainsFunc(twf.testVMOutputs(), func(o testVMOutput) bool { return o.name == restored.name }) {
+		t.Errorf("testVMOutputs() = %+v, want the outputs of %s", twf.testVMOutputs(), restored.name)
+	}
+}
+
+// TestCreateMachineImageRoundTrip tests that a test VM can be restored from a
+// machine image of another test VM.
+func TestCreateMachineImageRoundTrip(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	machineImage, err := tvm.CreateMachineImage("mi", false)
+	if err != nil {
+		t.Fatalf("failed to create machine image: %v", err)
+	}
+	if !twf.createsMachineImages {
+		t.Errorf("twf.createsMachineImages = false, want true")
+	}
+	step, ok := twf.wf.Steps["create-machine-image-vm-1"]
+	if !ok {
+		t.Fatalf("create-machine-image-vm-1 step missing")
+	}
+	if mi := (*step.CreateMachineImages)[0]; mi.Name != "mi" || mi.SourceInstance != "vm" {
+		t.Errorf("unexpected machine image: got %s from instance %s, want mi from instance vm", mi.Name, mi.SourceInstance)
+	}
+
+	restored, err := twf.CreateTestVMFromMachineImage(machineImage, "restored")
+	if err != nil {
+		t.Fatalf("failed to create vm from machine image: %v", err)
+	}
+	if restored.instance.SourceMachineImage != "mi" {
+		t.Errorf("restored.instance.SourceMachineImage = %q, want %q", restored.instance.SourceMachineImage, "mi")
+	}
+	if len(restored.instance.Disks) != 0 {
+		t.Errorf("restored vm has %d disks, want disks from the machine image", len(restored.instance.Disks))
+	}
+	if !slices.Contains(twf.wf.Dependencies["create-vms-2"], "create-machine-image-vm-1") {
+		t.Errorf("create-vms-2 has deps %v, want a dependency on create-machine-image-vm-1", twf.wf.Dependencies["create-vms-2"])
+	}
+	if _, err := twf.getLastStepForVM(restored.name); err != nil {
+		t.Errorf("failed to get last step for %s: %v", restored.name, err)
+	}
+	if !slices.ContainsFunc(twf.testVMOutputs(), func(o testVMOutput) bool { return o.name == restored.name }) {
+		t.Errorf("testVMOutputs() = %+v, want the outputs of %s", twf.testVMOutputs(), restored.name)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	daisyCompute "github.com/GoogleCloudPlatform/compute-daisy/compute"
+	"google.golang.org/api/compute/v1"
+)
+
+// imageFileExtensions are the extensions of the image files which can be
+// imported. Raw disks are archived as disk.raw in a tar.gz before the import,
+// like GCE expects.
+var imageFileExtensions = []string{".tar.gz", ".tgz", ".raw"}
+
+var invalidImageNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ImageImportOpts are the options of ImportImageFiles.
+type ImageImportOpts struct {
+	Client        daisyCompute.Client
+	StorageClient *storage.Client
+	// Project is the project the images are created in. Test projects must be
+	// able to use its images.
+	Project string
+	// GCSPath is where local image files are uploaded, in gs://[...] form. The
+	// daisy bucket of the project is used if it is empty.
+	GCSPath string
+	// Files are the local paths or gs:// URLs of the image files.
+	Files []string
+	// GuestOSFeatures are the guest OS features of the images, like GVNIC.
+	GuestOSFeatures []string
+	// Architecture is the architecture of the images, X86_64 or ARM64.
+	Architecture string
+}
+
+// ImageImport is a set of temporary images imported from image files by
+// ImportImageFiles.
+type ImageImport struct {
+	// ID suffixes the names of the images, so that they don't collide with
+	// the images of other imports of the same files.
+	ID      string
+	Project string
+	// Images are the partial URLs of the images, in the order of the files.
+	Images []string
+	// uploads are the gs:// URLs of the local image files uploaded to GCS, in
+	// the order of the files, empty for image files which were already in
+	// GCS or which failed to upload.
+	uploads       []string
+	client        daisyCompute.Client
+	storageClient *storage.Client
+}
+
+// ImportImageFiles uploads local image files to GCS and creates temporary
+// images from them, so that they can be tested before they are published.
+// Image files in GCS are imported in place. The images are named after the
+// files, so that tests depending on the image name still apply. Call Cleanup
+// to delete the images and the uploaded image files.
+func ImportImageFiles(ctx context.Context, opts *ImageImportOpts) (*ImageImport, error) {
+	imp := &ImageImport{ID: randomID(5), Project: opts.Project, client: opts.Client, storageClient: opts.StorageClient, uploads: make([]string, len(opts.Files))}
+	var uploadPrefix string
+	for _, file := range opts.Files {
+		if !slices.ContainsFunc(imageFileExtensions, func(ext string) bool { return strings.HasSuffix(file, ext) }) {
+			return nil, fmt.Errorf("image file %q must be one of %s", file, strings.Join(imageFileExtensions, ", "))
+		}
+		if strings.HasPrefix(file, "gs://") && strings.HasSuffix(file, ".raw") {
+			return nil, fmt.Errorf("image file %q in GCS must be a tar.gz archive", file)
+		}
+		if !strings.HasPrefix(file, "gs://") && uploadPrefix == "" {
+			prefix, err := getGCSPrefix(ctx, opts.StorageClient, opts.Project, opts.GCSPath)
+			if err != nil {
+				return nil, err
+			}
+			uploadPrefix = prefix + "/image_files"
+		}
+		imp.Images = append(imp.Images, fmt.Sprintf("projects/%s/global/images/%s", opts.Project, imageFileImageName(file, imp.ID)))
+	}
+
+	errs := make([]error, len(opts.Files))
+	var wg sync.WaitGroup
+	for i, file := range opts.Files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			source := file
+			if !strings.HasPrefix(file, "gs://") {
+				source = fmt.Sprintf("%s/%s.tar.gz", uploadPrefix, path.Base(imp.Images[i]))
+				log.Printf("Uploading image file %s to %s", file, source)
+				if err := uploadImageFile(ctx, opts.StorageClient, file, source); err != nil {
+					errs[i] = fmt.Errorf("failed to upload image file %s: %v", file, err)
+					return
+				}
+				imp.uploads[i] = source
+			}
+			image := &compute.Image{
+				Name:         path.Base(imp.Images[i]),
+				Description:  fmt.Sprintf("Imported by cloud-image-tests from %s", file),
+				Architecture: opts.Architecture,
+				RawDisk:      &compute.ImageRawDisk{Source: "https://storage.googleapis.com/" + strings.TrimPrefix(source, "gs://")},
+			}
+			for _, feature := range opts.GuestOSFeatures {
+				image.GuestOsFeatures = append(image.GuestOsFeatures, &compute.GuestOsFeature{Type: feature})
+			}
+			log.Printf("Creating image %s from %s", imp.Images[i], source)
+			if err := opts.Client.CreateImage(opts.Project, image); err != nil {
+				errs[i] = fmt.Errorf("failed to create image from %s: %v", file, err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			// Delete the images which were created.
+			imp.Cleanup()
+			return nil, err
+		}
+	}
+	return imp, nil
+}
+
+// Cleanup deletes the imported images and the image files uploaded to GCS,
+// returning the partial URLs of the deleted images, the gs:// URLs of the
+// deleted image files and the errors encountered. Image files which were
+// already in GCS are kept.
+func (i *ImageImport) Cleanup() ([]string, []error) {
+	var deleted []string
+	var errs []error
+	for _, image := range i.Images {
+		if err := i.client.DeleteImage(i.Project, path.Base(image)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete image %s: %v", image, err))
+			continue
+		}
+		deleted = append(deleted, image)
+	}
+	ctx := context.Background()
+	for _, upload := range i.uploads {
+		if upload == "" {
+			continue
+		}
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(upload, "gs://"), "/")
+		if err := i.storageClient.Bucket(bucket).Object(object).Delete(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete image file %s: %v", upload, err))
+			continue
+		}
+		deleted = append(deleted, upload)
+	}
+	return deleted, errs
+}
+
+// imageFileImageName returns the name of the image imported from an image
+// file, like debian-12-bookworm-v20260101-abcde for
+// gs://bucket/debian-12-bookworm-v20260101.tar.gz.
+func imageFileImageName(file, id string) string {
+	name := strings.ToLower(path.Base(file))
+	for _, ext := range imageFileExtensions {
+		name = strings.TrimSuffix(name, ext)
+	}
+	name = strings.Trim(invalidImageNameChars.ReplaceAllString(name, "-"), "-")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "image-" + name
+	}
+	// Image names are at most 63 characters long.
+	if maxLen := 63 - len(id) - 1; len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], "-")
+	}
+	return name + "-" + id
+}
+
+// uploadImageFile uploads a local image file to the gs:// URL dst, archiving
+// raw disks in a tar.gz.
+func uploadImageFile(ctx context.Context, storageClient *storage.Client, file, dst string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Cancelling the context before closing the writer discards the object.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	bucket, object, _ := strings.Cut(strings.TrimPrefix(dst, "gs://"), "/")
+	w := storageClient.Bucket(bucket).Object(object).NewWriter(ctx)
+	if strings.HasSuffix(file, ".raw") {
+		err = writeRawDiskArchive(w, f)
+	} else {
+		_, err = io.Copy(w, f)
+	}
+	if err != nil {
+		cancel()
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// writeRawDiskArchive writes the raw disk f as disk.raw in a tar.gz archive.
+func writeRawDiskArchive(w io.Writer, f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "disk.raw", Mode: 0644, Size: fi.Size(), Typeflag: tar.TypeReg, Format: tar.FormatGNU}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// randomID returns a random string of lowercase letters and digits, like the
+// IDs of daisy workflows.
+func randomID(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(b)
+}
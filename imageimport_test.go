@@ -0,0 +1,156 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	daisycompute "github.com/GoogleCloudPlatform/compute-daisy/compute"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+)
+
+func TestImageFileImageName(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "gs://bucket/debian-12-bookworm-v20260101.tar.gz", want: "debian-12-bookworm-v20260101-abcde"},
+		{file: "/tmp/out/RHEL_9.4_SAP.raw", want: "rhel-9-4-sap-abcde"},
+		{file: "2026-build.tgz", want: "image-2026-build-abcde"},
+		{file: strings.Repeat("a", 80) + ".raw", want: strings.Repeat("a", 57) + "-abcde"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			got := imageFileImageName(tc.file, "abcde")
+			if got != tc.want {
+				t.Errorf("imageFileImageName(%q) = %q, want %q", tc.file, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestImageImportCleanup tests that the imported images and the uploaded image
+// files are deleted by name, and image files which were already in GCS are
+// kept.
+func TestImageImportCleanup(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, r.Method+" "+r.URL.Path)
+	}
+	_, daisyFake, err := daisycompute.NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		if r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/projects/test-project/global/images/") {
+			fmt.Fprint(w, `{"Status":"DONE"}`)
+		} else {
+			w.WriteHeader(404)
+			fmt.Fprint(w, "URL and Method not recognized:", r.Method, r.URL)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	storageFake, err := storage.NewClient(context.Background(), option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imp := &ImageImport{
+		ID:      "abcde",
+		Project: "test-project",
+		Images: []string{
+			"projects/test-project/global/images/debian-12-abcde",
+			"projects/test-project/global/images/rhel-9-abcde",
+		},
+		uploads:       []string{"gs://bucket/prefix/image_files/debian-12-abcde.tar.gz", ""},
+		client:        daisyFake,
+		storageClient: storageFake,
+	}
+	deleted, errs := imp.Cleanup()
+	if len(errs) > 0 {
+		t.Errorf("Cleanup() returned errors: %v", errs)
+	}
+	wantDeleted := []string{
+		"projects/test-project/global/images/debian-12-abcde",
+		"projects/test-project/global/images/rhel-9-abcde",
+		"gs://bucket/prefix/image_files/debian-12-abcde.tar.gz",
+	}
+	if diff := cmp.Diff(wantDeleted, deleted); diff != "" {
+		t.Errorf("Cleanup() returned unexpected deleted resources (-want +got):\n%s", diff)
+	}
+	sort.Strings(got)
+	want := []string{
+		"DELETE /b/bucket/o/prefix/image_files/debian-12-abcde.tar.gz",
+		"DELETE /projects/test-project/global/images/debian-12-abcde",
+		"DELETE /projects/test-project/global/images/rhel-9-abcde",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Cleanup() sent unexpected requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteRawDiskArchive(t *testing.T) {
+	disk := filepath.Join(t.TempDir(), "disk.raw")
+	if err := os.WriteFile(disk, []byte("disk content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := writeRawDiskArchive(&buf, f); err != nil {
+		t.Fatalf("writeRawDiskArchive() = %v", err)
+	}
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "disk.raw" || string(content) != "disk content" {
+		t.Errorf("archive has %s with %q, want disk.raw with %q", hdr.Name, content, "disk content")
+	}
+}
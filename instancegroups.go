@@ -0,0 +1,282 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/migutils"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iterator"
+)
+
+// ManagedInstanceGroup is a managed instance group of test VMs running the
+// image under test. Daisy cannot create managed instance groups, so the group
+// is created by the wrapper on a controller test VM, which waits for every
+// member to upload its test results and deletes the group before finishing.
+// The results of the members are reported with the results of the workflow.
+// Members get half of the workflow timeout, so that the controller VM has time
+// to create and delete the group.
+//
+// Members are attached to the network of the controller VM, so tests which
+// need a custom network attach the controller VM to it. Tests which act on the
+// group, such as autohealing or rolling update tests, run on the controller VM
+// and use the migutils package.
+type ManagedInstanceGroup struct {
+	name         string
+	testWorkflow *TestWorkflow
+	controller   *TestVM
+	spec         *migutils.Spec
+	// metadata is the metadata of the members, converted to the instance
+	// template metadata when the workflow is finalized.
+	metadata map[string]string
+}
+
+// CreateManagedInstanceGroup creates a zonal managed instance group of size
+// test VMs, and its controller test VM with the given name. By default,
+// members run all tests and the controller VM runs none.
+func (t *TestWorkflow) CreateManagedInstanceGroup(name string, size int64) (*ManagedInstanceGroup, error) {
+	return t.createManagedInstanceGroup(name, size, false)
+}
+
+// CreateRegionalManagedInstanceGroup is like CreateManagedInstanceGroup, but
+// the group spreads its members across the zones of the region of the test
+// zone.
+func (t *TestWorkflow) CreateRegionalManagedInstanceGroup(name string, size int64) (*ManagedInstanceGroup, error) {
+	return t.createManagedInstanceGroup(name, size, true)
+}
+
+func (t *TestWorkflow) createManagedInstanceGroup(name string, size int64, regional bool) (*ManagedInstanceGroup, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("managed instance group %s must have at least one member", name)
+	}
+	controller, err := t.CreateTestVM(name)
+	if err != nil {
+		return nil, err
+	}
+	controller.AddScope("https://www.googleapis.com/auth/cloud-platform")
+	// An empty filter would run every test, including the tests of the
+	// members.
+	controller.RunTests("^$")
+
+	// Resource names end with the workflow ID so that leftovers are found by
+	// the workflow cleanup.
+	resourceName := fmt.Sprintf("%s-${ID}", name)
+	m := &ManagedInstanceGroup{
+		name:         name,
+		testWorkflow: t,
+		controller:   controller,
+		spec: &migutils.Spec{
+			Name:             resourceName,
+			Regional:         regional,
+			Size:             size,
+			BaseInstanceName: resourceName,
+			Template: &compute.InstanceTemplate{
+				Name: resourceName,
+				Properties: &compute.InstanceProperties{
+					Disks: []*compute.AttachedDisk{
+						{
+							Boot:             true,
+							AutoDelete:       true,
+							InitializeParams: &compute.AttachedDiskInitializeParams{SourceImage: t.ImageURL},
+						},
+					},
+					ServiceAccounts: []*compute.ServiceAccount{
+						{
+							Email:  "default",
+							Scopes: []string{"https://www.googleapis.com/auth/devstorage.read_write"},
+						},
+					},
+				},
+			},
+			// The trailing slash makes the wrapper on each member upload its
+			// results to an object named after the member.
+			ResultsURL: fmt.Sprintf("${OUTSPATH}/%s/", name),
+		},
+		metadata: make(map[string]string),
+	}
+	m.setTestMetadata()
+	t.managedInstanceGroups = append(t.managedInstanceGroups, m)
+	return m, nil
+}
+
+// setTestMetadata sets the metadata the wrapper needs to run tests on the
+// members, like setInstanceTestMetadata does for test VMs.
+func (m *ManagedInstanceGroup) setTestMetadata() {
+	t := m.testWorkflow
+	var suffix string
+	if utils.HasFeature(t.Image, "WINDOWS") {
+		suffix = ".exe"
+		m.metadata["windows-startup-script-url"] = "${SOURCESPATH}/wrapper.exe"
+	} else {
+		m.metadata["startup-script-url"] = "${SOURCESPATH}/wrapper"
+	}
+	m.metadata["_test_vmname"] = m.name
+	m.metadata["_test_package_url"] = "${SOURCESPATH}/testpackage"
+	m.metadata["_test_properties_url"] = fmt.Sprintf("${OUTSPATH}/properties/%s/", m.name)
+	m.metadata["_test_package_name"] = fmt.Sprintf("image_test%s", suffix)
+	m.metadata["_test_results_url"] = m.spec.ResultsURL
+	m.metadata["_test_suite_name"] = getTestSuiteName(t)
+	m.metadata["_compute_endpoint"] = t.wf.ComputeEndpoint
+	m.metadata["_cit_timeout"] = memberTimeout(t.wf.DefaultTimeout)
+	m.metadata["_exclude_discrete_tests"] = t.testExcludeFilter
+	m.metadata[exceptions.MetadataKey] = exceptions.FileData()
+	m.metadata[utils.GuestOSMetadataKey] = t.GuestOS.Metadata()
+	m.metadata["enable-guest-attributes"] = "TRUE"
+}
+
+// memberTimeout returns the test timeout of the members of managed instance
+// groups for the given workflow timeout. The controller VM creates the group,
+// waits for the results of the members and deletes the group within the
+// workflow timeout, so members get half of it.
+func memberTimeout(timeout string) string {
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return timeout
+	}
+	return (d / 2).String()
+}
+
+// Controller returns the controller test VM of the managed instance group.
+func (m *ManagedInstanceGroup) Controller() *TestVM {
+	return m.controller
+}
+
+// RunTests runs only the named tests on the members. See TestVM.RunTests.
+func (m *ManagedInstanceGroup) RunTests(runtest string) {
+	m.AddMetadata("_test_run", runtest)
+}
+
+// AddMetadata adds the specified key:value pair to the metadata of the
+// members.
+func (m *ManagedInstanceGroup) AddMetadata(key, value string) {
+	m.metadata[key] = value
+}
+
+// AddScope adds the specified auth scope to the service account of the
+// members.
+func (m *ManagedInstanceGroup) AddScope(scope string) {
+	sa := m.spec.Template.Properties.ServiceAccounts[0]
+	sa.Scopes = append(sa.Scopes, scope)
+}
+
+// SetAutoHealing enables autohealing of the members with a TCP health check on
+// the given port. Members must serve the port, and the network of the
+// controller VM must allow health checks from 130.211.0.0/22 and
+// 35.191.0.0/16. Autohealing starts after initialDelaySec seconds, which must
+// cover the boot of the members.
+func (m *ManagedInstanceGroup) SetAutoHealing(port, initialDelaySec int64) {
+	m.spec.HealthCheck = &compute.HealthCheck{
+		Name:               m.spec.Name,
+		Type:               "TCP",
+		TcpHealthCheck:     &compute.TCPHealthCheck{Port: port},
+		CheckIntervalSec:   10,
+		TimeoutSec:         5,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	}
+	m.spec.AutoHealingInitialDelaySec = initialDelaySec
+}
+
+// AddStatefulDisk adds a blank disk of sizeGB to the members, which is
+// preserved when members are recreated by autohealing or updates. The disk is
+// deleted with the group.
+func (m *ManagedInstanceGroup) AddStatefulDisk(deviceName string, sizeGB int64) {
+	props := m.spec.Template.Properties
+	props.Disks = append(props.Disks, &compute.AttachedDisk{
+		DeviceName:       deviceName,
+		AutoDelete:       true,
+		InitializeParams: &compute.AttachedDiskInitializeParams{DiskSizeGb: sizeGB},
+	})
+	m.spec.StatefulDisks = append(m.spec.StatefulDisks, deviceName)
+}
+
+// finalizeManagedInstanceGroups completes the instance templates of the
+// managed instance groups and passes their specs to the controller VMs.
+func (t *TestWorkflow) finalizeManagedInstanceGroups() error {
+	for _, m := range t.managedInstanceGroups {
+		props := m.spec.Template.Properties
+		if props.MachineType == "" {
+			props.MachineType = t.MachineType.Name
+		}
+		for _, disk := range props.Disks {
+			if disk.InitializeParams.DiskType == "" {
+				disk.InitializeParams.DiskType = DiskTypeNeeded(props.MachineType)
+			}
+		}
+
+		keys := make([]string, 0, len(m.metadata))
+		for k := range m.metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		props.Metadata = &compute.Metadata{}
+		for _, k := range keys {
+			v := m.metadata[k]
+			props.Metadata.Items = append(props.Metadata.Items, &compute.MetadataItems{Key: k, Value: &v})
+		}
+
+		spec, err := json.Marshal(m.spec)
+		if err != nil {
+			return fmt.Errorf("failed to encode managed instance group %s: %v", m.name, err)
+		}
+		m.controller.AddMetadata(migutils.SpecMetadataKey, string(spec))
+	}
+	return nil
+}
+
+// results returns the test results uploaded by the members of the managed
+// instance group. Members replaced during the test report their results too.
+func (m *ManagedInstanceGroup) results(ctx context.Context) ([]string, error) {
+	// Daisy substitutes the workflow variables of the spec in the controller
+	// VM metadata when it runs.
+	spec := &migutils.Spec{}
+	if err := json.Unmarshal([]byte(m.controller.instance.Metadata[migutils.SpecMetadataKey]), spec); err != nil {
+		return nil, fmt.Errorf("failed to decode managed instance group %s: %v", m.name, err)
+	}
+	u, err := url.Parse(spec.ResultsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results url of managed instance group %s: %v", m.name, err)
+	}
+	var results []string
+	it := client.Bucket(u.Host).Objects(ctx, &storage.Query{Prefix: strings.TrimPrefix(u.Path, "/")})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list results of managed instance group %s: %v", m.name, err)
+		}
+		out, err := utils.DownloadGCSObject(ctx, client, fmt.Sprintf("gs://%s/%s", attrs.Bucket, attrs.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get results of managed instance group %s member %s: %v", m.name, attrs.Name, err)
+		}
+		results = append(results, string(out))
+	}
+	if int64(len(results)) < spec.Size {
+		return nil, fmt.Errorf("got results of %d members of managed instance group %s, want %d", len(results), m.name, spec.Size)
+	}
+	return results, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/migutils"
+)
+
+// TestCreateManagedInstanceGroup tests that the managed instance group spec is
+// passed to the controller VM with the instance template of the members.
+func TestCreateManagedInstanceGroup(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	twf.ImageURL = "projects/test-project/global/images/test-image"
+	twf.MachineType.Name = "n1-standard-1"
+	mig, err := twf.CreateRegionalManagedInstanceGroup("mig", 3)
+	if err != nil {
+		t.Fatalf("failed to create managed instance group: %v", err)
+	}
+	mig.RunTests("TestMember")
+	mig.SetAutoHealing(80, 300)
+	mig.AddStatefulDisk("data", 10)
+	mig.Controller().RunTests("TestAutohealing")
+
+	if err := twf.finalizeManagedInstanceGroups(); err != nil {
+		t.Fatalf("twf.finalizeManagedInstanceGroups() = %v, want nil", err)
+	}
+	controller := mig.Controller().instance
+	if !slices.Contains(controller.Scopes, "https://www.googleapis.com/auth/cloud-platform") {
+		t.Errorf("controller scopes %v are missing cloud-platform", controller.Scopes)
+	}
+	if got := controller.Metadata["_cit_timeout"]; got != "30m" {
+		t.Errorf("controller _cit_timeout = %q, want %q", got, "30m")
+	}
+	if got := controller.Metadata["_test_run"]; got != "TestAutohealing" {
+		t.Errorf("controller _test_run = %q, want %q", got, "TestAutohealing")
+	}
+	spec := &migutils.Spec{}
+	if err := json.Unmarshal([]byte(controller.Metadata[migutils.SpecMetadataKey]), spec); err != nil {
+		t.Fatalf("failed to decode spec from controller metadata: %v", err)
+	}
+	if spec.Name != "mig-${ID}" || !spec.Regional || spec.Size != 3 {
+		t.Errorf("unexpected spec: name %q, regional %t, size %d", spec.Name, spec.Regional, spec.Size)
+	}
+	if spec.HealthCheck == nil || spec.HealthCheck.TcpHealthCheck.Port != 80 || spec.AutoHealingInitialDelaySec != 300 {
+		t.Errorf("unexpected autohealing: health check %v, delay %d", spec.HealthCheck, spec.AutoHealingInitialDelaySec)
+	}
+	if !slices.Equal(spec.StatefulDisks, []string{"data"}) {
+		t.Errorf("spec.StatefulDisks = %v, want [data]", spec.StatefulDisks)
+	}
+
+	props := spec.Template.Properties
+	if props.MachineType != "n1-standard-1" {
+		t.Errorf("template machine type = %q, want %q", props.MachineType, "n1-standard-1")
+	}
+	if len(props.Disks) != 2 {
+		t.Fatalf("template has %d disks, want 2", len(props.Disks))
+	}
+	if boot := props.Disks[0]; !boot.Boot || boot.InitializeParams.SourceImage != twf.ImageURL || boot.InitializeParams.DiskType == "" {
+		t.Errorf("unexpected boot disk: boot %t, image %q, type %q", boot.Boot, boot.InitializeParams.SourceImage, boot.InitializeParams.DiskType)
+	}
+	metadata := make(map[string]string)
+	for _, item := range props.Metadata.Items {
+		metadata[item.Key] = *item.Value
+	}
+	for k, want := range map[string]string{
+		"_test_run":          "TestMember",
+		"_cit_timeout":       "15m0s",
+		"_test_results_url":  "${OUTSPATH}/mig/",
+		"startup-script-url": "${SOURCESPATH}/wrapper",
+	} {
+		if metadata[k] != want {
+			t.Errorf("template metadata %s = %q, want %q", k, metadata[k], want)
+		}
+	}
+}
+
+// TestCreateManagedInstanceGroupEmpty tests that managed instance groups must
+// have members.
+func TestCreateManagedInstanceGroupEmpty(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	if _, err := twf.CreateManagedInstanceGroup("mig", 0); err == nil {
+		t.Errorf("twf.CreateManagedInstanceGroup(%q, 0) = nil, want error", "mig")
+	}
+}
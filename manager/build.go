@@ -0,0 +1,311 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"golang.org/x/sync/errgroup"
+)
+
+// buildArtifact is a binary the test workflows run, built from the source
+// tree.
+type buildArtifact struct {
+	// name is the name of the binary in the local path.
+	name string
+	// pkg is the package of the binary, relative to the source tree.
+	pkg string
+	// test indicates that the binary is the test binary of pkg.
+	test   bool
+	goos   string
+	goarch string
+}
+
+// workflowArtifacts returns the binaries run by the test workflows, sorted by
+// name.
+func workflowArtifacts(testWorkflows []*imagetest.TestWorkflow) []buildArtifact {
+	artifacts := make(map[string]buildArtifact)
+	for _, twf := range testWorkflows {
+		testPackage, wrapper, goos, goarch := twf.TestBinaries()
+		artifacts[testPackage] = buildArtifact{name: testPackage, pkg: "./test_suites/" + twf.Name, test: true, goos: goos, goarch: goarch}
+		artifacts[wrapper] = buildArtifact{name: wrapper, pkg: "./cmd/wrapper", goos: goos, goarch: goarch}
+	}
+	var sorted []buildArtifact
+	for _, a := range artifacts {
+		sorted = append(sorted, a)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+// buildTestBinaries builds the binaries run by the test workflows, and the
+// test lists of their suites, from the source tree srcDir to outDir. Only the
+// suites of the test workflows are built, for the platforms of their images.
+// Binaries are cached in cacheDir by the hash of the sources they are built
+// from.
+func buildTestBinaries(ctx context.Context, srcDir, cacheDir, outDir string, testWorkflows []*imagetest.TestWorkflow) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	goVersion, err := goCommand(ctx, srcDir, nil, "env", "GOVERSION")
+	if err != nil {
+		return err
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	// Each build runs about 3 busy processes, compile, vet and link.
+	g.SetLimit(max(1, (runtime.NumCPU()-1)/3))
+	for _, a := range workflowArtifacts(testWorkflows) {
+		g.Go(func() error {
+			return buildArtifactCached(ctx, srcDir, cacheDir, outDir, strings.TrimSpace(string(goVersion)), a)
+		})
+	}
+	suites := make(map[string]bool)
+	for _, twf := range testWorkflows {
+		if suites[twf.Name] {
+			continue
+		}
+		suites[twf.Name] = true
+		g.Go(func() error {
+			return writeTestList(filepath.Join(srcDir, "test_suites", twf.Name), filepath.Join(outDir, twf.Name+"_tests.txt"))
+		})
+	}
+	return g.Wait()
+}
+
+// buildArtifactCached copies the binary from the cache to outDir, building it
+// first if the cache does not have a binary built from the same sources.
+func buildArtifactCached(ctx context.Context, srcDir, cacheDir, outDir, goVersion string, a buildArtifact) error {
+	hash, err := artifactHash(ctx, srcDir, goVersion, a)
+	if err != nil {
+		return fmt.Errorf("failed to hash the sources of %s: %v", a.name, err)
+	}
+	cached := filepath.Join(cacheDir, hash, a.name)
+	if _, err := os.Stat(cached); err == nil {
+		log.Printf("Using cached %s", a.name)
+		return copyFile(cached, filepath.Join(outDir, a.name))
+	}
+
+	log.Printf("Building %s from %s for %s/%s", a.name, a.pkg, a.goos, a.goarch)
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return err
+	}
+	tmp := cached + ".tmp"
+	args := []string{"build", "-o", tmp, a.pkg}
+	if a.test {
+		args = []string{"test", "-c", "-tags", "cit", "-o", tmp, a.pkg}
+	}
+	if _, err := goCommand(ctx, srcDir, a.env(), args...); err != nil {
+		return fmt.Errorf("failed to build %s: %v", a.name, err)
+	}
+	if _, err := os.Stat(tmp); os.IsNotExist(err) {
+		// go test -c writes no binary for packages without tests on the
+		// platform, like suites which only test Linux.
+		log.Printf("%s has no tests for %s/%s", a.pkg, a.goos, a.goarch)
+		return nil
+	}
+	if err := os.Rename(tmp, cached); err != nil {
+		return err
+	}
+	return copyFile(cached, filepath.Join(outDir, a.name))
+}
+
+func (a buildArtifact) env() []string {
+	return []string{"CGO_ENABLED=0", "GOOS=" + a.goos, "GOARCH=" + a.goarch}
+}
+
+// listedPackage is the output of go list -json used to hash the sources of a
+// binary.
+type listedPackage struct {
+	ImportPath   string
+	Dir          string
+	Standard     bool
+	GoFiles      []string
+	EmbedFiles   []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Module       *struct {
+		Path    string
+		Version string
+		Main    bool
+	}
+}
+
+// artifactHash hashes the go version, the build of the binary, and the
+// sources of the packages it is built from. Packages of other modules are
+// hashed by their module version.
+func artifactHash(ctx context.Context, srcDir, goVersion string, a buildArtifact) (string, error) {
+	args := []string{"list", "-deps", "-json", "-tags", "cit"}
+	if a.test {
+		args = append(args, "-test")
+	}
+	out, err := goCommand(ctx, srcDir, a.env(), append(args, a.pkg)...)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %s/%s test=%v\n", goVersion, a.pkg, a.goos, a.goarch, a.test)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var p listedPackage
+		if err := dec.Decode(&p); err != nil {
+			return "", err
+		}
+		switch {
+		case strings.HasSuffix(p.ImportPath, ".test"):
+			// The generated test main package is derived from the test files.
+			continue
+		case p.Standard:
+			fmt.Fprintln(h, p.ImportPath)
+		case p.Module != nil && !p.Module.Main:
+			fmt.Fprintf(h, "%s %s@%s\n", p.ImportPath, p.Module.Path, p.Module.Version)
+		default:
+			for _, files := range [][]string{p.GoFiles, p.EmbedFiles, p.TestGoFiles, p.XTestGoFiles} {
+				for _, f := range files {
+					if err := hashFile(h, filepath.Join(p.Dir, f)); err != nil {
+						return "", err
+					}
+				}
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintln(w, path)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// goCommand runs the go command in dir with the additional environment
+// variables, returning its output.
+func goCommand(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s: %v: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return out, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeTestList writes the tests of the suite package in dir to path, one
+// per line, like the -test.list flag of the linux/amd64 test binary.
+func writeTestList(dir, path string) error {
+	tests, err := suiteTests(dir, "linux", "amd64")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, test := range tests {
+		b.WriteString(test + "\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// suiteTests returns the tests of the suite package in dir built for goos and
+// goarch with the cit tag, in the order of their files.
+func suiteTests(dir, goos, goarch string) ([]string, error) {
+	bctx := build.Default
+	bctx.GOOS, bctx.GOARCH = goos, goarch
+	bctx.CgoEnabled = false
+	bctx.BuildTags = []string{"cit"}
+	pkg, err := bctx.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the test files of %s: %v", dir, err)
+	}
+	fset := token.NewFileSet()
+	var tests []string
+	for _, file := range append(pkg.TestGoFiles, pkg.XTestGoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, file), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && isTestFunc(fn) {
+				tests = append(tests, fn.Name.Name)
+			}
+		}
+	}
+	return tests, nil
+}
+
+// isTestFunc returns whether the function is a test run by go test, like
+// func TestXxx(t *testing.T).
+func isTestFunc(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	if fn.Recv != nil || !strings.HasPrefix(name, "Test") {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(name[len("Test"):]); unicode.IsLower(r) {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "T"
+}
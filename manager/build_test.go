@@ -0,0 +1,91 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestWorkflowArtifacts(t *testing.T) {
+	windows := []*compute.GuestOsFeature{{Type: "WINDOWS"}}
+	testWorkflows := []*imagetest.TestWorkflow{
+		{Name: "suitea", Image: &compute.Image{Architecture: "X86_64"}, ImageURL: "projects/debian-cloud/global/images/family/debian-12"},
+		{Name: "suitea", Image: &compute.Image{Architecture: "ARM64"}, ImageURL: "projects/debian-cloud/global/images/family/debian-12-arm64"},
+		{Name: "suiteb", Image: &compute.Image{Architecture: "X86_64"}, ImageURL: "projects/debian-cloud/global/images/family/debian-12"},
+		{Name: "suiteb", Image: &compute.Image{GuestOsFeatures: windows}, ImageURL: "projects/windows-cloud/global/images/family/windows-2022"},
+	}
+	var got []string
+	for _, a := range workflowArtifacts(testWorkflows) {
+		got = append(got, a.name+" "+a.pkg+" "+a.goos+"/"+a.goarch)
+	}
+	want := []string{
+		"suitea.amd64.test ./test_suites/suitea linux/amd64",
+		"suitea.arm64.test ./test_suites/suitea linux/arm64",
+		"suiteb.amd64.test ./test_suites/suiteb linux/amd64",
+		"suiteb64.exe ./test_suites/suiteb windows/amd64",
+		"wrapp64.exe ./cmd/wrapper windows/amd64",
+		"wrapper.amd64 ./cmd/wrapper linux/amd64",
+		"wrapper.arm64 ./cmd/wrapper linux/arm64",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("workflowArtifacts() returned unexpected artifacts (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteTestList(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"setup.go": "package suite\n\nfunc TestSetup() {}\n",
+		"suite_test.go": `//go:build cit
+// +build cit
+
+package suite
+
+import "testing"
+
+func TestMain(m *testing.M) {}
+func TestB(t *testing.T) {}
+func TestA(t *testing.T) {}
+func Testlower(t *testing.T) {}
+func helperTest(t *testing.T) {}
+func BenchmarkA(b *testing.B) {}
+`,
+		"suite_windows_test.go": "//go:build cit\n\npackage suite\n\nimport \"testing\"\n\nfunc TestWindows(t *testing.T) {}\n",
+		"nocit_test.go":         "package suite\n\nimport \"testing\"\n\nfunc TestNotCIT(t *testing.T) {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "suite_tests.txt")
+	if err := writeTestList(dir, out); err != nil {
+		t.Fatalf("writeTestList() = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test files without the cit tag are built too, like with go test -tags cit.
+	if want := "TestNotCIT\nTestB\nTestA\n"; string(got) != want {
+		t.Errorf("writeTestList() wrote %q, want %q", got, want)
+	}
+}
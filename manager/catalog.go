@@ -0,0 +1,242 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/compute-daisy/compute"
+	"google.golang.org/api/option"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+// catalogCommand is the name of the subcommand printing the catalog.
+const catalogCommand = "catalog"
+
+// catalogSuite is the catalog entry of a registered test suite.
+type catalogSuite struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Tests       []string       `json:"tests"`
+	Flags       []catalogFlag  `json:"flags,omitempty"`
+	Images      []catalogImage `json:"images,omitempty"`
+}
+
+// catalogFlag is a manager flag interpreted by a test suite.
+type catalogFlag struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default string `json:"default"`
+}
+
+// catalogImage is whether a test suite runs on an image.
+type catalogImage struct {
+	Image      string `json:"image"`
+	Run        bool   `json:"run"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Failure is set when the exception skipping the suite has expired.
+	Failure string `json:"failure,omitempty"`
+}
+
+// catalogPlatforms are the platforms whose tests are listed in the catalog,
+// as GOOS/GOARCH pairs. Suites have tests specific to Linux or Windows.
+var catalogPlatforms = [][2]string{{"linux", "amd64"}, {"windows", "amd64"}}
+
+// catalog prints the registered test suites matching the -filter and -exclude
+// flags, with their tests, flags and description, and whether they run on each
+// of the -images. Tests are parsed from the suite sources in the -build_from
+// source tree, or the current directory.
+func catalog() error {
+	filterRegex, excludeRegex, err := suiteRegexes()
+	if err != nil {
+		return err
+	}
+
+	fmtImages, imgs, err := resolveImages()
+	if err != nil {
+		return err
+	}
+
+	srcDir := *buildFrom
+	if srcDir == "" {
+		srcDir = "."
+	}
+	var suites []catalogSuite
+	for _, suite := range imagetest.RegisteredSuites() {
+		if !suiteSelected(suite.Name, filterRegex, excludeRegex) {
+			continue
+		}
+		tests, err := catalogTests(filepath.Join(srcDir, "test_suites", suite.Name))
+		if err != nil {
+			log.Printf("Could not list the tests of suite %s: %v", suite.Name, err)
+		}
+		cs := catalogSuite{Name: suite.Name, Description: suite.Metadata.Description, Tests: tests}
+		// Suites prefix the names of their flags with the suite name.
+		flag.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, suite.Name+"_") {
+				cs.Flags = append(cs.Flags, catalogFlag{Name: f.Name, Usage: f.Usage, Default: f.DefValue})
+			}
+		})
+		for idx, i := range imgs {
+			ci := catalogImage{Image: fmtImages[idx]}
+			if reason, err := suite.SkipReason(i); err != nil {
+				ci.Failure = err.Error()
+			} else {
+				ci.Run, ci.SkipReason = reason == "", reason
+			}
+			cs.Images = append(cs.Images, ci)
+		}
+		suites = append(suites, cs)
+	}
+
+	switch *catalogFormat {
+	case "json":
+		return writeCatalogJSON(os.Stdout, suites)
+	case "table":
+		return writeCatalogTable(os.Stdout, suites, fmtImages)
+	default:
+		return fmt.Errorf("unknown catalog format %q, must be table or json", *catalogFormat)
+	}
+}
+
+// catalogTests returns the tests of the suite package in dir on every catalog
+// platform, without duplicates.
+func catalogTests(dir string) ([]string, error) {
+	var tests []string
+	for _, p := range catalogPlatforms {
+		platformTests, err := suiteTests(dir, p[0], p[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, test := range platformTests {
+			if !slices.Contains(tests, test) {
+				tests = append(tests, test)
+			}
+		}
+	}
+	return tests, nil
+}
+
+// suiteRegexes compiles the -filter and -exclude flags of the subcommands
+// selecting test suites. The regexes are nil if the flags are not set.
+func suiteRegexes() (filterRegex, excludeRegex *regexp.Regexp, err error) {
+	if *filter != "" {
+		if filterRegex, err = regexp.Compile(*filter); err != nil {
+			return nil, nil, fmt.Errorf("-filter flag not valid: %v", err)
+		}
+	}
+	if *exclude != "" {
+		if excludeRegex, err = regexp.Compile(*exclude); err != nil {
+			return nil, nil, fmt.Errorf("-exclude flag not valid: %v", err)
+		}
+	}
+	return filterRegex, excludeRegex, nil
+}
+
+// resolveImages gets the -images, returning their formatted names and the
+// images they resolve to.
+func resolveImages() ([]string, []*computev1.Image, error) {
+	var fmtImages []string
+	var imgs []*computev1.Image
+	if *images == "" {
+		return nil, nil, nil
+	}
+	ctx := context.Background()
+	var opts []option.ClientOption
+	if *computeEndpointOverride != "" {
+		opts = append(opts, option.WithEndpoint(*computeEndpointOverride))
+	}
+	computeclient, err := compute.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create compute client: %v", err)
+	}
+	imageList := strings.Split(*images, ",")
+	if isImageSelector(*images) {
+		if imageList, err = resolveImageSelectors(computeclient, *images); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, image := range imageList {
+		if image == "" {
+			continue
+		}
+		fmtImage, err := formatImageName(image)
+		if err != nil {
+			return nil, nil, err
+		}
+		i, err := getImage(computeclient, fmtImage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get image %q: %w", fmtImage, err)
+		}
+		fmtImages = append(fmtImages, fmtImage)
+		imgs = append(imgs, i)
+	}
+	return fmtImages, imgs, nil
+}
+
+func writeCatalogJSON(w io.Writer, suites []catalogSuite) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(suites)
+}
+
+// writeCatalogTable writes one row per suite, with a column per image.
+func writeCatalogTable(w io.Writer, suites []catalogSuite, images []string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := []string{"SUITE", "DESCRIPTION", "TESTS", "FLAGS"}
+	for _, image := range images {
+		header = append(header, image[strings.LastIndex(image, "/")+1:])
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, s := range suites {
+		var flags []string
+		for _, f := range s.Flags {
+			flags = append(flags, "-"+f.Name)
+		}
+		row := []string{s.Name, s.Description, orNone(strings.Join(s.Tests, ",")), orNone(strings.Join(flags, ","))}
+		for _, i := range s.Images {
+			switch {
+			case i.Failure != "":
+				row = append(row, "fail: "+i.Failure)
+			case i.Run:
+				row = append(row, "run")
+			default:
+				row = append(row, "skip: "+i.SkipReason)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteCatalogTable(t *testing.T) {
+	suites := []catalogSuite{
+		{
+			Name:        "suitea",
+			Description: "Tests a.",
+			Tests:       []string{"TestA", "TestB"},
+			Flags:       []catalogFlag{{Name: "suitea_filter"}},
+			Images:      []catalogImage{{Image: "projects/debian-cloud/global/images/family/debian-12", Run: true}},
+		},
+		{
+			Name:        "suiteb",
+			Description: "Tests b.",
+			Images:      []catalogImage{{Image: "projects/debian-cloud/global/images/family/debian-12", SkipReason: "suiteb only supports OS families windows"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeCatalogTable(&buf, suites, []string{"projects/debian-cloud/global/images/family/debian-12"}); err != nil {
+		t.Fatalf("writeCatalogTable() = %v, want nil", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := [][]string{
+		{"SUITE", "DESCRIPTION", "TESTS", "FLAGS", "debian-12"},
+		{"suitea", "Tests a.", "TestA,TestB", "-suitea_filter", "run"},
+		{"suiteb", "Tests b.", "-", "-", "skip: suiteb only supports OS families windows"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("writeCatalogTable() wrote %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		for _, field := range want[i] {
+			if !strings.Contains(line, field) {
+				t.Errorf("line %d %q does not contain %q", i, line, field)
+			}
+		}
+	}
+}
+
+// TestCatalogTests tests that the catalog lists the tests of every platform
+// from the suite sources.
+func TestCatalogTests(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"setup.go":              "package suite\n\nfunc TestSetup() {}\n",
+		"suite_test.go":         "package suite\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n",
+		"suite_linux_test.go":   "package suite\n\nimport \"testing\"\n\nfunc TestLinux(t *testing.T) {}\nfunc TestShared(t *testing.T) {}\n",
+		"suite_windows_test.go": "package suite\n\nimport \"testing\"\n\nfunc TestWindows(t *testing.T) {}\nfunc TestShared(t *testing.T) {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := catalogTests(dir)
+	if err != nil {
+		t.Fatalf("catalogTests() = %v, want nil", err)
+	}
+	want := []string{"TestLinux", "TestShared", "TestA", "TestWindows"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("catalogTests() returned unexpected tests (-want +got):\n%s", diff)
+	}
+	if _, err := catalogTests(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("catalogTests() for a missing suite = nil, want error")
+	}
+}
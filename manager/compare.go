@@ -0,0 +1,331 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/jstemmer/go-junit-report/v2/junit"
+)
+
+// imagePair is a candidate image and the baseline image its results are
+// compared with.
+type imagePair struct {
+	candidate string
+	baseline  string
+}
+
+// pairBaselineImages pairs each candidate image with the baseline image at the
+// same position, or with the only baseline image. Images are formatted with
+// formatImageName.
+func pairBaselineImages(candidates, baselines []string) ([]imagePair, error) {
+	if len(baselines) != 1 && len(baselines) != len(candidates) {
+		return nil, fmt.Errorf("got %d baseline images for %d images, want 1 or one per image", len(baselines), len(candidates))
+	}
+	var pairs []imagePair
+	for i, candidate := range candidates {
+		baseline := baselines[0]
+		if len(baselines) > 1 {
+			baseline = baselines[i]
+		}
+		if candidate == baseline {
+			return nil, fmt.Errorf("image %s is its own baseline", candidate)
+		}
+		pairs = append(pairs, imagePair{candidate: candidate, baseline: baseline})
+	}
+	// Results are named after the last element of the image path, so
+	// different images must not share it.
+	names := make(map[string]string)
+	for _, p := range pairs {
+		for _, image := range []string{p.candidate, p.baseline} {
+			name := path.Base(image)
+			if other, ok := names[name]; ok && other != image {
+				return nil, fmt.Errorf("images %s and %s cannot be compared, their results would have the same name %s", other, image, name)
+			}
+			names[name] = image
+		}
+	}
+	return pairs, nil
+}
+
+// imageComparison is the comparison of the results of the test suites on a
+// candidate image with their results on its baseline image.
+type imageComparison struct {
+	imagePair
+	tests []testComparison
+}
+
+// testComparison is the result of a test on a baseline and a candidate image.
+type testComparison struct {
+	suite     string
+	test      string
+	baseline  string
+	candidate string
+	metrics   []metricDelta
+}
+
+// regression returns whether the test passes on the baseline image but fails
+// or did not run on the candidate image.
+func (c testComparison) regression() bool {
+	return c.baseline == "pass" && (c.candidate == "fail" || c.candidate == "missing")
+}
+
+// metricDelta is a metric measured by a test on both images.
+type metricDelta struct {
+	name          string
+	unit          string
+	baseline      float64
+	candidate     float64
+	lowerIsBetter bool
+}
+
+// direction returns whether the candidate value is better or worse than the
+// baseline value, or "" if they are equal.
+func (d metricDelta) direction() string {
+	switch {
+	case d.candidate == d.baseline:
+		return ""
+	case (d.candidate < d.baseline) == d.lowerIsBetter:
+		return "better"
+	default:
+		return "worse"
+	}
+}
+
+func (d metricDelta) String() string {
+	s := fmt.Sprintf("%s: %g -> %g", d.name, d.baseline, d.candidate)
+	if d.unit != "" {
+		s += " " + d.unit
+	}
+	var change []string
+	if d.baseline != 0 {
+		change = append(change, fmt.Sprintf("%+.1f%%", (d.candidate-d.baseline)/math.Abs(d.baseline)*100))
+	}
+	if direction := d.direction(); direction != "" {
+		change = append(change, direction)
+	}
+	if len(change) > 0 {
+		s += " (" + strings.Join(change, ", ") + ")"
+	}
+	return s
+}
+
+// compareResults compares the results of the test workflows on each candidate
+// image with the results of the same test suites on its baseline image. Suites
+// which did not run on both images are left out.
+func compareResults(pairs []imagePair, testWorkflows []*imagetest.TestWorkflow, suites junit.Testsuites) []imageComparison {
+	results := make(map[string]junit.Testsuite)
+	for _, s := range suites.Suites {
+		results[s.Name] = s
+	}
+	workflows := make(map[string]*imagetest.TestWorkflow)
+	for _, twf := range testWorkflows {
+		workflows[fmt.Sprintf("%s %s %d", twf.Name, twf.ImageURL, twf.Sample)] = twf
+	}
+
+	var comparisons []imageComparison
+	for _, p := range pairs {
+		c := imageComparison{imagePair: p}
+		for _, twf := range testWorkflows {
+			if twf.ImageURL != p.candidate {
+				continue
+			}
+			baselineWorkflow, ok := workflows[fmt.Sprintf("%s %s %d", twf.Name, p.baseline, twf.Sample)]
+			if !ok {
+				continue
+			}
+			candidate, ok := results[twf.ResultName()]
+			if !ok {
+				continue
+			}
+			baseline, ok := results[baselineWorkflow.ResultName()]
+			if !ok {
+				continue
+			}
+			c.tests = append(c.tests, compareTestsuites(twf.Name, baseline, candidate)...)
+		}
+		sort.Slice(c.tests, func(i, j int) bool {
+			if c.tests[i].suite != c.tests[j].suite {
+				return c.tests[i].suite < c.tests[j].suite
+			}
+			return c.tests[i].test < c.tests[j].test
+		})
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// compareTestsuites compares the test cases of a suite on both images. Tests
+// which only ran on one image are compared with a missing result.
+func compareTestsuites(suite string, baseline, candidate junit.Testsuite) []testComparison {
+	var tests []testComparison
+	baselineCases := make(map[string]junit.Testcase)
+	for _, tc := range baseline.Testcases {
+		baselineCases[tc.Name] = tc
+	}
+	baselineMetrics, candidateMetrics := testMetrics(baseline), testMetrics(candidate)
+	seen := make(map[string]bool)
+	for _, tc := range candidate.Testcases {
+		seen[tc.Name] = true
+		c := testComparison{suite: suite, test: tc.Name, baseline: "missing", candidate: testcaseStatus(tc)}
+		if btc, ok := baselineCases[tc.Name]; ok {
+			c.baseline = testcaseStatus(btc)
+			c.metrics = compareMetrics(baselineMetrics[tc.Name], candidateMetrics[tc.Name])
+		}
+		tests = append(tests, c)
+	}
+	for _, tc := range baseline.Testcases {
+		if !seen[tc.Name] {
+			tests = append(tests, testComparison{suite: suite, test: tc.Name, baseline: testcaseStatus(tc), candidate: "missing"})
+		}
+	}
+	return tests
+}
+
+// testMetrics returns the metrics recorded by the tests of the suite, by test.
+func testMetrics(ts junit.Testsuite) map[string][]utils.Metric {
+	metrics := make(map[string][]utils.Metric)
+	for _, m := range imagetest.ResultMetrics(ts) {
+		metrics[m.Test] = append(metrics[m.Test], m)
+	}
+	return metrics
+}
+
+// compareMetrics returns the metrics recorded on both images, in the order
+// they were recorded on the candidate image.
+func compareMetrics(baselineMetrics, candidateMetrics []utils.Metric) []metricDelta {
+	baseline := make(map[string]utils.Metric)
+	for _, m := range baselineMetrics {
+		baseline[m.Key()] = m
+	}
+	var deltas []metricDelta
+	for _, m := range candidateMetrics {
+		if b, ok := baseline[m.Key()]; ok && b.Unit == m.Unit {
+			deltas = append(deltas, metricDelta{name: m.Key(), unit: m.Unit, baseline: b.Value, candidate: m.Value, lowerIsBetter: m.LowerIsBetter()})
+		}
+	}
+	return deltas
+}
+
+func testcaseStatus(tc junit.Testcase) string {
+	switch {
+	case tc.Failure != nil, tc.Error != nil:
+		return "fail"
+	case tc.Skipped != nil:
+		return "skip"
+	default:
+		return "pass"
+	}
+}
+
+// regressions returns the number of tests which regressed on the candidate
+// image.
+func (c imageComparison) regressions() int {
+	regressions := 0
+	for _, t := range c.tests {
+		if t.regression() {
+			regressions++
+		}
+	}
+	return regressions
+}
+
+// missingRegressions returns the number of tests which pass on the baseline
+// images but did not run on the candidate images. Unlike the failed tests,
+// they are not counted by candidateFailures.
+func missingRegressions(comparisons []imageComparison) int {
+	missing := 0
+	for _, c := range comparisons {
+		for _, t := range c.tests {
+			if t.regression() && t.candidate == "missing" {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// writeComparison writes the results of each candidate image side by side with
+// the results of its baseline image. Tests which pass on the baseline image but
+// fail or did not run on the candidate image are marked as regressions, and
+// metrics measured on both images are shown with their delta and whether the
+// candidate is better or worse.
+func writeComparison(w io.Writer, comparisons []imageComparison) error {
+	for i, c := range comparisons {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Candidate %s vs baseline %s: %d regressions\n", c.candidate, c.baseline, c.regressions())
+		if len(c.tests) == 0 {
+			fmt.Fprintln(w, "No test suite ran on both images.")
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "SUITE\tTEST\tBASELINE\tCANDIDATE\tCHANGE\tMETRICS")
+		for _, t := range c.tests {
+			var change string
+			switch {
+			case t.regression():
+				change = "REGRESSION"
+			case t.baseline == "fail" && t.candidate == "pass":
+				change = "fixed"
+			}
+			var metrics []string
+			for _, m := range t.metrics {
+				metrics = append(metrics, m.String())
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.suite, t.test, t.baseline, t.candidate, orNone(change), orNone(strings.Join(metrics, ", ")))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// candidateFailures returns the number of failed tests and errors of the test
+// suites, leaving out the suites which only ran on baseline images.
+func candidateFailures(pairs []imagePair, testWorkflows []*imagetest.TestWorkflow, suites junit.Testsuites) int {
+	candidates := make(map[string]bool)
+	for _, p := range pairs {
+		candidates[p.candidate] = true
+	}
+	baselineOnly := make(map[string]bool)
+	for _, p := range pairs {
+		if candidates[p.baseline] {
+			continue
+		}
+		for _, twf := range testWorkflows {
+			if twf.ImageURL == p.baseline {
+				baselineOnly[twf.ResultName()] = true
+			}
+		}
+	}
+	failures := 0
+	for _, s := range suites.Suites {
+		if !baselineOnly[s.Name] {
+			failures += s.Failures + s.Errors
+		}
+	}
+	return failures
+}
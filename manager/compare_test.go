@@ -0,0 +1,207 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/jstemmer/go-junit-report/v2/junit"
+)
+
+const (
+	candidateImage = "projects/my-project/global/images/debian-12-bookworm-v20260101-abcde"
+	baselineImage  = "projects/debian-cloud/global/images/family/debian-12"
+)
+
+func TestPairBaselineImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		baselines  []string
+		want       []imagePair
+		wantErr    bool
+	}{
+		{
+			name:       "one baseline per image",
+			candidates: []string{"projects/p/global/images/a", "projects/p/global/images/b"},
+			baselines:  []string{"projects/p/global/images/c", "projects/p/global/images/d"},
+			want:       []imagePair{{"projects/p/global/images/a", "projects/p/global/images/c"}, {"projects/p/global/images/b", "projects/p/global/images/d"}},
+		},
+		{
+			name:       "single baseline",
+			candidates: []string{"projects/p/global/images/a", "projects/p/global/images/b"},
+			baselines:  []string{"projects/p/global/images/c"},
+			want:       []imagePair{{"projects/p/global/images/a", "projects/p/global/images/c"}, {"projects/p/global/images/b", "projects/p/global/images/c"}},
+		},
+		{
+			name:       "count mismatch",
+			candidates: []string{"projects/p/global/images/a", "projects/p/global/images/b", "projects/p/global/images/c"},
+			baselines:  []string{"projects/p/global/images/d", "projects/p/global/images/e"},
+			wantErr:    true,
+		},
+		{
+			name:       "own baseline",
+			candidates: []string{"projects/p/global/images/a"},
+			baselines:  []string{"projects/p/global/images/a"},
+			wantErr:    true,
+		},
+		{
+			name:       "same result name",
+			candidates: []string{"projects/p/global/images/family/debian-12"},
+			baselines:  []string{"projects/q/global/images/family/debian-12"},
+			wantErr:    true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pairBaselineImages(tc.candidates, tc.baselines)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("pairBaselineImages(%v, %v) = %v, want error: %v", tc.candidates, tc.baselines, err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("pairBaselineImages(%v, %v) = %v, want %v", tc.candidates, tc.baselines, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("pairBaselineImages(%v, %v) = %v, want %v", tc.candidates, tc.baselines, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func comparisonFixture() ([]imagePair, []*imagetest.TestWorkflow, junit.Testsuites) {
+	pairs := []imagePair{{candidate: candidateImage, baseline: baselineImage}}
+	testWorkflows := []*imagetest.TestWorkflow{
+		{Name: "imageboot", ImageURL: candidateImage},
+		{Name: "imageboot", ImageURL: baselineImage},
+		{Name: "networkperf", ImageURL: candidateImage},
+	}
+	suites := junit.Testsuites{Suites: []junit.Testsuite{
+		{
+			Name:     "imageboot-debian-12-bookworm-v20260101-abcde",
+			Failures: 1,
+			Testcases: []junit.Testcase{
+				{Name: "TestGuestBoot", SystemOut: &junit.Output{Data: "image_boot_test.go:90: Guest booted successfully"}},
+				{Name: "TestBootTime", Failure: &junit.Result{Data: "[FAILED] Boot time of 25 is greater than limit of 20"}},
+				{Name: "TestGuestSecureBoot", Skipped: &junit.Result{Data: "secure boot is not supported"}},
+			},
+		},
+		{
+			Name:     "imageboot-debian-12",
+			Failures: 1,
+			Testcases: []junit.Testcase{
+				{Name: "TestGuestBoot", Failure: &junit.Result{Data: "guest did not boot"}},
+				{Name: "TestBootTime", SystemOut: &junit.Output{Data: "metric boot_time = 20 s"}},
+				{Name: "TestGuestReboot", SystemOut: &junit.Output{Data: "marker file exist"}},
+			},
+		},
+		{
+			Name:     "networkperf-debian-12-bookworm-v20260101-abcde",
+			Failures: 1,
+			Testcases: []junit.Testcase{
+				{Name: "TestNetworkPerformance", Failure: &junit.Result{Data: "Did not meet performance expectation"}},
+			},
+		},
+	}}
+	suites.Suites[0].AddProperty("metric", `{"test":"TestBootTime","name":"boot_time","value":25,"unit":"s"}`)
+	suites.Suites[1].AddProperty("metric", `{"test":"TestBootTime","name":"boot_time","value":20,"unit":"s"}`)
+	suites.Suites[1].AddProperty("metric", `{"test":"TestGuestReboot","name":"reboot_time","value":30,"unit":"s"}`)
+	return pairs, testWorkflows, suites
+}
+
+func TestCompareResults(t *testing.T) {
+	pairs, testWorkflows, suites := comparisonFixture()
+	comparisons := compareResults(pairs, testWorkflows, suites)
+	if len(comparisons) != 1 {
+		t.Fatalf("compareResults() returned %d comparisons, want 1", len(comparisons))
+	}
+	want := []struct {
+		test       string
+		baseline   string
+		candidate  string
+		regression bool
+		metrics    string
+	}{
+		{test: "TestBootTime", baseline: "pass", candidate: "fail", regression: true, metrics: "boot_time: 20 -> 25 s (+25.0%, worse)"},
+		{test: "TestGuestBoot", baseline: "fail", candidate: "pass"},
+		{test: "TestGuestReboot", baseline: "pass", candidate: "missing", regression: true},
+		{test: "TestGuestSecureBoot", baseline: "missing", candidate: "skip"},
+	}
+	got := comparisons[0].tests
+	if len(got) != len(want) {
+		t.Fatalf("compareResults() compared %d tests, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		var metrics []string
+		for _, m := range got[i].metrics {
+			metrics = append(metrics, m.String())
+		}
+		if got[i].suite != "imageboot" || got[i].test != w.test || got[i].baseline != w.baseline || got[i].candidate != w.candidate || got[i].regression() != w.regression || strings.Join(metrics, ", ") != w.metrics {
+			t.Errorf("compareResults() test %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	pairs, testWorkflows, suites := comparisonFixture()
+	var b strings.Builder
+	if err := writeComparison(&b, compareResults(pairs, testWorkflows, suites)); err != nil {
+		t.Fatalf("writeComparison() = %v, want nil", err)
+	}
+	for _, want := range []string{
+		"Candidate " + candidateImage + " vs baseline " + baselineImage + ": 2 regressions",
+		"REGRESSION",
+		"fixed",
+		"boot_time: 20 -> 25 s (+25.0%, worse)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("writeComparison() wrote %q, want it to contain %q", b.String(), want)
+		}
+	}
+}
+
+func TestMetricDelta(t *testing.T) {
+	tests := []struct {
+		delta metricDelta
+		want  string
+	}{
+		{delta: metricDelta{name: "boot_time", unit: "s", baseline: 20, candidate: 15, lowerIsBetter: true}, want: "boot_time: 20 -> 15 s (-25.0%, better)"},
+		{delta: metricDelta{name: "throughput", unit: "Gbps", baseline: 20, candidate: 15}, want: "throughput: 20 -> 15 Gbps (-25.0%, worse)"},
+		{delta: metricDelta{name: "throughput", unit: "Gbps", baseline: 20, candidate: 20}, want: "throughput: 20 -> 20 Gbps (+0.0%)"},
+		{delta: metricDelta{name: "retransmits", unit: "retransmits", baseline: 0, candidate: 3, lowerIsBetter: true}, want: "retransmits: 0 -> 3 retransmits (worse)"},
+	}
+	for _, tc := range tests {
+		if got := tc.delta.String(); got != tc.want {
+			t.Errorf("metricDelta.String() = %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestMissingRegressions(t *testing.T) {
+	pairs, testWorkflows, suites := comparisonFixture()
+	if got := missingRegressions(compareResults(pairs, testWorkflows, suites)); got != 1 {
+		t.Errorf("missingRegressions() = %d, want 1", got)
+	}
+}
+
+func TestCandidateFailures(t *testing.T) {
+	pairs, testWorkflows, suites := comparisonFixture()
+	if got := candidateFailures(pairs, testWorkflows, suites); got != 2 {
+		t.Errorf("candidateFailures() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
+)
+
+// exceptionsCommand is the name of the subcommand reporting the expired and
+// unused exceptions.
+const exceptionsCommand = "exceptions"
+
+// exceptionsReport prints the exceptions of the exceptions file which have
+// expired, and those of the suites selected by the -filter and -exclude flags
+// which match none of the -images.
+func exceptionsReport() error {
+	l, err := exceptions.Default()
+	if err != nil {
+		return err
+	}
+	filterRegex, excludeRegex, err := suiteRegexes()
+	if err != nil {
+		return err
+	}
+	var suites []string
+	for _, suite := range imagetest.RegisteredSuites() {
+		if suiteSelected(suite.Name, filterRegex, excludeRegex) {
+			suites = append(suites, suite.Name)
+		}
+	}
+	_, imgs, err := resolveImages()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, i := range imgs {
+		names = append(names, i.Name)
+	}
+	expired, unused := l.Report(names, suites, time.Now())
+	if len(names) == 0 {
+		// Without images, every exception would be reported as unused.
+		unused = nil
+	}
+	return writeExceptionsReport(os.Stdout, expired, unused)
+}
+
+func writeExceptionsReport(w io.Writer, expired, unused []*exceptions.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"STATUS", "SUITE", "TEST", "IMAGE", "TRACKING", "EXPIRES", "REASON"}, "\t"))
+	for _, report := range []struct {
+		status  string
+		entries []*exceptions.Entry
+	}{{"expired", expired}, {"unused", unused}} {
+		for _, e := range report.entries {
+			fmt.Fprintln(tw, strings.Join([]string{report.status, suiteOrFeature(e), orNone(e.Test), e.Image, e.Tracking, e.Expires, e.Reason}, "\t"))
+		}
+	}
+	return tw.Flush()
+}
+
+// suiteOrFeature returns the suite of the exception, or its feature for
+// feature exceptions.
+func suiteOrFeature(e *exceptions.Entry) string {
+	if e.Feature != "" {
+		return "feature:" + e.Feature
+	}
+	return e.Suite
+}
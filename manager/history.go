@@ -0,0 +1,319 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"cloud.google.com/go/storage"
+)
+
+// Statuses of the metrics checked against their history.
+const (
+	metricRegressed = "REGRESSION"
+	metricImproved  = "improvement"
+	metricUnchanged = "no change"
+	metricNoHistory = "insufficient history"
+)
+
+// minHistoryValues is the number of values a metric needs in the history to
+// be checked.
+const minHistoryValues = 2
+
+// metricsHistory is the history of the metrics of previous runs, stored as
+// metric records in a local file or a GCS object.
+type metricsHistory struct {
+	path    string
+	records []metricRecord
+	// generation is the generation of the GCS object read, 0 if it did not
+	// exist, so that concurrent updates are not overwritten.
+	generation int64
+}
+
+// historyKey identifies the values of a metric of a test on an image family
+// across runs. Images without a family are identified by their name. The
+// samples recorded by utils.RecordSamples share the key of the metric, so that
+// the samples of the repeats of a run are compared together.
+func historyKey(r metricRecord) string {
+	image := r.ImageFamily
+	if image == "" {
+		image = path.Base(r.Image)
+	}
+	m := r.Metric
+	if _, ok := m.Labels["sample"]; ok {
+		m.Labels = maps.Clone(m.Labels)
+		delete(m.Labels, "sample")
+	}
+	return image + " " + r.Test + " " + m.Key()
+}
+
+// isSampleStatistic returns whether the record is a statistic of the samples
+// recorded by utils.RecordSamples, which is not checked against the history
+// as the samples are.
+func isSampleStatistic(r metricRecord) bool {
+	_, ok := r.Labels["statistic"]
+	return ok
+}
+
+// loadMetricsHistory reads the history at p, a local path or a gs:// URL. A
+// missing history is empty.
+func loadMetricsHistory(ctx context.Context, client *storage.Client, p string) (*metricsHistory, error) {
+	h := &metricsHistory{path: p}
+	var data []byte
+	if bucket, object, ok := strings.Cut(strings.TrimPrefix(p, "gs://"), "/"); ok && strings.HasPrefix(p, "gs://") {
+		r, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return h, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		h.generation = r.Attrs.Generation
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(p)
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var r metricRecord
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("invalid metric record %q in %s: %v", s.Text(), p, err)
+		}
+		h.records = append(h.records, r)
+	}
+	return h, s.Err()
+}
+
+// save adds the records to the history and writes it back where it was read
+// from. GCS objects are only written if they were not updated since they were
+// read.
+func (h *metricsHistory) save(ctx context.Context, client *storage.Client, records []metricRecord) error {
+	var b bytes.Buffer
+	if err := writeMetrics(&b, append(h.records, records...)); err != nil {
+		return err
+	}
+	bucket, object, ok := strings.Cut(strings.TrimPrefix(h.path, "gs://"), "/")
+	if !ok || !strings.HasPrefix(h.path, "gs://") {
+		return os.WriteFile(h.path, b.Bytes(), 0644)
+	}
+	cond := storage.Conditions{GenerationMatch: h.generation}
+	if h.generation == 0 {
+		cond = storage.Conditions{DoesNotExist: true}
+	}
+	w := client.Bucket(bucket).Object(object).If(cond).NewWriter(ctx)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// baseline returns the last window values of the metric in the history.
+func (h *metricsHistory) baseline(key string, window int) []float64 {
+	var values []float64
+	for _, r := range h.records {
+		if historyKey(r) == key {
+			values = append(values, r.Value)
+		}
+	}
+	if len(values) > window {
+		values = values[len(values)-window:]
+	}
+	return values
+}
+
+// metricCheck is a metric of a run checked against its history.
+type metricCheck struct {
+	key            string
+	unit           string
+	baselineMean   float64
+	baselineStdDev float64
+	baselineCount  int
+	sampleMean     float64
+	sampleCount    int
+	// pValue is the one-sided p-value of the change, in the direction of the
+	// sample mean, adjusted for the number of checked metrics.
+	pValue float64
+	status string
+}
+
+// relativeChange returns the change of the sample mean relative to the
+// baseline mean.
+func (c metricCheck) relativeChange() float64 {
+	diff := c.sampleMean - c.baselineMean
+	if c.baselineMean == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), diff)
+	}
+	return diff / math.Abs(c.baselineMean)
+}
+
+// checkMetrics compares the samples of each metric of the run with its last
+// window values in the history. A change is significant when its one-sided
+// p-value, adjusted with the Benjamini-Hochberg procedure since a run checks
+// many metrics at once, is below alpha. Significant changes smaller than
+// minChange relative to the baseline mean are reported as no change, so that
+// metrics with a very stable history don't fail runs for negligible changes.
+func checkMetrics(h *metricsHistory, records []metricRecord, window int, alpha, minChange float64) []metricCheck {
+	samples := make(map[string][]float64)
+	lowerIsBetter := make(map[string]bool)
+	units := make(map[string]string)
+	var keys []string
+	for _, r := range records {
+		if isSampleStatistic(r) {
+			continue
+		}
+		key := historyKey(r)
+		if _, ok := samples[key]; !ok {
+			keys = append(keys, key)
+		}
+		samples[key] = append(samples[key], r.Value)
+		lowerIsBetter[key] = r.LowerIsBetter()
+		units[key] = r.Unit
+	}
+	sort.Strings(keys)
+
+	checks := make([]metricCheck, 0, len(keys))
+	// checked are the indexes of the checks with enough history, worse
+	// whether they changed for the worse and pValues their one-sided p-values.
+	var checked []int
+	var worse []bool
+	var pValues []float64
+	for _, key := range keys {
+		c := metricCheck{key: key, unit: units[key], sampleCount: len(samples[key])}
+		c.sampleMean, _ = meanVariance(samples[key])
+		baseline := h.baseline(key, window)
+		c.baselineCount = len(baseline)
+		if len(baseline) < minHistoryValues {
+			c.status = metricNoHistory
+			if len(baseline) > 0 {
+				c.baselineMean, _ = meanVariance(baseline)
+			}
+			checks = append(checks, c)
+			continue
+		}
+		var variance float64
+		c.baselineMean, variance = meanVariance(baseline)
+		c.baselineStdDev = math.Sqrt(variance)
+
+		t, df := tStatistic(baseline, samples[key])
+		if lowerIsBetter[key] {
+			t = -t
+		}
+		// t is now positive when the samples are better than the baseline.
+		pWorse, pBetter := studentTSurvival(-t, df), studentTSurvival(t, df)
+		checked = append(checked, len(checks))
+		worse = append(worse, pWorse < pBetter)
+		pValues = append(pValues, math.Min(pWorse, pBetter))
+		checks = append(checks, c)
+	}
+
+	for j, p := range benjaminiHochberg(pValues) {
+		c := &checks[checked[j]]
+		c.pValue = p
+		switch {
+		case p >= alpha || math.Abs(c.relativeChange()) < minChange:
+			c.status = metricUnchanged
+		case worse[j]:
+			c.status = metricRegressed
+		default:
+			c.status = metricImproved
+		}
+	}
+	return checks
+}
+
+// writeMetricChecks writes the metrics of the run checked against their
+// history, one row per metric.
+func writeMetricChecks(w io.Writer, checks []metricCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMAGE TEST METRIC\tHISTORY\tRUN\tDELTA\tP-VALUE\tSTATUS")
+	for _, c := range checks {
+		history, delta, pValue := "-", "-", "-"
+		if c.baselineCount > 0 {
+			history = fmt.Sprintf("%.4g ± %.2g %s (n=%d)", c.baselineMean, c.baselineStdDev, c.unit, c.baselineCount)
+		}
+		if c.baselineCount > 0 && c.baselineMean != 0 {
+			delta = fmt.Sprintf("%+.1f%%", c.relativeChange()*100)
+		}
+		if c.status != metricNoHistory {
+			pValue = fmt.Sprintf("%.3g", c.pValue)
+		}
+		run := fmt.Sprintf("%.4g %s (n=%d)", c.sampleMean, c.unit, c.sampleCount)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.key, history, run, delta, pValue, c.status)
+	}
+	return tw.Flush()
+}
+
+// checkMetricsHistory checks the metrics of the run against the history at
+// historyPath with checkMetrics, writes the checks to w, and appends the
+// metrics which did not regress to the history, so that regressions don't
+// shift the baseline. It returns the number of regressed metrics.
+func checkMetricsHistory(ctx context.Context, client *storage.Client, historyPath string, records []metricRecord, window int, alpha, minChange float64, w io.Writer) (int, error) {
+	h, err := loadMetricsHistory(ctx, client, historyPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load metrics history: %v", err)
+	}
+	checks := checkMetrics(h, records, window, alpha, minChange)
+	if err := writeMetricChecks(w, checks); err != nil {
+		return 0, err
+	}
+	regressed := make(map[string]bool)
+	for _, c := range checks {
+		if c.status == metricRegressed {
+			regressed[c.key] = true
+		}
+	}
+	var kept []metricRecord
+	for _, r := range records {
+		if !isSampleStatistic(r) && !regressed[historyKey(r)] {
+			kept = append(kept, r)
+		}
+	}
+	if err := h.save(ctx, client, kept); err != nil {
+		return len(regressed), fmt.Errorf("failed to update metrics history: %v", err)
+	}
+	return len(regressed), nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/google/go-cmp/cmp"
+)
+
+func historyRecord(family, name string, value float64, unit string) metricRecord {
+	return metricRecord{
+		Image:       "projects/debian-cloud/global/images/debian-12-bookworm-v20260101",
+		ImageFamily: family,
+		Metric:      utils.Metric{Test: "TestPerf", Name: name, Value: value, Unit: unit},
+	}
+}
+
+func TestCheckMetrics(t *testing.T) {
+	h := &metricsHistory{}
+	for _, v := range []float64{20, 21, 19, 20, 22, 18, 20} {
+		h.records = append(h.records, historyRecord("debian-12", "boot_time", v, "s"))
+	}
+	for _, v := range []float64{100, 98, 102, 99, 101} {
+		h.records = append(h.records, historyRecord("debian-12", "throughput", v, "Gbps"))
+		h.records = append(h.records, historyRecord("debian-11", "throughput", v, "Gbps"))
+	}
+	for _, v := range []float64{50, 50.1, 49.9, 50, 50.1, 49.9} {
+		h.records = append(h.records, historyRecord("debian-12", "latency", v, "ms"))
+	}
+	h.records = append(h.records, historyRecord("rhel-9", "boot_time", 30, "s"))
+	records := []metricRecord{
+		// Significant, but below the minimum change.
+		historyRecord("debian-12", "latency", 51, "ms"),
+		historyRecord("debian-12", "boot_time", 30, "s"),
+		historyRecord("debian-12", "throughput", 110, "Gbps"),
+		historyRecord("debian-12", "throughput", 111, "Gbps"),
+		historyRecord("debian-11", "throughput", 100.5, "Gbps"),
+		historyRecord("rhel-9", "boot_time", 60, "s"),
+	}
+
+	got := make(map[string]string)
+	for _, c := range checkMetrics(h, records, 30, 0.05, 0.05) {
+		got[c.key] = c.status
+	}
+	want := map[string]string{
+		"debian-12 TestPerf boot_time":  metricRegressed,
+		"debian-12 TestPerf throughput": metricImproved,
+		"debian-12 TestPerf latency":    metricUnchanged,
+		"debian-11 TestPerf throughput": metricUnchanged,
+		"rhel-9 TestPerf boot_time":     metricNoHistory,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("checkMetrics() returned unexpected statuses (-want +got):\n%s", diff)
+	}
+}
+
+// TestCheckMetricsSamples tests that the samples of a metric recorded by
+// utils.RecordSamples are checked together against the history of the metric,
+// and that their statistics are not checked.
+func TestCheckMetricsSamples(t *testing.T) {
+	h := &metricsHistory{}
+	for _, v := range []float64{100, 98, 102, 99, 101} {
+		h.records = append(h.records, historyRecord("debian-12", "throughput", v, "Gbps"))
+	}
+	var records []metricRecord
+	for i, v := range []float64{110, 111, 109} {
+		r := historyRecord("debian-12", "throughput", v, "Gbps")
+		r.Labels = map[string]string{"sample": strconv.Itoa(i)}
+		records = append(records, r)
+	}
+	median := historyRecord("debian-12", "throughput", 110, "Gbps")
+	median.Labels = map[string]string{"statistic": "median"}
+	records = append(records, median)
+
+	checks := checkMetrics(h, records, 30, 0.05, 0.05)
+	if len(checks) != 1 {
+		t.Fatalf("checkMetrics() returned %d checks, want 1: %+v", len(checks), checks)
+	}
+	if c := checks[0]; c.key != "debian-12 TestPerf throughput" || c.sampleCount != 3 || c.status != metricImproved {
+		t.Errorf("checkMetrics() = %+v, want 3 improved samples of debian-12 TestPerf throughput", c)
+	}
+	if records[0].Labels["sample"] != "0" {
+		t.Errorf("checkMetrics() modified the labels of the records to %v", records[0].Labels)
+	}
+}
+
+func TestMetricsHistoryBaseline(t *testing.T) {
+	h := &metricsHistory{}
+	for _, v := range []float64{1, 2, 3, 4} {
+		h.records = append(h.records, historyRecord("debian-12", "boot_time", v, "s"))
+	}
+	h.records = append(h.records, historyRecord("", "boot_time", 10, "s"))
+	if diff := cmp.Diff([]float64{3, 4}, h.baseline("debian-12 TestPerf boot_time", 2)); diff != "" {
+		t.Errorf("baseline() returned unexpected values (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]float64{10}, h.baseline("debian-12-bookworm-v20260101 TestPerf boot_time", 2)); diff != "" {
+		t.Errorf("baseline() of an image without a family returned unexpected values (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckMetricsHistory(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.json")
+	var history []metricRecord
+	for _, v := range []float64{20, 21, 19, 20} {
+		history = append(history, historyRecord("debian-12", "boot_time", v, "s"))
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMetrics(f, history); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	records := []metricRecord{
+		historyRecord("debian-12", "boot_time", 40, "s"),
+		historyRecord("debian-12", "throughput", 100, "Gbps"),
+	}
+	var b strings.Builder
+	regressions, err := checkMetricsHistory(ctx, nil, path, records, 30, 0.05, 0.05, &b)
+	if err != nil {
+		t.Fatalf("checkMetricsHistory() = %v, want nil", err)
+	}
+	if regressions != 1 {
+		t.Errorf("checkMetricsHistory() = %d regressions, want 1", regressions)
+	}
+	for _, want := range []string{"debian-12 TestPerf boot_time", metricRegressed, "debian-12 TestPerf throughput", metricNoHistory} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("checkMetricsHistory() wrote %q, want it to contain %q", b.String(), want)
+		}
+	}
+
+	// The regressed boot time is not added to the history.
+	h, err := loadMetricsHistory(ctx, nil, path)
+	if err != nil {
+		t.Fatalf("loadMetricsHistory() = %v, want nil", err)
+	}
+	if diff := cmp.Diff(append(history, records[1]), h.records); diff != "" {
+		t.Errorf("loadMetricsHistory() returned unexpected records (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadMetricsHistoryMissing(t *testing.T) {
+	h, err := loadMetricsHistory(context.Background(), nil, filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("loadMetricsHistory() = %v, want nil", err)
+	}
+	if len(h.records) != 0 {
+		t.Errorf("loadMetricsHistory() returned %d records, want 0", len(h.records))
+	}
+}
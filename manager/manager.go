@@ -0,0 +1,902 @@
+// Copyright 2024 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manager is a cli interface to the orchestration provided by the
+// imagetest library. It runs every test suite registered with
+// imagetest.Register, so a manager binary is a main package which imports the
+// suite packages to run and calls Main:
+//
+//	import (
+//		"github.com/GoogleCloudPlatform/cloud-image-tests/manager"
+//		_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/all"
+//		_ "example.com/my/suites/mysuite"
+//	)
+//
+//	func main() {
+//		manager.Main()
+//	}
+package manager
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/compute-daisy/compute"
+	"github.com/jstemmer/go-junit-report/v2/junit"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+var (
+	// zones is a flag.Value that represents a comma-separated list of zones. Initialized in init().
+	zones                   StringSlice
+	project                 = flag.String("project", "", "project to use for test runner")
+	testProjects            = flag.String("test_projects", "", "comma separated list of projects to be used for tests. defaults to the test runner project")
+	zone                    = flag.String("zone", "us-central1-a", "zone to be used for tests")
+	printwf                 = flag.Bool("print", false, "print out the parsed test workflows and exit")
+	validate                = flag.Bool("validate", false, "validate all the test workflows and exit")
+	argZoneOverride         = flag.Bool("zone_override", true, "argument provided zones (via -zone or -zones flags) will override tests hardcoded zones")
+	outPath                 = flag.String("out_path", "junit.xml", "junit xml path")
+	metricsSamples          = flag.Int("metrics_samples", 1, "number of times to run each test suite on each image, to sample the metrics recorded by the tests")
+	metricsHistoryPath      = flag.String("metrics_history", "", "local path or gs:// URL of the history of the metrics of previous runs, in the format of metrics_path. When set, metrics are checked for statistically significant regressions against their history, and appended to it")
+	metricsHistoryWindow    = flag.Int("metrics_history_window", 30, "number of the most recent values of each metric in metrics_history to compare with")
+	metricsSignificance     = flag.Float64("metrics_significance", 0.05, "p-value, adjusted for the number of metrics checked, below which a change of a metric compared with metrics_history is significant")
+	metricsMinChange        = flag.Float64("metrics_min_change", 0.05, "minimum change of a metric relative to its mean in metrics_history, like 0.05 for 5%, for a significant change to be reported as a regression or an improvement")
+	metricsPath             = flag.String("metrics_path", "metrics.json", "path of the metrics recorded by the tests, one JSON record per line, written when tests record metrics")
+	gcsPath                 = flag.String("gcs_path", "", "GCS Path for Daisy working directory")
+	writeLocalArtifacts     = flag.String("write_local_artifacts", "", "Local path to download test artifacts from gcs.")
+	localPath               = flag.String("local_path", "", "path where test output files are stored, can be modified for local testing")
+	images                  = flag.String("images", "", "comma separated list of images to test, or image selectors like project=debian-cloud,family~^debian-1[23],arch=ARM64 separated by semicolons")
+	timeout                 = flag.String("timeout", "30m", "timeout for the test suite")
+	computeEndpointOverride = flag.String("compute_endpoint_override", "", "compute client endpoint override")
+	parallelCount           = flag.Int("parallel_count", 5, "TestParallelCount")
+	parallelStagger         = flag.String("parallel_stagger", "60s", "parseable time.Duration to stagger each parallel test")
+	filter                  = flag.String("filter", "", "only run test suites matching filter")
+	exclude                 = flag.String("exclude", "", "skip test suites matching filter")
+	testExcludeFilter       = flag.String("exclude_discrete_tests", "", "skip individual tests within suites that match the regexp filter")
+	machineType             = flag.String("machine_type", "", "deprecated, use -x86_shape and/or -arm64_shape instead")
+	x86Shape                = flag.String("x86_shape", "n1-standard-1", "default x86(-32 and -64) vm shape for tests not requiring a specific shape")
+	arm64Shape              = flag.String("arm64_shape", "t2a-standard-1", "default arm64 vm shape for tests not requiring a specific shape")
+	setExitStatus           = flag.Bool("set_exit_status", true, "Exit with non-zero exit code if test suites are failing")
+	useReservations         = flag.Bool("use_reservations", false, "Whether to consume reservations when creating VMs. Will consume any reservation if reservation_urls is unspecified.")
+	reservationURLs         = flag.String("reservation_urls", "", "Comma separated list of partial URLs for reservations to consume.")
+	acceleratorType         = flag.String("accelerator_type", "", "Accelerator type to be used for accelerator tests")
+	allImageFamilies        = flag.String("all_image_families", "", "Single image project to test all image families in.")
+	architectureType        = flag.String("architecture_type", "", "Specific architecture to test on. Accepts one of x86 or arm64.")
+	sharedVPCHostProject    = flag.String("shared_vpc_host_project", "", "Shared VPC host project to attach test VMs to. Requires shared_vpc_network and shared_vpc_subnetwork.")
+	sharedVPCNetwork        = flag.String("shared_vpc_network", "", "Network in the Shared VPC host project to attach test VMs to.")
+	sharedVPCSubnetwork     = flag.String("shared_vpc_subnetwork", "", "Subnetwork in the Shared VPC host project to attach test VMs to. Must be in the region of the test zone(s).")
+	listSuites              = flag.Bool("list_suites", false, "print the registered test suites and exit")
+	listApplicable          = flag.Bool("list_applicable", false, "print which test suites run on each image and exit")
+	catalogFormat           = flag.String("catalog_format", "table", "output format of the catalog command, table or json")
+	imageFiles              = flag.String("image_files", "", "comma separated list of local or gs:// image files (.tar.gz, or .raw for local files) to import as temporary images and test")
+	imageFileFeatures       = flag.String("image_file_features", "", "comma separated list of guest OS features of the images imported from image_files, like UEFI_COMPATIBLE,GVNIC")
+	imageFileArchitecture   = flag.String("image_file_architecture", "X86_64", "architecture of the images imported from image_files, X86_64 or ARM64")
+	buildFrom               = flag.String("build_from", "", "cloud-image-tests source tree to build the binaries of the selected test suites from, for the platforms of the images, instead of using prebuilt binaries in local_path")
+	baselineImages          = flag.String("baseline_images", "", "comma separated list of baseline images, one per image or a single one for all images, to run the same test suites on and compare the results of the images with")
+	comparisonPath          = flag.String("comparison_path", "comparison.txt", "path of the comparison of the results of the images with baseline_images")
+	buildCache              = flag.String("build_cache", "", "directory caching the binaries built with build_from by the hash of their sources, defaults to cloud-image-tests in the user cache directory")
+
+	// zonesRoundRobinIdx points to an index in the list of zones.
+	// This is used to distribute tests across the list of zones in a round robin fashion,
+	// when the zones flag is set.
+	zonesRoundRobinIdx = -1
+)
+
+var (
+	// Maps an image family prefix to the project that hosts the image family.
+	// When modifying this map, please make sure that the image family prefix is
+	// also added to the imageKeys list.
+	projectMap = map[string]string{
+		"almalinux":          "almalinux-cloud",
+		"centos":             "centos-cloud",
+		"cos":                "cos-cloud",
+		"debian":             "debian-cloud",
+		"fedora-cloud":       "fedora-cloud",
+		"fedora-coreos":      "fedora-coreos-cloud",
+		"opensuse":           "opensuse-cloud",
+		"oracle-linux":       "oracle-linux-cloud",
+		"rhel-(.*-)?byos":    "rhel-byos-cloud",
+		"rhel-(.*-)?eus":     "rhel-cloud",
+		"rhel-(.*-)?lvm":     "rhel-cloud",
+		"rhel-(.*-)?sap":     "rhel-sap-cloud",
+		"rhel":               "rhel-cloud",
+		"rocky-linux":        "rocky-linux-cloud",
+		"sles-(.*-)?sap":     "suse-sap-cloud",
+		"sles":               "suse-cloud",
+		"sql-":               "windows-sql-cloud",
+		"ubuntu-(.*-)?pro":   "ubuntu-os-pro-cloud",
+		"ubuntu-accelerator": "ubuntu-os-accelerator-images",
+		"ubuntu":             "ubuntu-os-cloud",
+		"windows":            "windows-cloud",
+	}
+
+	// An ordered list is required because some image names are substrings of
+	// other image names. Map keys are returned in random order, so this ensures
+	// that we can set an order that always works.
+	imageKeys = []string{
+		"almalinux",
+		"centos",
+		"cos",
+		"debian",
+		"fedora-cloud",
+		"fedora-coreos",
+		"opensuse",
+		"oracle-linux",
+		"rhel-(.*-)?byos",
+		"rhel-(.*-)?eus",
+		"rhel-(.*-)?lvm",
+		"rhel-(.*-)?sap",
+		"rhel",
+		"rocky-linux",
+		"sles-(.*-)?sap",
+		"sles",
+		"sql-",
+		"ubuntu-(.*-)?pro",
+		"ubuntu-accelerator",
+		"ubuntu",
+		"windows",
+	}
+)
+
+// StringSlice is a flag.Value that represents a comma-separated list of strings.
+type StringSlice []string
+
+// Set implements flag.Value.Set.
+func (s *StringSlice) Set(value string) error {
+	*s = append(*s, strings.Split(strings.ReplaceAll(value, " ", ""), ",")...)
+	return nil
+}
+
+// String implements flag.Value.String.
+func (s *StringSlice) String() string {
+	return strings.Join(*s, ",")
+}
+
+type logWriter struct {
+	log *log.Logger
+}
+
+func (l *logWriter) Write(b []byte) (int, error) {
+	l.log.Print(string(b))
+	return len(b), nil
+}
+
+func init() {
+	flag.Var(&zones, "zones", "A comma-separated list of zones (e.g., --zones=\"us-east4, us-west1, us-west4\")")
+}
+
+// rotatedZones returns a slice of zones rotated such that the zone at
+// zonesRoundRobinIdx is first.
+func rotatedZones() []string {
+	if len(zones) == 0 {
+		return []string{*zone}
+	}
+	zonesRoundRobinIdx++
+	if zonesRoundRobinIdx >= len(zones) {
+		zonesRoundRobinIdx = 0
+	}
+	rotated := make([]string, len(zones))
+	for i := 0; i < len(zones); i++ {
+		idx := (zonesRoundRobinIdx + i) % len(zones)
+		rotated[i] = zones[idx]
+	}
+	return rotated
+}
+
+// displayZone returns the zone to be displayed in the log. If the zones
+// flag is set, its serialized string is returned. Otherwise, the zone flag is returned.
+func displayZone() string {
+	if len(zones) > 0 {
+		return zones.String()
+	}
+	return *zone
+}
+
+// Main parses the flags, then sets up and runs the test workflows of the
+// registered test suites matching the flags on the images.
+//
+// When the first argument is "catalog", Main instead prints the catalog of the
+// registered test suites, e.g. manager catalog -images debian-12 -catalog_format json.
+// When it is "exceptions", Main prints the exceptions which have expired or
+// match none of the images, e.g. manager exceptions -images debian-12,rhel-9.
+func Main() {
+	if len(os.Args) > 1 && os.Args[1] == catalogCommand {
+		flag.CommandLine.Parse(os.Args[2:])
+		if err := catalog(); err != nil {
+			log.Fatalf("Failed to print the catalog: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == exceptionsCommand {
+		flag.CommandLine.Parse(os.Args[2:])
+		if err := exceptionsReport(); err != nil {
+			log.Fatalf("Failed to report the exceptions: %v", err)
+		}
+		return
+	}
+	flag.Parse()
+	if *listSuites {
+		for _, suite := range imagetest.RegisteredSuites() {
+			fmt.Printf("%s\t%s\n", suite.Name, suite.Metadata.Description)
+		}
+		return
+	}
+	if *listApplicable {
+		if *images == "" && *allImageFamilies == "" {
+			log.Fatal("Must provide one of images or all_image_families arguments")
+			return
+		}
+		if *imageFiles != "" {
+			log.Fatal("list_applicable does not support image_files")
+			return
+		}
+	} else if *project == "" || (*zone == "" && len(zones) == 0) || (*images == "" && *allImageFamilies == "" && *imageFiles == "") {
+		log.Fatal("Must provide project, zone(s), and one of images, all_image_families or image_files arguments")
+		return
+	}
+	if *images != "" && *allImageFamilies != "" {
+		log.Fatal("Must provide one of images or all_image_families arguments, not both")
+		return
+	}
+
+	if *architectureType != "" && (*architectureType != "x86" && *architectureType != "arm64") {
+		log.Fatal("architecture_type must be blank, x86, or arm64")
+		return
+	}
+
+	if *metricsSamples < 1 {
+		log.Fatal("metrics_samples must be at least 1")
+		return
+	}
+
+	if *sharedVPCHostProject != "" && (*sharedVPCNetwork == "" || *sharedVPCSubnetwork == "") {
+		log.Fatal("shared_vpc_host_project requires shared_vpc_network and shared_vpc_subnetwork")
+		return
+	}
+
+	var testProjectsReal []string
+	if *testProjects == "" {
+		testProjectsReal = append(testProjectsReal, *project)
+	} else {
+		testProjectsReal = strings.Split(*testProjects, ",")
+	}
+
+	log.Printf("Running in project %s zone(s) %s. Tests will run in projects: %s", *project, displayZone(), testProjectsReal)
+	if *gcsPath != "" {
+		log.Printf("gcs_path set to %s", *gcsPath)
+	}
+
+	var filterRegex *regexp.Regexp
+	if *filter != "" {
+		var err error
+		filterRegex, err = regexp.Compile(*filter)
+		if err != nil {
+			log.Fatal("-filter flag not valid:", err)
+		}
+		log.Printf("using -filter %s", *filter)
+	}
+
+	var excludeRegex *regexp.Regexp
+	if *exclude != "" {
+		var err error
+		excludeRegex, err = regexp.Compile(*exclude)
+		if err != nil {
+			log.Fatal("-exclude flag not valid:", err)
+		}
+		log.Printf("using -exclude %s", *exclude)
+	}
+
+	if *testExcludeFilter != "" {
+		log.Printf("Using -exclude_discrete_tests %s", *testExcludeFilter)
+	}
+
+	if *machineType != "" {
+		log.Printf("The -machine_type flag is deprecated, please use -x86_shape and -arm64_shape instead. Retaining legacy behavior while this is set.")
+		*x86Shape = *machineType
+		*arm64Shape = *machineType
+	}
+
+	var reservationURLSlice []string
+	if *reservationURLs != "" {
+		reservationURLSlice = strings.Split(*reservationURLs, ",")
+	}
+
+	ctx := context.Background()
+	var computeclient compute.Client
+	var err error
+	if *computeEndpointOverride != "" {
+		log.Printf("Using compute endpoint %q", *computeEndpointOverride)
+		computeclient, err = compute.NewClient(ctx, option.WithEndpoint(*computeEndpointOverride))
+	} else {
+		computeclient, err = compute.NewClient(ctx)
+	}
+	if err != nil {
+		log.Fatalf("Could not create compute client:%v", err)
+	}
+
+	// Initialize the Compute API client
+	var computev1Client *computev1.Service
+	if *computeEndpointOverride != "" {
+		log.Printf("Using compute endpoint %q", *computeEndpointOverride)
+		computev1Client, err = computev1.NewService(ctx, option.WithEndpoint(*computeEndpointOverride))
+	} else {
+		computev1Client, err = computev1.NewService(ctx)
+	}
+	if err != nil {
+		log.Fatalf("Could not create compute v1 client: %v", err)
+	}
+
+	if isImageSelector(*images) {
+		resolved, err := resolveImageSelectors(computeclient, *images)
+		if err != nil {
+			log.Fatalf("Failed to resolve image selectors: %v", err)
+		}
+		log.Printf("Images selected by %q: %s", *images, strings.Join(resolved, ","))
+		if *printwf {
+			fmt.Printf("Images selected by %q:\n", *images)
+			for _, image := range resolved {
+				fmt.Printf("  %s\n", image)
+			}
+		}
+		*images = strings.Join(resolved, ",")
+	}
+
+	// Fetch active image families from the project
+	var imageList []string
+	if *allImageFamilies != "" {
+		projectName := *allImageFamilies
+		uniqueImageList := make(map[string]bool)
+
+		err := computev1Client.Images.List(projectName).Context(ctx).Pages(ctx, func(page *computev1.ImageList) error {
+			for _, projectImage := range page.Items {
+				if projectImage.Family != "" && !uniqueImageList[projectImage.Family] {
+					if projectImage.Deprecated == nil || (projectImage.Deprecated.State != "DEPRECATED" && projectImage.Deprecated.State != "OBSOLETE" && projectImage.Deprecated.State != "DELETED") {
+						uniqueImageList[projectImage.Family] = true
+						image := fmt.Sprintf("projects/%s/global/images/family/%s", projectName, projectImage.Family)
+						imageList = append(imageList, image)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed Compute API call: %v", err)
+		}
+
+		*images = strings.Join(imageList, ",")
+	}
+
+	storageclient, err := storage.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("failed to set up storage client: %v", err)
+	}
+
+	run, err := runTestWorkflows(ctx, computeclient, storageclient, filterRegex, excludeRegex, reservationURLSlice, testProjectsReal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if run == nil {
+		return
+	}
+	testWorkflows, comparePairs, suites := run.workflows, run.comparePairs, run.suites
+
+	if *writeLocalArtifacts != "" {
+		var wg sync.WaitGroup
+		for _, twf := range testWorkflows {
+			bkt := strings.TrimSuffix(strings.TrimPrefix(regexp.MustCompile(`gs://[a-z0-9][a-z0-9-_.]{2,62}[a-z0-9]/?`).FindString(twf.GCSPath), "gs://"), "/")
+			if bkt == "" {
+				log.Printf("could not find gcs bucket from %s for workflow %s", twf.GCSPath, twf.Name)
+				continue
+			}
+			gcsSubfolder := strings.TrimPrefix(twf.GCSPath, "gs://"+bkt+"/")
+			wg.Add(1)
+			go func(bucket, folder, dstDir string) {
+				defer wg.Done()
+				if err := downloadFolder(ctx, storageclient, bucket, folder, dstDir); err != nil {
+					log.Printf("failed to download test artifacts from folder %s in bucket %s to %s: %v\n", folder, bucket, dstDir, err)
+				}
+			}(bkt, gcsSubfolder, *writeLocalArtifacts)
+		}
+		wg.Wait()
+	}
+
+	bytes, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		log.Fatalf("failed to marshall result: %v", err)
+	}
+	bytes = []byte(fmt.Sprintf("%s%s", xml.Header, bytes))
+	var outFile *os.File
+	if artifacts := os.Getenv("ARTIFACTS"); artifacts != "" {
+		outFile, err = os.Create(artifacts + "/junit.xml")
+	} else {
+		outFile, err = os.Create(*outPath)
+	}
+	if err != nil {
+		log.Fatalf("failed to create output file: %v", err)
+	}
+	defer outFile.Close()
+
+	outFile.Write(bytes)
+	outFile.Write([]byte{'\n'})
+	fmt.Printf("%s\n", bytes)
+
+	records := metricRecords(suites, time.Now())
+	if len(records) > 0 {
+		var metricsFile *os.File
+		if artifacts := os.Getenv("ARTIFACTS"); artifacts != "" {
+			metricsFile, err = os.Create(artifacts + "/metrics.json")
+		} else {
+			metricsFile, err = os.Create(*metricsPath)
+		}
+		if err != nil {
+			log.Fatalf("failed to create metrics file: %v", err)
+		}
+		defer metricsFile.Close()
+		if err := writeMetrics(metricsFile, records); err != nil {
+			log.Fatalf("failed to write metrics: %v", err)
+		}
+	}
+
+	failures := suites.Errors + suites.Failures
+	if comparePairs != nil {
+		comparisons := compareResults(comparePairs, testWorkflows, suites)
+		if err := writeComparison(os.Stdout, comparisons); err != nil {
+			log.Fatalf("failed to write the comparison: %v", err)
+		}
+		compareFile, err := os.Create(*comparisonPath)
+		if err != nil {
+			log.Fatalf("failed to create comparison file: %v", err)
+		}
+		defer compareFile.Close()
+		if err := writeComparison(compareFile, comparisons); err != nil {
+			log.Fatalf("failed to write the comparison: %v", err)
+		}
+		// Failures on the baseline images are expected to be known.
+		failures = candidateFailures(comparePairs, testWorkflows, suites) + missingRegressions(comparisons)
+	}
+
+	if *metricsHistoryPath != "" {
+		regressions, err := checkMetricsHistory(ctx, storageclient, *metricsHistoryPath, records, *metricsHistoryWindow, *metricsSignificance, *metricsMinChange, os.Stdout)
+		if err != nil {
+			log.Fatalf("failed to check metrics history: %v", err)
+		}
+		failures += regressions
+	}
+
+	if *setExitStatus && failures != 0 {
+		log.Fatalf("test suite has error or failure")
+	}
+}
+
+// testRun is the test workflows run by runTestWorkflows and their results.
+type testRun struct {
+	workflows    []*imagetest.TestWorkflow
+	comparePairs []imagePair
+	suites       junit.Testsuites
+}
+
+// runTestWorkflows imports the image files, sets up the test workflows of the
+// selected test suites on every image and runs them. It returns a nil testRun
+// if it only listed, printed or validated the test workflows. The imported
+// images are deleted before it returns, whether it fails or not.
+func runTestWorkflows(ctx context.Context, computeclient compute.Client, storageclient *storage.Client, filterRegex, excludeRegex *regexp.Regexp, reservationURLSlice, testProjectsReal []string) (*testRun, error) {
+	// Import the image files as temporary images, deleted once the tests are
+	// done. Printing the test workflows creates no image.
+	if *imageFiles != "" && *printwf {
+		fmt.Println("Image files, not imported in print mode:")
+		for _, file := range strings.Split(*imageFiles, ",") {
+			fmt.Printf("  %s\n", file)
+		}
+		if *images == "" {
+			return nil, nil
+		}
+	} else if *imageFiles != "" {
+		opts := &imagetest.ImageImportOpts{
+			Client:        computeclient,
+			StorageClient: storageclient,
+			Project:       *project,
+			GCSPath:       *gcsPath,
+			Files:         strings.Split(*imageFiles, ","),
+			Architecture:  *imageFileArchitecture,
+		}
+		if *imageFileFeatures != "" {
+			opts.GuestOSFeatures = strings.Split(*imageFileFeatures, ",")
+		}
+		imp, err := imagetest.ImportImageFiles(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import image files: %v", err)
+		}
+		defer cleanupImportedImages(imp)
+		log.Printf("Imported image files as images: %s", strings.Join(imp.Images, ","))
+		if *images != "" {
+			*images += ","
+		}
+		*images += strings.Join(imp.Images, ",")
+	}
+
+	// Run the test suites on the baseline images too, to compare the results
+	// of each image with its baseline.
+	var comparePairs []imagePair
+	if *baselineImages != "" {
+		var candidates, baselines []string
+		for _, list := range []struct {
+			images string
+			dst    *[]string
+		}{{*images, &candidates}, {*baselineImages, &baselines}} {
+			for _, image := range strings.Split(list.images, ",") {
+				if image == "" {
+					continue
+				}
+				fmtImage, err := formatImageName(image)
+				if err != nil {
+					return nil, fmt.Errorf("failed to reformat image path: %v", err)
+				}
+				*list.dst = append(*list.dst, fmtImage)
+			}
+		}
+		var err error
+		comparePairs, err = pairBaselineImages(candidates, baselines)
+		if err != nil {
+			return nil, fmt.Errorf("invalid baseline_images: %v", err)
+		}
+		for _, baseline := range baselines {
+			if !slices.Contains(candidates, baseline) {
+				candidates = append(candidates, baseline)
+			}
+		}
+		*images = strings.Join(candidates, ",")
+	}
+
+	// Filter by architecture type if applicable
+	if *architectureType != "" {
+		filteredImages, err := filterByArchitecture(computeclient, *images, *architectureType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter images by architecture: %v", err)
+		}
+		*images = filteredImages
+	}
+
+	if *listApplicable {
+		if err := printApplicableSuites(computeclient, filterRegex, excludeRegex); err != nil {
+			return nil, fmt.Errorf("failed to list applicable test suites: %v", err)
+		}
+		return nil, nil
+	}
+
+	var testWorkflows []*imagetest.TestWorkflow
+	for _, suite := range imagetest.RegisteredSuites() {
+		if !suiteSelected(suite.Name, filterRegex, excludeRegex) {
+			continue
+		}
+
+		for _, image := range strings.Split(*images, ",") {
+			// Ignore empty strings.
+			if image == "" {
+				log.Print("Skipping empty image")
+				continue
+			}
+
+			var err error
+			image, err = formatImageName(image)
+			if err != nil {
+				return nil, fmt.Errorf("failed to reformat image path: %v", err)
+			}
+
+			for sample := 1; sample <= *metricsSamples; sample++ {
+				log.Printf("Add test workflow for test %s on image %s, sample %d", suite.Name, image, sample)
+				rZones := rotatedZones()
+				test, err := imagetest.NewTestWorkflow(&imagetest.TestWorkflowOpts{
+					Client:                  computeclient,
+					ComputeEndpointOverride: *computeEndpointOverride,
+					Name:                    suite.Name,
+					Image:                   image,
+					Timeout:                 *timeout,
+					Project:                 *project,
+					Zone:                    rZones[0],
+					Zones:                   rZones,
+					ExcludeFilter:           *testExcludeFilter,
+					X86Shape:                *x86Shape,
+					ARM64Shape:              *arm64Shape,
+					UseReservations:         *useReservations,
+					ReservationURLs:         reservationURLSlice,
+					AcceleratorType:         *acceleratorType,
+					ArgZoneOverride:         *argZoneOverride,
+					SharedVPCHostProject:    *sharedVPCHostProject,
+					SharedVPCNetwork:        *sharedVPCNetwork,
+					SharedVPCSubnetwork:     *sharedVPCSubnetwork,
+					Sample:                  sample,
+				}, suite.Setup)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create test workflow: %v", err)
+				}
+				testWorkflows = append(testWorkflows, test)
+				reason, err := suite.SkipReason(test.Image)
+				if err != nil {
+					log.Printf("Failing test %s on image %s: %v", suite.Name, image, err)
+					test.Fail(err.Error())
+					continue
+				}
+				if reason != "" {
+					log.Printf("Skipping test %s on image %s: %s", suite.Name, image, reason)
+					test.Skip(reason)
+					continue
+				}
+				if err := test.SetupFunc(test); err != nil {
+					return nil, fmt.Errorf("%s.TestSetup for %s failed: %v", suite.Name, image, err)
+				}
+			}
+		}
+	}
+
+	if len(testWorkflows) == 0 {
+		return nil, fmt.Errorf("no workflows to run")
+	}
+
+	log.Println("Done with setup")
+
+	if *buildFrom != "" {
+		if *localPath == "" {
+			dir, err := os.MkdirTemp("", "cloud-image-tests")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create the local path: %v", err)
+			}
+			*localPath = dir
+		}
+		cacheDir := *buildCache
+		if cacheDir == "" {
+			userCacheDir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the build cache: %v", err)
+			}
+			cacheDir = filepath.Join(userCacheDir, "cloud-image-tests")
+		}
+		log.Printf("Building test binaries from %s to %s", *buildFrom, *localPath)
+		if err := buildTestBinaries(ctx, *buildFrom, cacheDir, *localPath, testWorkflows); err != nil {
+			return nil, fmt.Errorf("failed to build test binaries: %v", err)
+		}
+	}
+
+	if *printwf {
+		imagetest.PrintTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath)
+		return nil, nil
+	}
+
+	if *validate {
+		if err := imagetest.ValidateTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath); err != nil {
+			log.Printf("Validate failed: %v\n", err)
+		}
+		return nil, nil
+	}
+
+	suites, err := imagetest.RunTests(ctx, storageclient, testWorkflows, *project, *gcsPath, *localPath, *parallelCount, *parallelStagger, testProjectsReal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run tests: %v", err)
+	}
+	return &testRun{workflows: testWorkflows, comparePairs: comparePairs, suites: suites}, nil
+}
+
+// cleanupImportedImages deletes the images imported from image files, and the
+// image files uploaded to GCS.
+func cleanupImportedImages(imp *imagetest.ImageImport) {
+	deleted, errs := imp.Cleanup()
+	for _, resource := range deleted {
+		log.Printf("Deleted %s", resource)
+	}
+	for _, err := range errs {
+		log.Printf("Failed to clean up imported images: %v", err)
+	}
+}
+
+func downloadFolder(ctx context.Context, client *storage.Client, bucket, folder, dstDir string) error {
+	// Create the destination directory if it doesn't exist.
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+
+	// List all objects in the folder.
+	query := &storage.Query{
+		Prefix: folder,
+	}
+	objs := client.Bucket(bucket).Objects(ctx, query)
+
+	// Download each object to the destination directory.
+	for {
+		obj, err := objs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		dstFile := filepath.Join(dstDir, obj.Name)
+		if strings.Contains(dstFile, "/sources/") {
+			continue
+		}
+		// Remote path might contain subfolders, create them locally too.
+		fileDstDir := filepath.Dir(dstFile)
+		if err := os.MkdirAll(fileDstDir, 0755); err != nil {
+			log.Printf("failed to create %s: %v", fileDstDir, err)
+			continue
+		}
+		file, err := os.Create(dstFile)
+		if err != nil {
+			log.Printf("failed to write %s: %v", dstFile, err)
+			continue
+		}
+
+		objReader, err := client.Bucket(bucket).Object(obj.Name).NewReader(ctx)
+		if err != nil {
+			log.Printf("failed to make reader for %s: %v", obj.Name, err)
+			continue
+		}
+
+		if _, err := io.Copy(file, objReader); err != nil {
+			log.Printf("failed to copy %s to disk: %v", obj.Name, err)
+			continue
+		}
+
+		if err := objReader.Close(); err != nil {
+			log.Printf("failed to close %s reader: %v", obj.Name, err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// suiteSelected returns whether the test suite matches the -filter and
+// -exclude flags.
+func suiteSelected(name string, filterRegex, excludeRegex *regexp.Regexp) bool {
+	if filterRegex != nil && !filterRegex.MatchString(name) {
+		return false
+	}
+	if excludeRegex != nil && excludeRegex.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+// printApplicableSuites prints whether each selected test suite runs on each
+// image, with the reason for suites which are skipped.
+func printApplicableSuites(computeclient compute.Client, filterRegex, excludeRegex *regexp.Regexp) error {
+	for _, image := range strings.Split(*images, ",") {
+		if image == "" {
+			continue
+		}
+		fmtImage, err := formatImageName(image)
+		if err != nil {
+			return err
+		}
+		i, err := getImage(computeclient, fmtImage)
+		if err != nil {
+			return fmt.Errorf("failed to get image %q: %w", fmtImage, err)
+		}
+		for _, suite := range imagetest.RegisteredSuites() {
+			if !suiteSelected(suite.Name, filterRegex, excludeRegex) {
+				continue
+			}
+			if reason, err := suite.SkipReason(i); err != nil {
+				fmt.Printf("%s\t%s\tfail\t%v\n", fmtImage, suite.Name, err)
+			} else if reason != "" {
+				fmt.Printf("%s\t%s\tskip\t%s\n", fmtImage, suite.Name, reason)
+			} else {
+				fmt.Printf("%s\t%s\trun\n", fmtImage, suite.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// getImage gets the image from a formatted image path.
+func getImage(computeclient compute.Client, fmtImage string) (*computev1.Image, error) {
+	fmtImageComponents := strings.Split(fmtImage, "/")
+	if strings.Contains(fmtImage, "family") {
+		return computeclient.GetImageFromFamily(fmtImageComponents[1], fmtImageComponents[len(fmtImageComponents)-1])
+	}
+	return computeclient.GetImage(fmtImageComponents[1], fmtImageComponents[len(fmtImageComponents)-1])
+}
+
+// Reformat image path into valid URI
+func formatImageName(image string) (string, error) {
+	if strings.Contains(image, "/") {
+		return image, nil
+	}
+
+	// Find the project of the image.
+	project := ""
+	for _, k := range imageKeys {
+		if utils.IsSAP(k) {
+			// sap follows a slightly different naming convention.
+			imageName := strings.Split(k, "-")[0]
+			if strings.HasPrefix(image, imageName) && utils.IsSAP(image) {
+				project = projectMap[k]
+				break
+			}
+		}
+		imageRegex, err := regexp.Compile(k)
+		if err != nil {
+			log.Fatalf("failed regex: %v", err)
+		}
+		if imageRegex.MatchString(image) {
+			project = projectMap[k]
+			break
+		}
+	}
+	if project == "" {
+		log.Fatalf("unknown image %q", image)
+	}
+
+	// Check whether the image is an image family or a specific image version.
+	vRegex, err := regexp.Compile(".*v([0-9]+)")
+	if err != nil {
+		return "", fmt.Errorf("failed regex: %v", err)
+	}
+	if vRegex.MatchString(image) {
+		return fmt.Sprintf("projects/%s/global/images/%s", project, image), nil
+	}
+	return fmt.Sprintf("projects/%s/global/images/family/%s", project, image), nil
+}
+
+// Filter images list by architecture type
+func filterByArchitecture(computeclient compute.Client, images string, arch string) (string, error) {
+	if arch == "" {
+		return images, nil
+	}
+
+	var filteredImages []string
+	for _, image := range strings.Split(images, ",") {
+		if image == "" {
+			continue
+		}
+
+		fmtImage, err := formatImageName(image)
+		if err != nil {
+			return "", err
+		}
+
+		i, err := getImage(computeclient, fmtImage)
+		if err != nil {
+			return "", fmt.Errorf("failed to get image %q: %w", fmtImage, err)
+		}
+
+		isArm := i.Architecture == "ARM64"
+		if arch == "x86" && isArm {
+			log.Printf("skipping over arm64 image: %s", fmtImage)
+			continue
+		}
+		if arch == "arm64" && !isArm {
+			log.Printf("skipping over x86 image: %s", fmtImage)
+			continue
+		}
+		filteredImages = append(filteredImages, fmtImage)
+	}
+	return strings.Join(filteredImages, ","), nil
+}
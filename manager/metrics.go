@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/jstemmer/go-junit-report/v2/junit"
+)
+
+// metricRecord is a metric recorded by a test, with the test suite and image
+// it was measured on, so that metrics can be charted per image over time.
+type metricRecord struct {
+	Time        string `json:"time"`
+	Suite       string `json:"suite"`
+	Image       string `json:"image"`
+	ImageFamily string `json:"image_family,omitempty"`
+	utils.Metric
+}
+
+// metricRecords returns the metrics recorded by the tests of the suites.
+func metricRecords(suites junit.Testsuites, now time.Time) []metricRecord {
+	var records []metricRecord
+	for _, s := range suites.Suites {
+		metrics := imagetest.ResultMetrics(s)
+		if len(metrics) == 0 {
+			continue
+		}
+		props := make(map[string]string)
+		for _, p := range *s.Properties {
+			props[p.Name] = p.Value
+		}
+		for _, m := range metrics {
+			records = append(records, metricRecord{
+				Time:        now.UTC().Format(time.RFC3339),
+				Suite:       s.Name,
+				Image:       props["image"],
+				ImageFamily: props["image_family"],
+				Metric:      m,
+			})
+		}
+	}
+	return records
+}
+
+// writeMetrics writes the metric records as JSON, one record per line.
+func writeMetrics(w io.Writer, records []metricRecord) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jstemmer/go-junit-report/v2/junit"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	withMetrics := junit.Testsuite{Name: "networkperf-debian-12"}
+	withMetrics.AddProperty("image_family", "debian-12")
+	withMetrics.AddProperty("image", "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-12-bookworm-v20260101")
+	withMetrics.AddProperty("metric", `{"test":"TestNetworkPerformance","name":"throughput","value":98.5,"unit":"Gbps","labels":{"interface":"0"}}`)
+	withoutMetrics := junit.Testsuite{Name: "imageboot-debian-12"}
+	withoutMetrics.AddProperty("image_family", "debian-12")
+	suites := junit.Testsuites{Suites: []junit.Testsuite{withMetrics, withoutMetrics, {Name: "oslogin-debian-12"}}}
+
+	var b strings.Builder
+	if err := writeMetrics(&b, metricRecords(suites, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))); err != nil {
+		t.Fatalf("writeMetrics() = %v, want nil", err)
+	}
+	want := `{"time":"2026-01-02T03:04:05Z","suite":"networkperf-debian-12","image":"https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-12-bookworm-v20260101","image_family":"debian-12","test":"TestNetworkPerformance","name":"throughput","value":98.5,"unit":"Gbps","labels":{"interface":"0"}}` + "\n"
+	if b.String() != want {
+		t.Errorf("writeMetrics() wrote %s, want %s", b.String(), want)
+	}
+}
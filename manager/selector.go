@@ -0,0 +1,337 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/compute-daisy/compute"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+// selectorOps are the operators of image selector terms, longest first so
+// that >= is not parsed as >.
+var selectorOps = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// selectorTerm is a condition on a property of images, like family~^debian.
+type selectorTerm struct {
+	key   string
+	op    string
+	value string
+	// values are the alternatives of the value separated by |, for the = and
+	// != operators.
+	values []string
+	re     *regexp.Regexp
+	date   time.Time
+}
+
+// imageSelector selects the images of projects matching all of its terms.
+type imageSelector struct {
+	expr     string
+	projects []string
+	terms    []selectorTerm
+	// states are the deprecation states of the selected images.
+	states []string
+	// latest selects only the most recent image of each family.
+	latest bool
+}
+
+// isImageSelector returns whether the -images value is a list of image
+// selectors rather than a list of images.
+func isImageSelector(s string) bool {
+	return strings.ContainsAny(s, "=~")
+}
+
+// parseImageSelectors parses image selectors separated by semicolons. A
+// selector is a comma separated list of terms, which are a key, an operator
+// and a value, like:
+//
+//	project=debian-cloud,family~^debian-1[23],arch=ARM64,feature=GVNIC
+//
+// The keys are:
+//   - project: the projects to list images from, separated by |. Defaults to
+//     the projects of the public images known to the manager.
+//   - family, name and label.<key>: the image family, name or label value,
+//     compared with =, != or with a regex with ~ and !~.
+//   - arch: the architecture, X86_64 or ARM64, compared with = or !=.
+//   - feature: a guest OS feature the images have with =, or don't have with !=.
+//   - created: the creation date, as YYYY-MM-DD or RFC 3339, compared with >,
+//     >=, < or <=.
+//   - state: the deprecation state, ACTIVE, DEPRECATED, OBSOLETE or DELETED,
+//     compared with = or !=. Defaults to ACTIVE.
+//   - latest: true, the default, to select only the most recent image of each
+//     family, or false to select all the images. The most recent image of a
+//     family is picked among the images in the selected states, before the
+//     other terms filter them, like the family resolves in GCE.
+//
+// Values of = and != may list alternatives separated by |.
+func parseImageSelectors(s string) ([]*imageSelector, error) {
+	var selectors []*imageSelector
+	for _, expr := range strings.Split(s, ";") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		sel, err := parseImageSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+	}
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no image selector in %q", s)
+	}
+	return selectors, nil
+}
+
+func parseImageSelector(expr string) (*imageSelector, error) {
+	sel := &imageSelector{expr: strings.TrimSpace(expr), states: []string{"ACTIVE"}, latest: true}
+	for _, s := range strings.Split(expr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		term, err := parseSelectorTerm(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image selector %q: %v", expr, err)
+		}
+		switch term.key {
+		case "project":
+			sel.projects = append(sel.projects, term.values...)
+		case "state":
+			sel.states = nil
+			for _, state := range []string{"ACTIVE", "DEPRECATED", "OBSOLETE", "DELETED"} {
+				if slices.Contains(term.values, state) == (term.op == "=") {
+					sel.states = append(sel.states, state)
+				}
+			}
+		case "latest":
+			sel.latest = term.value == "true"
+		default:
+			sel.terms = append(sel.terms, term)
+		}
+	}
+	if len(sel.projects) == 0 {
+		for _, p := range projectMap {
+			if !slices.Contains(sel.projects, p) {
+				sel.projects = append(sel.projects, p)
+			}
+		}
+		sort.Strings(sel.projects)
+	}
+	return sel, nil
+}
+
+func parseSelectorTerm(s string) (selectorTerm, error) {
+	i := strings.IndexAny(s, "!=~<>")
+	if i <= 0 {
+		return selectorTerm{}, fmt.Errorf("term %q must be a key, an operator and a value", s)
+	}
+	term := selectorTerm{key: s[:i]}
+	for _, op := range selectorOps {
+		if strings.HasPrefix(s[i:], op) {
+			term.op = op
+			break
+		}
+	}
+	term.value = s[i+len(term.op):]
+	term.values = strings.Split(term.value, "|")
+
+	var ops []string
+	switch {
+	case term.key == "project", term.key == "latest":
+		ops = []string{"="}
+	case term.key == "family", term.key == "name", strings.HasPrefix(term.key, "label."):
+		ops = []string{"=", "!=", "~", "!~"}
+	case term.key == "arch", term.key == "state":
+		ops = []string{"=", "!="}
+		for i, v := range term.values {
+			term.values[i] = strings.ToUpper(v)
+		}
+	case term.key == "feature":
+		ops = []string{"=", "!="}
+	case term.key == "created":
+		ops = []string{">", ">=", "<", "<="}
+	default:
+		return selectorTerm{}, fmt.Errorf("unknown key %q", term.key)
+	}
+	if !slices.Contains(ops, term.op) {
+		return selectorTerm{}, fmt.Errorf("key %q does not support operator %q", term.key, term.op)
+	}
+
+	var err error
+	switch {
+	case term.op == "~" || term.op == "!~":
+		if term.re, err = regexp.Compile(term.value); err != nil {
+			return selectorTerm{}, fmt.Errorf("invalid regex in %q: %v", s, err)
+		}
+	case term.key == "created":
+		if term.date, err = parseSelectorDate(term.value); err != nil {
+			return selectorTerm{}, err
+		}
+	case term.key == "latest" && term.value != "true" && term.value != "false":
+		return selectorTerm{}, fmt.Errorf("latest must be true or false, got %q", term.value)
+	}
+	return term, nil
+}
+
+func parseSelectorDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q must be formatted as YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// matchString compares a property of an image with the term.
+func (t selectorTerm) matchString(s string) bool {
+	switch t.op {
+	case "~":
+		return t.re.MatchString(s)
+	case "!~":
+		return !t.re.MatchString(s)
+	case "!=":
+		return !slices.Contains(t.values, s)
+	default:
+		return slices.Contains(t.values, s)
+	}
+}
+
+func (t selectorTerm) matches(image *computev1.Image) bool {
+	switch {
+	case t.key == "family":
+		return t.matchString(image.Family)
+	case t.key == "name":
+		return t.matchString(image.Name)
+	case strings.HasPrefix(t.key, "label."):
+		return t.matchString(image.Labels[strings.TrimPrefix(t.key, "label.")])
+	case t.key == "arch":
+		return t.matchString(image.Architecture)
+	case t.key == "feature":
+		has := slices.ContainsFunc(t.values, func(f string) bool { return utils.HasFeature(image, f) })
+		return has == (t.op == "=")
+	case t.key == "created":
+		created, err := time.Parse(time.RFC3339, image.CreationTimestamp)
+		if err != nil {
+			return false
+		}
+		switch t.op {
+		case ">":
+			return created.After(t.date)
+		case ">=":
+			return !created.Before(t.date)
+		case "<":
+			return created.Before(t.date)
+		default:
+			return !created.After(t.date)
+		}
+	}
+	return false
+}
+
+// imageState returns the deprecation state of the image.
+func imageState(image *computev1.Image) string {
+	if image.Deprecated == nil || image.Deprecated.State == "" {
+		return "ACTIVE"
+	}
+	return image.Deprecated.State
+}
+
+// createdAfter returns whether the image was created after the other image.
+// Images without a valid creation timestamp are the oldest.
+func createdAfter(image, other *computev1.Image) bool {
+	created, err := time.Parse(time.RFC3339, image.CreationTimestamp)
+	if err != nil {
+		return false
+	}
+	otherCreated, err := time.Parse(time.RFC3339, other.CreationTimestamp)
+	if err != nil {
+		return true
+	}
+	return created.After(otherCreated)
+}
+
+// selectImages returns the images of a project matching the selector. The
+// most recent image of each family is picked before the terms filter the
+// images, so that a family whose latest image doesn't match isn't replaced by
+// an older image.
+func (sel *imageSelector) selectImages(images []*computev1.Image) []*computev1.Image {
+	var candidates []*computev1.Image
+	latest := make(map[string]*computev1.Image)
+	for _, image := range images {
+		if !slices.Contains(sel.states, imageState(image)) {
+			continue
+		}
+		if !sel.latest || image.Family == "" {
+			candidates = append(candidates, image)
+			continue
+		}
+		if l, ok := latest[image.Family]; !ok || createdAfter(image, l) {
+			latest[image.Family] = image
+		}
+	}
+	for _, image := range latest {
+		candidates = append(candidates, image)
+	}
+	var selected []*computev1.Image
+	for _, image := range candidates {
+		if !slices.ContainsFunc(sel.terms, func(t selectorTerm) bool { return !t.matches(image) }) {
+			selected = append(selected, image)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+	return selected
+}
+
+// resolveImageSelectors lists the images of the projects of the selectors,
+// and returns the paths of the images matching any of the selectors.
+func resolveImageSelectors(computeclient compute.Client, s string) ([]string, error) {
+	selectors, err := parseImageSelectors(s)
+	if err != nil {
+		return nil, err
+	}
+	var resolved []string
+	projectImages := make(map[string][]*computev1.Image)
+	for _, sel := range selectors {
+		for _, project := range sel.projects {
+			images, ok := projectImages[project]
+			if !ok {
+				if images, err = computeclient.ListImages(project); err != nil {
+					return nil, fmt.Errorf("failed to list images of project %s: %v", project, err)
+				}
+				projectImages[project] = images
+			}
+			for _, image := range sel.selectImages(images) {
+				path := fmt.Sprintf("projects/%s/global/images/%s", project, image.Name)
+				if !slices.Contains(resolved, path) {
+					resolved = append(resolved, path)
+				}
+			}
+		}
+	}
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("no image matches %q", s)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"slices"
+	"testing"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+func TestParseImageSelectors(t *testing.T) {
+	tests := []struct {
+		selector    string
+		wantErr     bool
+		wantProject string
+	}{
+		{selector: "project=debian-cloud,family~^debian-1[23],arch=ARM64,feature=GVNIC", wantProject: "debian-cloud"},
+		{selector: "project=debian-cloud;project=rhel-cloud,latest=false", wantProject: "debian-cloud"},
+		{selector: "family~sap,created>=2026-01-01,state!=DELETED", wantProject: "rhel-sap-cloud"},
+		{selector: "label.team=images", wantProject: "windows-cloud"},
+		{selector: "color=blue", wantErr: true},
+		{selector: "arch~ARM", wantErr: true},
+		{selector: "family~(", wantErr: true},
+		{selector: "created>yesterday", wantErr: true},
+		{selector: "latest=maybe", wantErr: true},
+		{selector: "family", wantErr: true},
+		{selector: ";", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			selectors, err := parseImageSelectors(tc.selector)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseImageSelectors(%q) = %v, want error: %v", tc.selector, err, tc.wantErr)
+			}
+			if err == nil && !slices.Contains(selectors[0].projects, tc.wantProject) {
+				t.Errorf("parseImageSelectors(%q) projects = %v, want %s", tc.selector, selectors[0].projects, tc.wantProject)
+			}
+		})
+	}
+}
+
+func TestSelectImages(t *testing.T) {
+	images := []*computev1.Image{
+		{Name: "debian-11-bullseye-v20260101", Family: "debian-11", Architecture: "X86_64", CreationTimestamp: "2026-01-01T00:00:00.000-08:00"},
+		{Name: "debian-12-bookworm-v20250101", Family: "debian-12", Architecture: "X86_64", CreationTimestamp: "2025-01-01T00:00:00.000-08:00"},
+		{Name: "debian-12-bookworm-v20260101", Family: "debian-12", Architecture: "X86_64", CreationTimestamp: "2026-01-01T00:00:00.000-08:00", GuestOsFeatures: []*computev1.GuestOsFeature{{Type: "GVNIC"}}},
+		{Name: "debian-12-bookworm-arm64-v20260101", Family: "debian-12-arm64", Architecture: "ARM64", CreationTimestamp: "2026-01-01T00:00:00.000-08:00", GuestOsFeatures: []*computev1.GuestOsFeature{{Type: "GVNIC"}}, Labels: map[string]string{"track": "stable"}},
+		{Name: "debian-13-trixie-v20260101", Family: "debian-13", Architecture: "X86_64", CreationTimestamp: "2026-01-01T00:00:00.000-08:00", Deprecated: &computev1.DeprecationStatus{State: "DEPRECATED"}},
+		// Created across the end of daylight saving time, the PST image is
+		// the most recent although its timestamp sorts first.
+		{Name: "debian-14-forky-v20261101-pst", Family: "debian-14", Architecture: "X86_64", CreationTimestamp: "2026-11-01T01:10:00.000-08:00"},
+		{Name: "debian-14-forky-v20261101-pdt", Family: "debian-14", Architecture: "X86_64", CreationTimestamp: "2026-11-01T01:30:00.000-07:00"},
+	}
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{
+			selector: "project=debian-cloud",
+			want:     []string{"debian-11-bullseye-v20260101", "debian-12-bookworm-arm64-v20260101", "debian-12-bookworm-v20260101", "debian-14-forky-v20261101-pst"},
+		},
+		{
+			selector: "project=debian-cloud,family=debian-14",
+			want:     []string{"debian-14-forky-v20261101-pst"},
+		},
+		{
+			selector: "project=debian-cloud,family~^debian-1[23],arch=ARM64,feature=GVNIC",
+			want:     []string{"debian-12-bookworm-arm64-v20260101"},
+		},
+		{
+			selector: "project=debian-cloud,family=debian-12,latest=false",
+			want:     []string{"debian-12-bookworm-v20250101", "debian-12-bookworm-v20260101"},
+		},
+		{
+			selector: "project=debian-cloud,family=debian-12,latest=false,created<2025-06-01",
+			want:     []string{"debian-12-bookworm-v20250101"},
+		},
+		{
+			// The latest debian-12 image has GVNIC, so no older image of the
+			// family is selected instead.
+			selector: "project=debian-cloud,feature!=GVNIC,name!~bullseye|forky",
+			want:     nil,
+		},
+		{
+			selector: "project=debian-cloud,latest=false,feature!=GVNIC,name!~bullseye|forky",
+			want:     []string{"debian-12-bookworm-v20250101"},
+		},
+		{
+			selector: "project=debian-cloud,label.track=stable",
+			want:     []string{"debian-12-bookworm-arm64-v20260101"},
+		},
+		{
+			selector: "project=debian-cloud,state=deprecated|active,family=debian-13",
+			want:     []string{"debian-13-trixie-v20260101"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			sel, err := parseImageSelector(tc.selector)
+			if err != nil {
+				t.Fatalf("parseImageSelector(%q) = %v", tc.selector, err)
+			}
+			var got []string
+			for _, image := range sel.selectImages(images) {
+				got = append(got, image.Name)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("selectImages(%q) = %v, want %v", tc.selector, got, tc.want)
+			}
+		})
+	}
+}
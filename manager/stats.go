@@ -0,0 +1,166 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"math"
+	"sort"
+)
+
+// meanVariance returns the mean and the sample variance of the values.
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// tStatistic returns the t statistic of the difference between the mean of
+// the samples and the mean of the baseline values, and its degrees of freedom.
+// With several samples it is the statistic of Welch's t-test. With a single
+// sample, it is the statistic of the prediction interval of the baseline
+// values. The baseline must have at least 2 values.
+func tStatistic(baseline, samples []float64) (t, df float64) {
+	bMean, bVar := meanVariance(baseline)
+	sMean, sVar := meanVariance(samples)
+	nb, ns := float64(len(baseline)), float64(len(samples))
+
+	var se float64
+	if len(samples) == 1 {
+		se = math.Sqrt(bVar * (1 + 1/nb))
+		df = nb - 1
+	} else {
+		vb, vs := bVar/nb, sVar/ns
+		se = math.Sqrt(vb + vs)
+		if se > 0 {
+			df = (vb + vs) * (vb + vs) / (vb*vb/(nb-1) + vs*vs/(ns-1))
+		}
+	}
+	diff := sMean - bMean
+	switch {
+	case se > 0:
+		return diff / se, df
+	case diff == 0:
+		return 0, math.Max(df, 1)
+	default:
+		// Constant values which differ are as significant as it gets.
+		return math.Copysign(math.Inf(1), diff), math.Max(df, 1)
+	}
+}
+
+// studentTSurvival returns the probability that a Student's t variable with df
+// degrees of freedom is greater than t.
+func studentTSurvival(t, df float64) float64 {
+	if math.IsInf(t, 0) {
+		if t > 0 {
+			return 0
+		}
+		return 1
+	}
+	p := 0.5 * regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+	if t < 0 {
+		return 1 - p
+	}
+	return p
+}
+
+// regularizedIncompleteBeta returns I_x(a, b), evaluated with its continued
+// fraction.
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly below this point, use the
+	// symmetry I_x(a, b) = 1 - I_1-x(b, a) above it.
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction of the incomplete
+// beta function with the modified Lentz method.
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= maxIterations; m++ {
+		m := float64(m)
+		for _, num := range []float64{
+			m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m)),
+			-(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= d * c
+		}
+		if math.Abs(d*c-1) < epsilon {
+			break
+		}
+	}
+	return f
+}
+
+// benjaminiHochberg returns the p-values adjusted with the Benjamini-Hochberg
+// procedure, in the order of the p-values, so that rejecting the hypotheses
+// whose adjusted p-value is below alpha bounds the false discovery rate of
+// the rejections to alpha.
+func benjaminiHochberg(pValues []float64) []float64 {
+	n := len(pValues)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return pValues[order[i]] < pValues[order[j]] })
+	adjusted := make([]float64, n)
+	// The adjusted p-value of the rank k is the minimum of p*n/j over the
+	// ranks j >= k, computed from the largest p-value down.
+	minimum := 1.0
+	for k := n - 1; k >= 0; k-- {
+		i := order[k]
+		minimum = math.Min(minimum, pValues[i]*float64(n)/float64(k+1))
+		adjusted[i] = minimum
+	}
+	return adjusted
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeanVariance(t *testing.T) {
+	mean, variance := meanVariance([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(variance-32.0/7) > 1e-12 {
+		t.Errorf("meanVariance() = %g, %g, want 5, %g", mean, variance, 32.0/7)
+	}
+	if mean, variance := meanVariance([]float64{3}); mean != 3 || variance != 0 {
+		t.Errorf("meanVariance() of a single value = %g, %g, want 3, 0", mean, variance)
+	}
+}
+
+func TestStudentTSurvival(t *testing.T) {
+	tests := []struct {
+		t, df, want float64
+	}{
+		{t: 0, df: 5, want: 0.5},
+		{t: 2.228, df: 10, want: 0.025},
+		{t: -2.228, df: 10, want: 0.975},
+		{t: 1.645, df: 1e6, want: 0.05},
+		{t: 6.314, df: 1, want: 0.05},
+		{t: math.Inf(1), df: 3, want: 0},
+	}
+	for _, tc := range tests {
+		if got := studentTSurvival(tc.t, tc.df); math.Abs(got-tc.want) > 1e-3 {
+			t.Errorf("studentTSurvival(%g, %g) = %g, want %g", tc.t, tc.df, got, tc.want)
+		}
+	}
+}
+
+func TestTStatistic(t *testing.T) {
+	baseline := []float64{10, 12, 11, 13, 9}
+	// A single sample is compared with the prediction interval of the
+	// baseline, whose mean is 11 and standard deviation sqrt(2.5).
+	tStat, df := tStatistic(baseline, []float64{14})
+	if want := 3 / math.Sqrt(2.5*1.2); math.Abs(tStat-want) > 1e-12 || df != 4 {
+		t.Errorf("tStatistic() of a single sample = %g, %g, want %g, 4", tStat, df, want)
+	}
+	// Welch's t-test with equal variances and sizes has 2n-2 degrees of
+	// freedom.
+	tStat, df = tStatistic(baseline, []float64{13, 15, 14, 16, 12})
+	if want := 3 / math.Sqrt(1); math.Abs(tStat-want) > 1e-12 || math.Abs(df-8) > 1e-12 {
+		t.Errorf("tStatistic() of several samples = %g, %g, want %g, 8", tStat, df, want)
+	}
+	if tStat, _ := tStatistic([]float64{5, 5}, []float64{6}); !math.IsInf(tStat, 1) {
+		t.Errorf("tStatistic() of constant values = %g, want +Inf", tStat)
+	}
+}
+
+func TestBenjaminiHochberg(t *testing.T) {
+	got := benjaminiHochberg([]float64{0.04, 0.01, 0.03, 0.5})
+	// Sorted, the p-values are multiplied by 4/1, 4/2, 4/3 and 4/4, and each
+	// adjusted p-value is the minimum of the ones of the larger p-values.
+	want := []float64{0.04 * 4 / 3, 0.04, 0.04 * 4 / 3, 0.5}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("benjaminiHochberg() = %v, want %v", got, want)
+			break
+		}
+	}
+	if got := benjaminiHochberg(nil); len(got) != 0 {
+		t.Errorf("benjaminiHochberg(nil) = %v, want empty", got)
+	}
+}
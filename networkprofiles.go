@@ -0,0 +1,265 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
+	daisy "github.com/GoogleCloudPlatform/compute-daisy"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// NetworkProfileIPv4Only is an IPv4-only subnetwork on the external
+	// profile network.
+	NetworkProfileIPv4Only = "ipv4-only"
+	// NetworkProfileDualStackExternal is a dual stack subnetwork with external
+	// IPv6 access. This is the profile to use for a primary NIC that needs IPv6.
+	NetworkProfileDualStackExternal = "dual-stack-external"
+	// NetworkProfileDualStackInternal is a dual stack subnetwork with internal
+	// (ULA) IPv6 access on a separate network. Secondary NICs don't have
+	// external connectivity by default, so this is the profile to use for
+	// secondary NICs that need IPv6.
+	NetworkProfileDualStackInternal = "dual-stack-internal"
+	// NetworkProfileIPv6Only attaches an IPv6-only NIC to the dual stack
+	// subnetwork with external IPv6 access.
+	NetworkProfileIPv6Only = "ipv6-only"
+)
+
+var (
+	// networkProfileSpecs are the topologies backing each network profile.
+	// Profiles that share a network name share the same network resource.
+	networkProfileSpecs = map[string]networkProfileSpec{
+		NetworkProfileIPv4Only: {
+			network:         "profile-external",
+			subnetwork:      "profile-ipv4-only",
+			ipRange:         "10.128.0.0/24",
+			subnetStackType: "IPV4_ONLY",
+			stackType:       "IPV4_ONLY",
+		},
+		NetworkProfileDualStackExternal: {
+			network:         "profile-external",
+			subnetwork:      "profile-dual-external",
+			ipRange:         "10.128.1.0/24",
+			subnetStackType: "IPV4_IPV6",
+			stackType:       "IPV4_IPV6",
+			ipv6AccessType:  "EXTERNAL",
+		},
+		NetworkProfileDualStackInternal: {
+			network:         "profile-internal",
+			subnetwork:      "profile-dual-internal",
+			ipRange:         "10.0.0.0/24",
+			subnetStackType: "IPV4_IPV6",
+			stackType:       "IPV4_IPV6",
+			ipv6AccessType:  "INTERNAL",
+			ulaInternalIPv6: true,
+			firewallRules: []networkProfileFirewallRule{
+				{name: "profile-internal-allow-ipv4", protocol: "tcp", ranges: []string{"0.0.0.0/0"}},
+				{name: "profile-internal-allow-ipv6", protocol: "tcp", ranges: []string{"0::0/0"}},
+			},
+		},
+		NetworkProfileIPv6Only: {
+			network:         "profile-external",
+			subnetwork:      "profile-dual-external",
+			ipRange:         "10.128.1.0/24",
+			subnetStackType: "IPV4_IPV6",
+			stackType:       "IPV6_ONLY",
+			ipv6AccessType:  "EXTERNAL",
+		},
+	}
+
+	// noIPv6Images are the images of OS versions without IPv6 support. Images
+	// with IPv6 bugs have an ipv6 feature exception in the exceptions file
+	// instead.
+	noIPv6Images = []exceptions.Exception{
+		exceptions.Exception{
+			Match:    exceptions.ImageSLES,
+			Versions: "12",
+		},
+		exceptions.Exception{
+			Match:    exceptions.ImageUbuntu,
+			Versions: "16.04",
+		},
+	}
+)
+
+type networkProfileFirewallRule struct {
+	name     string
+	protocol string
+	ports    []string
+	ranges   []string
+}
+
+type networkProfileSpec struct {
+	network         string
+	subnetwork      string
+	ipRange         string
+	subnetStackType string
+	stackType       string
+	ipv6AccessType  string
+	ulaInternalIPv6 bool
+	firewallRules   []networkProfileFirewallRule
+}
+
+// NetworkProfile is a named network topology created on demand by
+// TestWorkflow.NetworkProfile.
+type NetworkProfile struct {
+	name           string
+	network        *Network
+	subnetwork     *Subnetwork
+	stackType      string
+	ipv6AccessType string
+}
+
+// Name returns the name of the network profile.
+func (p *NetworkProfile) Name() string {
+	return p.name
+}
+
+// Network returns the network backing the profile.
+func (p *NetworkProfile) Network() *Network {
+	return p.network
+}
+
+// Subnetwork returns the subnetwork backing the profile.
+func (p *NetworkProfile) Subnetwork() *Subnetwork {
+	return p.subnetwork
+}
+
+// SupportsIPv6 returns whether the image under test is expected to work on
+// IPv6 networks. It is false for OS versions without IPv6 support, and for
+// images with an unexpired ipv6 feature exception in the exceptions file.
+func (t *TestWorkflow) SupportsIPv6() bool {
+	if t.Image == nil {
+		return true
+	}
+	if exceptions.HasMatch(t.Image.Name, noIPv6Images) {
+		return false
+	}
+	// Expired exceptions no longer disable IPv6, so that the IPv6 tests fail
+	// until the bug is fixed or the exception is extended.
+	e, err := exceptions.MatchDefaultFeature("ipv6", t.Image.Name)
+	return e == nil || err != nil
+}
+
+// NetworkProfile returns the named network profile, creating its network,
+// subnetwork and firewall rules the first time it is requested. Subsequent
+// calls with the same name, or with a profile sharing the same network, reuse
+// the existing resources.
+func (t *TestWorkflow) NetworkProfile(name string) (*NetworkProfile, error) {
+	if p, ok := t.networkProfiles[name]; ok {
+		return p, nil
+	}
+	spec, ok := networkProfileSpecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown network profile %q", name)
+	}
+	if t.networkProfiles == nil {
+		t.networkProfiles = make(map[string]*NetworkProfile)
+	}
+
+	// Look for resources already created by another profile.
+	var network *Network
+	var subnetwork *Subnetwork
+	for _, p := range t.networkProfiles {
+		if p.network.name == spec.network {
+			network = p.network
+		}
+		if p.subnetwork.name == spec.subnetwork {
+			subnetwork = p.subnetwork
+		}
+	}
+
+	if network == nil {
+		// Specify AutoCreateSubnetworks explicitly to avoid creating legacy
+		// networks.
+		falseValue := false
+		var err error
+		network, err = t.CreateNetworkFromDaisyNetwork(&daisy.Network{
+			Network: compute.Network{
+				Name:                  spec.network,
+				Mtu:                   DefaultMTU,
+				EnableUlaInternalIpv6: spec.ulaInternalIPv6,
+			},
+			AutoCreateSubnetworks: &falseValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range spec.firewallRules {
+			if err := network.CreateFirewallRule(rule.name, rule.protocol, rule.ports, rule.ranges); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if subnetwork == nil {
+		var err error
+		subnetwork, err = network.CreateSubnetworkFromDaisySubnetwork(&daisy.Subnetwork{
+			Subnetwork: compute.Subnetwork{
+				Name:           spec.subnetwork,
+				IpCidrRange:    spec.ipRange,
+				StackType:      spec.subnetStackType,
+				Ipv6AccessType: spec.ipv6AccessType,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &NetworkProfile{
+		name:           name,
+		network:        network,
+		subnetwork:     subnetwork,
+		stackType:      spec.stackType,
+		ipv6AccessType: spec.ipv6AccessType,
+	}
+	t.networkProfiles[name] = p
+	return p, nil
+}
+
+// AddNetworkProfile adds a NIC to the test VM on the named network profile,
+// creating the profile if needed. The NIC uses the stack type of the profile.
+//
+// If the image under test does not support IPv6, dual stack NICs are attached
+// as IPv4-only, and IPv6-only NICs return an error. Callers can check
+// TestWorkflow.SupportsIPv6 to skip creating such VMs.
+func (t *TestVM) AddNetworkProfile(name string) error {
+	return t.AddNetworkProfileWithStackType(name, "")
+}
+
+// AddNetworkProfileWithStackType is like AddNetworkProfile, but overrides the
+// stack type of the NIC. This is useful to attach, for example, an IPv4-only
+// NIC to a dual stack profile. An empty stack type uses the profile's stack
+// type.
+func (t *TestVM) AddNetworkProfileWithStackType(name, stackType string) error {
+	p, err := t.testWorkflow.NetworkProfile(name)
+	if err != nil {
+		return err
+	}
+	if stackType == "" {
+		stackType = p.stackType
+	}
+	if !t.testWorkflow.SupportsIPv6() {
+		switch stackType {
+		case "IPV4_IPV6":
+			stackType = "IPV4_ONLY"
+		case "IPV6_ONLY":
+			return fmt.Errorf("image %s does not support IPv6, cannot add IPv6-only network profile %q", t.testWorkflow.Image.Name, name)
+		}
+	}
+	return t.AddCustomNetworkWithStackType(p.network, p.subnetwork, stackType, p.ipv6AccessType)
+}
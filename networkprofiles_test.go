@@ -0,0 +1,167 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"testing"
+)
+
+// TestNetworkProfile tests that network profiles create their resources once
+// and share networks between profiles.
+func TestNetworkProfile(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	for _, name := range []string{NetworkProfileIPv4Only, NetworkProfileDualStackExternal, NetworkProfileIPv6Only, NetworkProfileDualStackInternal, NetworkProfileIPv4Only} {
+		p, err := twf.NetworkProfile(name)
+		if err != nil {
+			t.Fatalf("twf.NetworkProfile(%q) = %v, want nil", name, err)
+		}
+		if p.Name() != name {
+			t.Errorf("p.Name() = %q, want %q", p.Name(), name)
+		}
+	}
+	networks := twf.wf.Steps[createNetworkStepName].CreateNetworks
+	if networks == nil || len(*networks) != 2 {
+		t.Fatalf("unexpected networks created: %v, want 2", networks)
+	}
+	subnetworks := twf.wf.Steps[createSubnetworkStepName].CreateSubnetworks
+	if subnetworks == nil || len(*subnetworks) != 3 {
+		t.Fatalf("unexpected subnetworks created: %v, want 3", subnetworks)
+	}
+	firewalls := twf.wf.Steps[createFirewallStepName].CreateFirewallRules
+	if firewalls == nil || len(*firewalls) != 2 {
+		t.Fatalf("unexpected firewall rules created: %v, want 2", firewalls)
+	}
+	for _, n := range *networks {
+		if n.AutoCreateSubnetworks == nil || *n.AutoCreateSubnetworks {
+			t.Errorf("network %s is not in custom mode", n.Name)
+		}
+		if n.Name == "profile-internal" && !n.EnableUlaInternalIpv6 {
+			t.Errorf("network %s does not enable ULA internal IPv6", n.Name)
+		}
+	}
+
+	if _, err := twf.NetworkProfile("unknown"); err == nil {
+		t.Errorf("twf.NetworkProfile(%q) = nil, want error", "unknown")
+	}
+}
+
+// TestAddNetworkProfile tests that *TestVM.AddNetworkProfile sets the NIC
+// stack type and access type from the profile.
+func TestAddNetworkProfile(t *testing.T) {
+	tests := []struct {
+		profile        string
+		stackType      string
+		wantStackType  string
+		wantAccessType string
+		wantSubnetwork string
+	}{
+		{
+			profile:        NetworkProfileIPv4Only,
+			wantStackType:  "IPV4_ONLY",
+			wantSubnetwork: "profile-ipv4-only",
+		},
+		{
+			profile:        NetworkProfileDualStackExternal,
+			wantStackType:  "IPV4_IPV6",
+			wantAccessType: "EXTERNAL",
+			wantSubnetwork: "profile-dual-external",
+		},
+		{
+			profile:        NetworkProfileDualStackInternal,
+			wantStackType:  "IPV4_IPV6",
+			wantAccessType: "INTERNAL",
+			wantSubnetwork: "profile-dual-internal",
+		},
+		{
+			profile:        NetworkProfileIPv6Only,
+			wantStackType:  "IPV6_ONLY",
+			wantAccessType: "EXTERNAL",
+			wantSubnetwork: "profile-dual-external",
+		},
+		{
+			profile:        NetworkProfileDualStackInternal,
+			stackType:      "IPV6_ONLY",
+			wantStackType:  "IPV6_ONLY",
+			wantAccessType: "INTERNAL",
+			wantSubnetwork: "profile-dual-internal",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.profile+tc.stackType, func(t *testing.T) {
+			twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+			tvm, err := twf.CreateTestVM("vm")
+			if err != nil {
+				t.Fatalf("failed to create test vm: %v", err)
+			}
+			if err := tvm.AddNetworkProfileWithStackType(tc.profile, tc.stackType); err != nil {
+				t.Fatalf("tvm.AddNetworkProfileWithStackType(%q, %q) = %v, want nil", tc.profile, tc.stackType, err)
+			}
+			nic := tvm.instance.NetworkInterfaces[0]
+			if nic.StackType != tc.wantStackType {
+				t.Errorf("nic.StackType = %q, want %q", nic.StackType, tc.wantStackType)
+			}
+			if nic.Ipv6AccessType != tc.wantAccessType {
+				t.Errorf("nic.Ipv6AccessType = %q, want %q", nic.Ipv6AccessType, tc.wantAccessType)
+			}
+			if nic.Subnetwork != tc.wantSubnetwork {
+				t.Errorf("nic.Subnetwork = %q, want %q", nic.Subnetwork, tc.wantSubnetwork)
+			}
+		})
+	}
+}
+
+func TestSupportsIPv6(t *testing.T) {
+	tests := []struct {
+		image string
+		want  bool
+	}{
+		{image: "debian-12-bookworm-v20260101", want: true},
+		{image: "rhel-9-4-sap-ha-v20260101", want: true},
+		{image: "sles-12-sp5-v20260101", want: false},
+		{image: "ubuntu-1604-xenial-v20210429", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+			twf.Image.Name = tc.image
+			if got := twf.SupportsIPv6(); got != tc.want {
+				t.Errorf("twf.SupportsIPv6() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestAddNetworkProfileNoIPv6 tests that images without IPv6 support fall back
+// to IPv4 on dual stack profiles and fail on IPv6-only profiles.
+func TestAddNetworkProfileNoIPv6(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	twf.Image.Name = "ubuntu-1604-xenial-v20210429"
+	if twf.SupportsIPv6() {
+		t.Fatalf("twf.SupportsIPv6() = true, want false for %s", twf.Image.Name)
+	}
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	if err := tvm.AddNetworkProfile(NetworkProfileDualStackExternal); err != nil {
+		t.Fatalf("tvm.AddNetworkProfile(%q) = %v, want nil", NetworkProfileDualStackExternal, err)
+	}
+	if got := tvm.instance.NetworkInterfaces[0].StackType; got != "IPV4_ONLY" {
+		t.Errorf("nic.StackType = %q, want %q", got, "IPV4_ONLY")
+	}
+	if err := tvm.AddNetworkProfile(NetworkProfileIPv6Only); err == nil {
+		t.Errorf("tvm.AddNetworkProfile(%q) = nil, want error", NetworkProfileIPv6Only)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
+	"google.golang.org/api/compute/v1"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Suite)
+)
+
+// OS families of images, used in the OSFamilies and ExcludedOSFamilies of
+// SuiteMetadata.
+const (
+	OSFamilyLinux   = "linux"
+	OSFamilyWindows = "windows"
+	OSFamilyCOS     = "cos"
+	OSFamilyDebian  = "debian"
+	OSFamilyEL      = "el"
+	OSFamilyRHEL    = "rhel"
+	OSFamilySUSE    = "suse"
+	OSFamilyUbuntu  = "ubuntu"
+)
+
+// osFamilies maps OS families to whether a guest OS belongs to them.
+var osFamilies = map[string]func(utils.GuestOS) bool{
+	OSFamilyLinux:   utils.GuestOS.IsLinux,
+	OSFamilyWindows: utils.GuestOS.IsWindows,
+	OSFamilyCOS:     func(g utils.GuestOS) bool { return g.Distro == utils.DistroCOS },
+	OSFamilyDebian:  func(g utils.GuestOS) bool { return g.Distro == utils.DistroDebian },
+	OSFamilyEL:      utils.GuestOS.IsEL,
+	OSFamilyRHEL:    func(g utils.GuestOS) bool { return g.Distro == utils.DistroRHEL },
+	OSFamilySUSE:    utils.GuestOS.IsSUSE,
+	OSFamilyUbuntu:  func(g utils.GuestOS) bool { return g.Distro == utils.DistroUbuntu },
+}
+
+// SuiteMetadata describes a registered test suite and the images and
+// resources it needs. The manager skips the suite on images which do not meet
+// its requirements without calling its setup function.
+type SuiteMetadata struct {
+	// Description is a one line description of what the suite tests.
+	Description string
+	// OSFamilies are the OS families supported by the suite. All OS families
+	// are supported if empty.
+	OSFamilies []string
+	// ExcludedOSFamilies are the OS families not supported by the suite.
+	ExcludedOSFamilies []string
+	// Architectures are the image architectures supported by the suite, like
+	// X86_64 or ARM64. All architectures are supported if empty. Images without
+	// an architecture are X86_64.
+	Architectures []string
+	// RequiredFeatures are the guest OS features images must have.
+	RequiredFeatures []string
+	// AnyOfFeatures are guest OS features images must have at least one of,
+	// like the confidential computing technologies a suite tests.
+	AnyOfFeatures []string
+	// ExcludedFeatures are the guest OS features images must not have.
+	ExcludedFeatures []string
+	// ExcludedImages are the images not supported by the suite for other
+	// reasons, like known bugs.
+	ExcludedImages []ImageExclusion
+	// RequiresGPUs indicates that the suite creates VMs with GPUs, so that it
+	// only runs on accelerator images.
+	RequiresGPUs bool
+	// ExclusiveProject indicates that the suite modifies project-level data and
+	// must have exclusive use of the project. See TestWorkflow.LockProject.
+	ExclusiveProject bool
+	// SkipImage returns why the suite does not run on the image for reasons
+	// the other fields can't express, like the flags of the suite, or an empty
+	// string. It is optional.
+	SkipImage func(image *compute.Image) string
+}
+
+// ImageExclusion excludes images from a test suite.
+type ImageExclusion struct {
+	// Regexp is matched against the image name.
+	Regexp string
+	// Reason is why matching images are excluded, reported as the skip
+	// message.
+	Reason string
+}
+
+// Suite is a test suite registered with Register.
+type Suite struct {
+	// Name is the name of the suite. It must match the name of the directory
+	// of the suite package, which is the name of its test binary.
+	Name string
+	// SetupFunc sets up the test workflow of the suite.
+	SetupFunc func(*TestWorkflow) error
+	Metadata  SuiteMetadata
+}
+
+// Register makes a test suite available to the manager. Suites call it from
+// an init function, so that a manager binary runs every suite package it
+// imports, including suites from other modules:
+//
+//	func init() {
+//		imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "..."})
+//	}
+//
+// Register panics if the name is empty, the setup function is nil, a suite
+// with the same name is already registered, or the metadata is invalid.
+func Register(name string, setupFunc func(*TestWorkflow) error, metadata SuiteMetadata) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("imagetest: Register called with an empty suite name")
+	}
+	if setupFunc == nil {
+		panic(fmt.Sprintf("imagetest: Register called with a nil setup function for suite %s", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("imagetest: Register called twice for suite %s", name))
+	}
+	for _, family := range append(slices.Clone(metadata.OSFamilies), metadata.ExcludedOSFamilies...) {
+		if _, ok := osFamilies[family]; !ok {
+			panic(fmt.Sprintf("imagetest: Register called with unknown OS family %q for suite %s", family, name))
+		}
+	}
+	for _, e := range metadata.ExcludedImages {
+		if _, err := regexp.Compile(e.Regexp); err != nil {
+			panic(fmt.Sprintf("imagetest: Register called with invalid image regexp for suite %s: %v", name, err))
+		}
+	}
+	registry[name] = &Suite{Name: name, SetupFunc: setupFunc, Metadata: metadata}
+}
+
+// RegisteredSuites returns the registered test suites, sorted by name.
+func RegisteredSuites() []Suite {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	suites := make([]Suite, 0, len(registry))
+	for _, s := range registry {
+		suites = append(suites, *s)
+	}
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Name < suites[j].Name })
+	return suites
+}
+
+// SkipReason returns why the suite does not run on the image, or an empty
+// string if the image meets the requirements of the suite and has no
+// exception in the exceptions file. It returns an error if the exception for
+// the image has expired, in which case the suite must fail instead of being
+// skipped.
+func (s Suite) SkipReason(image *compute.Image) (string, error) {
+	m := s.Metadata
+	guestOS := utils.GuestOSFromImage(image)
+	if len(m.OSFamilies) > 0 && !slices.ContainsFunc(m.OSFamilies, func(family string) bool { return osFamilies[family](guestOS) }) {
+		return fmt.Sprintf("%s only supports OS families %s", s.Name, strings.Join(m.OSFamilies, ", ")), nil
+	}
+	for _, family := range m.ExcludedOSFamilies {
+		if osFamilies[family](guestOS) {
+			return fmt.Sprintf("%s does not support OS family %s", s.Name, family), nil
+		}
+	}
+	arch := image.Architecture
+	if arch == "" {
+		arch = "X86_64"
+	}
+	if len(m.Architectures) > 0 && !slices.Contains(m.Architectures, arch) {
+		return fmt.Sprintf("%s only supports architectures %s", s.Name, strings.Join(m.Architectures, ", ")), nil
+	}
+	for _, feature := range m.RequiredFeatures {
+		if !utils.HasFeature(image, feature) {
+			return fmt.Sprintf("%s requires guest OS feature %s", s.Name, feature), nil
+		}
+	}
+	if len(m.AnyOfFeatures) > 0 && !slices.ContainsFunc(m.AnyOfFeatures, func(feature string) bool { return utils.HasFeature(image, feature) }) {
+		return fmt.Sprintf("%s requires one of guest OS features %s", s.Name, strings.Join(m.AnyOfFeatures, ", ")), nil
+	}
+	for _, feature := range m.ExcludedFeatures {
+		if utils.HasFeature(image, feature) {
+			return fmt.Sprintf("%s does not support guest OS feature %s", s.Name, feature), nil
+		}
+	}
+	if m.RequiresGPUs && !guestOS.HasVariant(utils.VariantAccelerator) {
+		return fmt.Sprintf("%s requires GPUs and only runs on accelerator images", s.Name), nil
+	}
+	for _, e := range m.ExcludedImages {
+		if regexp.MustCompile(e.Regexp).MatchString(image.Name) {
+			return e.Reason, nil
+		}
+	}
+	if m.SkipImage != nil {
+		if reason := m.SkipImage(image); reason != "" {
+			return reason, nil
+		}
+	}
+	e, err := exceptions.MatchDefault(s.Name, "", image.Name)
+	if err != nil {
+		return "", err
+	}
+	if e != nil {
+		return e.String(), nil
+	}
+	return "", nil
+}
+
+// Setup applies the project requirements of the suite to the test workflow,
+// then calls the setup function of the suite.
+func (s Suite) Setup(t *TestWorkflow) error {
+	if s.Metadata.ExclusiveProject {
+		t.LockProject()
+	}
+	return s.SetupFunc(t)
+}
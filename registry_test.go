@@ -0,0 +1,194 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// TestRegister tests that registered suites are returned sorted by name, and
+// that invalid registrations panic.
+func TestRegister(t *testing.T) {
+	setup := func(*TestWorkflow) error { return nil }
+	Register("registrytestb", setup, SuiteMetadata{Description: "b"})
+	Register("registrytesta", setup, SuiteMetadata{Description: "a"})
+
+	var got []Suite
+	for _, s := range RegisteredSuites() {
+		if s.Name == "registrytesta" || s.Name == "registrytestb" {
+			got = append(got, s)
+		}
+	}
+	if len(got) != 2 || got[0].Name != "registrytesta" || got[1].Name != "registrytestb" {
+		t.Fatalf("RegisteredSuites() = %v, want registrytesta and registrytestb in order", got)
+	}
+	if got[0].Metadata.Description != "a" || got[0].SetupFunc == nil {
+		t.Errorf("unexpected registered suite %v", got[0])
+	}
+
+	for _, tc := range []struct {
+		name      string
+		suite     string
+		setupFunc func(*TestWorkflow) error
+	}{
+		{name: "empty name", setupFunc: setup},
+		{name: "nil setup", suite: "registrytestc"},
+		{name: "duplicate", suite: "registrytesta", setupFunc: setup},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) did not panic", tc.suite)
+				}
+			}()
+			Register(tc.suite, tc.setupFunc, SuiteMetadata{})
+		})
+	}
+}
+
+// TestSuiteSkipReason tests that suites are skipped on images which don't
+// meet their requirements.
+func TestSuiteSkipReason(t *testing.T) {
+	windows := []*compute.GuestOsFeature{{Type: "WINDOWS"}}
+	tests := []struct {
+		name      string
+		suiteName string
+		metadata  SuiteMetadata
+		image     *compute.Image
+		wantSkip  bool
+	}{
+		{
+			name:  "no requirements",
+			image: &compute.Image{Name: "debian-12-v20260101"},
+		},
+		{
+			name:     "os family",
+			metadata: SuiteMetadata{OSFamilies: []string{OSFamilyWindows}},
+			image:    &compute.Image{Name: "debian-12-v20260101"},
+			wantSkip: true,
+		},
+		{
+			name:     "one of os families",
+			metadata: SuiteMetadata{OSFamilies: []string{OSFamilyRHEL, OSFamilyWindows}},
+			image:    &compute.Image{Name: "windows-server-2022-dc-v20260101", GuestOsFeatures: windows},
+		},
+		{
+			name:     "excluded os family",
+			metadata: SuiteMetadata{ExcludedOSFamilies: []string{OSFamilyCOS}},
+			image:    &compute.Image{Name: "cos-117-18613-0-79"},
+			wantSkip: true,
+		},
+		{
+			name:     "architecture",
+			metadata: SuiteMetadata{Architectures: []string{"X86_64"}},
+			image:    &compute.Image{Name: "debian-12-arm64-v20260101", Architecture: "ARM64"},
+			wantSkip: true,
+		},
+		{
+			name:     "architecture unset",
+			metadata: SuiteMetadata{Architectures: []string{"X86_64"}},
+			image:    &compute.Image{Name: "debian-12-v20260101"},
+		},
+		{
+			name:     "any of features",
+			metadata: SuiteMetadata{AnyOfFeatures: []string{"SEV_CAPABLE", "TDX_CAPABLE"}},
+			image:    &compute.Image{Name: "ubuntu-2404-noble-amd64-v20260101", GuestOsFeatures: []*compute.GuestOsFeature{{Type: "TDX_CAPABLE"}}},
+		},
+		{
+			name:     "none of features",
+			metadata: SuiteMetadata{AnyOfFeatures: []string{"SEV_CAPABLE", "TDX_CAPABLE"}},
+			image:    &compute.Image{Name: "ubuntu-2404-noble-amd64-v20260101"},
+			wantSkip: true,
+		},
+		{
+			name:     "required feature",
+			metadata: SuiteMetadata{RequiredFeatures: []string{"GVNIC"}},
+			image:    &compute.Image{Name: "debian-12-v20260101"},
+			wantSkip: true,
+		},
+		{
+			name:     "excluded feature",
+			metadata: SuiteMetadata{ExcludedFeatures: []string{"WINDOWS"}},
+			image:    &compute.Image{Name: "windows-server-2022-dc-v20260101", GuestOsFeatures: windows},
+			wantSkip: true,
+		},
+		{
+			name:     "gpus",
+			metadata: SuiteMetadata{RequiresGPUs: true},
+			image:    &compute.Image{Name: "ubuntu-accelerator-2404-amd64-with-nvidia-570-v20260101"},
+		},
+		{
+			name:     "excluded image",
+			metadata: SuiteMetadata{ExcludedImages: []ImageExclusion{{Regexp: "sles-16", Reason: "broken"}}},
+			image:    &compute.Image{Name: "sles-16-0-v20260101"},
+			wantSkip: true,
+		},
+		{
+			name:     "skip image",
+			metadata: SuiteMetadata{SkipImage: func(image *compute.Image) string { return "unsupported flags" }},
+			image:    &compute.Image{Name: "debian-12-v20260101"},
+			wantSkip: true,
+		},
+		{
+			name:     "skip image unset reason",
+			metadata: SuiteMetadata{SkipImage: func(image *compute.Image) string { return "" }},
+			image:    &compute.Image{Name: "debian-12-v20260101"},
+		},
+		{
+			name:      "exceptions file",
+			suiteName: "oslogin",
+			image:     &compute.Image{Name: "sles-16-0-v20260101"},
+			wantSkip:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Suite{Name: "suite", Metadata: tc.metadata}
+			if tc.suiteName != "" {
+				s.Name = tc.suiteName
+			}
+			reason, err := s.SkipReason(tc.image)
+			if err != nil {
+				t.Fatalf("SkipReason(%s) failed: %v", tc.image.Name, err)
+			}
+			if (reason != "") != tc.wantSkip {
+				t.Errorf("SkipReason(%s) = %q, want skip: %v", tc.image.Name, reason, tc.wantSkip)
+			}
+		})
+	}
+}
+
+// TestSuiteSetup tests that the project requirements of a suite are applied
+// before its setup function is called.
+func TestSuiteSetup(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("suite", "image", "30m")
+	var called bool
+	s := Suite{
+		Name:      "suite",
+		SetupFunc: func(*TestWorkflow) error { called = true; return nil },
+		Metadata:  SuiteMetadata{ExclusiveProject: true},
+	}
+	if err := s.Setup(twf); err != nil {
+		t.Fatalf("Setup() = %v, want nil", err)
+	}
+	if !called {
+		t.Errorf("Setup() did not call the setup function")
+	}
+	if !twf.lockProject {
+		t.Errorf("Setup() did not lock the project")
+	}
+}
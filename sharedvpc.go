@@ -0,0 +1,167 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	daisy "github.com/GoogleCloudPlatform/compute-daisy"
+	computeBeta "google.golang.org/api/compute/v0.beta"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// subnetworkUsePermission is the permission needed to attach VMs to a
+// subnetwork, granted by roles/compute.networkUser.
+const subnetworkUsePermission = "compute.subnetworks.use"
+
+// newComputeService returns a compute service for the API calls the daisy
+// client doesn't have. It is replaced in tests.
+var newComputeService = func(ctx context.Context, endpoint string) (*compute.Service, error) {
+	var opts []option.ClientOption
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	return compute.NewService(ctx, opts...)
+}
+
+// SharedVPCNetwork returns an existing network and subnetwork in a Shared VPC
+// host project, for use with AddCustomNetwork and AddCustomNetworkWithStackType.
+// The subnetwork must be in the region of the workflow zone. The returned
+// resources are not created or deleted by the workflow, but firewall rules
+// created on the network are created in the host project and cleaned up with
+// the workflow.
+//
+// This runs a preflight check that the host project is a Shared VPC host, that
+// the network and subnetwork are visible to the caller and that the caller may
+// attach VMs to the subnetwork, so that missing permissions fail the setup
+// rather than VM creation.
+func (t *TestWorkflow) SharedVPCNetwork(hostProject, networkName, subnetworkName string) (*Network, *Subnetwork, error) {
+	if hostProject == "" || networkName == "" || subnetworkName == "" {
+		return nil, nil, fmt.Errorf("host project, network and subnetwork are required for Shared VPC")
+	}
+	region := regionFromZone(t.Zone.Name)
+	if region == "" {
+		return nil, nil, fmt.Errorf("could not determine region from zone %q", t.Zone.Name)
+	}
+	if err := t.checkSharedVPC(hostProject, region, networkName, subnetworkName); err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(t.sharedVPCHostProjects, hostProject) {
+		t.sharedVPCHostProjects = append(t.sharedVPCHostProjects, hostProject)
+	}
+
+	falseValue := false
+	network := &Network{
+		name:         fmt.Sprintf("projects/%s/global/networks/%s", hostProject, networkName),
+		testWorkflow: t,
+		network: &daisy.Network{
+			Network:               compute.Network{Name: networkName},
+			Resource:              daisy.Resource{Project: hostProject},
+			AutoCreateSubnetworks: &falseValue,
+		},
+		project: hostProject,
+	}
+	subnetwork := &Subnetwork{
+		name:         fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", hostProject, region, subnetworkName),
+		testWorkflow: t,
+		subnetwork: &daisy.Subnetwork{
+			Subnetwork: compute.Subnetwork{Name: subnetworkName, Network: network.name, Region: region},
+			Resource:   daisy.Resource{Project: hostProject},
+		},
+		network: network,
+	}
+	return network, subnetwork, nil
+}
+
+// checkSharedVPC verifies the Shared VPC host project, network and subnetwork
+// can be read with the workflow client, and that the caller has the
+// compute.subnetworks.use permission on the subnetwork. Reading the subnetwork
+// only requires compute.subnetworks.get, which doesn't allow attaching VMs.
+func (t *TestWorkflow) checkSharedVPC(hostProject, region, networkName, subnetworkName string) error {
+	project, err := t.Client.GetProject(hostProject)
+	if err != nil {
+		return fmt.Errorf("shared VPC preflight: cannot get host project %s: %v", hostProject, err)
+	}
+	if project.XpnProjectStatus != "HOST" {
+		return fmt.Errorf("shared VPC preflight: project %s is not a Shared VPC host project", hostProject)
+	}
+	network, err := t.Client.GetNetwork(hostProject, networkName)
+	if err != nil {
+		return fmt.Errorf("shared VPC preflight: cannot get network %s in host project %s: %v", networkName, hostProject, err)
+	}
+	subnetwork, err := t.Client.GetSubnetwork(hostProject, region, subnetworkName)
+	if err != nil {
+		return fmt.Errorf("shared VPC preflight: cannot get subnetwork %s in region %s of host project %s: %v", subnetworkName, region, hostProject, err)
+	}
+	if path.Base(subnetwork.Network) != network.Name {
+		return fmt.Errorf("shared VPC preflight: subnetwork %s belongs to network %s, not %s", subnetworkName, path.Base(subnetwork.Network), networkName)
+	}
+
+	ctx := context.Background()
+	service, err := newComputeService(ctx, t.wf.ComputeEndpoint)
+	if err != nil {
+		return fmt.Errorf("shared VPC preflight: failed to create compute service: %v", err)
+	}
+	resp, err := service.Subnetworks.TestIamPermissions(hostProject, region, subnetworkName, &compute.TestPermissionsRequest{Permissions: []string{subnetworkUsePermission}}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("shared VPC preflight: cannot test permissions on subnetwork %s of host project %s: %v", subnetworkName, hostProject, err)
+	}
+	if !slices.Contains(resp.Permissions, subnetworkUsePermission) {
+		return fmt.Errorf("shared VPC preflight: missing permission %s on subnetwork %s in region %s of host project %s, grant roles/compute.networkUser on it", subnetworkUsePermission, subnetworkName, region, hostProject)
+	}
+	return nil
+}
+
+// SharedVPC returns the Shared VPC network and subnetwork test VMs are
+// attached to by default, as set by TestWorkflowOpts, or nil if there are
+// none.
+func (t *TestWorkflow) SharedVPC() (*Network, *Subnetwork) {
+	return t.sharedVPCNetwork, t.sharedVPCSubnetwork
+}
+
+// attachSharedVPC attaches every test VM that doesn't have a network set to
+// the workflow Shared VPC subnetwork, if there is one.
+func (t *TestWorkflow) attachSharedVPC() {
+	if t.sharedVPCNetwork == nil || t.sharedVPCSubnetwork == nil {
+		return
+	}
+	for _, step := range t.wf.Steps {
+		if step.CreateInstances == nil {
+			continue
+		}
+		for _, vm := range step.CreateInstances.Instances {
+			if len(vm.NetworkInterfaces) == 0 {
+				vm.NetworkInterfaces = []*compute.NetworkInterface{{}}
+			}
+			if vm.NetworkInterfaces[0].Network == "" {
+				vm.NetworkInterfaces[0].Network = t.sharedVPCNetwork.name
+				vm.NetworkInterfaces[0].Subnetwork = t.sharedVPCSubnetwork.name
+			}
+		}
+		for _, vm := range step.CreateInstances.InstancesBeta {
+			if len(vm.NetworkInterfaces) == 0 {
+				vm.NetworkInterfaces = []*computeBeta.NetworkInterface{{}}
+			}
+			if vm.NetworkInterfaces[0].Network == "" {
+				vm.NetworkInterfaces[0].Network = t.sharedVPCNetwork.name
+				vm.NetworkInterfaces[0].Subnetwork = t.sharedVPCSubnetwork.name
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	daisycompute "github.com/GoogleCloudPlatform/compute-daisy/compute"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// fakeSubnetworkPermissions makes newComputeService return a fake service
+// which grants the given permissions on the subnetwork for the rest of the
+// test.
+func fakeSubnetworkPermissions(t *testing.T, granted ...string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/projects/host-project/regions/us-central1/subnetworks/shared-subnetwork/testIamPermissions" {
+			w.WriteHeader(404)
+			fmt.Fprint(w, "URL and Method not recognized:", r.Method, r.URL)
+			return
+		}
+		req := &compute.TestPermissionsRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		resp := &compute.TestPermissionsResponse{}
+		for _, p := range req.Permissions {
+			if slices.Contains(granted, p) {
+				resp.Permissions = append(resp.Permissions, p)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	orig := newComputeService
+	newComputeService = func(ctx context.Context, _ string) (*compute.Service, error) {
+		return compute.NewService(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	}
+	t.Cleanup(func() { newComputeService = orig })
+}
+
+// sharedVPCTestClient returns a fake daisy client serving the Shared VPC host
+// project, and grants compute.subnetworks.use on its subnetwork.
+func sharedVPCTestClient(t *testing.T, xpnStatus string) daisycompute.Client {
+	t.Helper()
+	fakeSubnetworkPermissions(t, subnetworkUsePermission)
+	_, daisyFake, err := daisycompute.NewTestClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.String() == "/projects/host-project?alt=json&prettyPrint=false" {
+			fmt.Fprintf(w, `{"Name": "host-project", "xpnProjectStatus": %q}`, xpnStatus)
+		} else if r.Method == "GET" && r.URL.String() == "/projects/host-project/global/networks/shared-network?alt=json&prettyPrint=false" {
+			fmt.Fprint(w, `{"Name": "shared-network", "SelfLink": "projects/host-project/global/networks/shared-network"}`)
+		} else if r.Method == "GET" && r.URL.String() == "/projects/host-project/regions/us-central1/subnetworks/shared-subnetwork?alt=json&prettyPrint=false" {
+			fmt.Fprint(w, `{"Name": "shared-subnetwork", "Network": "projects/host-project/global/networks/shared-network"}`)
+		} else {
+			w.WriteHeader(404)
+			fmt.Fprint(w, "URL and Method not recognized:", r.Method, r.URL)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return daisyFake
+}
+
+// TestSharedVPCNetwork tests that *TestWorkflow.SharedVPCNetwork runs the
+// preflight checks and returns resources in the host project.
+func TestSharedVPCNetwork(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	twf.Zone.Name = "us-central1-a"
+	twf.Client = sharedVPCTestClient(t, "HOST")
+
+	network, subnetwork, err := twf.SharedVPCNetwork("host-project", "shared-network", "shared-subnetwork")
+	if err != nil {
+		t.Fatalf("twf.SharedVPCNetwork() = %v, want nil", err)
+	}
+	if want := "projects/host-project/global/networks/shared-network"; network.name != want {
+		t.Errorf("network.name = %q, want %q", network.name, want)
+	}
+	if want := "projects/host-project/regions/us-central1/subnetworks/shared-subnetwork"; subnetwork.name != want {
+		t.Errorf("subnetwork.name = %q, want %q", subnetwork.name, want)
+	}
+	if len(twf.sharedVPCHostProjects) != 1 || twf.sharedVPCHostProjects[0] != "host-project" {
+		t.Errorf("twf.sharedVPCHostProjects = %v, want [host-project]", twf.sharedVPCHostProjects)
+	}
+
+	// VMs can be attached to the Shared VPC, and firewall rules are created in
+	// the host project.
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	if err := tvm.AddCustomNetwork(network, subnetwork); err != nil {
+		t.Errorf("tvm.AddCustomNetwork() = %v, want nil", err)
+	}
+	if err := network.CreateFirewallRule("allow-tcp", "tcp", nil, nil); err != nil {
+		t.Fatalf("network.CreateFirewallRule() = %v, want nil", err)
+	}
+	firewall := (*twf.wf.Steps[createFirewallStepName].CreateFirewallRules)[0]
+	if firewall.Project != "host-project" {
+		t.Errorf("firewall.Project = %q, want %q", firewall.Project, "host-project")
+	}
+	if firewall.Network != network.name {
+		t.Errorf("firewall.Network = %q, want %q", firewall.Network, network.name)
+	}
+	if _, err := network.CreateSubnetwork("subnetwork", "10.0.0.0/24"); err == nil {
+		t.Errorf("network.CreateSubnetwork() = nil, want error for Shared VPC network")
+	}
+}
+
+// TestSharedVPCNetworkPreflight tests that the preflight checks fail for
+// projects which are not Shared VPC hosts or resources which can't be read.
+func TestSharedVPCNetworkPreflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		xpnStatus  string
+		network    string
+		subnetwork string
+		missingUse bool
+	}{
+		{
+			name:       "not a host project",
+			xpnStatus:  "UNSPECIFIED_XPN_PROJECT_STATUS",
+			network:    "shared-network",
+			subnetwork: "shared-subnetwork",
+		},
+		{
+			name:       "missing network",
+			xpnStatus:  "HOST",
+			network:    "other-network",
+			subnetwork: "shared-subnetwork",
+		},
+		{
+			name:       "missing subnetwork",
+			xpnStatus:  "HOST",
+			network:    "shared-network",
+			subnetwork: "other-subnetwork",
+		},
+		{
+			name:       "missing subnetworks.use permission",
+			xpnStatus:  "HOST",
+			network:    "shared-network",
+			subnetwork: "shared-subnetwork",
+			missingUse: true,
+		},
+		{
+			name:      "empty subnetwork",
+			xpnStatus: "HOST",
+			network:   "shared-network",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+			twf.Zone.Name = "us-central1-a"
+			twf.Client = sharedVPCTestClient(t, tc.xpnStatus)
+			if tc.missingUse {
+				fakeSubnetworkPermissions(t)
+			}
+			if _, _, err := twf.SharedVPCNetwork("host-project", tc.network, tc.subnetwork); err == nil {
+				t.Errorf("twf.SharedVPCNetwork() = nil, want error")
+			}
+		})
+	}
+}
+
+// TestAttachSharedVPC tests that VMs without a custom network are attached to
+// the workflow Shared VPC subnetwork.
+func TestAttachSharedVPC(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	twf.Zone.Name = "us-central1-a"
+	twf.Client = sharedVPCTestClient(t, "HOST")
+	var err error
+	twf.sharedVPCNetwork, twf.sharedVPCSubnetwork, err = twf.SharedVPCNetwork("host-project", "shared-network", "shared-subnetwork")
+	if err != nil {
+		t.Fatalf("twf.SharedVPCNetwork() = %v, want nil", err)
+	}
+	defaultVM, err := twf.CreateTestVM("default")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	gvnicVM, err := twf.CreateTestVMBeta("gvnic")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	gvnicVM.UseGVNIC()
+	customVM, err := twf.CreateTestVM("custom")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	network, err := twf.CreateNetwork("network", true)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	if err := customVM.AddCustomNetwork(network, nil); err != nil {
+		t.Fatalf("failed to add custom network: %v", err)
+	}
+
+	twf.attachSharedVPC()
+
+	if nic := defaultVM.instance.NetworkInterfaces[0]; nic.Network != twf.sharedVPCNetwork.name || nic.Subnetwork != twf.sharedVPCSubnetwork.name {
+		t.Errorf("default VM NIC = %s/%s, want Shared VPC", nic.Network, nic.Subnetwork)
+	}
+	if nic := gvnicVM.instancebeta.NetworkInterfaces[0]; nic.Network != twf.sharedVPCNetwork.name || nic.NicType != "GVNIC" {
+		t.Errorf("GVNIC VM NIC = %s/%s, want Shared VPC with GVNIC", nic.Network, nic.NicType)
+	}
+	if nic := customVM.instance.NetworkInterfaces[0]; nic.Network != "network" {
+		t.Errorf("custom VM NIC network = %s, want network", nic.Network)
+	}
+}
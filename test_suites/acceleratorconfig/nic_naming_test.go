@@ -41,9 +41,9 @@ var (
 // Test that for each CX-7 NIC, it's named after the GPU on the same PCI switch.
 func TestNICNaming(t *testing.T) {
 	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("utils.GetMetadata(ctx, instance, image) = err %v want nil", err)
+		t.Fatalf("utils.GetGuestOS() = err %v want nil", err)
 	}
 	for i := 0; i < 2; i++ {
 		mac, err := utils.GetMetadata(ctx, "instance", "network-interfaces", fmt.Sprintf("%d", i), "mac")
@@ -70,7 +70,7 @@ func TestNICNaming(t *testing.T) {
 		if !rdmaNICNameRegex.MatchString(iface.Name) {
 			// Allow images that don't have intent-based names yet to match the predictable name scheme instead.
 			// TODO remove exceptions when predictable name scheme is incorporated in each image.
-			if utils.IsUbuntu(image) || utils.IsRocky(image) {
+			if guestOS.Distro == utils.DistroUbuntu || guestOS.Distro == utils.DistroRocky {
 				if !predictableNICNameRegex.MatchString(iface.Name) {
 					t.Errorf("NIC name %q does not match predictable name scheme %q", iface.Name, predictableNICNameRegex.String())
 				}
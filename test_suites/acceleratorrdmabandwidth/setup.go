@@ -25,9 +25,15 @@ var (
 	Name = "acceleratorrdmabandwidth"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:      "Tests RDMA bandwidth between accelerator VMs.",
+		ExclusiveProject: true,
+	})
+}
+
 // TestSetup sets up test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	t.LockProject()
 	nics, err := acceleratorutils.CreateNetwork(t)
 	if err != nil {
 		return err
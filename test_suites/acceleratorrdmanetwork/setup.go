@@ -25,9 +25,15 @@ var (
 	Name = "acceleratorrdmanetwork"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:      "Tests the RDMA network configuration of accelerator VMs.",
+		ExclusiveProject: true,
+	})
+}
+
 // TestSetup sets up test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	t.LockProject()
 	nics, err := acceleratorutils.CreateNetwork(t)
 	if err != nil {
 		return err
@@ -26,9 +26,15 @@ var (
 	Name = "acceleratorrdmawriteimmediate"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:      "Tests RDMA write with immediate between accelerator VMs.",
+		ExclusiveProject: true,
+	})
+}
+
 // TestSetup sets up test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	t.LockProject()
 	nics, err := acceleratorutils.CreateNetwork(t)
 	if err != nil {
 		return err
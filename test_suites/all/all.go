@@ -0,0 +1,66 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package all imports every test suite of this repository, which registers them
+// with imagetest.Register. Manager binaries import it for its side effects.
+package all
+
+import (
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorconfig"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratornccl"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdma"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmabandwidth"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmanetwork"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/acceleratorrdmawriteimmediate"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/compatmanager"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/cvm"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/derivedimage"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/disk"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/guestagent"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/hostnamevalidation"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/hotattach"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/imageboot"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/licensevalidation"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/livemigrate"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/loadbalancer"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/lssd"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/lvmvalidation"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/mdsmtls"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/mdsroutes"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/metadata"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/mig"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/network"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkconfig"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkinterfacenaming"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/networkperf"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/nicsetup"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/oslogin"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packagemanager"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packageupgrade"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/packagevalidation"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/pluginmanager"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/rhel"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/security"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/shapeperf"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/shapevalidation"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/sharedvpc"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/sql"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/ssh"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/storageperf"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/suspendresume"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/vmspec"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/windowscontainers"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/winrm"
+	_ "github.com/GoogleCloudPlatform/cloud-image-tests/test_suites/wsfc"
+)
@@ -16,11 +16,8 @@
 package compatmanager
 
 import (
-	"fmt"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 	"github.com/GoogleCloudPlatform/compute-daisy"
 	"google.golang.org/api/compute/v1"
 )
@@ -53,32 +50,14 @@ Get-Process | Format-List * | Out-File -FilePath 'C:\sysprep.txt' -Encoding ASCI
 `
 )
 
-var ubuntuExceptions = []exceptions.Exception{
-	{
-		Match:   "ubuntu-2404-lts-(amd64|arm64)-guest-agent-stable.*",
-		Version: 2404,
-	},
-	{
-		Match:   "ubuntu-2504-(amd64|arm64)-guest-agent-stable.*",
-		Version: 2504,
-	},
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests the guest agent compat manager.",
+	})
 }
 
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	// TODO(b/460869613): Re-enable compat manager tests on Windows once the bug is fixed.
-	if utils.HasFeature(t.Image, "WINDOWS") {
-		t.Skip("Compat manager is temporarily disabled on Windows images.")
-		return nil
-	}
-
-	hasException := exceptions.HasMatch(t.Image.Name, ubuntuExceptions)
-
-	if hasException {
-		t.Skip(fmt.Sprintf("Skipping test for image %q, derived image does not have compat manager disabled", t.Image.Name))
-		return nil
-	}
-
 	defaultVM, err := t.CreateTestVM("compatmanager")
 	if err != nil {
 		return err
@@ -220,13 +220,13 @@ func TestLiveMigrate(t *testing.T) {
 }
 
 func TestTDXAttestation(t *testing.T) {
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("couldn't get image from metadata")
+		t.Fatalf("couldn't get guest OS: %v", err)
 	}
 	ctx := utils.Context(t)
 	// For Ubuntu image, the tdx_guest module was moved to linux-modules-extra package in the 1016 and newer kernels.
-	if utils.IsUbuntu(image) {
+	if guestOS.Distro == utils.DistroUbuntu {
 		kernelVersionCmd := exec.CommandContext(ctx, "uname", "-r")
 		kernelVersionOut, err := kernelVersionCmd.CombinedOutput()
 		if err != nil {
@@ -251,13 +251,13 @@ func TestTDXAttestation(t *testing.T) {
 				upperKernelRev = 1014
 			}
 
-			if strings.Contains(image, "-2404-") {
+			if guestOS.Version == "24.04" {
 				// Kernel revisions for 24.04
 				lowerKernelRev = 1006
 				upperKernelRev = 1008
 			}
 			// Installing linux-modules-extra-gcp is required only on some kernel versions of 2204 and 2404
-			if (strings.Contains(image, "-2204-") || strings.Contains(image, "-2404-")) &&
+			if (guestOS.Version == "22.04" || guestOS.Version == "24.04") &&
 				int(kernelRev) >= lowerKernelRev && int(kernelRev) < upperKernelRev {
 				if _, err := exec.CommandContext(ctx, "apt-get", "update", "-y").CombinedOutput(); err != nil {
 					t.Fatalf(`exec.CommandContext(ctx, "apt-get", "update", "-y").CombinedOutput() = %v, want nil`, err)
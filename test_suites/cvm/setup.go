@@ -29,6 +29,13 @@ var Name = "cvm"
 // cvm machine tyes are only available in us-central1-a.
 var dedicatedZone = "us-central1-a"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:   "Tests confidential VM types and attestation.",
+		AnyOfFeatures: []string{"SEV_CAPABLE", "SEV_SNP_CAPABLE", "TDX_CAPABLE"},
+	})
+}
+
 // TestSetup sets up test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	for _, feature := range t.ImageBeta.GuestOsFeatures {
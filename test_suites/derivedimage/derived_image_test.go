@@ -0,0 +1,129 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package derivedimage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// sourceIdentityFile is where the source VM records its identity. It is
+	// kept in the derived image, since the guest flush does not remove it.
+	sourceIdentityFile = "/var/lib/cit-derivedimage/source.json"
+	// hostKeyTimeout is how long derived VMs wait for the guest agent to
+	// generate the SSH host keys.
+	hostKeyTimeout = 2 * time.Minute
+)
+
+// identity is what tells instances booted from the same image apart.
+type identity struct {
+	// MachineID is the content of /etc/machine-id.
+	MachineID string
+	// HostKeys maps the names of the public SSH host key files to their
+	// contents.
+	HostKeys map[string]string
+}
+
+// readIdentity reads the identity of the running VM.
+func readIdentity(t *testing.T) identity {
+	t.Helper()
+	machineID, err := os.ReadFile("/etc/machine-id")
+	if err != nil {
+		t.Fatalf("Failed to read /etc/machine-id: %v", err)
+	}
+	id := identity{MachineID: strings.TrimSpace(string(machineID)), HostKeys: make(map[string]string)}
+	keys, err := filepath.Glob("/etc/ssh/ssh_host_*_key.pub")
+	if err != nil {
+		t.Fatalf("Failed to list the SSH host keys: %v", err)
+	}
+	for _, key := range keys {
+		content, err := os.ReadFile(key)
+		if err != nil {
+			t.Fatalf("Failed to read SSH host key %s: %v", key, err)
+		}
+		id.HostKeys[filepath.Base(key)] = strings.TrimSpace(string(content))
+	}
+	return id
+}
+
+// readSourceIdentity reads the identity recorded by the source VM.
+func readSourceIdentity(t *testing.T) identity {
+	t.Helper()
+	data, err := os.ReadFile(sourceIdentityFile)
+	if err != nil {
+		t.Fatalf("Failed to read the identity of the source VM: %v", err)
+	}
+	var id identity
+	if err := json.Unmarshal(data, &id); err != nil {
+		t.Fatalf("Failed to parse the identity of the source VM: %v", err)
+	}
+	return id
+}
+
+// TestRecordSourceIdentity records the machine ID and SSH host keys of the
+// source VM, before the guest flush removes them from the derived image.
+func TestRecordSourceIdentity(t *testing.T) {
+	id := readIdentity(t)
+	if id.MachineID == "" || len(id.HostKeys) == 0 {
+		t.Fatalf("Source VM has machine ID %q and %d SSH host keys, want both", id.MachineID, len(id.HostKeys))
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		t.Fatalf("Failed to encode the identity: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(sourceIdentityFile), 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(sourceIdentityFile), err)
+	}
+	if err := os.WriteFile(sourceIdentityFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", sourceIdentityFile, err)
+	}
+}
+
+// TestHostKeysRegenerated tests that the SSH host keys of a VM booted from the
+// derived image differ from those of the source VM.
+func TestHostKeysRegenerated(t *testing.T) {
+	source := readSourceIdentity(t)
+
+	// The guest agent generates the host keys of the new instance at boot,
+	// possibly while the tests start.
+	id := readIdentity(t)
+	for deadline := time.Now().Add(hostKeyTimeout); len(id.HostKeys) < len(source.HostKeys) && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Second)
+		id = readIdentity(t)
+	}
+	if len(id.HostKeys) == 0 {
+		t.Fatal("No SSH host keys were generated")
+	}
+	for name, key := range id.HostKeys {
+		if key == source.HostKeys[name] {
+			t.Errorf("SSH host key %s is the same as on the source VM", name)
+		}
+	}
+}
+
+// TestMachineIDRegenerated tests that the machine ID of a VM booted from the
+// derived image differs from the machine ID of the source VM.
+func TestMachineIDRegenerated(t *testing.T) {
+	source := readSourceIdentity(t)
+	id := readIdentity(t)
+	if id.MachineID == "" {
+		t.Fatal("/etc/machine-id is empty")
+	}
+	if id.MachineID == source.MachineID {
+		t.Errorf("Machine ID %s is the same as on the source VM", id.MachineID)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package derivedimage tests that images captured from a VM of the image under
+// test boot as new instances.
+package derivedimage
+
+import (
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+)
+
+// Name is the name of the test package. It must match the directory name.
+var Name = "derivedimage"
+
+// derivedVMs is the number of VMs booted from the derived image.
+const derivedVMs = 2
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:        "Tests that images derived from a VM regenerate their SSH host keys and machine ID.",
+		OSFamilies:         []string{imagetest.OSFamilyLinux},
+		ExcludedOSFamilies: []string{imagetest.OSFamilyCOS},
+	})
+}
+
+// TestSetup sets up the test workflow.
+func TestSetup(t *imagetest.TestWorkflow) error {
+	source, err := t.CreateTestVM("source")
+	if err != nil {
+		return err
+	}
+	source.RunTests("TestRecordSourceIdentity")
+
+	image, err := source.CreateDerivedImage("derived")
+	if err != nil {
+		return err
+	}
+	vms, err := image.CreateTestVMs("derived", derivedVMs)
+	if err != nil {
+		return err
+	}
+	for _, vm := range vms {
+		vm.RunTests("TestHostKeysRegenerated|TestMachineIDRegenerated")
+	}
+	return nil
+}
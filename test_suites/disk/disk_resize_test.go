@@ -47,6 +47,10 @@ func TestDiskResize(t *testing.T) {
 	if strings.Contains(image, "lvm") {
 		t.Skip("disk expansion not supported on RHEL LVM images")
 	}
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("couldn't get guest OS: %v", err)
+	}
 
 	_, err = os.Stat(markerFile)
 
@@ -60,14 +64,14 @@ func TestDiskResize(t *testing.T) {
 	}
 
 	// Total blocks * size per block = total space in bytes
-	if err := verifyDiskSize(resizeDiskSize, image); err != nil {
+	if err := verifyDiskSize(resizeDiskSize, guestOS); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func getDiskSize(image string) (int64, error) {
+func getDiskSize(guestOS utils.GuestOS) (int64, error) {
 	diskPath := "/"
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		diskPath = "/mnt/stateful_partition"
 	}
 
@@ -89,8 +93,8 @@ func getDiskSize(image string) (int64, error) {
 	return 0, fmt.Errorf("could not find disk size in fstat output %s", fstatOutString)
 }
 
-func verifyDiskSize(expectedGb int, image string) error {
-	diskSize, err := getDiskSize(image)
+func verifyDiskSize(expectedGb int, guestOS utils.GuestOS) error {
+	diskSize, err := getDiskSize(guestOS)
 	if err != nil {
 		return fmt.Errorf("could not get disk size: err %v", err)
 	}
@@ -48,6 +48,10 @@ const (
 	resizeDiskSize = 200
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests disk resize, read and write, and device naming."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	rebootInst := &daisy.Instance{}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 	"google.golang.org/api/compute/v1"
 )
 
@@ -105,11 +106,17 @@ func testServiceConfigLinux(t *testing.T, image string) {
 	afterDependencies := []string{"network-online.target", "NetworkManager.service", "systemd-networkd.service"}
 	services := []string{"google-guest-agent-manager", "google-guest-agent", "google-guest-compat-manager"}
 
-	// TODO(b/478951370): Remove this exception once the bug is fixed.
-	isOldAgentDistro := utils.IsCOS(image) || utils.IsUbuntu(image) || utils.IsSLES(image) || utils.IsOpenSUSE(image)
-	if !strings.Contains(image, "guest-agent") && isOldAgentDistro {
-		// Old agent package installed on COS, SLES, OpenSUSE and Ubuntu images only has google-guest-agent service.
-		services = []string{"google-guest-agent"}
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("utils.GetGuestOS() = %v, want nil", err)
+	}
+	// The old agent package of the excepted images only has the
+	// google-guest-agent service, unlike the images built with the agent.
+	if !guestOS.HasVariant(utils.VariantGuestAgent) {
+		if e := exceptions.MatchTest(t, Name, image); e != nil {
+			t.Logf("Only testing the google-guest-agent service: %v", e)
+			services = []string{"google-guest-agent"}
+		}
 	}
 
 	t.Logf("Testing service config for image: %s, services: %v", image, services)
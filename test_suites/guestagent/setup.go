@@ -28,6 +28,10 @@ const (
 	mwlidVMName = "mwlid" // Name of the VM used for mwlid tests.
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests guest agent features such as telemetry and snapshot scripts."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	diskType := imagetest.DiskTypeNeeded(t.MachineType.Name)
@@ -61,7 +65,41 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		if err != nil {
 			return err
 		}
-		snapshotvm.RunTests("TestSnapshotScripts")
+		snapshotvm.RunTests("^TestSnapshotScripts$")
+	}
+
+	// Restore VMs from a guest flush snapshot and machine image of VMs with
+	// snapshot scripts, taken by the test workflow once their tests finish.
+	if !t.GuestOS.IsWindows() && !strings.Contains(diskType, "hyperdisk") {
+		snapshotSourceVM, err := t.CreateTestVMMultipleDisks([]*compute.Disk{{Name: "snapshotSource", Type: diskType}}, &daisy.Instance{})
+		if err != nil {
+			return err
+		}
+		snapshotSourceVM.RunTests("TestPrepareSnapshotScripts")
+		snapshot, err := snapshotSourceVM.CreateSnapshot("snapshotscripts", true)
+		if err != nil {
+			return err
+		}
+		snapshotRestoredVM, err := t.CreateTestVMFromSnapshot(snapshot, "snapshotRestored")
+		if err != nil {
+			return err
+		}
+		snapshotRestoredVM.RunTests("TestRestoredSnapshotScripts")
+
+		machineImageSourceVM, err := t.CreateTestVMMultipleDisks([]*compute.Disk{{Name: "machineImageSource", Type: diskType}}, &daisy.Instance{})
+		if err != nil {
+			return err
+		}
+		machineImageSourceVM.RunTests("TestPrepareSnapshotScripts")
+		machineImage, err := machineImageSourceVM.CreateMachineImage("snapshotscripts", true)
+		if err != nil {
+			return err
+		}
+		machineImageRestoredVM, err := t.CreateTestVMFromMachineImage(machineImage, "machineImageRestored")
+		if err != nil {
+			return err
+		}
+		machineImageRestoredVM.RunTests("TestRestoredSnapshotScripts")
 	}
 
 	if utils.HasFeature(t.Image, "WINDOWS") {
@@ -76,7 +114,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		windowsaccountVM.RunTests("TestWindowsPasswordReset|TestDiagnostic")
 	}
 
-	if !utils.IsWindowsImage(t.Image.Name) {
+	if !t.GuestOS.IsWindows() {
 		// Only test SSH host keys on non-Windows images.
 		vm, err := t.CreateTestVM("sshkeytest")
 		if err != nil {
@@ -88,7 +126,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 
 	// This section is for testing MWLID. It is only run on guest-agent derived images.
 	// TODO(b/534559869): Remove this skip for Windows once the MWLID tests are stable for Windows.
-	if strings.Contains(t.Image.Name, "guest-agent") && !utils.IsWindowsImage(t.Image.Name) {
+	if t.GuestOS.HasVariant(utils.VariantGuestAgent) && !t.GuestOS.IsWindows() {
 		project := t.Project.Name
 		zone := t.Zone.Name
 		projectNumber := "281997379984" // compute-image-test-pool-001 (where the CA pool was created)
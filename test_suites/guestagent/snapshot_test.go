@@ -147,3 +147,29 @@ func TestSnapshotScripts(t *testing.T) {
 
 	verifySnapshotSuccess(t)
 }
+
+// TestPrepareSnapshotScripts enables the snapshot scripts, for the guest flush
+// snapshot or machine image the test workflow takes once the tests finish.
+func TestPrepareSnapshotScripts(t *testing.T) {
+	utils.LinuxOnly(t)
+	snapshotTestPrep(t)
+}
+
+// TestRestoredSnapshotScripts tests that on a VM restored from a guest flush
+// snapshot or machine image, the pre snapshot script ran once before the disk
+// was captured, and the post snapshot script only after it.
+func TestRestoredSnapshotScripts(t *testing.T) {
+	utils.LinuxOnly(t)
+	pre, err := os.ReadFile("/etc/google/snapshots/pre-snapshot-write")
+	if err != nil {
+		t.Fatalf("Pre snapshot script did not run before the disk was captured: %v", err)
+	}
+	if strings.Count(string(pre), "\n") != 1 {
+		t.Errorf("Unexpected number of exections of /etc/google/snapshots/pre.sh, want 1 got %d", strings.Count(string(pre), "\n"))
+	}
+	if _, err := os.Stat("/etc/google/snapshots/post-snapshot-write"); err == nil {
+		t.Errorf("Post snapshot script ran before the disk was captured")
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Failed to check the post snapshot script output: %v", err)
+	}
+}
@@ -86,7 +86,7 @@ func TestHostname(t *testing.T) {
 	// See https://man7.org/linux/man-pages/man7/hostname.7.html for more details.
 	// On minimal images, the hostname is the shortname.
 	hostnameExceptions := []exceptions.Exception{
-		exceptions.Exception{Version: 2404, Type: exceptions.GreaterThanOrEqualTo},
+		exceptions.Exception{Versions: ">=24.04"},
 	}
 
 	// 'hostname' in metadata is fully qualified domain name.
@@ -112,23 +112,23 @@ func TestHostname(t *testing.T) {
 
 // TestCustomHostname tests the 'fully qualified domain name'.
 func TestCustomHostname(t *testing.T) {
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("Couldn't get image from metadata")
+		t.Fatalf("Couldn't get guest OS: %v", err)
 	}
 
 	// guest-configs does not support wicked
-	if utils.IsSLES(image) {
+	if guestOS.Distro == utils.DistroSLES {
 		t.Skip("SLES doesn't support custom hostnames.")
 	}
-	if utils.IsSUSE(image) {
+	if guestOS.Distro == utils.DistroOpenSUSE {
 		t.Skip("SUSE doesn't support custom hostnames.")
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		// Does not have updated guest-configs with systemd-network support.
 		t.Skip("Not supported on cos")
 	}
-	if utils.IsUbuntu(image) {
+	if guestOS.Distro == utils.DistroUbuntu {
 		// Does not have updated guest-configs with systemd-network support.
 		t.Skip("Not supported on ubuntu")
 	}
@@ -161,9 +161,8 @@ func TestFQDN(t *testing.T) {
 
 	printSuseDebugInfo(t)
 	if hostname != metadataHostname {
-		// TODO(b/460799853): Remove this exception once the bug is fixed.
-		if (utils.IsSLES(image) || utils.IsOpenSUSE(image)) && strings.Contains(image, "arm") {
-			t.Skipf("Skipping TestFQDN for SLES ARM image: %q due to b/460799853, got hostname: %q, want hostname: %q", image, hostname, metadataHostname)
+		if e := exceptions.MatchTest(t, Name, image); e != nil {
+			t.Skipf("Skipping TestFQDN for image %q, got hostname: %q, want hostname: %q: %v", image, hostname, metadataHostname, e)
 		}
 		t.Errorf("hostname -f does not match metadata. Expected: %q got: %q", metadataHostname, hostname)
 	}
@@ -197,12 +196,12 @@ func printLeaseFiles(t *testing.T, dir, prefix string) {
 }
 
 func isSles(t *testing.T) bool {
-	content, err := os.ReadFile("/etc/os-release")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Logf("Error reading /etc/os-release: %v, defaulting isSLES to false", err)
+		t.Logf("Error getting guest OS: %v, defaulting isSLES to false", err)
 		return false
 	}
-	return strings.Contains(string(content), "sles")
+	return guestOS.Distro == utils.DistroSLES
 }
 
 // printSuseDebugInfo prints the DHCP lease information as reported by wicked.
@@ -324,23 +323,23 @@ func TestHostKeysGeneratedOnce(t *testing.T) {
 func TestHostsFile(t *testing.T) {
 	utils.LinuxOnly(t)
 	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("couldn't get image from metadata")
+		t.Fatalf("couldn't get guest OS: %v", err)
 	}
-	if utils.IsSLES(image) {
+	if guestOS.Distro == utils.DistroSLES {
 		// guest-configs does not support wicked
 		t.Skip("Not supported on SLES")
 	}
-	if utils.IsSUSE(image) {
+	if guestOS.Distro == utils.DistroOpenSUSE {
 		// guest-configs does not support wicked
 		t.Skip("Not supported on SUSE")
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		// Does not have updated guest-configs with systemd-network support.
 		t.Skip("Not supported on cos")
 	}
-	if utils.IsUbuntu(image) {
+	if guestOS.Distro == utils.DistroUbuntu {
 		// Does not have updated guest-configs with systemd-network support.
 		t.Skip("Not supported on ubuntu")
 	}
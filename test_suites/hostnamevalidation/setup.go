@@ -23,6 +23,10 @@ import (
 // Name is the name of the test package. It must match the directory name.
 var Name = "hostnamevalidation"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests the hostname, FQDN and SSH host keys set from metadata."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	vm1, err := t.CreateTestVM("vm1")
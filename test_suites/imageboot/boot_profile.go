@@ -0,0 +1,233 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imageboot
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bootPhaseBudgetsAttribute is the metadata attribute holding the boot phase
+// budgets, in the format of the imageboot_phase_budgets flag. The phases are
+// only recorded as metrics when it's unset.
+const bootPhaseBudgetsAttribute = "boot-phase-budgets"
+
+// bootPhase is a phase of the boot, like the kernel or the userspace, and its
+// duration.
+type bootPhase struct {
+	name     string
+	duration time.Duration
+}
+
+// unitTime is the time a systemd unit took to start, and for units of the
+// critical chain the time it became active after the start of the userspace.
+type unitTime struct {
+	unit       string
+	activeAt   time.Duration
+	activation time.Duration
+}
+
+// windowsBootPhases are the event data of the boot performance event of Windows
+// which are boot phases, with the names of the phases.
+var windowsBootPhases = map[string]string{
+	"MainPathBootTime":                 "main_path",
+	"BootPostBootTime":                 "post_boot",
+	"BootPrefetchInitTime":             "prefetch_init",
+	"BootKernelInitTime":               "kernel_init",
+	"BootDriverInitTime":               "driver_init",
+	"BootDevicesInitTime":              "devices_init",
+	"BootSmssInitTime":                 "smss_init",
+	"BootCriticalServicesInitTime":     "critical_services_init",
+	"BootUserProfileProcessingTime":    "user_profile",
+	"BootMachineProfileProcessingTime": "machine_profile",
+	"BootExplorerInitTime":             "explorer_init",
+}
+
+// See man 7 systemd.time
+var (
+	systemdDurationUnits = map[string]time.Duration{
+		"us":    time.Microsecond,
+		"µs":    time.Microsecond,
+		"ms":    time.Millisecond,
+		"s":     time.Second,
+		"min":   time.Minute,
+		"h":     time.Hour,
+		"d":     24 * time.Hour,
+		"w":     7 * 24 * time.Hour,
+		"month": 2629800 * time.Second,
+		"y":     31557600 * time.Second,
+	}
+	systemdDurationRegex = regexp.MustCompile(`^([0-9.]+)([a-zµ]+)$`)
+	systemdPhaseRegex    = regexp.MustCompile(`^(.+) \((\w+)\)$`)
+	criticalChainRegex   = regexp.MustCompile(`^[\s│├└─]*(\S+) @(.+?)(?: \+(.+))?$`)
+	bootPhaseBudgetRegex = regexp.MustCompile(`^[a-z_]+$`)
+)
+
+// parseSystemdDuration parses a duration printed by systemd, like 1min 2.345s.
+func parseSystemdDuration(s string) (time.Duration, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var d time.Duration
+	for _, f := range fields {
+		match := systemdDurationRegex.FindStringSubmatch(f)
+		if match == nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		unit, ok := systemdDurationUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("invalid unit %q of duration %q", match[2], s)
+		}
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+		}
+		d += time.Duration(math.Round(value * float64(unit)))
+	}
+	return d, nil
+}
+
+// parseSystemdAnalyzeTime returns the boot phases of the output of
+// systemd-analyze time, like:
+//
+//	Startup finished in 1.234s (firmware) + 2.345s (kernel) + 10.5s (userspace) = 14.079s
+func parseSystemdAnalyzeTime(output string) ([]bootPhase, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	rest, ok := strings.CutPrefix(line, "Startup finished in ")
+	if !ok {
+		return nil, fmt.Errorf("unexpected systemd-analyze time output %q", output)
+	}
+	rest, _, _ = strings.Cut(rest, " = ")
+	var phases []bootPhase
+	for _, part := range strings.Split(rest, " + ") {
+		match := systemdPhaseRegex.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			return nil, fmt.Errorf("unexpected boot phase %q in systemd-analyze time output", part)
+		}
+		d, err := parseSystemdDuration(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("boot phase %s: %v", match[2], err)
+		}
+		phases = append(phases, bootPhase{name: match[2], duration: d})
+	}
+	return phases, nil
+}
+
+// parseSystemdAnalyzeBlame returns the units of the output of systemd-analyze
+// blame, slowest first.
+func parseSystemdAnalyzeBlame(output string) ([]unitTime, error) {
+	var units []unitTime
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		d, err := parseSystemdDuration(strings.Join(fields[:len(fields)-1], " "))
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %v", fields[len(fields)-1], err)
+		}
+		units = append(units, unitTime{unit: fields[len(fields)-1], activation: d})
+	}
+	return units, nil
+}
+
+// parseSystemdAnalyzeCriticalChain returns the units of the output of
+// systemd-analyze critical-chain, from the default target to the first unit
+// of the chain.
+func parseSystemdAnalyzeCriticalChain(output string) ([]unitTime, error) {
+	var units []unitTime
+	for _, line := range strings.Split(output, "\n") {
+		match := criticalChainRegex.FindStringSubmatch(strings.TrimRight(line, " "))
+		if match == nil {
+			continue
+		}
+		u := unitTime{unit: match[1]}
+		var err error
+		if u.activeAt, err = parseSystemdDuration(match[2]); err != nil {
+			return nil, fmt.Errorf("unit %s: %v", u.unit, err)
+		}
+		if match[3] != "" {
+			if u.activation, err = parseSystemdDuration(match[3]); err != nil {
+				return nil, fmt.Errorf("unit %s: %v", u.unit, err)
+			}
+		}
+		units = append(units, u)
+	}
+	if len(units) == 0 {
+		return nil, fmt.Errorf("no unit in systemd-analyze critical-chain output %q", output)
+	}
+	return units, nil
+}
+
+// slowestUnits returns the n units which took the longest to start.
+func slowestUnits(units []unitTime, n int) []unitTime {
+	units = append([]unitTime(nil), units...)
+	sort.SliceStable(units, func(i, j int) bool { return units[i].activation > units[j].activation })
+	return units[:min(n, len(units))]
+}
+
+// parseWindowsBootEvent returns the boot phases of the event data of the boot
+// performance event of Windows, one Name=Value per line with values in
+// milliseconds.
+func parseWindowsBootEvent(output string) ([]bootPhase, error) {
+	var phases []bootPhase
+	for _, line := range strings.Split(output, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		phase, ok := windowsBootPhases[name]
+		if !ok {
+			continue
+		}
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("boot phase %s: invalid duration %q: %v", name, value, err)
+		}
+		phases = append(phases, bootPhase{name: phase, duration: time.Duration(ms) * time.Millisecond})
+	}
+	if len(phases) == 0 {
+		return nil, fmt.Errorf("no boot phase in boot performance event data %q", output)
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i].name < phases[j].name })
+	return phases, nil
+}
+
+// parseBootPhaseBudgets parses boot phase budgets like kernel=5,userspace=20,
+// in seconds.
+func parseBootPhaseBudgets(s string) (map[string]float64, error) {
+	budgets := make(map[string]float64)
+	for _, budget := range strings.Split(s, ",") {
+		if strings.TrimSpace(budget) == "" {
+			continue
+		}
+		phase, value, ok := strings.Cut(strings.TrimSpace(budget), "=")
+		if !ok || !bootPhaseBudgetRegex.MatchString(phase) {
+			return nil, fmt.Errorf("invalid boot phase budget %q, want <phase>=<seconds>", budget)
+		}
+		maxTime, err := strconv.ParseFloat(value, 64)
+		if err != nil || maxTime <= 0 {
+			return nil, fmt.Errorf("invalid boot phase budget %q, want a positive number of seconds", budget)
+		}
+		budgets[phase] = maxTime
+	}
+	return budgets, nil
+}
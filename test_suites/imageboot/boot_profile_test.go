@@ -0,0 +1,191 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imageboot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const systemdAnalyzeCriticalChain = `The time when unit became active or started is printed after the "@" character.
+The time the unit took to start is printed after the "+" character.
+
+graphical.target @12.503s
+└─multi-user.target @12.502s
+  └─google-guest-agent.service @9.871s +2.630s
+    └─network-online.target @9.860s
+      └─systemd-networkd-wait-online.service @1min 2.1s +7.5s
+        └─systemd-networkd.service @1.234s +150ms
+`
+
+func TestParseSystemdDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "2.345s", want: 2345 * time.Millisecond},
+		{in: "1min 2.5s", want: 62500 * time.Millisecond},
+		{in: "150ms", want: 150 * time.Millisecond},
+		{in: "820us", want: 820 * time.Microsecond},
+		{in: "1h 2min", want: 62 * time.Minute},
+		{in: "", wantErr: true},
+		{in: "2 s", wantErr: true},
+		{in: "3parsecs", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseSystemdDuration(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseSystemdDuration(%q) = %v, want error: %v", tc.in, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("parseSystemdDuration(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseSystemdAnalyzeTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []bootPhase
+		wantErr bool
+	}{
+		{
+			name:   "initrd",
+			output: "Startup finished in 2.345s (kernel) + 3.1s (initrd) + 1min 10.5s (userspace) = 1min 15.945s\ngraphical.target reached after 1min 10.4s in userspace.\n",
+			want: []bootPhase{
+				{name: "kernel", duration: 2345 * time.Millisecond},
+				{name: "initrd", duration: 3100 * time.Millisecond},
+				{name: "userspace", duration: 70500 * time.Millisecond},
+			},
+		},
+		{
+			name:   "uefi",
+			output: "Startup finished in 4.012s (firmware) + 820ms (loader) + 1.5s (kernel) + 9.7s (userspace) = 16.032s\n",
+			want: []bootPhase{
+				{name: "firmware", duration: 4012 * time.Millisecond},
+				{name: "loader", duration: 820 * time.Millisecond},
+				{name: "kernel", duration: 1500 * time.Millisecond},
+				{name: "userspace", duration: 9700 * time.Millisecond},
+			},
+		},
+		{
+			name:    "not finished",
+			output:  "Bootup is not yet finished (org.freedesktop.systemd1.Manager.FinishTimestampMonotonic=0).\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid phase",
+			output:  "Startup finished in 2.345s kernel = 2.345s\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSystemdAnalyzeTime(tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseSystemdAnalyzeTime() = %v, want error: %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(bootPhase{})); diff != "" {
+				t.Errorf("parseSystemdAnalyzeTime() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSystemdAnalyzeBlame(t *testing.T) {
+	output := "1min 2.003s cloud-init.service\n     5.120s google-guest-agent.service\n      80ms systemd-journald.service\n"
+	got, err := parseSystemdAnalyzeBlame(output)
+	if err != nil {
+		t.Fatalf("parseSystemdAnalyzeBlame() = %v, want nil", err)
+	}
+	want := []unitTime{
+		{unit: "cloud-init.service", activation: 62003 * time.Millisecond},
+		{unit: "google-guest-agent.service", activation: 5120 * time.Millisecond},
+		{unit: "systemd-journald.service", activation: 80 * time.Millisecond},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(unitTime{})); diff != "" {
+		t.Errorf("parseSystemdAnalyzeBlame() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, err := parseSystemdAnalyzeBlame("soon cloud-init.service\n"); err == nil {
+		t.Errorf("parseSystemdAnalyzeBlame() with an invalid duration = nil, want error")
+	}
+}
+
+func TestParseSystemdAnalyzeCriticalChain(t *testing.T) {
+	got, err := parseSystemdAnalyzeCriticalChain(systemdAnalyzeCriticalChain)
+	if err != nil {
+		t.Fatalf("parseSystemdAnalyzeCriticalChain() = %v, want nil", err)
+	}
+	want := []unitTime{
+		{unit: "graphical.target", activeAt: 12503 * time.Millisecond},
+		{unit: "multi-user.target", activeAt: 12502 * time.Millisecond},
+		{unit: "google-guest-agent.service", activeAt: 9871 * time.Millisecond, activation: 2630 * time.Millisecond},
+		{unit: "network-online.target", activeAt: 9860 * time.Millisecond},
+		{unit: "systemd-networkd-wait-online.service", activeAt: 62100 * time.Millisecond, activation: 7500 * time.Millisecond},
+		{unit: "systemd-networkd.service", activeAt: 1234 * time.Millisecond, activation: 150 * time.Millisecond},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(unitTime{})); diff != "" {
+		t.Errorf("parseSystemdAnalyzeCriticalChain() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	slowest := slowestUnits(got, 2)
+	if len(slowest) != 2 || slowest[0].unit != "systemd-networkd-wait-online.service" || slowest[1].unit != "google-guest-agent.service" {
+		t.Errorf("slowestUnits(2) = %v, want systemd-networkd-wait-online.service and google-guest-agent.service", slowest)
+	}
+
+	if _, err := parseSystemdAnalyzeCriticalChain("Bootup is not yet finished.\n"); err == nil {
+		t.Errorf("parseSystemdAnalyzeCriticalChain() without units = nil, want error")
+	}
+}
+
+func TestParseWindowsBootEvent(t *testing.T) {
+	output := "BootTsVersion=2\r\nBootStartTime=2026-01-02T10:00:00.5000000Z\r\nBootTime=61234\r\nMainPathBootTime=31234\r\nBootKernelInitTime=12\r\nBootPostBootTime=30000\r\n"
+	got, err := parseWindowsBootEvent(output)
+	if err != nil {
+		t.Fatalf("parseWindowsBootEvent() = %v, want nil", err)
+	}
+	want := []bootPhase{
+		{name: "kernel_init", duration: 12 * time.Millisecond},
+		{name: "main_path", duration: 31234 * time.Millisecond},
+		{name: "post_boot", duration: 30 * time.Second},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(bootPhase{})); diff != "" {
+		t.Errorf("parseWindowsBootEvent() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	for _, output := range []string{"BootTsVersion=2\r\n", "MainPathBootTime=soon\r\n"} {
+		if _, err := parseWindowsBootEvent(output); err == nil {
+			t.Errorf("parseWindowsBootEvent(%q) = nil, want error", output)
+		}
+	}
+}
+
+func TestBootPhaseBudgets(t *testing.T) {
+	budgets, err := parseBootPhaseBudgets("userspace=20, kernel=5.5,")
+	if err != nil {
+		t.Fatalf("parseBootPhaseBudgets() = %v, want nil", err)
+	}
+	if diff := cmp.Diff(map[string]float64{"kernel": 5.5, "userspace": 20}, budgets); diff != "" {
+		t.Errorf("parseBootPhaseBudgets() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	for _, s := range []string{"kernel", "kernel=0", "kernel=fast", "Kernel Time=5"} {
+		if _, err := parseBootPhaseBudgets(s); err == nil {
+			t.Errorf("parseBootPhaseBudgets(%q) = nil, want error", s)
+		}
+	}
+}
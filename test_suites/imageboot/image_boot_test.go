@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"google.golang.org/api/compute/v1"
 )
 
 // The values have been decided based on running spot tests for different images.
@@ -71,12 +72,12 @@ func mountEFIVarsCOS(t *testing.T) error {
 	}
 
 	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("[FAILED] unable to get image from metadata with equivalent command %q: %v", metadataCurlCmd, err)
+		t.Fatalf("[FAILED] unable to get the guest OS: %v", err)
 	}
 
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		cmd := exec.CommandContext(ctx, "mount", "-t", "efivarfs", "efivarfs", "/sys/firmware/efi/efivars/")
 		_, err := cmd.Output()
 		if err != nil {
@@ -211,6 +212,7 @@ func TestBootTime(t *testing.T) {
 	if bootTime < 0 {
 		t.Fatalf("[FAILED] Invalid boot time, services started before boot.")
 	}
+	utils.RecordMetric(t, "boot_time", float64(bootTime), "s", nil)
 	maxBootTime := defaultMaxBootTime
 	for _, threshold := range imageBootTimeThresholds {
 		if strings.Contains(image, threshold.Image) {
@@ -226,6 +228,210 @@ func TestBootTime(t *testing.T) {
 	}
 }
 
+// TestBootProfile records the duration of the phases of the boot, and checks
+// them against their budgets when the workflow sets budgets. The boot profile
+// is uploaded as artifacts.
+func TestBootProfile(t *testing.T) {
+	ctx := utils.Context(t)
+	var budgets map[string]float64
+	if s, err := utils.GetMetadata(ctx, "instance", "attributes", bootPhaseBudgetsAttribute); err == nil {
+		if budgets, err = parseBootPhaseBudgets(s); err != nil {
+			t.Fatalf("[FAILED] invalid %s metadata: %v", bootPhaseBudgetsAttribute, err)
+		}
+	}
+	var phases []bootPhase
+	if utils.IsWindows() {
+		phases = windowsBootProfile(ctx, t)
+	} else {
+		phases = linuxBootProfile(ctx, t)
+	}
+	measured := make(map[string]bool)
+	for _, p := range phases {
+		measured[p.name] = true
+		utils.RecordMetric(t, "boot_phase_time", p.duration.Seconds(), "s", map[string]string{"phase": p.name})
+		if maxTime, ok := budgets[p.name]; ok && p.duration.Seconds() > maxTime {
+			t.Errorf("[FAILED] Boot phase %s took %.3fs, more than its budget of %gs", p.name, p.duration.Seconds(), maxTime)
+		}
+	}
+	for phase := range budgets {
+		if !measured[phase] {
+			t.Logf("Boot phase %s was not measured on this image, its budget is not checked.", phase)
+		}
+	}
+}
+
+// linuxBootProfile returns the boot phases of systemd-analyze time, once the
+// boot is finished. The output of systemd-analyze time, blame and
+// critical-chain and the boot timestamps of systemd are saved as artifacts.
+func linuxBootProfile(ctx context.Context, t *testing.T) []bootPhase {
+	t.Helper()
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	var output []byte
+	for {
+		// systemd-analyze time fails until all the jobs of the boot are done.
+		var err error
+		cmd := exec.CommandContext(ctxWithTimeout, "systemd-analyze", "time")
+		if output, err = cmd.Output(); err == nil {
+			break
+		}
+		if ctxWithTimeout.Err() != nil {
+			jobs, _ := exec.CommandContext(ctx, "systemctl", "list-jobs").CombinedOutput()
+			t.Fatalf("[FAILED] boot did not finish, %q = %v, pending jobs:\n%s", cmd.String(), err, jobs)
+		}
+		time.Sleep(5 * time.Second)
+	}
+	utils.WriteArtifact(t, "systemd-analyze-time.txt", output)
+	phases, err := parseSystemdAnalyzeTime(string(output))
+	if err != nil {
+		t.Fatalf("[FAILED] %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "systemctl", "show", "--property=FirmwareTimestampMonotonic,LoaderTimestampMonotonic,KernelTimestamp,InitRDTimestampMonotonic,UserspaceTimestampMonotonic,FinishTimestampMonotonic")
+	if output, err = cmd.Output(); err != nil {
+		t.Errorf("[FAILED] exec.CommandContext(ctx, %s) = %v want nil", cmd.String(), err)
+	} else {
+		utils.WriteArtifact(t, "systemd-timestamps.txt", output)
+	}
+
+	cmd = exec.CommandContext(ctx, "systemd-analyze", "blame")
+	if output, err = cmd.Output(); err != nil {
+		t.Errorf("[FAILED] exec.CommandContext(ctx, %s) = %v want nil", cmd.String(), err)
+	} else {
+		utils.WriteArtifact(t, "systemd-analyze-blame.txt", output)
+		if units, err := parseSystemdAnalyzeBlame(string(output)); err != nil {
+			t.Logf("failed to parse %q output: %v", cmd.String(), err)
+		} else {
+			for _, u := range slowestUnits(units, 5) {
+				t.Logf("Slow unit: %s took %s to start", u.unit, u.activation)
+			}
+		}
+	}
+
+	cmd = exec.CommandContext(ctx, "systemd-analyze", "critical-chain")
+	if output, err = cmd.Output(); err != nil {
+		t.Errorf("[FAILED] exec.CommandContext(ctx, %s) = %v want nil", cmd.String(), err)
+	} else {
+		utils.WriteArtifact(t, "systemd-analyze-critical-chain.txt", output)
+		if units, err := parseSystemdAnalyzeCriticalChain(string(output)); err != nil {
+			t.Logf("failed to parse %q output: %v", cmd.String(), err)
+		} else {
+			t.Logf("Default target %s reached after %s in userspace", units[0].unit, units[0].activeAt)
+			for _, u := range slowestUnits(units, 5) {
+				if u.activation > 0 {
+					t.Logf("Slow unit on the critical chain: %s took %s to start, active after %s", u.unit, u.activation, u.activeAt)
+				}
+			}
+		}
+	}
+	return phases
+}
+
+// windowsBootProfile returns the boot phases of the boot performance event of
+// the current boot, which Windows logs once the post boot phase is over. The
+// event data is saved as an artifact. The test is skipped if the event isn't
+// logged, like when the log is disabled.
+func windowsBootProfile(ctx context.Context, t *testing.T) []bootPhase {
+	t.Helper()
+	cmd := `Get-WinEvent -FilterHashtable @{LogName='Microsoft-Windows-Diagnostics-Performance/Operational'; Id=100; StartTime=(Get-CimInstance Win32_OperatingSystem).LastBootUpTime} -MaxEvents 1 -ErrorAction SilentlyContinue | ForEach-Object { ([xml]$_.ToXml()).Event.EventData.Data | ForEach-Object { $_.Name + '=' + $_.'#text' } }`
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	var output string
+	for {
+		out, err := utils.RunPowershellCmd(cmd)
+		if err != nil {
+			t.Fatalf("[FAILED] utils.RunPowershellCmd(%s) = stderr: %v err: %v want err: nil", cmd, out.Stderr, err)
+		}
+		if output = strings.TrimSpace(out.Stdout); output != "" {
+			break
+		}
+		if ctxWithTimeout.Err() != nil {
+			t.Skipf("The boot performance event was not logged after %s, the Diagnostics-Performance log may be disabled.", 10*time.Minute)
+		}
+		time.Sleep(10 * time.Second)
+	}
+	utils.WriteArtifact(t, "boot-performance-event.txt", []byte(output))
+	phases, err := parseWindowsBootEvent(output)
+	if err != nil {
+		t.Fatalf("[FAILED] %v", err)
+	}
+	return phases
+}
+
+// TestTimeToReady measures from this VM the time the probed VM, created once
+// this VM has booted, takes from its creation to print FINISHED-BOOTING on its
+// serial port, to start its guest agent and to accept SSH connections, and to
+// accept RDP connections on Windows. It only runs with
+// -imageboot_time_to_ready.
+func TestTimeToReady(t *testing.T) {
+	ctx := utils.Context(t)
+	vmname, err := utils.GetRealVMName(ctx, probedVMName)
+	if err != nil {
+		t.Fatalf("[FAILED] failed to get real vm name: %v", err)
+	}
+	project, zone, err := utils.GetProjectZone(ctx)
+	if err != nil {
+		t.Fatalf("[FAILED] failed to get project and zone: %v", err)
+	}
+	client, err := utils.GetDaisyClient(ctx)
+	if err != nil {
+		t.Fatalf("[FAILED] failed to create compute client: %v", err)
+	}
+	pembytes, err := utils.DownloadPrivateKey(ctx, probeUser)
+	if err != nil {
+		t.Fatalf("[FAILED] failed to download private key: %v", err)
+	}
+
+	// The probed VM is being created once this VM has booted.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	var instance *compute.Instance
+	for {
+		if instance, err = client.GetInstance(project, zone, vmname); err == nil {
+			break
+		}
+		if ctxWithTimeout.Err() != nil {
+			t.Fatalf("[FAILED] probed VM %s was not created: %v", vmname, err)
+		}
+		time.Sleep(probeInterval)
+	}
+	created, err := time.Parse(time.RFC3339, instance.CreationTimestamp)
+	if err != nil {
+		t.Fatalf("[FAILED] time.Parse(time.RFC3339, %q) = %v want nil", instance.CreationTimestamp, err)
+	}
+	t.Logf("Probed VM %s created at %s", vmname, created.Format(time.RFC3339Nano))
+
+	serial := &serialWatcher{read: func(start int64) (*compute.SerialPortOutput, error) {
+		return client.GetSerialPortOutput(project, zone, vmname, 1, start)
+	}}
+	probes := []readinessProbe{
+		{metric: "time_to_booted", ready: serial.matcher(bootedRegex)},
+		{metric: "time_to_agent_ready", ready: serial.matcher(agentReadyRegex)},
+	}
+	if utils.IsWindows() {
+		// Windows images don't ship an SSH server: the probed VM installs
+		// google-compute-engine-ssh while it boots, so that the time to SSH
+		// includes the install and isn't comparable to the one of Linux.
+		probes = append(probes,
+			readinessProbe{metric: "time_to_ssh_with_install", ready: sshProbe(probeUser, vmname+":22", pembytes)},
+			readinessProbe{metric: "time_to_rdp", ready: tcpProbe(vmname + ":3389")},
+		)
+	} else {
+		probes = append(probes, readinessProbe{metric: "time_to_ssh", ready: sshProbe(probeUser, vmname+":22", pembytes)})
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+	defer cancel()
+	durations, errs := runReadinessProbes(probeCtx, created, probeInterval, probes)
+	for _, p := range probes {
+		d, ok := durations[p.metric]
+		if !ok {
+			t.Errorf("[FAILED] probed VM %s was not ready for %s: %v", vmname, p.metric, errs[p.metric])
+			continue
+		}
+		utils.RecordMetric(t, p.metric, d.Seconds(), "s", nil)
+	}
+}
+
 func findInstanceStartTime(ctx context.Context, t *testing.T) time.Time {
 	t.Helper()
 	if utils.IsWindows() {
@@ -255,13 +461,17 @@ func findInstanceStartTime(ctx context.Context, t *testing.T) time.Time {
 }
 
 // Find the time at which all essential services have been started. The list of
-// essential services is decided from the image name.
+// essential services is decided from the guest OS.
 func findEssentialServiceStartTime(ctx context.Context, t *testing.T, image string) time.Time {
 	t.Helper()
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("[FAILED] unable to get the guest OS: %v", err)
+	}
 	essentialServices := []string{"google-guest-agent.service", "google-guest-agent-manager.service", "sshd.service"}
-	if utils.IsWindowsImage(image) {
+	if guestOS.IsWindows() {
 		essentialServices = []string{"GCEAgent", "GCEAgentManager"}
-	} else if utils.IsUbuntu(image) {
+	} else if guestOS.Distro == utils.DistroUbuntu {
 		essentialServices = []string{"google-guest-agent.service", "google-guest-agent-manager.service", "ssh.service"}
 	}
 	latestStartTime := time.Time{}
@@ -0,0 +1,157 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imageboot
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// probedVMName is the name of the VM whose readiness TestTimeToReady
+	// measures from the prober VM.
+	probedVMName = "probed"
+	// probeUser is the user TestTimeToReady connects to the probed VM as.
+	probeUser = "probe-user"
+	// probeInterval is the interval between two attempts of a probe.
+	probeInterval = time.Second
+	// probeDialTimeout bounds the TCP connections of the probes, which hang
+	// while the probed VM drops packets during its boot.
+	probeDialTimeout = 5 * time.Second
+)
+
+var (
+	// bootedRegex matches the line the wrapper prints on the serial port when
+	// it starts, once the guest has booted.
+	bootedRegex = regexp.MustCompile(`FINISHED-BOOTING`)
+	// agentReadyRegex matches the line the guest agent prints on the serial
+	// port once it started and read the metadata.
+	agentReadyRegex = regexp.MustCompile(`(?i)(GCE|Guest) Agent (Manager )?Started`)
+)
+
+// readinessProbe checks a readiness of the probed VM, like accepting SSH
+// connections. Probes which return an error are retried.
+type readinessProbe struct {
+	// metric is the name of the metric of the time the probed VM took to be
+	// ready, like time_to_ssh.
+	metric string
+	ready  func(ctx context.Context) (bool, error)
+}
+
+// runReadinessProbes runs the probes every interval until they succeed or the
+// context is done. It returns the time from start to the first success of
+// each probe, and the last error of the probes which never succeeded.
+func runReadinessProbes(ctx context.Context, start time.Time, interval time.Duration, probes []readinessProbe) (map[string]time.Duration, map[string]error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	durations := make(map[string]time.Duration)
+	errs := make(map[string]error)
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p readinessProbe) {
+			defer wg.Done()
+			var lastErr error
+			for {
+				ready, err := p.ready(ctx)
+				if ready {
+					mu.Lock()
+					durations[p.metric] = time.Since(start)
+					mu.Unlock()
+					return
+				}
+				if err != nil {
+					lastErr = err
+				}
+				select {
+				case <-ctx.Done():
+					if lastErr == nil {
+						lastErr = ctx.Err()
+					}
+					mu.Lock()
+					errs[p.metric] = lastErr
+					mu.Unlock()
+					return
+				case <-time.After(interval):
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	return durations, errs
+}
+
+// serialWatcher reads the serial port output of the probed VM incrementally.
+type serialWatcher struct {
+	read func(start int64) (*compute.SerialPortOutput, error)
+
+	mu       sync.Mutex
+	next     int64
+	contents strings.Builder
+}
+
+// matcher returns a probe function which succeeds once the serial port output
+// of the VM matches the regexp.
+func (w *serialWatcher) matcher(re *regexp.Regexp) func(context.Context) (bool, error) {
+	return func(context.Context) (bool, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		out, err := w.read(w.next)
+		if err != nil {
+			return false, err
+		}
+		w.contents.WriteString(out.Contents)
+		w.next = out.Next
+		return re.MatchString(w.contents.String()), nil
+	}
+}
+
+// tcpProbe returns a probe function which succeeds once the address accepts
+// TCP connections, like the RDP port of Windows.
+func tcpProbe(address string) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		dialer := &net.Dialer{Timeout: probeDialTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return false, err
+		}
+		conn.Close()
+		return true, nil
+	}
+}
+
+// sshProbe returns a probe function which succeeds once the user completes an
+// SSH handshake with the address.
+func sshProbe(user, address string, pembytes []byte) func(context.Context) (bool, error) {
+	dial := tcpProbe(address)
+	return func(ctx context.Context) (bool, error) {
+		// Check that the port is open first, the SSH client has no timeout.
+		if ok, err := dial(ctx); !ok {
+			return false, err
+		}
+		client, err := utils.CreateClient(user, address, pembytes)
+		if err != nil {
+			return false, err
+		}
+		client.Close()
+		return true, nil
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imageboot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+func TestRunReadinessProbes(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	calls := 0
+	probes := []readinessProbe{
+		{metric: "time_to_ready", ready: func(context.Context) (bool, error) { return true, nil }},
+		{metric: "time_to_third", ready: func(context.Context) (bool, error) {
+			calls++
+			if calls < 3 {
+				return false, errors.New("connection refused")
+			}
+			return true, nil
+		}},
+		{metric: "time_to_never", ready: func(context.Context) (bool, error) { return false, errors.New("connection refused") }},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	durations, errs := runReadinessProbes(ctx, start, time.Millisecond, probes)
+	for _, metric := range []string{"time_to_ready", "time_to_third"} {
+		if d, ok := durations[metric]; !ok || d < time.Minute {
+			t.Errorf("runReadinessProbes() %s = %s (found: %v), want at least a minute", metric, d, ok)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("time_to_third probe called %d times, want 3", calls)
+	}
+	if _, ok := durations["time_to_never"]; ok {
+		t.Errorf("runReadinessProbes() returned a duration for time_to_never, want none")
+	}
+	if err := errs["time_to_never"]; err == nil || err.Error() != "connection refused" {
+		t.Errorf("runReadinessProbes() time_to_never error = %v, want connection refused", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("runReadinessProbes() returned errors %v, want only time_to_never", errs)
+	}
+}
+
+func TestSerialWatcher(t *testing.T) {
+	chunks := []string{"Booting\nGCE Agent Sta", "rted (version 20260101.00)\n2026/01/01 10:00:00 FINISHED-BOO", "TING\n"}
+	var starts []int64
+	w := &serialWatcher{read: func(start int64) (*compute.SerialPortOutput, error) {
+		starts = append(starts, start)
+		if len(starts) > len(chunks) {
+			return &compute.SerialPortOutput{Next: start}, nil
+		}
+		return &compute.SerialPortOutput{Contents: chunks[len(starts)-1], Next: start + int64(len(chunks[len(starts)-1]))}, nil
+	}}
+	// The probes share the output read by each other.
+	agentReady, booted := w.matcher(agentReadyRegex), w.matcher(bootedRegex)
+	for i, want := range []struct{ agentReady, booted bool }{{false, false}, {true, true}, {true, true}} {
+		gotAgentReady, err := agentReady(context.Background())
+		if err != nil {
+			t.Fatalf("agent ready probe %d = %v, want nil", i, err)
+		}
+		gotBooted, err := booted(context.Background())
+		if err != nil {
+			t.Fatalf("booted probe %d = %v, want nil", i, err)
+		}
+		if gotAgentReady != want.agentReady || gotBooted != want.booted {
+			t.Errorf("probes %d = agent ready %v, booted %v, want %v, %v", i, gotAgentReady, gotBooted, want.agentReady, want.booted)
+		}
+	}
+	if starts[1] != int64(len(chunks[0])) {
+		t.Errorf("second serial read started at %d, want %d", starts[1], len(chunks[0]))
+	}
+
+	failing := &serialWatcher{read: func(int64) (*compute.SerialPortOutput, error) { return nil, errors.New("not found") }}
+	if ready, err := failing.matcher(bootedRegex)(context.Background()); ready || err == nil {
+		t.Errorf("probe of a failing serial read = %v, %v, want false and an error", ready, err)
+	}
+}
+
+func TestTCPProbe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v, want nil", err)
+	}
+	address := l.Addr().String()
+	if ready, err := tcpProbe(address)(context.Background()); !ready || err != nil {
+		t.Errorf("tcpProbe(%s) of a listening address = %v, %v, want true, nil", address, ready, err)
+	}
+	l.Close()
+	if ready, err := tcpProbe(address)(context.Background()); ready || err == nil {
+		t.Errorf("tcpProbe(%s) of a closed address = %v, %v, want false and an error", address, ready, err)
+	}
+}
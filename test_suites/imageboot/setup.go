@@ -17,6 +17,7 @@
 package imageboot
 
 import (
+	"flag"
 	"regexp"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
@@ -26,6 +27,11 @@ import (
 // Name is the name of the test package. It must match the directory name.
 var Name = "imageboot"
 
+var (
+	phaseBudgets = flag.String("imageboot_phase_budgets", "", "comma separated list of <phase>=<seconds> budgets of the boot phases measured by TestBootProfile, e.g. \"kernel=5,userspace=20\". When unset, the boot phases are only recorded as metrics")
+	timeToReady  = flag.Bool("imageboot_time_to_ready", false, "run TestTimeToReady, which creates a prober and a probed VM to measure the time the image takes to accept SSH connections")
+)
+
 var sbUnsupported = []*regexp.Regexp{
 	// Permanent exceptions
 	regexp.MustCompile("debian-1[01].*arm64"),
@@ -40,6 +46,10 @@ var sbUnsupported = []*regexp.Regexp{
 	regexp.MustCompile("(sles-15|opensuse-leap).*arm64"), // https://bugzilla.suse.com/show_bug.cgi?id=1214761
 }
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests that the image boots, reboots and supports secure boot."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	vm, err := t.CreateTestVM("boot")
@@ -61,7 +71,19 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	if err != nil {
 		return err
 	}
-	vm3.RunTests("TestBootTime")
+	if *phaseBudgets != "" {
+		if _, err := parseBootPhaseBudgets(*phaseBudgets); err != nil {
+			return err
+		}
+		vm3.AddMetadata(bootPhaseBudgetsAttribute, *phaseBudgets)
+	}
+	vm3.RunTests("TestBootTime|TestBootProfile")
+
+	if *timeToReady {
+		if err := setupTimeToReady(t); err != nil {
+			return err
+		}
+	}
 
 	for _, r := range sbUnsupported {
 		if r.MatchString(t.Image.Name) {
@@ -79,3 +101,29 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	vm4.RunTests("TestGuestSecureBoot")
 	return nil
 }
+
+// setupTimeToReady creates the prober VM of TestTimeToReady and the VM it
+// probes.
+func setupTimeToReady(t *imagetest.TestWorkflow) error {
+	publicKey, err := t.AddSSHKey(probeUser)
+	if err != nil {
+		return err
+	}
+	prober, err := t.CreateTestVM("prober")
+	if err != nil {
+		return err
+	}
+	prober.AddScope("https://www.googleapis.com/auth/cloud-platform")
+	prober.RunTests("TestTimeToReady")
+	probed, err := prober.CreateProbedVM(probedVMName)
+	if err != nil {
+		return err
+	}
+	probed.AddUser(probeUser, publicKey)
+	if utils.HasFeature(t.Image, "WINDOWS") {
+		probed.AddMetadata("enable-windows-ssh", "true")
+		probed.AddMetadata("sysprep-specialize-script-cmd", "googet -noconfirm=true install google-compute-engine-ssh")
+	}
+	probed.RunTests("TestGuestBoot$")
+	return nil
+}
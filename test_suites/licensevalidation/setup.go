@@ -33,21 +33,21 @@ var slesMajorVersionRe = regexp.MustCompile(`(sles-[0-9]+)`)
 // Name is the name of the test package. It must match the directory name.
 var Name = "licensevalidation"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests the licenses of the image.",
+		ExcludedImages: []imagetest.ImageExclusion{
+			// Windows client images are not published, and openSUSE Leap images
+			// don't have licenses.
+			{Regexp: "windows-11", Reason: "Skipping license check for Windows 11 client images."},
+			{Regexp: "windows-10", Reason: "Skipping license check for Windows 10 client images."},
+			{Regexp: "opensuse-leap", Reason: "Skipping license check for opensuse-leap images."},
+		},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	// Skipping license check for Windows Client Images (We don't publish them) & openSUSE Leap Images
-	// (They don't have licenses).
-	switch {
-	case strings.Contains(t.Image.Name, "windows-11"):
-		t.Skip("Skipping license check for Windows 11 client images.")
-	case strings.Contains(t.Image.Name, "windows-10"):
-		t.Skip("Skipping license check for Windows 10 client images.")
-	case strings.Contains(t.Image.Name, "opensuse-leap"):
-		t.Skip("Skipping license check for opensuse-leap images.")
-	case strings.Contains(t.Image.Name, "opensuse-leap-arm64"):
-		t.Skip("Skipping license check for opensuse-leap-arm64 images.")
-	}
-
 	licensetests := "TestLicenses"
 	if utils.HasFeature(t.Image, "WINDOWS") {
 		licensetests += "|TestWindowsActivationStatus"
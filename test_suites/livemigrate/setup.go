@@ -25,6 +25,10 @@ import (
 // Name is the name of the test package. It must match the directory name.
 var Name = "livemigrate"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests that VMs survive live migration."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	lm := &daisy.Instance{}
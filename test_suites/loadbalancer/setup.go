@@ -17,8 +17,6 @@
 package loadbalancer
 
 import (
-	"regexp"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/compute-daisy"
 	"google.golang.org/api/compute/v1"
@@ -39,12 +37,16 @@ var (
 	l7clientVMip4addr = "10.1.2.60"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests VMs as L3 and L7 load balancer backends.",
+		// Alias IPs are disabled on COS and required for cloud load balancers.
+		ExcludedOSFamilies: []string{imagetest.OSFamilyCOS},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if regexp.MustCompile(`^cos-`).MatchString(t.Image.Family) {
-		// Alias IPs are disabled on COS and required for cloud load balancers.
-		return nil
-	}
 	t.CreatesLoadBalancers = true
 	lbnet, err := t.CreateNetwork("loadbalancer", false)
 	if err != nil {
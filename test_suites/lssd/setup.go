@@ -16,8 +16,6 @@
 package lssd
 
 import (
-	"regexp"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 	daisy "github.com/GoogleCloudPlatform/compute-daisy"
@@ -26,7 +24,6 @@ import (
 
 // Name is the name of the test package. It must match the directory name.
 var Name = "lssd"
-var slesHotattachSkip = regexp.MustCompile("sles-15-sp5|sles-15-sp6")
 
 const (
 	// the path to write the file on linux
@@ -35,13 +32,12 @@ const (
 	windowsMountDriveLetter = "F"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests local SSD mounting."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if slesHotattachSkip.MatchString(t.Image.Name) {
-		// TODO(b/434563751): Re-enable hotattach test for opensuse images once the bug is fixed.
-		t.Skip("Skipping hotattach test for opensuse images because of b/434563751")
-		return nil
-	}
 	if t.Image.Architecture != "ARM64" && utils.HasFeature(t.Image, "GVNIC") {
 		lssdMountInst := &daisy.Instance{}
 		lssdMountInst.Zone = "us-central1-a"
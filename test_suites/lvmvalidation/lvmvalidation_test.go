@@ -15,7 +15,6 @@ package lvmvalidation
 
 import (
 	"bufio"
-	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -38,30 +37,30 @@ func runCmd(t *testing.T, command string, args ...string) (string, string, error
 	return string(stdout), "", nil
 }
 
-// getSAPStatus checks if the image name indicates a RHEL for SAP image.
-func getSAPStatus(ctx context.Context) (bool, error) {
-	imageName, err := utils.GetMetadata(ctx, "instance", "image")
+// getSAPStatus returns true if the image is RHEL for SAP, false otherwise.
+func getSAPStatus() (bool, error) {
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		return false, fmt.Errorf("failed to get image name: %v", err)
+		return false, fmt.Errorf("failed to get guest OS: %v", err)
 	}
-	return utils.IsSAP(imageName), nil
+	return guestOS.HasVariant(utils.VariantSAP), nil
 }
 
 // getLVMStatus returns true if the image is LVM, false otherwise.
-func getLVMStatus(ctx context.Context) (bool, error) {
-	imageName, err := utils.GetMetadata(ctx, "instance", "image")
+func getLVMStatus() (bool, error) {
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		return false, fmt.Errorf("failed to get image name: %v", err)
+		return false, fmt.Errorf("failed to get guest OS: %v", err)
 	}
-	return utils.IsRHELLVM(imageName), nil
+	return guestOS.HasVariant(utils.VariantLVM), nil
 }
 
 // TestLVMPackage checks the lvm2 package install status.
 // If the image is LVM, the lvm2 package should be installed.
 // If the image is not LVM, the lvm2 package should not be installed.
 func TestLVMPackage(t *testing.T) {
-	isLVM, err := getLVMStatus(utils.Context(t))
-	isSAP, err := getSAPStatus(utils.Context(t))
+	isLVM, err := getLVMStatus()
+	isSAP, err := getSAPStatus()
 	if err != nil {
 		t.Fatalf("Failed to get LVM status: %v", err)
 	}
@@ -89,7 +88,7 @@ func TestLVMExists(t *testing.T) {
 		t.Skip("LVM validation test only supports Linux images.")
 	}
 
-	isLVM, err := getLVMStatus(utils.Context(t))
+	isLVM, err := getLVMStatus()
 	if err != nil {
 		t.Fatalf("Failed to get LVM status: %v", err)
 	}
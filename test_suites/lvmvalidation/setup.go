@@ -17,21 +17,22 @@ package lvmvalidation
 
 import (
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test suite.
 var Name = "lvmvalidation"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests the LVM layout of LVM images.",
+		OSFamilies:  []string{imagetest.OSFamilyRHEL},
+	})
+}
+
 // TestSetup sets up the test workflow.
 // For LVM images it should test that LVM is present and the partitions are correct.
 // For non-lvm it should ensure that LVM isn't installed and that the partitions are normal.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	// skip if not rhel image
-	if !utils.IsRHEL(t.Image.Name) {
-		t.Skip("LVM validation test only supports Red Hat images.")
-	}
-
 	vm, err := t.CreateTestVM("lvmTest")
 	if err != nil {
 		return err
@@ -14,18 +14,20 @@ package mdsmtls
 
 import (
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
 const Name = "mdsmtls"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:      "Tests mTLS to the metadata server.",
+		RequiredFeatures: []string{"UEFI_COMPATIBLE"},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if !utils.HasFeature(t.Image, "UEFI_COMPATIBLE") {
-		return nil
-	}
-
 	vm, err := t.CreateTestVM("mtlscreds")
 	if err != nil {
 		return err
@@ -15,14 +15,13 @@
 package mdsroutes
 
 import (
-	"context"
 	"net"
 	"os/exec"
-	"strings"
 	"testing"
 	"time"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 )
 
 const (
@@ -32,13 +31,13 @@ const (
 // Skip secondary NICs on Windows. Guest agent doesn't manage NICs on Windows,
 // so the routes/behavior are more unpredictable.
 // TODO(b/428199320): Remove check once fix is implemented.
-func shouldSkipSecondaryNICMDSCheck(ctx context.Context, t *testing.T) bool {
+func shouldSkipSecondaryNICMDSCheck(t *testing.T) bool {
 	t.Helper()
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("Failed to get image from metadata: %v", err)
+		t.Fatalf("Failed to get guest OS: %v", err)
 	}
-	return utils.IsWindows() || utils.IsSLES(image) || strings.Contains(image, "debian-13")
+	return guestOS.IsWindows() || guestOS.Distro == utils.DistroSLES || (guestOS.Distro == utils.DistroDebian && guestOS.MajorVersion() == "13")
 }
 
 func TestMetadataPath(t *testing.T) {
@@ -56,6 +55,11 @@ func TestMetadataPath(t *testing.T) {
 // Test that only the primary NIC has a route to the MDS.
 func TestMDSRoutes(t *testing.T) {
 	ctx := utils.Context(t)
+	image, err := utils.GetMetadata(ctx, "instance", "image")
+	if err != nil {
+		t.Fatalf("Failed to get image from metadata: %v", err)
+	}
+	exceptions.SkipTest(t, Name, image)
 
 	allIfaces, err := net.Interfaces()
 	if err != nil {
@@ -64,7 +68,7 @@ func TestMDSRoutes(t *testing.T) {
 	ifaces := utils.FilterLoopbackTunnelingInterfaces(allIfaces)
 
 	for i, iface := range ifaces {
-		if i != 0 && shouldSkipSecondaryNICMDSCheck(ctx, t) {
+		if i != 0 && shouldSkipSecondaryNICMDSCheck(t) {
 			break
 		}
 
@@ -17,37 +17,21 @@
 package mdsroutes
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 )
 
 // Name is the name of the test package. It must match the directory name.
 var Name = "mdsroutes"
 
-var (
-	unsupportedImages = []exceptions.Exception{
-		{
-			Match: exceptions.ImageCOS,
-		},
-		{
-			// TODO(b/428199320): Remove this once the bug is fixed.
-			Match:   exceptions.ImageSLES,
-			Version: 15,
-			Type:    exceptions.Equal,
-		},
-	}
-)
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:        "Tests routes to the metadata server.",
+		ExcludedOSFamilies: []string{imagetest.OSFamilyCOS},
+	})
+}
 
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if exceptions.HasMatch(t.Image.Family, unsupportedImages) {
-		t.Skip(fmt.Sprintf("MDS routes not supported on %s", t.Image.Family))
-		return nil
-	}
-
 	network1, err := t.CreateNetwork("network-1", false)
 	if err != nil {
 		return err
@@ -84,15 +68,5 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	if err := multinicVM.AddAliasIPRanges("10.14.8.0/24", "secondary-range"); err != nil {
 		return err
 	}
-
-	tests := []string{"TestDNS", "TestMetadataPath"}
-
-	// TODO(b/428199320): Remove this once the bug is fixed.
-	if !exceptions.MatchAll(t.Image.Name, exceptions.ImageUbuntu, exceptions.Exception{Version: 2204}) {
-		tests = append(tests, "TestMDSRoutes")
-		return nil
-	}
-	multinicVM.RunTests(strings.Join(tests, "|"))
-
 	return nil
 }
@@ -17,7 +17,6 @@ package metadata
 import (
 	"context"
 	"io"
-	"os"
 	"os/exec"
 	"strings"
 	"testing"
@@ -34,12 +33,12 @@ func guestAgentPackageName() string {
 }
 
 func isUbuntu(ctx context.Context, t *testing.T) bool {
-	content, err := os.ReadFile("/etc/os-release")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Logf("Could not read os-release: %v, defaulting skip agent reinstall to false", err)
+		t.Logf("Could not get guest OS: %v, defaulting skip agent reinstall to false", err)
 		return false
 	}
-	return utils.IsUbuntu(string(content))
+	return guestOS.Distro == utils.DistroUbuntu
 }
 
 func isVersionMismatch(ctx context.Context, t *testing.T, pkg string) bool {
@@ -57,7 +56,7 @@ func isVersionMismatch(ctx context.Context, t *testing.T, pkg string) bool {
 	}
 
 	// Ubuntu built/installed packages includes "ubuntu" in the version string.
-	return !utils.IsUbuntu(string(out))
+	return !strings.Contains(string(out), "ubuntu")
 }
 
 func reinstallGuestAgent(ctx context.Context, t *testing.T) {
@@ -45,11 +45,11 @@ func testScripts(t *testing.T, stage string, success bool) {
 	}
 
 	// Exceptions for certain Linux images.
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		return
 	}
 
@@ -49,6 +49,10 @@ const (
 //go:embed *
 var scripts embed.FS
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests metadata scripts and metadata server access."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 
@@ -53,13 +53,13 @@ func TestShutdownScripts(t *testing.T) {
 	ctx := utils.Context(t)
 	testScripts(t, "shutdown", true)
 
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("utils.GetMetadata(ctx, instance, image) = err %v want nil", err)
+		t.Fatalf("utils.GetGuestOS() = err %v want nil", err)
 	}
 
 	// Only perform agent reinstall for non-COS images.
-	if !utils.IsCOS(image) {
+	if guestOS.Distro != utils.DistroCOS {
 		reinstallGuestAgent(ctx, t)
 		testScripts(t, "shutdown", false)
 	}
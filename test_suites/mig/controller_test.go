@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mig
+
+import (
+	"path"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/migutils"
+)
+
+// getGroup returns the managed instance group of the controller VM, once its
+// members are healthy.
+func getGroup(t *testing.T) *migutils.Group {
+	t.Helper()
+	ctx := utils.Context(t)
+	group, err := migutils.Get(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the managed instance group: %v", err)
+	}
+	if err := group.WaitForHealthy(ctx); err != nil {
+		t.Fatalf("Members did not pass the health check of port %d: %v", healthCheckPort, err)
+	}
+	return group
+}
+
+// TestAutohealing tests that members pass the autohealing health check, and
+// that a failed member is repaired by the group.
+func TestAutohealing(t *testing.T) {
+	ctx := utils.Context(t)
+	group := getGroup(t)
+	instances, err := group.ListInstances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := instances[0].Instance
+	t.Logf("Stopping member %s", path.Base(failed))
+	if err := group.StopInstance(ctx, failed); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.WaitForStable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.WaitForHealthy(ctx); err != nil {
+		t.Errorf("Group did not repair member %s: %v", path.Base(failed), err)
+	}
+}
+
+// TestRollingUpdate tests that every member is replaced by a rolling update,
+// and keeps its stateful disk.
+func TestRollingUpdate(t *testing.T) {
+	ctx := utils.Context(t)
+	group := getGroup(t)
+	if err := group.RollingUpdate(ctx, map[string]string{expectStateKey: "true"}); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := group.ListInstances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mi := range instances {
+		if mi.Version == nil || path.Base(mi.Version.InstanceTemplate) != group.TemplateName() {
+			t.Errorf("Member %s was not updated to instance template %s", path.Base(mi.Instance), group.TemplateName())
+		}
+	}
+	if err := group.WaitForHealthy(ctx); err != nil {
+		t.Errorf("Updated members did not pass the health check: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mig
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+const (
+	// stateMountPoint is where members mount their stateful disk.
+	stateMountPoint = "/mnt/cit-mig-state"
+	// stateFile is the file on the stateful disk holding the name of the
+	// member which created it.
+	stateFile = "member"
+)
+
+// TestStatefulDisk tests that the stateful disk of members is kept when they
+// are recreated. Members format the blank disk on their first boot and record
+// their name on it, and members recreated by the rolling update check it.
+func TestStatefulDisk(t *testing.T) {
+	ctx := utils.Context(t)
+	name, err := utils.GetInstanceName(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectState, _ := utils.GetMetadata(ctx, "instance", "attributes", expectStateKey)
+	device := filepath.Join("/dev/disk/by-id", "google-"+statefulDisk)
+
+	// blkid exits with 2 when the device has no filesystem.
+	if err := exec.Command("blkid", device).Run(); err != nil {
+		if expectState == "true" {
+			t.Fatalf("Stateful disk %s has no filesystem after the rolling update: %v", device, err)
+		}
+		if out, err := exec.Command("mkfs.ext4", "-F", device).CombinedOutput(); err != nil {
+			t.Fatalf("Failed to format stateful disk %s: %v, output: %s", device, err, out)
+		}
+	}
+	if err := os.MkdirAll(stateMountPoint, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("mount", device, stateMountPoint).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to mount stateful disk %s: %v, output: %s", device, err, out)
+	}
+	defer exec.Command("umount", stateMountPoint).Run()
+
+	file := filepath.Join(stateMountPoint, stateFile)
+	state, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		if got := strings.TrimSpace(string(state)); got != name {
+			t.Errorf("Stateful disk holds the state of member %q, want %q", got, name)
+		}
+	case os.IsNotExist(err) && expectState != "true":
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to record state: %v", err)
+		}
+	default:
+		t.Fatalf("Failed to read the state recorded by the member before the rolling update: %v", err)
+	}
+}
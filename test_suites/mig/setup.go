@@ -0,0 +1,73 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mig tests the image under test as the instance template of a managed
+// instance group.
+package mig
+
+import (
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+)
+
+// Name is the name of the test package. It must match the directory name.
+var Name = "mig"
+
+const (
+	// groupSize is the number of members of the managed instance group.
+	groupSize = 2
+	// healthCheckPort is the port of the autohealing health check. sshd
+	// serves it on every Linux image.
+	healthCheckPort = 22
+	// autoHealingDelaySec is the time members have to boot before autohealing
+	// checks them.
+	autoHealingDelaySec = 300
+	// statefulDisk is the device name of the stateful disk of the members.
+	statefulDisk = "state"
+	// expectStateKey is the metadata key set by the rolling update, which
+	// tells members that their stateful disk must hold their state.
+	expectStateKey = "_cit_mig_expect_state"
+)
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:        "Tests autohealing, rolling updates and stateful disks of managed instance groups of the image.",
+		OSFamilies:         []string{imagetest.OSFamilyLinux},
+		ExcludedOSFamilies: []string{imagetest.OSFamilyCOS},
+	})
+}
+
+// TestSetup sets up the test workflow.
+func TestSetup(t *imagetest.TestWorkflow) error {
+	network, err := t.CreateNetwork("mig", false)
+	if err != nil {
+		return err
+	}
+	subnetwork, err := network.CreateSubnetwork("mig", "10.128.0.0/20")
+	if err != nil {
+		return err
+	}
+	if err := network.CreateFirewallRule("allow-mig-health-check", "tcp", []string{"22"}, []string{"130.211.0.0/22", "35.191.0.0/16"}); err != nil {
+		return err
+	}
+
+	group, err := t.CreateManagedInstanceGroup("mig", groupSize)
+	if err != nil {
+		return err
+	}
+	if err := group.Controller().AddCustomNetwork(network, subnetwork); err != nil {
+		return err
+	}
+	group.SetAutoHealing(healthCheckPort, autoHealingDelaySec)
+	group.AddStatefulDisk(statefulDisk, 10)
+	group.RunTests("TestStatefulDisk")
+	group.Controller().RunTests("TestAutohealing|TestRollingUpdate")
+	return nil
+}
@@ -26,19 +26,30 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 )
 
 const (
 	markerFile = "/var/boot-marker"
 )
 
-func TestAliases(t *testing.T) {
-	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+// skipExcepted skips the running test on the images of its exceptions.
+func skipExcepted(t *testing.T) {
+	t.Helper()
+	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
 	if err != nil {
 		t.Fatalf("could not determine image: %v", err)
 	}
-	if utils.IsCOS(image) {
+	exceptions.SkipTest(t, Name, image)
+}
+
+func TestAliases(t *testing.T) {
+	skipExcepted(t)
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("could not determine guest OS: %v", err)
+	}
+	if guestOS.Distro == utils.DistroCOS {
 		t.Skipf("COS does not support IP aliases")
 	}
 	if err := verifyIPAliases(t); err != nil {
@@ -47,12 +58,12 @@ func TestAliases(t *testing.T) {
 }
 
 func TestAliasAfterReboot(t *testing.T) {
-	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	skipExcepted(t)
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		t.Skipf("COS does not support IP aliases")
 	}
 	_, err = os.Stat(markerFile)
@@ -89,18 +100,18 @@ func verifyIPAliases(t *testing.T) error {
 }
 
 func skipIfUbuntu(t *testing.T) {
-	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsUbuntu(image) {
+	if guestOS.Distro == utils.DistroUbuntu {
 		t.Skipf("Skipping test for Ubuntu images due to b/434210587")
 	}
 }
 
 func TestNetworkManagerRestart(t *testing.T) {
 	utils.LinuxOnly(t)
+	skipExcepted(t)
 
 	ctx := utils.Context(t)
 	iface := readNic(ctx, t, 0)
@@ -135,6 +146,7 @@ func TestNetworkManagerRestart(t *testing.T) {
 
 func TestGgactlCommand(t *testing.T) {
 	utils.LinuxOnly(t)
+	skipExcepted(t)
 	ctx := utils.Context(t)
 	if !utils.CheckLinuxCmdExists("ggactl_plugin") {
 		t.Skipf("ggactl_plugin executable not found, skipping test")
@@ -191,11 +203,11 @@ func restartNetworkManager(ctx context.Context, t *testing.T) {
 
 func readNic(ctx context.Context, t *testing.T, id int) net.Interface {
 	t.Helper()
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		t.Skipf("COS does not support IP aliases")
 	}
 	iface, err := utils.GetInterface(ctx, id)
@@ -206,6 +218,7 @@ func readNic(ctx context.Context, t *testing.T, id int) net.Interface {
 }
 
 func TestAliasAgentRestart(t *testing.T) {
+	skipExcepted(t)
 	ctx := utils.Context(t)
 	iface := readNic(ctx, t, 0)
 
@@ -231,18 +244,9 @@ func TestAliasAgentRestart(t *testing.T) {
 
 func TestAliasAgentRestartWithIPForwardingConfigFalse(t *testing.T) {
 	utils.LinuxOnly(t)
+	skipExcepted(t)
 	ctx := utils.Context(t)
 
-	image, err := utils.GetMetadata(ctx, "instance", "image")
-	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
-	}
-
-	// TODO(b/448377923): Remove this skip once the agent is released.
-	if strings.Contains(image, "guest-agent-stable") || strings.Contains(image, "ubuntu") || strings.Contains(image, "sles") {
-		t.Skipf("Skipping test as it is not expected to pass on previous version of guest agent (image: %s).", image)
-	}
-
 	iface := readNic(ctx, t, 0)
 
 	t.Cleanup(func() {
@@ -121,11 +121,11 @@ func TestWaitForPing(t *testing.T) {
 	}
 	ctx := utils.Context(t)
 	wantPings := 2
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		allowTCPCmd := exec.CommandContext(ctx, "iptables", "-A", "INPUT", "-p", "tcp", "-j", "ACCEPT")
 		if out, err := allowTCPCmd.CombinedOutput(); err != nil {
 			t.Fatalf("iptables -A INPUT -p tcp -j ACCEPT failed: %s %v", out, err)
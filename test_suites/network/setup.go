@@ -17,7 +17,6 @@ package network
 
 import (
 	"regexp"
-	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
@@ -37,6 +36,10 @@ type InstanceConfig struct {
 var vm1Config = InstanceConfig{name: "ping1", ip: "192.168.0.2"}
 var vm2Config = InstanceConfig{name: "ping2", ip: "192.168.0.3"}
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests network configuration such as MTU, DHCP and alias IPs."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	network1, err := t.CreateNetwork("network-1", false)
@@ -80,11 +83,10 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	vm1.RunTests("TestStaticIP|TestSendPing|TestDHCP|TestDefaultMTU|TestNTP")
 
 	multinictests := "TestWaitForPing"
-	// TODO(b/485679396): Remove this skip once the bug is fixed.
-	// We are skipping on SLES 15 and 16 because network route is added via cloud-netconfig.service instead of guest-agent.
-	// The alias IP test currently only checks whether the alias IP route is added by the guest agent,
-	// rather than just checking for whether the route exists
-	if !utils.HasFeature(t.Image, "WINDOWS") && !strings.Contains(t.Image.Name, "sles-15") && !strings.Contains(t.Image.Name, "sles-16") && !strings.Contains(t.Image.Name, "opensuse-leap") && !strings.Contains(t.Image.Name, "ubuntu-1604") && !strings.Contains(t.Image.Name, "ubuntu-pro-1604") && !utils.IsCOS(t.Image.Name) {
+	// Images with known alias bugs skip the alias tests with their exceptions.
+	g := t.GuestOS
+	noAliasTests := g.IsWindows() || g.Distro == utils.DistroCOS || (g.Distro == utils.DistroUbuntu && g.Version == "16.04")
+	if !noAliasTests {
 		multinictests += "|TestAlias|TestGgactlCommand|TestNetworkManagerRestart"
 	}
 
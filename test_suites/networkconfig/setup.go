@@ -26,6 +26,10 @@ import (
 // Name is the name of the test package. It must match the directory name.
 const Name = "networkconfig"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests the network configuration written by the guest environment."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	vm, err := createMachine(t, t.MachineType.Name, t.Zone.Name)
@@ -0,0 +1,326 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkperf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// benchmarkEngine is the network benchmark tool the startup scripts run
+// between the client and the server.
+type benchmarkEngine string
+
+const (
+	iperf2Engine  benchmarkEngine = "iperf2"
+	iperf3Engine  benchmarkEngine = "iperf3"
+	netperfEngine benchmarkEngine = "netperf"
+)
+
+// benchmarkMode is what a benchmark measures, named after the netperf tests.
+type benchmarkMode string
+
+const (
+	// tcpStreamMode measures the TCP throughput, and the retransmits when the
+	// engine reports them.
+	tcpStreamMode benchmarkMode = "TCP_STREAM"
+	// udpStreamMode measures the UDP throughput and packets per second.
+	udpStreamMode benchmarkMode = "UDP_STREAM"
+	// tcpRRMode measures the round trip latency of TCP request/responses.
+	tcpRRMode benchmarkMode = "TCP_RR"
+)
+
+// engineModes are the modes each engine supports.
+var engineModes = map[benchmarkEngine][]benchmarkMode{
+	iperf2Engine:  {tcpStreamMode, udpStreamMode},
+	iperf3Engine:  {tcpStreamMode, udpStreamMode},
+	netperfEngine: {tcpStreamMode, udpStreamMode, tcpRRMode},
+}
+
+// Units of the benchmark metrics.
+const (
+	gbpsUnit        = "Gbps"
+	ppsUnit         = "pps"
+	microsecondUnit = "us"
+	retransmitsUnit = "retransmits"
+)
+
+// benchmarkMetric is a value measured by a benchmark.
+type benchmarkMetric struct {
+	name  string
+	value float64
+	unit  string
+}
+
+// parseBenchmarkModes parses a comma separated list of modes, which defaults
+// to TCP_STREAM.
+func parseBenchmarkModes(modesStr string) ([]benchmarkMode, error) {
+	var modes []benchmarkMode
+	for _, part := range strings.Split(modesStr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		switch mode := benchmarkMode(part); mode {
+		case tcpStreamMode, udpStreamMode, tcpRRMode:
+			modes = append(modes, mode)
+		default:
+			return nil, fmt.Errorf("invalid benchmark mode: %q", part)
+		}
+	}
+	if len(modes) == 0 {
+		modes = append(modes, tcpStreamMode)
+	}
+	return modes, nil
+}
+
+// validateBenchmark checks that the engine supports the modes.
+func validateBenchmark(engine benchmarkEngine, modes []benchmarkMode) error {
+	supported, ok := engineModes[engine]
+	if !ok {
+		return fmt.Errorf("unknown benchmark engine: %q", engine)
+	}
+	for _, mode := range modes {
+		if !slices.Contains(supported, mode) {
+			return fmt.Errorf("benchmark engine %s does not support mode %s", engine, mode)
+		}
+	}
+	return nil
+}
+
+// resultsKey is the guest attribute the client uploads the output of a sample
+// of the benchmark of the mode on an interface to.
+func resultsKey(mode benchmarkMode, ifaceIndex, sample int) string {
+	return fmt.Sprintf("results-%s-%d-%d", strings.ToLower(string(mode)), ifaceIndex, sample)
+}
+
+// parseBenchmarkResult parses the output of the benchmark of the mode run
+// with the engine, as uploaded by the client startup script.
+func parseBenchmarkResult(engine benchmarkEngine, mode benchmarkMode, output string) ([]benchmarkMetric, error) {
+	switch engine {
+	case iperf2Engine:
+		return parseIperf2(mode, output)
+	case iperf3Engine:
+		return parseIperf3(mode, output)
+	case netperfEngine:
+		return parseNetperf(mode, output)
+	}
+	return nil, fmt.Errorf("unknown benchmark engine: %q", engine)
+}
+
+var (
+	// iperf2RateRe matches the interval and rate of a SUM line, like
+	// "[SUM] 0.0-30.0 sec 35.0 GBytes 10.0 Gbits/sec".
+	iperf2RateRe = regexp.MustCompile(`([0-9.]+)\s*-\s*([0-9.]+)\s+sec\s+.*?([0-9.]+)\s+([KMG]?)bits/sec`)
+	// iperf2DatagramsRe matches the datagrams sent by UDP clients, like
+	// "[SUM] Sent 2550000 datagrams".
+	iperf2DatagramsRe = regexp.MustCompile(`Sent\s+([0-9]+)\s+datagrams`)
+)
+
+// bitsPerSecondPrefixes are the multipliers of the unit prefixes of the
+// rates, to Gbps.
+var bitsPerSecondPrefixes = map[string]float64{"": 1e-9, "K": 1e-6, "M": 1e-3, "G": 1}
+
+// parseIperf2 parses the SUM lines of the output of iperf2 clients with
+// parallel streams. The last SUM line is the total of the run.
+func parseIperf2(mode benchmarkMode, output string) ([]benchmarkMetric, error) {
+	var throughput, duration float64
+	var datagrams int64
+	found := false
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		line := s.Text()
+		if m := iperf2RateRe.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.ParseFloat(m[1], 64)
+			end, _ := strconv.ParseFloat(m[2], 64)
+			rate, err := strconv.ParseFloat(m[3], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate in %q: %v", line, err)
+			}
+			throughput, duration, found = rate*bitsPerSecondPrefixes[m[4]], end-start, true
+		}
+		if m := iperf2DatagramsRe.FindStringSubmatch(line); m != nil {
+			datagrams, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no iperf2 rate found in %q", output)
+	}
+	metrics := []benchmarkMetric{{name: "throughput", value: throughput, unit: gbpsUnit}}
+	if mode == udpStreamMode {
+		if datagrams == 0 || duration <= 0 {
+			return nil, fmt.Errorf("no iperf2 datagram count found in %q", output)
+		}
+		metrics = append(metrics, benchmarkMetric{name: "packets_per_second", value: float64(datagrams) / duration, unit: ppsUnit})
+	}
+	return metrics, nil
+}
+
+// iperf3Output is the part of the JSON output of iperf3 clients which is
+// parsed.
+type iperf3Output struct {
+	Error string `json:"error"`
+	End   struct {
+		SumSent struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+			Retransmits   int64   `json:"retransmits"`
+		} `json:"sum_sent"`
+		SumReceived struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+		// Sum is the total of UDP tests.
+		Sum struct {
+			Seconds       float64 `json:"seconds"`
+			BitsPerSecond float64 `json:"bits_per_second"`
+			Packets       int64   `json:"packets"`
+			LostPackets   int64   `json:"lost_packets"`
+		} `json:"sum"`
+	} `json:"end"`
+}
+
+// parseIperf3 parses the JSON output of iperf3 clients run with -J.
+func parseIperf3(mode benchmarkMode, output string) ([]benchmarkMetric, error) {
+	var out iperf3Output
+	if err := json.Unmarshal([]byte(output), &out); err != nil {
+		return nil, fmt.Errorf("invalid iperf3 output %q: %v", output, err)
+	}
+	if out.Error != "" {
+		return nil, fmt.Errorf("iperf3 failed: %s", out.Error)
+	}
+	switch mode {
+	case tcpStreamMode:
+		if out.End.SumReceived.BitsPerSecond <= 0 {
+			return nil, fmt.Errorf("no iperf3 TCP throughput found in %q", output)
+		}
+		return []benchmarkMetric{
+			{name: "throughput", value: out.End.SumReceived.BitsPerSecond / 1e9, unit: gbpsUnit},
+			{name: "retransmits", value: float64(out.End.SumSent.Retransmits), unit: retransmitsUnit},
+		}, nil
+	case udpStreamMode:
+		sum := out.End.Sum
+		if sum.BitsPerSecond <= 0 || sum.Seconds <= 0 {
+			return nil, fmt.Errorf("no iperf3 UDP throughput found in %q", output)
+		}
+		return []benchmarkMetric{
+			{name: "throughput", value: sum.BitsPerSecond / 1e9, unit: gbpsUnit},
+			{name: "packets_per_second", value: float64(sum.Packets-sum.LostPackets) / sum.Seconds, unit: ppsUnit},
+		}, nil
+	}
+	return nil, fmt.Errorf("iperf3 does not support mode %s", mode)
+}
+
+// netperfThroughputUnits are the multipliers of the throughput units of
+// netperf stream tests, to Gbps.
+var netperfThroughputUnits = map[string]float64{"10^0bits/s": 1e-9, "10^3bits/s": 1e-6, "10^6bits/s": 1e-3, "10^9bits/s": 1}
+
+// parseNetperf parses the output of netperf clients run with the -k output
+// selectors THROUGHPUT, THROUGHPUT_UNITS, MEAN_LATENCY,
+// LOCAL_TRANSPORT_RETRANS, REMOTE_RECV_CALLS and ELAPSED_TIME, one KEY=VALUE
+// per line. The output of parallel clients is concatenated: throughputs,
+// packet rates and retransmits are summed, and latencies averaged.
+func parseNetperf(mode benchmarkMode, output string) ([]benchmarkMetric, error) {
+	var records []map[string]string
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(s.Text()), "=")
+		if !ok {
+			continue
+		}
+		// A repeated key starts the output of the next client.
+		if len(records) == 0 {
+			records = append(records, make(map[string]string))
+		}
+		if _, ok := records[len(records)-1][key]; ok {
+			records = append(records, make(map[string]string))
+		}
+		records[len(records)-1][key] = value
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no netperf result found in %q", output)
+	}
+
+	var throughput, pps, latency, retransmits float64
+	hasRetransmits := false
+	for _, r := range records {
+		value := func(key string) (float64, error) {
+			v, err := strconv.ParseFloat(r[key], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid netperf %s %q: %v", key, r[key], err)
+			}
+			return v, nil
+		}
+		switch mode {
+		case tcpStreamMode, udpStreamMode:
+			multiplier, ok := netperfThroughputUnits[r["THROUGHPUT_UNITS"]]
+			if !ok {
+				return nil, fmt.Errorf("unknown netperf throughput unit %q", r["THROUGHPUT_UNITS"])
+			}
+			v, err := value("THROUGHPUT")
+			if err != nil {
+				return nil, err
+			}
+			throughput += v * multiplier
+			if mode == tcpStreamMode {
+				// Retransmits are -1 when the platform doesn't report them.
+				if v, err := value("LOCAL_TRANSPORT_RETRANS"); err == nil && v >= 0 {
+					retransmits += v
+					hasRetransmits = true
+				}
+				continue
+			}
+			calls, err := value("REMOTE_RECV_CALLS")
+			if err != nil {
+				return nil, err
+			}
+			elapsed, err := value("ELAPSED_TIME")
+			if err != nil {
+				return nil, err
+			}
+			if elapsed <= 0 {
+				return nil, fmt.Errorf("invalid netperf ELAPSED_TIME %q", r["ELAPSED_TIME"])
+			}
+			pps += calls / elapsed
+		case tcpRRMode:
+			v, err := value("MEAN_LATENCY")
+			if err != nil {
+				return nil, err
+			}
+			latency += v / float64(len(records))
+		default:
+			return nil, fmt.Errorf("netperf does not support mode %s", mode)
+		}
+	}
+
+	switch mode {
+	case tcpStreamMode:
+		metrics := []benchmarkMetric{{name: "throughput", value: throughput, unit: gbpsUnit}}
+		if hasRetransmits {
+			metrics = append(metrics, benchmarkMetric{name: "retransmits", value: retransmits, unit: retransmitsUnit})
+		}
+		return metrics, nil
+	case udpStreamMode:
+		return []benchmarkMetric{
+			{name: "throughput", value: throughput, unit: gbpsUnit},
+			{name: "packets_per_second", value: pps, unit: ppsUnit},
+		}, nil
+	default:
+		return []benchmarkMetric{{name: "latency", value: latency, unit: microsecondUnit}}, nil
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networkperf
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const iperf3TCPOutput = `{
+	"start": {"connecting_to": {"host": "192.168.0.3", "port": 5001}},
+	"intervals": [],
+	"end": {
+		"streams": [{"sender": {"bits_per_second": 9.1e9, "retransmits": 7}}],
+		"sum_sent": {"seconds": 30, "bytes": 34125000000, "bits_per_second": 9.1e9, "retransmits": 12},
+		"sum_received": {"seconds": 30, "bytes": 33750000000, "bits_per_second": 9.0e9}
+	}
+}`
+
+const iperf3UDPOutput = `{
+	"end": {
+		"sum": {"seconds": 30, "bytes": 3750000000, "bits_per_second": 1.0e9, "jitter_ms": 0.01, "lost_packets": 3000, "packets": 2403000, "lost_percent": 0.12}
+	}
+}`
+
+const netperfTCPStreamOutput = `THROUGHPUT=4500.50
+THROUGHPUT_UNITS=10^6bits/s
+LOCAL_TRANSPORT_RETRANS=3
+THROUGHPUT=4499.50
+THROUGHPUT_UNITS=10^6bits/s
+LOCAL_TRANSPORT_RETRANS=5
+`
+
+const netperfUDPStreamOutput = `THROUGHPUT=950.00
+THROUGHPUT_UNITS=10^6bits/s
+REMOTE_RECV_CALLS=2400000
+ELAPSED_TIME=30.00
+`
+
+const netperfTCPRROutput = `MEAN_LATENCY=40.5
+THROUGHPUT=24600.12
+THROUGHPUT_UNITS=Trans/s
+MEAN_LATENCY=39.5
+THROUGHPUT=25100.40
+THROUGHPUT_UNITS=Trans/s
+`
+
+func TestParseBenchmarkResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  benchmarkEngine
+		mode    benchmarkMode
+		output  string
+		want    []benchmarkMetric
+		wantErr bool
+	}{
+		{
+			name:   "iperf2_tcp_gbits",
+			engine: iperf2Engine,
+			mode:   tcpStreamMode,
+			output: "[SUM] 0.0-30.0 sec 35.0 GBytes 10.0 Gbits/sec\n",
+			want:   []benchmarkMetric{{name: "throughput", value: 10, unit: gbpsUnit}},
+		},
+		{
+			name:   "iperf2_tcp_mbits",
+			engine: iperf2Engine,
+			mode:   tcpStreamMode,
+			output: "[SUM] 0.0000-30.0000 sec 3.20 GBytes 917 Mbits/sec\n",
+			want:   []benchmarkMetric{{name: "throughput", value: 0.917, unit: gbpsUnit}},
+		},
+		{
+			name:   "iperf2_tcp_last_sum",
+			engine: iperf2Engine,
+			mode:   tcpStreamMode,
+			output: "[SUM] 0.0-10.0 sec 11.0 GBytes 9.50 Gbits/sec\n[SUM] 0.0-30.0 sec 35.0 GBytes 9.80 Gbits/sec\n",
+			want:   []benchmarkMetric{{name: "throughput", value: 9.8, unit: gbpsUnit}},
+		},
+		{
+			name:   "iperf2_udp",
+			engine: iperf2Engine,
+			mode:   udpStreamMode,
+			output: "[SUM] 0.0-30.0 sec 3.50 GBytes 1.00 Gbits/sec\n[SUM] Sent 2550000 datagrams\n",
+			want: []benchmarkMetric{
+				{name: "throughput", value: 1, unit: gbpsUnit},
+				{name: "packets_per_second", value: 85000, unit: ppsUnit},
+			},
+		},
+		{
+			name:    "iperf2_empty",
+			engine:  iperf2Engine,
+			mode:    tcpStreamMode,
+			output:  "",
+			wantErr: true,
+		},
+		{
+			name:    "iperf2_udp_without_datagrams",
+			engine:  iperf2Engine,
+			mode:    udpStreamMode,
+			output:  "[SUM] 0.0-30.0 sec 3.50 GBytes 1.00 Gbits/sec\n",
+			wantErr: true,
+		},
+		{
+			name:   "iperf3_tcp",
+			engine: iperf3Engine,
+			mode:   tcpStreamMode,
+			output: iperf3TCPOutput,
+			want: []benchmarkMetric{
+				{name: "throughput", value: 9, unit: gbpsUnit},
+				{name: "retransmits", value: 12, unit: retransmitsUnit},
+			},
+		},
+		{
+			name:   "iperf3_udp",
+			engine: iperf3Engine,
+			mode:   udpStreamMode,
+			output: iperf3UDPOutput,
+			want: []benchmarkMetric{
+				{name: "throughput", value: 1, unit: gbpsUnit},
+				{name: "packets_per_second", value: 80000, unit: ppsUnit},
+			},
+		},
+		{
+			name:    "iperf3_error",
+			engine:  iperf3Engine,
+			mode:    tcpStreamMode,
+			output:  `{"error": "unable to connect to server: Connection refused"}`,
+			wantErr: true,
+		},
+		{
+			name:    "iperf3_not_json",
+			engine:  iperf3Engine,
+			mode:    tcpStreamMode,
+			output:  "iperf3: error - unable to connect to server",
+			wantErr: true,
+		},
+		{
+			name:   "netperf_tcp_stream",
+			engine: netperfEngine,
+			mode:   tcpStreamMode,
+			output: netperfTCPStreamOutput,
+			want: []benchmarkMetric{
+				{name: "throughput", value: 9, unit: gbpsUnit},
+				{name: "retransmits", value: 8, unit: retransmitsUnit},
+			},
+		},
+		{
+			name:   "netperf_tcp_stream_without_retransmits",
+			engine: netperfEngine,
+			mode:   tcpStreamMode,
+			output: "THROUGHPUT=9.5\nTHROUGHPUT_UNITS=10^9bits/s\nLOCAL_TRANSPORT_RETRANS=-1\n",
+			want:   []benchmarkMetric{{name: "throughput", value: 9.5, unit: gbpsUnit}},
+		},
+		{
+			name:   "netperf_udp_stream",
+			engine: netperfEngine,
+			mode:   udpStreamMode,
+			output: netperfUDPStreamOutput,
+			want: []benchmarkMetric{
+				{name: "throughput", value: 0.95, unit: gbpsUnit},
+				{name: "packets_per_second", value: 80000, unit: ppsUnit},
+			},
+		},
+		{
+			name:   "netperf_tcp_rr",
+			engine: netperfEngine,
+			mode:   tcpRRMode,
+			output: netperfTCPRROutput,
+			want:   []benchmarkMetric{{name: "latency", value: 40, unit: microsecondUnit}},
+		},
+		{
+			name:    "netperf_unknown_unit",
+			engine:  netperfEngine,
+			mode:    tcpStreamMode,
+			output:  "THROUGHPUT=1000\nTHROUGHPUT_UNITS=10^6bytes/s\n",
+			wantErr: true,
+		},
+		{
+			name:    "netperf_empty",
+			engine:  netperfEngine,
+			mode:    tcpRRMode,
+			output:  "netperf: send_omni: connect_data_socket failed: No route to host\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseBenchmarkResult(tc.engine, tc.mode, tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseBenchmarkResult() = %v, want error: %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(benchmarkMetric{}), cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("parseBenchmarkResult() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateBenchmark(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  benchmarkEngine
+		modes   []benchmarkMode
+		wantErr bool
+	}{
+		{name: "iperf2_tcp", engine: iperf2Engine, modes: []benchmarkMode{tcpStreamMode}},
+		{name: "netperf_all", engine: netperfEngine, modes: []benchmarkMode{tcpStreamMode, udpStreamMode, tcpRRMode}},
+		{name: "iperf3_rr", engine: iperf3Engine, modes: []benchmarkMode{tcpRRMode}, wantErr: true},
+		{name: "unknown_engine", engine: "ttcp", modes: []benchmarkMode{tcpStreamMode}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateBenchmark(tc.engine, tc.modes); (err != nil) != tc.wantErr {
+				t.Errorf("validateBenchmark(%s, %v) = %v, want error: %v", tc.engine, tc.modes, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBenchmarkModes(t *testing.T) {
+	tests := []struct {
+		modesStr string
+		want     []benchmarkMode
+		wantErr  bool
+	}{
+		{modesStr: "", want: []benchmarkMode{tcpStreamMode}},
+		{modesStr: "TCP_STREAM, TCP_RR", want: []benchmarkMode{tcpStreamMode, tcpRRMode}},
+		{modesStr: "UDP_STREAM", want: []benchmarkMode{udpStreamMode}},
+		{modesStr: "TCP_CRR", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseBenchmarkModes(tc.modesStr)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseBenchmarkModes(%q) = %v, want error: %v", tc.modesStr, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("parseBenchmarkModes(%q) returned unexpected diff (-want +got):\n%s", tc.modesStr, diff)
+		}
+	}
+}
+
+func TestResultsKey(t *testing.T) {
+	if got, want := resultsKey(tcpStreamMode, 1, 2), "results-tcp_stream-1-2"; got != want {
+		t.Errorf("resultsKey(%s, 1, 2) = %q, want %q", tcpStreamMode, got, want)
+	}
+}
@@ -18,17 +18,29 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/networkutils"
 )
 
 const (
 	driverPath = "/sys/class/net/%s/device/driver"
 )
 
+// nicDrivers are the Linux drivers of the network interfaces of each NIC type.
+var nicDrivers = map[string][]string{
+	networkutils.NICTypeGVNIC:     {"gvnic", "gve"},
+	networkutils.NICTypeVIRTIONET: {"virtio_net"},
+	networkutils.NICTypeIDPF:      {"idpf"},
+	networkutils.NICTypeIRDMA:     {"idpf"},
+	networkutils.NICTypeMRDMA:     {"mlx5_core"},
+}
+
 func CheckGVNICPresent(interfaceName string) error {
 	file := fmt.Sprintf(driverPath, interfaceName)
 	data, err := os.Readlink(file)
@@ -71,3 +83,28 @@ func TestGVNICExists(t *testing.T) {
 		t.Fatalf("Error: %v", errMsg.Error())
 	}
 }
+
+// TestNICDrivers checks that each interface has the driver of its NIC type.
+func TestNICDrivers(t *testing.T) {
+	utils.LinuxOnly(t)
+	ctx := utils.Context(t)
+	nicTypesStr, err := utils.GetMetadata(ctx, "instance", "attributes", "nic-types")
+	if err != nil {
+		t.Fatalf("Getting nic-types: %v", err)
+	}
+	for i, nicType := range strings.Split(nicTypesStr, ",") {
+		iface, err := utils.GetInterface(ctx, i)
+		if err != nil {
+			t.Errorf("Couldn't find interface %d: %v", i, err)
+			continue
+		}
+		data, err := os.Readlink(fmt.Sprintf(driverPath, iface.Name))
+		if err != nil {
+			t.Errorf("Couldn't get the driver of interface %d (%s): %v", i, iface.Name, err)
+			continue
+		}
+		if driver := filepath.Base(data); !slices.Contains(nicDrivers[nicType], driver) {
+			t.Errorf("Interface %d (%s) of type %s has driver %s, want one of %v", i, iface.Name, nicType, driver, nicDrivers[nicType])
+		}
+	}
+}
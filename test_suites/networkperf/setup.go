@@ -18,17 +18,19 @@ package networkperf
 
 import (
 	"embed"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/networkutils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
 	daisy "github.com/GoogleCloudPlatform/compute-daisy"
 	"google.golang.org/api/compute/v1"
 )
@@ -41,6 +43,10 @@ var (
 	useSpotInstances = flag.Bool("networkperf_use_spot_instances", false, "use spot instances for networkperf test cases")
 	networkTiers     = flag.String("networkperf_network_tiers", "", "comma separated list of network tiers to test (DEFAULT|TIER_1)")
 	nicTypes         = flag.String("networkperf_nic_types", "", "NIC types. Comma separated list of <NIC_TYPE>:<COUNT>. e.g. \"GVNIC:2\" or \"GVNIC:2,MRDMA:8\". If unspecified, defaults to a single GVNIC.")
+	engine           = flag.String("networkperf_engine", string(iperf2Engine), "network benchmark engine (iperf2|iperf3|netperf)")
+	modes            = flag.String("networkperf_modes", string(tcpStreamMode), "comma separated list of benchmark modes to run (TCP_STREAM|UDP_STREAM|TCP_RR). TCP_RR requires the netperf engine")
+	repeat           = flag.Int("networkperf_repeat", 1, "number of samples of each benchmark mode, run one after the other on the same VMs. Multiplies the workflow timeout")
+	statistic        = flag.String("networkperf_statistic", string(utils.SelectMedian), "sample the throughput targets apply to (worst|best|median): worst is the lowest throughput, best the highest")
 )
 
 type networkTier string
@@ -56,11 +62,13 @@ const (
 // networkPerfConfig is a collection of related test configuration data
 // which expands into individual networkPerfTest test cases.
 type networkPerfConfig struct {
-	machineType string        // Machine Type used for test
-	arch        string        // CPU architecture for the machine type.
-	networks    []networkTier // Network tiers to test.
-	zone        string        // (optional) zone required for machine type.
-	nicTypes    string        // (optional) NIC types for the machine. Defaults to a single GVNIC if unspecified. Follows the format of the flag --networkperf_nic_types.
+	machineType string          // Machine Type used for test
+	arch        string          // CPU architecture for the machine type.
+	networks    []networkTier   // Network tiers to test.
+	zone        string          // (optional) zone required for machine type.
+	nicTypes    string          // (optional) NIC types for the machine. Defaults to a single GVNIC if unspecified. Follows the format of the flag --networkperf_nic_types.
+	engine      benchmarkEngine // (optional) benchmark engine. Defaults to iperf2 if unspecified.
+	modes       []benchmarkMode // (optional) benchmark modes, run one after the other. Defaults to TCP_STREAM if unspecified.
 }
 
 // networkPerfTest is a single test case measuring network performance for
@@ -73,6 +81,8 @@ type networkPerfTest struct {
 	network     networkTier
 	mtu         int
 	nicTypes    []string
+	engine      benchmarkEngine
+	modes       []benchmarkMode
 }
 
 var defaultNetworkPerfTestConfigs = []networkPerfConfig{
@@ -146,12 +156,26 @@ var defaultNetworkPerfTestConfigs = []networkPerfConfig{
 func expandNetworkTestConfigs(configs []networkPerfConfig) ([]networkPerfTest, error) {
 	var tests []networkPerfTest
 	for _, config := range configs {
+		engine := config.engine
+		if engine == "" {
+			engine = iperf2Engine
+		}
+		modes := config.modes
+		if len(modes) == 0 {
+			modes = []benchmarkMode{tcpStreamMode}
+		}
+		if err := validateBenchmark(engine, modes); err != nil {
+			return nil, fmt.Errorf("config for %s: %w", config.machineType, err)
+		}
 		for _, mtu := range []int{imagetest.DefaultMTU, imagetest.JumboFramesMTU} {
 			for _, network := range config.networks {
 				nicTypes, err := networkutils.ExpandNICTypes(config.nicTypes)
 				if err != nil {
 					return nil, err
 				}
+				if err := validateNICTypes(nicTypes); err != nil {
+					return nil, fmt.Errorf("config for %s: %w", config.machineType, err)
+				}
 
 				test := networkPerfTest{
 					name:        fmt.Sprintf("%s_%d_%s", config.machineType, mtu, network),
@@ -161,6 +185,8 @@ func expandNetworkTestConfigs(configs []networkPerfConfig) ([]networkPerfTest, e
 					network:     network,
 					mtu:         mtu,
 					nicTypes:    nicTypes,
+					engine:      engine,
+					modes:       modes,
 				}
 				tests = append(tests, test)
 			}
@@ -191,9 +217,6 @@ func filterNetworkTestConfigs(
 //go:embed startupscripts/*
 var scripts embed.FS
 
-//go:embed targets/*
-var targets embed.FS
-
 const (
 	linuxInstallStartupScriptURI = "startupscripts/linux_common.sh"
 	linuxServerStartupScriptURI  = "startupscripts/linux_serverstartup.sh"
@@ -203,58 +226,87 @@ const (
 	windowsServerStartupScriptURI  = "startupscripts/windows_serverstartup.ps1"
 	windowsClientStartupScriptURI  = "startupscripts/windows_clientstartup.ps1"
 
-	targetsURL      = "targets/default_targets.txt"
-	tier1TargetsURL = "targets/tier1_targets.txt"
+	// benchmarkPorts are the ports of the benchmark servers, from 5001 for the
+	// first interface, and of the data connections of netperf, from 5101.
+	benchmarkPorts = "5001-6999"
 )
 
-// getExpectedPerf gets the expected performance of the given machine type. Since the targets map only contains breakpoints in vCPUs at which
-// each machine type's expected performance changes, find the highest breakpoint at which the expected performance would change, then return
-// the performance at said breakpoint.
-func getExpectedPerf(targetMap map[string]int, machineType *compute.MachineType) (int, error) {
-	// Return if already at breakpoint.
-	perf, found := targetMap[machineType.Name]
-	if found {
-		return perf, nil
+// validateNICTypes checks that networkperf supports the NIC types. The first NIC
+// must not be an RDMA NIC, since the VMs need its external access to install
+// the benchmark engine.
+func validateNICTypes(nicTypes []string) error {
+	for _, nicType := range nicTypes {
+		switch nicType {
+		case networkutils.NICTypeGVNIC, networkutils.NICTypeVIRTIONET, networkutils.NICTypeIDPF, networkutils.NICTypeIRDMA, networkutils.NICTypeMRDMA:
+		default:
+			return fmt.Errorf("unsupported NIC type: %q", nicType)
+		}
+	}
+	if networkutils.IsRDMANIC(nicTypes[0]) {
+		return fmt.Errorf("the first NIC must not be an RDMA NIC, got %s", nicTypes[0])
 	}
+	return nil
+}
 
-	numCPUs := machineType.GuestCpus
+// nicFeatures are the guest OS features images need for NIC types.
+var nicFeatures = map[string]string{
+	networkutils.NICTypeGVNIC: "GVNIC",
+	networkutils.NICTypeIDPF:  "IDPF",
+}
 
-	// Decrement numCPUs until a breakpoint is found.
-	for !found {
-		numCPUs--
-		perf, found = targetMap[regexp.MustCompile("-[0-9]+$").ReplaceAllString(machineType.Name, fmt.Sprintf("-%d", numCPUs))]
-		if !found && numCPUs <= 1 {
-			return 0, fmt.Errorf("Error: appropriate perf target not found for %v", machineType)
+// missingNICFeature returns the guest OS feature of the NIC types the image
+// lacks, or "" if it supports all of them.
+func missingNICFeature(image *compute.Image, nicTypes []string) string {
+	for _, nicType := range nicTypes {
+		if feature, ok := nicFeatures[nicType]; ok && !utils.HasFeature(image, feature) {
+			return feature
 		}
 	}
-	return perf, nil
+	return ""
 }
 
-func perfTarget(machineType *compute.MachineType, networkTier networkTier) (int, error) {
-	var targetsFile string
+// perfTarget gets the expected throughput of an interface of the NIC type on
+// the machine type and network tier from the performance targets.
+func perfTarget(machineType *compute.MachineType, networkTier networkTier, nicType string) (*perftargets.Target, error) {
 	switch networkTier {
-	case defaultTier:
-		targetsFile = targetsURL
-	case tier1Tier:
-		targetsFile = tier1TargetsURL
+	case defaultTier, tier1Tier:
 	default:
-		return 0, fmt.Errorf("unknown network tier: %q", networkTier)
+		return nil, fmt.Errorf("unknown network tier: %q", networkTier)
 	}
+	return perftargets.Lookup(perftargets.Query{
+		MachineType: machineType.Name,
+		VCPUs:       machineType.GuestCpus,
+		Tier:        string(networkTier),
+		NICType:     nicType,
+		Metric:      "throughput",
+	})
+}
 
-	var perfTargets map[string]int
-	perfTargetsString, err := targets.ReadFile(targetsFile)
-	if err != nil {
-		return 0, err
-	}
-	if err := json.Unmarshal(perfTargetsString, &perfTargets); err != nil {
-		return 0, err
+// throughputTargets returns the minimum TCP throughput of each interface, and
+// of all the interfaces together. RDMA NICs have their own bandwidth and are
+// checked on their own. The other NICs share the egress bandwidth of the VM, so
+// that several of them are only checked together, with a 0 minimum each.
+func throughputTargets(nicTypes []string, lookup func(nicType string) (*perftargets.Target, error)) ([]float64, float64, error) {
+	minimums := make([]float64, len(nicTypes))
+	var aggregate, shared float64
+	var sharedIfaces []int
+	for i, nicType := range nicTypes {
+		target, err := lookup(nicType)
+		if err != nil {
+			return nil, 0, err
+		}
+		if networkutils.IsRDMANIC(nicType) {
+			minimums[i] = target.Minimum()
+			aggregate += target.Minimum()
+			continue
+		}
+		shared = max(shared, target.Minimum())
+		sharedIfaces = append(sharedIfaces, i)
 	}
-	perfTarget, err := getExpectedPerf(perfTargets, machineType)
-	if err != nil {
-		return 0, fmt.Errorf("could not get default perf target: %v", err)
+	if len(sharedIfaces) == 1 {
+		minimums[sharedIfaces[0]] = shared
 	}
-
-	return perfTarget, nil
+	return minimums, aggregate + shared, nil
 }
 
 func startupScripts(image *compute.Image) (string, string, error) {
@@ -300,42 +352,62 @@ func sanitizeResourceName(name string) string {
 }
 
 type createNetworkOptions struct {
-	namePrefix string
-	region     string
-	mtu        int
-	nicTypes   []string
+	namePrefix  string
+	project     string
+	zone        string
+	region      string
+	machineType string
+	mtu         int
+	nicTypes    []string
 }
 
 func createNetworks(t *imagetest.TestWorkflow, opts *createNetworkOptions) ([]*networkConfig, error) {
 	var networkConfigs []*networkConfig
+	createdNetworks := make(map[string]*imagetest.Network)
 
 	for ifaceIndex, nicType := range opts.nicTypes {
-		if nicType != "GVNIC" {
-			return nil, fmt.Errorf("unsupported nic type: %q", nicType)
+		daisyNetwork, err := networkutils.DaisyNetworkForNIC(nicType, ifaceIndex, opts.project, opts.zone, imagetest.IsMetal(opts.machineType))
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(daisyNetwork.NetworkProfile, "-metal") {
+			return nil, fmt.Errorf("%s NICs of metal machine types are not supported", nicType)
 		}
+		// Networks are per test case, and NICs sharing a network, like MRDMA
+		// NICs, share it within the test case.
+		daisyNetwork.Name = opts.namePrefix + sanitizeResourceName(daisyNetwork.Name)
+		daisyNetwork.Mtu = int64(opts.mtu)
 
 		ifNameprefix := fmt.Sprintf("%s%d", opts.namePrefix, ifaceIndex)
 
-		network, err := t.CreateNetwork(ifNameprefix, false)
-		if err != nil {
-			return nil, err
+		network, ok := createdNetworks[daisyNetwork.Name]
+		if !ok {
+			network, err = t.CreateNetworkFromDaisyNetwork(daisyNetwork)
+			if err != nil {
+				return nil, err
+			}
+			createdNetworks[daisyNetwork.Name] = network
 		}
 		subnetwork, err := network.CreateSubnetwork(ifNameprefix, networkPrefix(ifaceIndex))
 		if err != nil {
 			return nil, err
 		}
 		subnetwork.SetRegion(opts.region)
-		if err := network.CreateFirewallRule("allow-iperf-"+ifNameprefix, "tcp", []string{"5001-5010"}, []string{networkPrefix(ifaceIndex)}); err != nil {
-			return nil, err
-		}
-		if opts.mtu == imagetest.JumboFramesMTU {
-			network.SetMTU(imagetest.JumboFramesMTU)
+		// The networks of RDMA network profiles don't support firewall rules,
+		// and allow the traffic between their interfaces.
+		if !networkutils.IsRDMANIC(nicType) {
+			if err := network.CreateFirewallRule("allow-iperf-"+ifNameprefix, "tcp", []string{benchmarkPorts}, []string{networkPrefix(ifaceIndex)}); err != nil {
+				return nil, err
+			}
+			if err := network.CreateFirewallRule("allow-iperf-udp-"+ifNameprefix, "udp", []string{benchmarkPorts}, []string{networkPrefix(ifaceIndex)}); err != nil {
+				return nil, err
+			}
 		}
 
 		networkConfigs = append(networkConfigs, &networkConfig{
-			network:    network,
-			subnetwork: subnetwork,
-			nicType:    nicType,
+			networkName:    daisyNetwork.Name,
+			subnetworkName: ifNameprefix,
+			nicType:        nicType,
 		})
 	}
 
@@ -343,9 +415,9 @@ func createNetworks(t *imagetest.TestWorkflow, opts *createNetworkOptions) ([]*n
 }
 
 type networkConfig struct {
-	network    *imagetest.Network
-	subnetwork *imagetest.Subnetwork
-	nicType    string
+	networkName    string
+	subnetworkName string
+	nicType        string
 }
 
 func createMachine(
@@ -372,13 +444,8 @@ func createMachine(
 			Preemptible: true,
 		}
 	}
-
-	vm, err := t.CreateTestVMMultipleDisks([]*compute.Disk{&disk}, instance)
-	if err != nil {
-		return nil, fmt.Errorf("creating machine: %w", err)
-	}
-	vm.ForceMachineType(machineType)
-	vm.ForceZone(zone)
+	// The interfaces are set on the instance rather than with AddCustomNetwork,
+	// since several of them may be on the same network.
 	for ifaceIndex, networkConfig := range networkConfigs {
 		var address string
 		if machinePrefix == "client" {
@@ -389,14 +456,23 @@ func createMachine(
 			return nil, fmt.Errorf("unknown machine prefix: %q", machinePrefix)
 		}
 
-		if err := vm.AddCustomNetwork(networkConfig.network, networkConfig.subnetwork); err != nil {
-			return nil, fmt.Errorf("adding network: %w", err)
-		}
-		if err := vm.SetPrivateIP(networkConfig.network, address); err != nil {
-			return nil, fmt.Errorf("setting private IP: %w", err)
-		}
+		instance.NetworkInterfaces = append(instance.NetworkInterfaces, &compute.NetworkInterface{
+			NicType:       networkConfig.nicType,
+			Network:       networkConfig.networkName,
+			Subnetwork:    networkConfig.subnetworkName,
+			StackType:     "IPV4_ONLY",
+			NetworkIP:     address,
+			AccessConfigs: networkutils.AccessConfigsForNIC(networkConfig.nicType),
+		})
 	}
 
+	vm, err := t.CreateTestVMMultipleDisks([]*compute.Disk{&disk}, instance)
+	if err != nil {
+		return nil, fmt.Errorf("creating machine: %w", err)
+	}
+	vm.ForceMachineType(machineType)
+	vm.ForceZone(zone)
+
 	return vm, nil
 }
 
@@ -449,11 +525,17 @@ func testConfigs(t *imagetest.TestWorkflow) ([]networkPerfTest, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing network tiers: %w", err)
 	}
+	benchmarkModes, err := parseBenchmarkModes(*modes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing benchmark modes: %w", err)
+	}
 	networkPerfTestConfig := networkPerfConfig{
 		machineType: t.MachineType.Name,
 		zone:        t.Zone.Name,
 		networks:    networkTiers,
 		nicTypes:    *nicTypes,
+		engine:      benchmarkEngine(*engine),
+		modes:       benchmarkModes,
 	}
 	networkPerfTests, err := expandNetworkTestConfigs([]networkPerfConfig{networkPerfTestConfig})
 	if err != nil {
@@ -462,41 +544,134 @@ func testConfigs(t *imagetest.TestWorkflow) ([]networkPerfTest, error) {
 	return networkPerfTests, nil
 }
 
-// TestSetup sets up the test workflow.
-func TestSetup(t *imagetest.TestWorkflow) error {
-	if !utils.HasFeature(t.Image, "GVNIC") {
-		t.Skip(fmt.Sprintf("%q does not support GVNIC", t.Image.Name))
+// unsupportedFlags returns why the image does not support the benchmark
+// engine, modes or NIC types of the flags, or an empty string. The default
+// test cases only use the TCP_STREAM mode of iperf2, which all images support.
+// Invalid flags are reported by TestSetup.
+func unsupportedFlags(image *compute.Image) string {
+	if *testFilter != "" {
+		return ""
+	}
+	benchmarkModes, err := parseBenchmarkModes(*modes)
+	if err != nil {
+		return ""
 	}
+	nics, err := networkutils.ExpandNICTypes(*nicTypes)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case utils.HasFeature(image, "WINDOWS"):
+		if benchmarkEngine(*engine) != iperf2Engine || !slices.Equal(benchmarkModes, []benchmarkMode{tcpStreamMode}) {
+			return "only the TCP_STREAM mode of iperf2 is supported on Windows"
+		}
+		if slices.ContainsFunc(nics, networkutils.IsRDMANIC) {
+			return "RDMA NICs are not supported on Windows"
+		}
+	case utils.GuestOSFromImage(image).Distro == utils.DistroCOS:
+		// The startup scripts can only install a static iperf2 build on COS.
+		if benchmarkEngine(*engine) != iperf2Engine {
+			return fmt.Sprintf("only the iperf2 engine is supported on COS, not %s", *engine)
+		}
+	}
+	return ""
+}
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests network performance against expected bandwidth.",
+		SkipImage:   unsupportedFlags,
+	})
+}
 
+// TestSetup sets up the test workflow.
+func TestSetup(t *imagetest.TestWorkflow) error {
 	networkPerfTests, err := testConfigs(t)
 	if err != nil {
 		return fmt.Errorf("getting test configs: %w", err)
 	}
+	var supported []networkPerfTest
+	var missingFeature string
+	for _, tc := range networkPerfTests {
+		if feature := missingNICFeature(t.Image, tc.nicTypes); feature != "" {
+			missingFeature = feature
+			continue
+		}
+		supported = append(supported, tc)
+	}
+	if len(supported) == 0 && missingFeature != "" {
+		t.Skip(fmt.Sprintf("%q does not support %s", t.Image.Name, missingFeature))
+		return nil
+	}
+	networkPerfTests = supported
+	if *repeat < 1 {
+		return fmt.Errorf("invalid -networkperf_repeat %d, want at least 1", *repeat)
+	}
+	// The samples are taken one after the other on the same VMs.
+	if err := t.ScaleTimeout(*repeat); err != nil {
+		return err
+	}
+	if _, err := utils.ParseSampleSelection(*statistic); err != nil {
+		return fmt.Errorf("parsing -networkperf_statistic: %w", err)
+	}
 
+	// Resolve the targets of all the test cases before creating any resource,
+	// so that machine types without a target fail the setup.
+	type testCase struct {
+		networkPerfTest
+		zone, region string
+		machine      *compute.MachineType
+		// throughputs are the minimum TCP throughput of each interface, and
+		// aggregate the one of all of them.
+		throughputs []float64
+		aggregate   float64
+	}
+	var testCases []testCase
+	var targetErrs []error
 	for _, tc := range networkPerfTests {
-		var region string
-		var zone string
+		c := testCase{networkPerfTest: tc}
 		if tc.zone == "" {
-			zone = t.Zone.Name
-			region = path.Base(t.Zone.Region)
+			c.zone = t.Zone.Name
+			c.region = path.Base(t.Zone.Region)
 		} else {
 			z, err := t.Client.GetZone(t.Project.Name, tc.zone)
 			if err != nil {
 				return err
 			}
-			zone = z.Name
-			region = path.Base(z.Region)
+			c.zone = z.Name
+			c.region = path.Base(z.Region)
 		}
-		machine, err := t.Client.GetMachineType(t.Project.Name, zone, tc.machineType)
+		c.machine, err = t.Client.GetMachineType(t.Project.Name, c.zone, tc.machineType)
 		if err != nil {
 			return err
 		}
+		// Only the TCP throughput has a target, other modes only record
+		// their metrics.
+		if slices.Contains(tc.modes, tcpStreamMode) {
+			c.throughputs, c.aggregate, err = throughputTargets(tc.nicTypes, func(nicType string) (*perftargets.Target, error) {
+				return perfTarget(c.machine, tc.network, nicType)
+			})
+			if err != nil {
+				targetErrs = append(targetErrs, fmt.Errorf("test case %s: %w", tc.name, err))
+				continue
+			}
+		}
+		testCases = append(testCases, c)
+	}
+	if err := errors.Join(targetErrs...); err != nil {
+		return fmt.Errorf("getting perf targets: %w", err)
+	}
 
+	for _, tc := range testCases {
+		zone, region := tc.zone, tc.region
 		networkOpts := &createNetworkOptions{
-			namePrefix: sanitizeResourceName(tc.name),
-			region:     region,
-			mtu:        tc.mtu,
-			nicTypes:   tc.nicTypes,
+			namePrefix:  sanitizeResourceName(tc.name),
+			project:     t.Project.Name,
+			zone:        zone,
+			region:      region,
+			machineType: tc.machineType,
+			mtu:         tc.mtu,
+			nicTypes:    tc.nicTypes,
 		}
 		networkConfigs, err := createNetworks(t, networkOpts)
 		if err != nil {
@@ -539,25 +714,40 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 			clientVM.SetStartupScript(clientStartup)
 		}
 
-		perfTarget, err := perfTarget(machine, tc.network)
-		if err != nil {
-			return fmt.Errorf("getting perf target: %w", err)
-		}
-
 		clientVM.AddMetadata("enable-guest-attributes", "TRUE")
 		clientVM.AddMetadata("num-parallel-tests", fmt.Sprint(len(networkConfigs)))
 		serverVM.AddMetadata("num-parallel-tests", fmt.Sprint(len(networkConfigs)))
 		for i := range networkConfigs {
 			clientVM.AddMetadata("iperftarget-"+fmt.Sprint(i), serverAddress(i))
 		}
-		clientVM.AddMetadata("expectedperf", fmt.Sprint(perfTarget))
+		if tc.throughputs != nil {
+			for i, throughput := range tc.throughputs {
+				clientVM.AddMetadata("expectedperf-"+fmt.Sprint(i), fmt.Sprint(throughput))
+			}
+			clientVM.AddMetadata("expectedperf-aggregate", fmt.Sprint(tc.aggregate))
+		}
+		var modeNames []string
+		for _, mode := range tc.modes {
+			modeNames = append(modeNames, string(mode))
+		}
+		for _, vm := range []*imagetest.TestVM{serverVM, clientVM} {
+			vm.AddMetadata("benchmark-engine", string(tc.engine))
+			vm.AddMetadata("benchmark-modes", strings.Join(modeNames, ","))
+			vm.AddMetadata("benchmark-repeat", fmt.Sprint(*repeat))
+		}
+		clientVM.AddMetadata("benchmark-statistic", *statistic)
 		clientVM.AddMetadata("network-tier", string(tc.network))
 
-		clientVM.UseGVNIC()
-		serverVM.UseGVNIC()
+		for _, vm := range []*imagetest.TestVM{serverVM, clientVM} {
+			vm.AddMetadata("nic-types", strings.Join(tc.nicTypes, ","))
+		}
 
-		serverVM.RunTests("TestGVNICExists")
-		clientVM.RunTests("TestGVNICExists|TestNetworkPerformance")
+		serverTests := "TestNICDrivers"
+		if tc.nicTypes[0] == networkutils.NICTypeGVNIC {
+			serverTests += "|TestGVNICExists"
+		}
+		serverVM.RunTests(serverTests)
+		clientVM.RunTests(serverTests + "|TestNetworkPerformance")
 	}
 	return nil
 }
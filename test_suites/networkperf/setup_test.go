@@ -1,9 +1,12 @@
 package networkperf
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/compute/v1"
 )
 
 func TestNetworkAddressHelpers(t *testing.T) {
@@ -104,3 +107,112 @@ func TestParseNetworkTiers(t *testing.T) {
 		})
 	}
 }
+
+func TestThroughputTargets(t *testing.T) {
+	targets := map[string]*perftargets.Target{
+		"GVNIC":      {Target: 100, Tolerance: 0.5},
+		"VIRTIO_NET": {Target: 40, Tolerance: 0.5},
+		"MRDMA":      {Target: 400, Tolerance: 0.5},
+	}
+	lookup := func(nicType string) (*perftargets.Target, error) {
+		if target, ok := targets[nicType]; ok {
+			return target, nil
+		}
+		return nil, fmt.Errorf("no target for %s", nicType)
+	}
+	tests := []struct {
+		name          string
+		nicTypes      []string
+		wantMinimums  []float64
+		wantAggregate float64
+		wantErr       bool
+	}{
+		{
+			name:          "single_gvnic",
+			nicTypes:      []string{"GVNIC"},
+			wantMinimums:  []float64{50},
+			wantAggregate: 50,
+		},
+		{
+			name:          "shared_bandwidth",
+			nicTypes:      []string{"GVNIC", "VIRTIO_NET"},
+			wantMinimums:  []float64{0, 0},
+			wantAggregate: 50,
+		},
+		{
+			name:          "rdma",
+			nicTypes:      []string{"GVNIC", "MRDMA", "MRDMA"},
+			wantMinimums:  []float64{50, 200, 200},
+			wantAggregate: 450,
+		},
+		{
+			name:     "missing_target",
+			nicTypes: []string{"GVNIC", "IRDMA"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			minimums, aggregate, err := throughputTargets(tc.nicTypes, lookup)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("throughputTargets(%v) = %v, want error: %v", tc.nicTypes, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantMinimums, minimums); diff != "" {
+				t.Errorf("throughputTargets(%v) returned unexpected minimums diff (-want +got):\n%s", tc.nicTypes, diff)
+			}
+			if aggregate != tc.wantAggregate {
+				t.Errorf("throughputTargets(%v) aggregate = %g, want %g", tc.nicTypes, aggregate, tc.wantAggregate)
+			}
+		})
+	}
+}
+
+func TestValidateNICTypes(t *testing.T) {
+	tests := []struct {
+		nicTypes []string
+		wantErr  bool
+	}{
+		{nicTypes: []string{"GVNIC"}},
+		{nicTypes: []string{"GVNIC", "MRDMA", "MRDMA"}},
+		{nicTypes: []string{"IDPF", "IRDMA"}},
+		{nicTypes: []string{"VIRTIO_NET", "GVNIC"}},
+		{nicTypes: []string{"MRDMA", "GVNIC"}, wantErr: true},
+		{nicTypes: []string{"GVNIC", "E1000"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		if err := validateNICTypes(tc.nicTypes); (err != nil) != tc.wantErr {
+			t.Errorf("validateNICTypes(%v) = %v, want error: %v", tc.nicTypes, err, tc.wantErr)
+		}
+	}
+}
+
+func TestUnsupportedFlags(t *testing.T) {
+	windows := &compute.Image{Name: "windows-server-2022-dc-v20260101", GuestOsFeatures: []*compute.GuestOsFeature{{Type: "WINDOWS"}}}
+	cos := &compute.Image{Name: "cos-117-18613-0-79"}
+	debian := &compute.Image{Name: "debian-12-v20260101"}
+	tests := []struct {
+		name     string
+		image    *compute.Image
+		engine   string
+		modes    string
+		nicTypes string
+		wantSkip bool
+	}{
+		{name: "windows iperf2", image: windows, engine: "iperf2", modes: "TCP_STREAM"},
+		{name: "windows netperf", image: windows, engine: "netperf", modes: "TCP_STREAM", wantSkip: true},
+		{name: "windows udp", image: windows, engine: "iperf2", modes: "TCP_STREAM,UDP_STREAM", wantSkip: true},
+		{name: "windows rdma", image: windows, engine: "iperf2", modes: "TCP_STREAM", nicTypes: "GVNIC:1,MRDMA:1", wantSkip: true},
+		{name: "cos iperf2", image: cos, engine: "iperf2", modes: "TCP_STREAM,UDP_STREAM"},
+		{name: "cos iperf3", image: cos, engine: "iperf3", modes: "TCP_STREAM", wantSkip: true},
+		{name: "debian netperf", image: debian, engine: "netperf", modes: "TCP_RR"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func(e, m, n string) { *engine, *modes, *nicTypes = e, m, n }(*engine, *modes, *nicTypes)
+			*engine, *modes, *nicTypes = tc.engine, tc.modes, tc.nicTypes
+			if reason := unsupportedFlags(tc.image); (reason != "") != tc.wantSkip {
+				t.Errorf("unsupportedFlags(%s) = %q, want skip: %v", tc.image.Name, reason, tc.wantSkip)
+			}
+		})
+	}
+}
@@ -12,8 +12,10 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
-# This script installs iperf on a VM and attempts to connect to an iperf
-# server to test the network bandwidth between the two VMs.
+# This script installs the benchmark engine on a VM and attempts to connect to
+# the server to test the network performance between the two VMs, in each of
+# the benchmark modes. Each mode is run repeat times, and the output of each
+# sample is uploaded to the results-<mode>-<interface>-<sample> guest attribute.
 
 hostname=$(curl http://metadata.google.internal/computeMetadata/v1/instance/hostname -H "Metadata-Flavor: Google" | cut -d"." -f1)
 numtests=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/num-parallel-tests -H "Metadata-Flavor: Google")
@@ -21,7 +23,44 @@ sleepduration=5
 timeout=0
 
 function outfile_name() {
-  echo "$hostname-$1.txt"
+  echo "$hostname-$1-$2-$3.txt"
+}
+
+# run_benchmark runs the client of the engine in a mode against a target and
+# port, writing its output to stdout.
+function run_benchmark() {
+  local mode=$1 target=$2 port=$3 i=$4
+  case "$engine:$mode" in
+    iperf2:UDP_STREAM)
+      iperf -t $duration -c "$target" -u -b 10G -P $parallelcount -p "$port" 2>&1
+      ;;
+    iperf3:TCP_STREAM)
+      iperf3 -J -i 0 -t $duration -c "$target" -P $parallelcount -p "$port" 2>&1
+      ;;
+    iperf3:UDP_STREAM)
+      iperf3 -J -i 0 -t $duration -c "$target" -u -b 0 -P $parallelcount -p "$port" 2>&1
+      ;;
+    netperf:*)
+      # Each netperf instance runs a single stream, run parallelcount of them
+      # for the stream modes. The data ports must be distinct and allowed by
+      # the firewall.
+      local count=$parallelcount
+      if [[ "$mode" == "TCP_RR" ]]; then
+        count=1
+      fi
+      for k in $(seq 0 $((count-1))); do
+        netperf -H "$target" -p "$port" -P 0 -t "$mode" -l $duration -- \
+          -k THROUGHPUT,THROUGHPUT_UNITS,MEAN_LATENCY,LOCAL_TRANSPORT_RETRANS,REMOTE_RECV_CALLS,ELAPSED_TIME \
+          -P ,$((5101+i*100+k)) > "/tmp/netperf-$i-$k.txt" 2>&1 &
+      done
+      wait
+      cat /tmp/netperf-$i-*.txt
+      rm -f /tmp/netperf-$i-*.txt
+      ;;
+    *)
+      iperf -t $duration -c "$target" -P $parallelcount -p "$port" 2>&1
+      ;;
+  esac
 }
 
 # Ensure the server is up and running.
@@ -52,22 +91,30 @@ if [[ $timeout -ge $maxtimeout ]]; then
 fi
 sleep "$sleepduration"
 
-# Run iperf in parallel.
-for i in $(seq 0 $((numtests-1))); do
-  port=$((5001+i))
-  iperftarget=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/iperftarget-$i -H "Metadata-Flavor: Google")
+for mode in ${modes//,/ }; do
+  for sample in $(seq 0 $((repeat-1))); do
+    # Run the benchmark on each interface in parallel.
+    for i in $(seq 0 $((numtests-1))); do
+      port=$((5001+i))
+      iperftarget=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/iperftarget-$i -H "Metadata-Flavor: Google")
 
-  echo "$(date +"%Y-%m-%d %T"): Running iperf client with target $iperftarget"
-  (iperf -t 30 -c "$iperftarget" -P $parallelcount -p "$port" 2>&1 | tee "$(outfile_name $i)") &
-done
+      echo "$(date +"%Y-%m-%d %T"): Running $engine $mode client sample $sample with target $iperftarget"
+      (run_benchmark "$mode" "$iperftarget" "$port" "$i" | tee "$(outfile_name $mode $i $sample)") &
+    done
 
-wait
+    wait
 
-# Upload results.
-for i in $(seq 0 $((numtests-1))); do
-  results=$(cat "$(outfile_name $i)" | grep SUM | tr -s ' ' 2>&1)
-  for retry_backoff in $(seq 0 2); do
-    sleep $retry_backoff
-    curl -X PUT --data "$results" http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/testing/results-$i -H "Metadata-Flavor: Google" && break
+    # Upload results.
+    for i in $(seq 0 $((numtests-1))); do
+      if [[ "$engine" == "iperf2" ]]; then
+        results=$(cat "$(outfile_name $mode $i $sample)" | grep SUM | tr -s ' ' 2>&1)
+      else
+        results=$(cat "$(outfile_name $mode $i $sample)")
+      fi
+      for retry_backoff in $(seq 0 2); do
+        sleep $retry_backoff
+        curl -X PUT --data "$results" http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/testing/results-${mode,,}-$i-$sample -H "Metadata-Flavor: Google" && break
+      done
+    done
   done
 done
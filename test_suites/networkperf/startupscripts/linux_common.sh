@@ -15,12 +15,49 @@
 #!/bin/bash
 maxtimeout=300
 parallelcount=12
+duration=30
+
+# The benchmark engine (iperf2, iperf3 or netperf) and the modes to run
+# (TCP_STREAM, UDP_STREAM or TCP_RR).
+engine=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/benchmark-engine -H "Metadata-Flavor: Google")
+modes=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/benchmark-modes -H "Metadata-Flavor: Google")
+# The number of samples of each mode, run one after the other.
+repeat=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/benchmark-repeat -H "Metadata-Flavor: Google")
+if ! [[ "$repeat" =~ ^[1-9][0-9]*$ ]]; then
+  repeat=1
+fi
+case "$engine" in
+  iperf3) pkg=iperf3 ;;
+  netperf) pkg=netperf ;;
+  *) engine=iperf2; pkg=iperf ;;
+esac
+
+# netperf isn't packaged by every distribution, build it from source if it
+# couldn't be installed.
+function build_netperf() {
+  if command -v netperf > /dev/null 2>&1; then
+    return
+  fi
+  echo "$(date +"%Y-%m-%d %T"): Building netperf from source."
+  curl -L "https://github.com/HewlettPackard/netperf/archive/refs/tags/netperf-2.7.0.tar.gz" > /tmp/netperf.tar.gz
+  cd /tmp
+  tar -xvf netperf.tar.gz
+  cd netperf-netperf-2.7.0
+  # netperf 2.7.0 doesn't build with -fno-common, the default of recent compilers.
+  CFLAGS=-fcommon ./configure --build="$(uname -m)-unknown-linux-gnu"
+  make
+  sudo make install
+  cd /
+}
 
 if [[ -f /usr/bin/apt ]]; then
-  echo "$(date +"%Y-%m-%d %T"): apt found Installing iperf."
-  sudo apt update && sudo apt install -y iperf netcat-openbsd
+  echo "$(date +"%Y-%m-%d %T"): apt found Installing $pkg."
+  sudo apt update && sudo apt install -y "$pkg" netcat-openbsd
+  if [[ "$engine" == "netperf" ]]; then
+    sudo apt install -y gcc make
+  fi
 elif [[ -f /bin/dnf ]]; then
-  echo "$(date +"%Y-%m-%d %T"): dnf found Installing iperf."
+  echo "$(date +"%Y-%m-%d %T"): dnf found Installing $pkg."
   os=$(cat /etc/redhat-release)
   arch=$(uname -p)
   if [[ "$os" == *"release 9"* ]]; then
@@ -41,16 +78,25 @@ elif [[ -f /bin/dnf ]]; then
       sudo dnf -y install epel-release
     fi
   fi
-  sudo sudo dnf makecache && sudo dnf -y install iperf netcat
+  sudo sudo dnf makecache && sudo dnf -y install "$pkg" netcat
+  if [[ "$engine" == "netperf" ]]; then
+    sudo dnf -y install gcc make
+  fi
 elif [[ -f /bin/yum ]]; then
-  echo "$(date +"%Y-%m-%d %T"): yum found Installing iperf."
+  echo "$(date +"%Y-%m-%d %T"): yum found Installing $pkg."
   yum install https://dl.fedoraproject.org/pub/epel/epel-release-latest-7.noarch.rpm
-  sudo sudo yum makecache && sudo yum -y install iperf netcat
+  sudo sudo yum makecache && sudo yum -y install "$pkg" netcat
+  if [[ "$engine" == "netperf" ]]; then
+    sudo yum -y install gcc make
+  fi
 elif [[ -f /usr/bin/zypper ]]; then
-  echo "$(date +"%Y-%m-%d %T"): zypper found Installing iperf."
+  echo "$(date +"%Y-%m-%d %T"): zypper found Installing $pkg."
   arch=$(uname -p)
 
-  if [[ "$arch" = "aarch64" ]]; then
+  if [[ "$engine" != "iperf2" ]]; then
+    sudo zypper --no-gpg-checks refresh
+    sudo zypper --no-gpg-checks --non-interactive install "$pkg" || sudo zypper --non-interactive install gcc make
+  elif [[ "$arch" = "aarch64" ]]; then
     # For now, only SLES15 SP5 and OpenSUSE are ARM64.
     curl -L "https://sourceforge.net/projects/iperf2/files/iperf-2.1.9.tar.gz/download" > /tmp/iperf.tar.gz
     cd /tmp
@@ -67,6 +113,10 @@ elif [[ -f /usr/bin/zypper ]]; then
   fi
   parallelcount=4
 elif grep CHROMEOS /etc/lsb-release > /dev/null 2>&1; then
+  if [[ "$engine" != "iperf2" ]]; then
+    echo "COS detected. Only the iperf2 engine is supported, not $engine."
+    exit 1
+  fi
   echo "COS detected. Installing iperf."
   curl -L "https://sourceforge.net/projects/iperf2/files/iperf-2.1.9.tar.gz/download" > /tmp/iperf.tar.gz
   cd /run
@@ -86,5 +136,10 @@ elif grep CHROMEOS /etc/lsb-release > /dev/null 2>&1; then
   cp src/iperf /run/iperf
   export PATH="/run/iperf:$PATH"
 
-  iptables -A INPUT -p tcp -m tcp --dport 5001:5010 -j ACCEPT
+  iptables -A INPUT -p tcp -m tcp --dport 5001:6999 -j ACCEPT
+  iptables -A INPUT -p udp -m udp --dport 5001:6999 -j ACCEPT
+fi
+
+if [[ "$engine" == "netperf" ]]; then
+  build_netperf
 fi
@@ -12,12 +12,23 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
-# This script installs iperf on a VM and starts an iperf server for the client
-# to test the network bandwidth between the two VMs.
+# This script installs the benchmark engine on a VM and starts a server for the
+# client to test the network performance between the two VMs.
 
-echo "Starting iperf server"
+echo "Starting $engine server"
 numtests=$(curl http://metadata.google.internal/computeMetadata/v1/instance/attributes/num-parallel-tests -H "Metadata-Flavor: Google")
 for i in $(seq 0 $((numtests-1))); do
   port=$((5001+i))
-  iperf -s -P $parallelcount -p $port &
+  case "$engine" in
+    iperf3)
+      iperf3 -s -p $port -D
+      ;;
+    netperf)
+      netserver -p $port
+      ;;
+    *)
+      iperf -s -P $parallelcount -p $port &
+      iperf -s -u -p $port &
+      ;;
+  esac
 done
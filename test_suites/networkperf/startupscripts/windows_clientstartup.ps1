@@ -21,6 +21,14 @@ catch {
   Write-Host "Failed to get num-parallel-tests from metadata: $_"
   exit 1
 }
+# The number of samples of the benchmark, run one after the other.
+$repeat = 1
+try {
+  $repeat = [int](Invoke-RestMethod -Headers @{'Metadata-Flavor'='Google'} -Uri http://metadata.google.internal/computeMetadata/v1/instance/attributes/benchmark-repeat -UseBasicParsing)
+}
+catch {
+  Write-Host "Failed to get benchmark-repeat from metadata, running a single sample: $_"
+}
 $baseport = 5001
 $sleepduration = 5
 $conn_timeout_sec = 300
@@ -51,37 +59,40 @@ for ($i = 0; $i -lt $numtests; $i++) {
 
 Start-Sleep -s $sleepduration
 
-# Perform the test, and upload results.
-$jobs = @()
-for ($i = 0; $i -lt $numtests; $i++) {
-    $iperftarget = Invoke-RestMethod -Uri "http://metadata.google.internal/computeMetadata/v1/instance/attributes/iperftarget-$i" -Headers @{'Metadata-Flavor'='Google'} -ErrorAction Stop -UseBasicParsing
-    $port = $baseport + $i
-    $outfile="C:\iperf\$hostname-$i.txt"
-    Write-Host "$(Get-Date -Format 'yyyy-MM-dd HH:mm:ss'): Running iperf client $i with target $iperftarget`:$port"
-    $jobs += Start-Job -ScriptBlock {
-        & "$using:exepath\iperf.exe" -c $using:iperftarget -p $using:port -t 30 -P 16 *> $using:outfile
+# Perform the test, and upload results of each sample.
+for ($s = 0; $s -lt $repeat; $s++) {
+    $jobs = @()
+    for ($i = 0; $i -lt $numtests; $i++) {
+        $iperftarget = Invoke-RestMethod -Uri "http://metadata.google.internal/computeMetadata/v1/instance/attributes/iperftarget-$i" -Headers @{'Metadata-Flavor'='Google'} -ErrorAction Stop -UseBasicParsing
+        $port = $baseport + $i
+        $outfile="C:\iperf\$hostname-$i-$s.txt"
+        Write-Host "$(Get-Date -Format 'yyyy-MM-dd HH:mm:ss'): Running iperf client $i sample $s with target $iperftarget`:$port"
+        $jobs += Start-Job -ScriptBlock {
+            & "$using:exepath\iperf.exe" -c $using:iperftarget -p $using:port -t 30 -P 16 *> $using:outfile
+        }
     }
-}
 
-Write-Host 'Waiting for tests to complete...'
-Wait-Job -Job $jobs
-Get-Job | Receive-Job # Gets job output/errors if any.
-Write-Host 'All tests completed.'
+    Write-Host 'Waiting for tests to complete...'
+    Wait-Job -Job $jobs
+    Get-Job | Receive-Job # Gets job output/errors if any.
+    Get-Job | Remove-Job
+    Write-Host "All tests of sample $s completed."
 
-for ($i = 0; $i -lt $numtests; $i++) {
-    $outfile="C:\iperf\$hostname-$i.txt"
-    $metadata="http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/testing/results-$i"
-    Write-Host "Uploading results from $outfile to $metadata"
-    $results = (Get-Content -Path $outfile | Select-String -Pattern 'SUM' | Select-Object -Last 1) -replace '\s+',' '
-    for ($j = 0; $j -lt 3; $j++) {
-        Start-Sleep -Seconds $j
-        try {
-            $results | Invoke-RestMethod -Method 'Put' -Uri $metadata -Headers @{'Metadata-Flavor'='Google'} -ErrorAction Stop -UseBasicParsing
-            Write-Host "Successfully uploaded results for test $i"
-            break
-        }
-        catch {
-            Write-Host "Attempt $j failed to upload results for test $i -- $($PSItem.Exception.Message)"
+    for ($i = 0; $i -lt $numtests; $i++) {
+        $outfile="C:\iperf\$hostname-$i-$s.txt"
+        $metadata="http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/testing/results-tcp_stream-$i-$s"
+        Write-Host "Uploading results from $outfile to $metadata"
+        $results = (Get-Content -Path $outfile | Select-String -Pattern 'SUM' | Select-Object -Last 1) -replace '\s+',' '
+        for ($j = 0; $j -lt 3; $j++) {
+            Start-Sleep -Seconds $j
+            try {
+                $results | Invoke-RestMethod -Method 'Put' -Uri $metadata -Headers @{'Metadata-Flavor'='Google'} -ErrorAction Stop -UseBasicParsing
+                Write-Host "Successfully uploaded results for test $i sample $s"
+                break
+            }
+            catch {
+                Write-Host "Attempt $j failed to upload results for test $i sample $s -- $($PSItem.Exception.Message)"
+            }
         }
     }
 }
@@ -22,6 +22,6 @@ $exepath="C:\iperf\iperf-2.0.9-win64"
 [Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12
 Invoke-WebRequest -Uri $iperfurl -OutFile $iperfzippath
 Expand-Archive -Path $iperfzippath -DestinationPath $zipdir
-New-NetFirewallRule -DisplayName 'allow-iperf' -Direction Inbound -LocalPort 5001-5010 -Protocol TCP -Action Allow
+New-NetFirewallRule -DisplayName 'allow-iperf' -Direction Inbound -LocalPort 5001-6999 -Protocol TCP -Action Allow
 
 Set-Location $exepath
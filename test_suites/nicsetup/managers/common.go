@@ -48,6 +48,8 @@ var (
 	vmNameOnce sync.Once
 	// image is the image of the instance.
 	image string
+	// guestOS is the guest OS of the instance.
+	guestOS utils.GuestOS
 )
 
 // EthernetInterface represents an ethernet interface.
@@ -86,6 +88,10 @@ func init() {
 	if err != nil {
 		fmt.Printf("couldn't get image from metadata: %v\n", err)
 	}
+	guestOS, err = utils.GetGuestOS()
+	if err != nil {
+		fmt.Printf("couldn't get guest OS: %v\n", err)
+	}
 }
 
 // VerifyNIC verifies whether the configurations for the given NIC and
@@ -170,17 +176,15 @@ func SkipPrimaryRollback(t *testing.T, singleNIC bool) bool {
 	return singleNIC && exceptions.HasMatch(image, []exceptions.Exception{
 		exceptions.Exception{
 			Match: "debian-12(?:-arm64)?-guest-agent-stable.*",
-			Type:  exceptions.Equal,
 		},
 	})
 }
 
-// IsUbuntu2004 returns true if the image is Ubuntu 18.04.
+// IsUbuntu2004 returns true if the image is Ubuntu 20.04.
 func IsUbuntu2004(t *testing.T) bool {
 	t.Helper()
 	return exceptions.MatchAll(image, exceptions.ImageUbuntu, exceptions.Exception{
-		Version: 2004,
-		Type:    exceptions.Equal,
+		Versions: "20.04",
 	})
 }
 
@@ -188,8 +192,7 @@ func IsUbuntu2004(t *testing.T) bool {
 func IsUbuntu1804(t *testing.T) bool {
 	t.Helper()
 	return exceptions.MatchAll(image, exceptions.ImageUbuntu, exceptions.Exception{
-		Version: 1804,
-		Type:    exceptions.Equal,
+		Versions: "18.04",
 	})
 }
 
@@ -198,14 +201,12 @@ func shouldUseNetplan(t *testing.T) bool {
 	// Ubuntu 18.04 is a special case where netplan is present, but we don't use it.
 	return !exceptions.HasMatch(image, []exceptions.Exception{
 		exceptions.Exception{
-			Match:   exceptions.ImageUbuntu,
-			Version: 1804,
-			Type:    exceptions.Equal,
+			Match:    exceptions.ImageUbuntu,
+			Versions: "18.04",
 		},
 		exceptions.Exception{
-			Match:   exceptions.ImageUbuntu,
-			Version: 1604,
-			Type:    exceptions.Equal,
+			Match:    exceptions.ImageUbuntu,
+			Versions: "16.04",
 		},
 	})
 }
@@ -25,10 +25,6 @@ import (
 	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
-	daisy "github.com/GoogleCloudPlatform/compute-daisy"
-	"google.golang.org/api/compute/v1"
 )
 
 const (
@@ -47,63 +43,24 @@ var (
 
 	// possibleVMTypes is the list of possible VM types for the test.
 	possibleVMTypes = []string{"multi", "single", "both"}
-
-	// ipv6Exceptions are the list of images that do not support IPv6.
-	ipv6Exceptions = []exceptions.Exception{
-		exceptions.Exception{
-			Match:   exceptions.ImageSLES,
-			Version: 12,
-			Type:    exceptions.Equal,
-		},
-		exceptions.Exception{
-			Match:   exceptions.ImageUbuntu,
-			Version: 1604,
-			Type:    exceptions.Equal,
-		},
-		exceptions.Exception{
-			// TODO(b/440780139): rhel-9-0-sap-ha and rhel-8-6-sap-ha does not work
-			// with IPv6. Remove the exception if it is expected to work and bug is
-			// fixed.
-			Match: "rhel-(9-0|8-6)-sap(-byos|-ha)?",
-		},
-	}
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests NIC configurations with single and multiple NICs.",
+		OSFamilies:  []string{imagetest.OSFamilyLinux},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if utils.HasFeature(t.Image, "WINDOWS") {
-		return nil
-	}
-
-	if strings.Contains(t.Image.Name, "sles-16") {
-		t.Skip("b/511534265: Skipping nicsetup test for SLES 16 due to flaky tests.")
-		return nil
-	}
-
 	// Verify the VM type for the test.
 	if !slices.Contains(possibleVMTypes, *vmtype) {
 		return fmt.Errorf("invalid vmtype: %s\nMust be one of: %v", *vmtype, possibleVMTypes)
 	}
 
 	// Verify that the image supports IPv6.
-	supportsIpv6 := !exceptions.HasMatch(t.Image.Name, ipv6Exceptions)
-
-	// Create an primary network.
-	network1, err := t.CreateNetwork("network1", false)
-	if err != nil {
-		return err
-	}
-	subnetwork1, err := network1.CreateSubnetworkFromDaisySubnetwork(&daisy.Subnetwork{
-		Subnetwork: compute.Subnetwork{
-			Name:           "dual",
-			IpCidrRange:    "10.128.0.0/24",
-			StackType:      "IPV4_IPV6",
-			Ipv6AccessType: "EXTERNAL",
-		},
-	})
-	if err != nil {
-		return err
-	}
+	supportsIpv6 := t.SupportsIPv6()
 
 	// List of all VMs to create.
 	var allVMs []*imagetest.TestVM
@@ -118,7 +75,9 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		if err != nil {
 			return err
 		}
-		ipv4VM1.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_ONLY", "")
+		if err := ipv4VM1.AddNetworkProfile(imagetest.NetworkProfileIPv4Only); err != nil {
+			return err
+		}
 		allSingleVMs = append(allSingleVMs, ipv4VM1)
 
 		// Only create dual stack and IPv6 only VMs if the image supports IPv6.
@@ -131,8 +90,12 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 			if err != nil {
 				return err
 			}
-			dualstackVM1.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_IPV6", "")
-			ipv6VM1.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV6_ONLY", "EXTERNAL")
+			if err := dualstackVM1.AddNetworkProfile(imagetest.NetworkProfileDualStackExternal); err != nil {
+				return err
+			}
+			if err := ipv6VM1.AddNetworkProfile(imagetest.NetworkProfileIPv6Only); err != nil {
+				return err
+			}
 
 			allSingleVMs = append(allSingleVMs, dualstackVM1, ipv6VM1)
 		}
@@ -141,51 +104,25 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 
 	// The following are all multi NIC VMs.
 	if *vmtype != "single" {
-		// Create a second network. The subnetworks are internal IPv6 because
-		// secondary NICs don't have external connectivity by default. We specify
-		// AutoCreateSubnetworks explicitly to avoid creating legacy networks.
-		falseValue := false
-		network2, err := t.CreateNetworkFromDaisyNetwork(&daisy.Network{
-			Network: compute.Network{
-				Name:                  "network2",
-				EnableUlaInternalIpv6: true,
-			},
-			AutoCreateSubnetworks: &falseValue,
-		})
-		if err != nil {
-			return err
-		}
-		subnetwork2, err := network2.CreateSubnetworkFromDaisySubnetwork(&daisy.Subnetwork{
-			Subnetwork: compute.Subnetwork{
-				Name:           "dual-2",
-				IpCidrRange:    "10.0.0.0/24",
-				StackType:      "IPV4_IPV6",
-				Ipv6AccessType: "INTERNAL",
-			},
-		})
+		// The secondary NICs use the internal dual stack profile because secondary
+		// NICs don't have external connectivity by default.
+		internalProfile, err := t.NetworkProfile(imagetest.NetworkProfileDualStackInternal)
 		if err != nil {
 			return err
 		}
 
-		// Add firewall rules allowing TCP traffic.
-		if err := network2.CreateFirewallRule("allow-connection-ipv4", "tcp", nil, []string{"0.0.0.0/0"}); err != nil {
-			return err
-		}
-		if err := network2.CreateFirewallRule("allow-connection-ipv6", "tcp", nil, []string{"0::0/0"}); err != nil {
-			return err
-		}
-
 		// Create the ping VMs. There's one for each subnetwork.
 		pingVM, err := t.CreateTestVM("ping")
 		if err != nil {
 			return err
 		}
-		if supportsIpv6 {
-			pingVM.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_IPV6", "INTERNAL")
-		} else {
-			pingVM.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_ONLY", "")
+		// The ping VM falls back to IPv4 only if the image doesn't support IPv6.
+		if err := pingVM.AddNetworkProfile(imagetest.NetworkProfileDualStackInternal); err != nil {
+			return err
+		}
+		if err := pingVM.SetPrivateIP(internalProfile.Network(), pingVMIPv4); err != nil {
+			return err
 		}
-		pingVM.SetPrivateIP(network2, pingVMIPv4)
 		pingVM.AddScope("https://www.googleapis.com/auth/compute") // Compute scope is needed for setting metadata.
 		pingVM.AddMetadata(supportIpv6Key, strconv.FormatBool(supportsIpv6))
 		pingVM.RunTests("TestEmpty")
@@ -195,8 +132,9 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		if err != nil {
 			return err
 		}
-		ipv4ipv4.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_ONLY", "")
-		ipv4ipv4.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_ONLY", "")
+		if err := addNetworkProfiles(ipv4ipv4, imagetest.NetworkProfileIPv4Only, "IPV4_ONLY"); err != nil {
+			return err
+		}
 		allMultiVMs = append(allMultiVMs, ipv4ipv4)
 
 		if supportsIpv6 {
@@ -230,26 +168,27 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 			}
 
 			// Add networks to VMs. Primary NIC must be EXTERNAL IPv6 for tests to work.
-			ipv4dual.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_ONLY", "")
-			ipv4dual.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_IPV6", "INTERNAL")
-
-			ipv4ipv6.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_ONLY", "")
-			ipv4ipv6.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV6_ONLY", "INTERNAL")
-
-			dualipv4.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_IPV6", "")
-			dualipv4.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_ONLY", "INTERNAL")
-
-			dualdual.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_IPV6", "")
-			dualdual.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_IPV6", "INTERNAL")
-
-			dualipv6.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV4_IPV6", "")
-			dualipv6.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV6_ONLY", "INTERNAL")
-
-			ipv6ipv4.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV6_ONLY", "EXTERNAL")
-			ipv6ipv4.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_ONLY", "")
-
-			ipv6dual.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV6_ONLY", "EXTERNAL")
-			ipv6dual.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV4_IPV6", "INTERNAL")
+			if err := addNetworkProfiles(ipv4dual, imagetest.NetworkProfileIPv4Only, ""); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(ipv4ipv6, imagetest.NetworkProfileIPv4Only, "IPV6_ONLY"); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(dualipv4, imagetest.NetworkProfileDualStackExternal, "IPV4_ONLY"); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(dualdual, imagetest.NetworkProfileDualStackExternal, ""); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(dualipv6, imagetest.NetworkProfileDualStackExternal, "IPV6_ONLY"); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(ipv6ipv4, imagetest.NetworkProfileIPv6Only, "IPV4_ONLY"); err != nil {
+				return err
+			}
+			if err := addNetworkProfiles(ipv6dual, imagetest.NetworkProfileIPv6Only, ""); err != nil {
+				return err
+			}
 
 			allMultiVMs = append(allMultiVMs, ipv4dual, ipv4ipv6, dualipv4, dualdual, dualipv6, ipv6ipv4, ipv6dual)
 
@@ -260,8 +199,9 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 					return err
 				}
 
-				ipv6ipv6.AddCustomNetworkWithStackType(network1, subnetwork1, "IPV6_ONLY", "EXTERNAL")
-				ipv6ipv6.AddCustomNetworkWithStackType(network2, subnetwork2, "IPV6_ONLY", "INTERNAL")
+				if err := addNetworkProfiles(ipv6ipv6, imagetest.NetworkProfileIPv6Only, "IPV6_ONLY"); err != nil {
+					return err
+				}
 
 				allMultiVMs = append(allMultiVMs, ipv6ipv6)
 			}
@@ -278,3 +218,16 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	}
 	return nil
 }
+
+// addNetworkProfiles adds the network profile of the primary NIC and the
+// internal dual stack profile of the secondary NIC to the VM. The secondary
+// NIC has the stack type, or the stack type of the profile if it's empty.
+func addNetworkProfiles(vm *imagetest.TestVM, primary, secondaryStackType string) error {
+	if err := vm.AddNetworkProfile(primary); err != nil {
+		return err
+	}
+	if secondaryStackType == "" {
+		return vm.AddNetworkProfile(imagetest.NetworkProfileDualStackInternal)
+	}
+	return vm.AddNetworkProfileWithStackType(imagetest.NetworkProfileDualStackInternal, secondaryStackType)
+}
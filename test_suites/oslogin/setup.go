@@ -23,13 +23,11 @@ import (
 	"math/rand"
 	"os"
 	"regexp"
-	"strings"
 	"sync"
 
 	"cloud.google.com/go/secretmanager/apiv1"
 	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 	"google.golang.org/api/iterator"
 )
 
@@ -90,25 +88,15 @@ var (
 	}
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests OS Login.",
+		OSFamilies:  []string{imagetest.OSFamilyLinux},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if utils.HasFeature(t.Image, "WINDOWS") {
-		t.Skip("OSLogin not supported on windows")
-		return nil
-	}
-
-	// TODO(b/440641320): Remove this skip once the bug is fixed.
-	if strings.Contains(t.Image.Name, "rhel-9-0-sap") {
-		t.Skip("OSLogin is not working on rhel-9-0-sap images, skipping the test until b/440641320 is fixed.")
-		return nil
-	}
-
-	// TODO(b/468323433): Remove this skip once the bug is fixed.
-	if strings.Contains(t.Image.Name, "sles-16") || strings.Contains(t.Image.Name, "opensuse-leap-16") {
-		t.Skip(fmt.Sprintf("OSLogin is not working on sles-16 images, skipping the test on %q until b/468323433 is fixed.", t.Image.Name))
-		return nil
-	}
-
 	initialize2FA.Do(func() {
 		ctx := context.Background()
 		secretClient, err := secretmanager.NewClient(ctx)
@@ -16,72 +16,44 @@
 package packagemanager
 
 import (
-	"strings"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/compute-daisy"
-	"google.golang.org/api/compute/v1"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
 var Name = "packagemanager"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests the guest agent package manager."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	network1, err := t.CreateNetwork("network-1", false)
-	if err != nil {
-		return err
-	}
-	ipv4OnlySubnet := &daisy.Subnetwork{}
-	ipv4OnlySubnet.Name = "ipv4Only"
-	ipv4OnlySubnet.StackType = "IPV4_ONLY"
-	ipv4OnlySubnet.IpCidrRange = "10.128.0.0/24"
-	_, err = network1.CreateSubnetworkFromDaisySubnetwork(ipv4OnlySubnet)
-	if err != nil {
-		return err
-	}
-
-	dualStackSubnet := &daisy.Subnetwork{}
-	dualStackSubnet.Name = "dualStack"
-	dualStackSubnet.StackType = "IPV4_IPV6"
-	dualStackSubnet.IpCidrRange = "10.128.1.0/24"
-	dualStackSubnet.Ipv6AccessType = "EXTERNAL"
-	_, err = network1.CreateSubnetworkFromDaisySubnetwork(dualStackSubnet)
-	if err != nil {
-		return err
+	// Dual stack NICs fall back to IPv4-only on images without IPv6 support,
+	// which the ipv4only VM already tests.
+	if t.SupportsIPv6() {
+		dualStackVM, err := t.CreateTestVM("dualstack")
+		if err != nil {
+			return err
+		}
+		if err := dualStackVM.AddNetworkProfile(imagetest.NetworkProfileDualStackExternal); err != nil {
+			return err
+		}
+		dualStackVM.RunTests("TestRepoReachabilityDualStack")
 	}
 
-	dualStack := &daisy.Instance{}
-	dualStack.NetworkInterfaces = []*compute.NetworkInterface{
-		{
-			StackType:  "IPV4_IPV6",
-			Network:    "network-1",
-			Subnetwork: "dualStack",
-		},
-	}
-	dualStackVM, err := t.CreateTestVMMultipleDisks([]*compute.Disk{{Name: "dualstack"}}, dualStack)
+	ipv4OnlyVM, err := t.CreateTestVM("ipv4only")
 	if err != nil {
 		return err
 	}
-	dualStackVM.RunTests("TestRepoReachabilityDualStack")
-
-	ipv4Only := &daisy.Instance{}
-	ipv4Only.NetworkInterfaces = []*compute.NetworkInterface{
-		{
-			StackType:  "IPV4_ONLY",
-			Network:    "network-1",
-			Subnetwork: "ipv4Only",
-		},
-	}
-	ipv4OnlyVM, err := t.CreateTestVMMultipleDisks([]*compute.Disk{{Name: "ipv4only"}}, ipv4Only)
-	if err != nil {
+	if err := ipv4OnlyVM.AddNetworkProfile(imagetest.NetworkProfileIPv4Only); err != nil {
 		return err
 	}
 	ipv4OnlyVM.RunTests("TestRepoReachabilityIPv4Only")
 
 	// Running the test only on guest-agent images to avoid noise and ensure its
 	// not flaky.
-	if strings.Contains(t.Image.Name, "guest-agent") {
+	if t.GuestOS.HasVariant(utils.VariantGuestAgent) {
 		removeAgentVM, err := t.CreateTestVM("removeagent")
 		if err != nil {
 			return err
@@ -31,6 +31,10 @@ const (
 	platformScope = "https://www.googleapis.com/auth/cloud-platform"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests upgrading guest environment packages."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	// These tests are against googet which is only used on Windows
@@ -59,11 +59,15 @@ func TestStandardPrograms(t *testing.T) {
 	if err != nil {
 		t.Fatalf("couldn't get image from metadata")
 	}
-	if utils.IsSLES(image) || utils.IsSUSE(image) || utils.IsOracle(image) {
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("couldn't get guest OS: %v", err)
+	}
+	if guestOS.IsSUSE() || guestOS.Distro == utils.DistroOracle {
 		// SLES/SUSE/Oracle does not have the Google Cloud SDK installed.
 		t.Skip("Cloud SDK Not installed on SLES/SUSE/Oracle")
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		// COS does not have the Google Cloud SDK installed.
 		t.Skip("Cloud SDK Not supported on COS")
 	}
@@ -76,7 +80,7 @@ func TestStandardPrograms(t *testing.T) {
 		t.Fatalf("gcloud not installed properly: %v, output: %s", err, gb.String())
 	}
 
-	if utils.IsUbuntu(image) && utils.IsAccelerator(image) {
+	if guestOS.Distro == utils.DistroUbuntu && guestOS.HasVariant(utils.VariantAccelerator) {
 		// TODO(b/469342129): Remove this once the package is made available by nvidia.
 		if strings.Contains(image, "580") {
 			t.Logf("Skipping add-nvidia-repositories test for ubuntu accelerator images due to missing libnvsdm-580 package, see b/469342129")
@@ -111,6 +115,10 @@ func TestGuestPackages(t *testing.T) {
 	if err != nil {
 		t.Fatalf("couldn't determine image from metadata")
 	}
+	guestOS, err := utils.GetGuestOS()
+	if err != nil {
+		t.Fatalf("couldn't get guest OS: %v", err)
+	}
 
 	// What command to list all packages
 	listPkgs := func() ([]string, error) {
@@ -164,7 +172,7 @@ func TestGuestPackages(t *testing.T) {
 		}
 	}
 
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		listPkgs = func() ([]string, error) {
 			o, err := os.ReadFile("/etc/cos-package-info.json")
 			var pkgs []string
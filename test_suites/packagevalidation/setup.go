@@ -26,6 +26,10 @@ import (
 // Name is the name of the test package. It must match the directory name.
 var Name = "packagevalidation"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests the packages installed on the image."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	vm1, err := t.CreateTestVM("installedPackages")
@@ -17,14 +17,17 @@
 package pluginmanager
 
 import (
-	"strings"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
 var Name = "pluginmanager"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests guest agent plugin management."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	defaultVM, err := t.CreateTestVM("plugincleanup")
@@ -33,7 +36,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	}
 	defaultVM.RunTests("TestPluginCleanup")
 
-	if !strings.Contains(t.Image.Name, "guest-agent") && (strings.Contains(t.Image.Name, "sles") || strings.Contains(t.Image.Name, "ubuntu")) {
+	if g := t.GuestOS; !g.HasVariant(utils.VariantGuestAgent) && (g.Distro == utils.DistroSLES || g.Distro == utils.DistroUbuntu) {
 		noCorePluginVM, err := t.CreateTestVM("nocoreplugin")
 		if err != nil {
 			return err
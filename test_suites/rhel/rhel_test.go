@@ -29,12 +29,12 @@ const (
 
 func TestVersionLock(t *testing.T) {
 	utils.LinuxOnly(t)
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("Failed to read image metadata: %v", err)
+		t.Fatalf("Failed to get guest OS: %v", err)
 	}
 
-	isEUSOrSAP := utils.IsSAP(image) || utils.IsRHELEUS(image)
+	isEUSOrSAP := guestOS.HasVariant(utils.VariantSAP) || guestOS.HasVariant(utils.VariantEUS)
 	data, releaseVerFileErr := os.ReadFile(releaseVerFile)
 	expectedMajorVersion, err := utils.GetMetadata(utils.Context(t), "instance", "attributes", "rhel-major-version")
 	if err != nil {
@@ -70,14 +70,14 @@ func TestVersionLock(t *testing.T) {
 
 func TestRhuiPackage(t *testing.T) {
 	utils.LinuxOnly(t)
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("Failed to read image metadata: %v", err)
+		t.Fatalf("Failed to get guest OS: %v", err)
 	}
 
-	isBYOS := utils.IsBYOS(image)
-	isEUS := utils.IsRHELEUS(image)
-	isSAP := utils.IsSAP(image)
+	isBYOS := guestOS.HasVariant(utils.VariantBYOS)
+	isEUS := guestOS.HasVariant(utils.VariantEUS)
+	isSAP := guestOS.HasVariant(utils.VariantSAP)
 
 	expectedMajorVersion, err := utils.GetMetadata(utils.Context(t), "instance", "attributes", "rhel-major-version")
 	if err != nil {
@@ -119,14 +119,14 @@ func TestRhuiPackage(t *testing.T) {
 
 func TestPackageInstallation(t *testing.T) {
 	utils.LinuxOnly(t)
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("Failed to read image metadata: %v", err)
+		t.Fatalf("Failed to get guest OS: %v", err)
 	}
 
-	isArm := strings.Contains(image, "arm")
-	isBYOS := utils.IsBYOS(image)
-	isSAP := utils.IsSAP(image)
+	isArm := guestOS.Architecture == "ARM64"
+	isBYOS := guestOS.HasVariant(utils.VariantBYOS)
+	isSAP := guestOS.HasVariant(utils.VariantSAP)
 
 	expectedMajorVersion, err := utils.GetMetadata(utils.Context(t), "instance", "attributes", "rhel-major-version")
 	if err != nil {
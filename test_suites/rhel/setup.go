@@ -16,38 +16,28 @@
 package rhel
 
 import (
-	"regexp"
-	"strings"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
 var Name = "rhel"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests RHEL specific image configuration.",
+		OSFamilies:  []string{imagetest.OSFamilyRHEL},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	image := t.Image
-	if !utils.IsRHEL(image.Name) {
-		t.Skip("Skipping RHEL test for non-RHEL images.")
-	}
-
 	vm, err := t.CreateTestVM("rhel")
 	if err != nil {
 		return err
 	}
 
-	rhelVersion := strings.TrimPrefix(regexp.MustCompile("rhel-[0-9]{1,2}-[0-9]{0,2}").FindString(image.Name), "rhel-")
-	rhelVersionSplit := strings.Split(rhelVersion, "-")
-	rhelMinorVersion := ""
-
-	if len(rhelVersionSplit) > 1 {
-		rhelMinorVersion = rhelVersionSplit[1]
-	}
-
-	vm.AddMetadata("rhel-major-version", rhelVersionSplit[0])
-	vm.AddMetadata("rhel-minor-version", rhelMinorVersion)
+	vm.AddMetadata("rhel-major-version", t.GuestOS.MajorVersion())
+	vm.AddMetadata("rhel-minor-version", t.GuestOS.MinorVersion())
 
 	vm.RunTests("TestVersionLock|TestRhuiPackage|TestPackageInstallation")
 	return nil
@@ -16,7 +16,6 @@ package security
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"io/ioutil"
 	"os/exec"
@@ -87,68 +86,48 @@ func TestKernelSecuritySettings(t *testing.T) {
 
 // TestAutomaticUpdates Check automatic security updates are installed or enabled.
 func TestAutomaticUpdates(t *testing.T) {
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("couldn't get image from metadata")
+		t.Fatalf("couldn't get guest OS: %v", err)
 	}
 
-	switch {
-	case utils.IsDebian(image):
-		if err := verifySecurityUpgrade(image); err != nil {
-			t.Fatal(err)
-		}
-		if err := verifyAutomaticUpdate(image); err != nil {
+	switch guestOS.Distro {
+	case utils.DistroDebian, utils.DistroUbuntu:
+		if err := verifySecurityUpgrade(guestOS); err != nil {
 			t.Fatal(err)
 		}
-	case utils.IsUbuntu(image):
-		if err := verifySecurityUpgrade(image); err != nil {
+		if err := verifyAutomaticUpdate(guestOS); err != nil {
 			t.Fatal(err)
 		}
-		if err := verifyAutomaticUpdate(image); err != nil {
+	case utils.DistroWindows:
+		if err := verifyAutomaticUpdate(guestOS); err != nil {
 			t.Fatal(err)
 		}
-	case utils.IsWindowsImage(image):
-		if err := verifyAutomaticUpdate(image); err != nil {
-			t.Fatal(err)
-		}
-	case utils.IsSUSE(image):
+	case utils.DistroOpenSUSE:
 		t.Skip("Not supported on SUSE")
-	case utils.IsSLES(image):
+	case utils.DistroSLES:
 		t.Skip("Not supported on SLES")
-	case utils.IsFedora(image):
+	case utils.DistroFedora:
 		t.Skip("Not supported on Fedora")
-	case utils.IsCentOS(image):
-		if err := verifyServiceEnabled(image); err != nil {
-			t.Fatal(err)
-		}
-	case utils.IsRHEL(image):
-		if err := verifyServiceEnabled(image); err != nil {
+	case utils.DistroCentOS, utils.DistroRHEL, utils.DistroAlmaLinux, utils.DistroRocky:
+		if err := verifyServiceEnabled(guestOS); err != nil {
 			t.Fatal(err)
 		}
-	case utils.IsAlmaLinux(image):
-		if err := verifyServiceEnabled(image); err != nil {
-			t.Fatal(err)
-		}
-	case utils.IsRocky(image):
-		if err := verifyServiceEnabled(image); err != nil {
-			t.Fatal(err)
-		}
-	case utils.IsOracle(image):
+	case utils.DistroOracle:
 		t.Skip("Not supported on Oracle Linux")
 	default:
-		t.Fatalf("image %s not support", image)
+		t.Fatalf("guest OS %s not supported", guestOS)
 	}
 }
 
 // TestPasswordSecurity Ensure that the system enforces strong passwords and correct lockouts.
 func TestPasswordSecurity(t *testing.T) {
-	ctx := utils.Context(t)
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("couldn't get image from metadata")
+		t.Fatalf("couldn't get guest OS: %v", err)
 	}
 
-	if err := verifySSHConfig(t, image); err != nil {
+	if err := verifySSHConfig(t, guestOS); err != nil {
 		t.Fatal(err)
 	}
 	if utils.IsWindows() {
@@ -156,16 +135,12 @@ func TestPasswordSecurity(t *testing.T) {
 	}
 
 	// Root password/login is disabled.
-	if err := verifyPassword(ctx); err != nil {
+	if err := verifyPassword(guestOS); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func verifyPassword(ctx context.Context) error {
-	image, err := utils.GetMetadata(ctx, "instance", "image")
-	if err != nil {
-		return fmt.Errorf("couldn't get image from metadata")
-	}
+func verifyPassword(guestOS utils.GuestOS) error {
 	fileBytes, err := ioutil.ReadFile("/etc/passwd")
 	if err != nil {
 		return err
@@ -200,7 +175,7 @@ func verifyPassword(ctx context.Context) error {
 			}
 		} else {
 			// SUSE has bin user with login access
-			if !utils.IsSLES(image) && !strings.Contains(shell, "false") && !strings.Contains(shell, "nologin") {
+			if guestOS.Distro != utils.DistroSLES && !strings.Contains(shell, "false") && !strings.Contains(shell, "nologin") {
 				return fmt.Errorf("account %s has the login shell %s", loginname, shell)
 			}
 		}
@@ -208,7 +183,7 @@ func verifyPassword(ctx context.Context) error {
 	return nil
 }
 
-func verifySSHConfig(t *testing.T, image string) error {
+func verifySSHConfig(t *testing.T, guestOS utils.GuestOS) error {
 	t.Helper()
 	var sshdConfig []byte
 	var err error
@@ -235,7 +210,7 @@ func verifySSHConfig(t *testing.T, image string) error {
 	if passwordauthsetting != "passwordauthentication no" {
 		return fmt.Errorf("sshd passwordauthentication setting is %q, want %q", passwordauthsetting, "passwordauthencation no")
 	}
-	if utils.IsSLES(image) || utils.IsSUSE(image) || utils.IsWindows() {
+	if guestOS.IsSUSE() || utils.IsWindows() {
 		// SLES ships with "PermitRootLogin yes" in SSHD config.
 		// This setting is meaningless on windows
 		return nil
@@ -251,17 +226,17 @@ func verifySSHConfig(t *testing.T, image string) error {
 // verifySecurityUpgrade Check that the security packages are marked for automatic update.
 // https://wiki.debian.org/UnattendedUpgrades
 // https://help.ubuntu.com/community/AutomaticSecurityUpdates
-func verifySecurityUpgrade(image string) error {
+func verifySecurityUpgrade(guestOS utils.GuestOS) error {
 	var expectedBlock, expectedLine string
-	switch {
-	case utils.IsDebian(image):
+	switch guestOS.Distro {
+	case utils.DistroDebian:
 		expectedBlock = unattendedUpgradeBlockDebian
 		expectedLine = expectedDebian
-	case utils.IsUbuntu(image):
+	case utils.DistroUbuntu:
 		expectedBlock = unattendedUpgradeBlockUbuntu
 		expectedLine = expectedUbuntu
 	default:
-		return fmt.Errorf("unsupported image %s", image)
+		return fmt.Errorf("unsupported guest OS %s", guestOS)
 	}
 	// First verify package installed
 	stdout, _, err := runCommand("dpkg-query", "-W", "--showformat", "${Status}", "unattended-upgrades")
@@ -294,10 +269,10 @@ func verifySecurityUpgrade(image string) error {
 	return fmt.Errorf("missing Unattended-Upgrade config")
 }
 
-func verifyServiceEnabled(image string) error {
+func verifyServiceEnabled(guestOS utils.GuestOS) error {
 	var serviceName string
 	switch {
-	case strings.Contains(image, "rhel-7") || strings.Contains(image, "centos-7"):
+	case (guestOS.Distro == utils.DistroRHEL || guestOS.Distro == utils.DistroCentOS) && guestOS.MajorVersion() == "7":
 		serviceName = "yum-cron"
 	default:
 		serviceName = "dnf-automatic.timer"
@@ -306,8 +281,8 @@ func verifyServiceEnabled(image string) error {
 	return err
 }
 
-func verifyAutomaticUpdate(image string) error {
-	if utils.IsWindowsImage(image) {
+func verifyAutomaticUpdate(guestOS utils.GuestOS) error {
+	if guestOS.IsWindows() {
 		AUOptions, err := utils.RunPowershellCmd(`Get-ItemProperty -Path HKLM:\software\policies\microsoft\windows\windowsupdate\au | Format-List -Property AUOptions`)
 		if err != nil {
 			return err
@@ -323,11 +298,11 @@ func verifyAutomaticUpdate(image string) error {
 	}
 	automaticUpdateConfig := string(output)
 	switch {
-	case strings.Contains(image, "debian-9"):
+	case guestOS.Distro == utils.DistroDebian && guestOS.MajorVersion() == "9":
 		if !strings.Contains(automaticUpdateConfig, `APT::Periodic::Enable "1";`) {
 			return fmt.Errorf(`"APT::Periodic::Enable" is not set to 1`)
 		}
-	case utils.IsUbuntu(image):
+	case guestOS.Distro == utils.DistroUbuntu:
 		// Ensure that we clean out obsolete debs within 7 days so that customer VMs
 		// don't leak disk space. The value below is in days, with 0 as
 		// disabled.
@@ -441,19 +416,20 @@ func TestSockets(t *testing.T) {
 		// SSH. If we didn't match any above, test logic is faulty.
 		t.Fatalf("No listening sockets")
 	}
-	image, err := utils.GetMetadata(utils.Context(t), "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("couldn't get image from metadata")
+		t.Fatalf("couldn't get guest OS: %v", err)
 	}
 
-	if utils.IsSAP(image) || utils.IsOracle(image) {
+	isSAP := guestOS.HasVariant(utils.VariantSAP)
+	if isSAP || guestOS.Distro == utils.DistroOracle {
 		// All SAP Images are permitted to have 'rpcbind' listening on
 		// port 111
 		allowedTCP = append(allowedTCP, "111")
 		allowedUDP = append(allowedUDP, "111")
 	}
 
-	if strings.Contains(image, "sles-16") {
+	if guestOS.Distro == utils.DistroSLES && guestOS.MajorVersion() == "16" {
 		// SLES 16 images have cockpit management interface which listens to
 		// port 9090.
 		// https://www.suse.com/c/cockpit-the-new-web-based-management-interface-for-sles-16/
@@ -461,7 +437,7 @@ func TestSockets(t *testing.T) {
 		allowedUDP = append(allowedUDP, "9090")
 	}
 
-	if !(strings.Contains(image, "rhel-7") && utils.IsSAP(image)) {
+	if !(guestOS.Distro == utils.DistroRHEL && guestOS.MajorVersion() == "7" && isSAP) {
 		// Skip UDP check on RHEL-7-SAP images which have old rpcbind
 		// which listens to random UDP ports.
 		if err := validateSockets(listenUDP, allowedUDP); err != nil {
@@ -20,6 +20,10 @@ import "github.com/GoogleCloudPlatform/cloud-image-tests"
 // Name is the name of the test package. It must match the directory name.
 var Name = "security"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests the security configuration of the image."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	vm, err := t.CreateTestVM("securitySetttings")
@@ -0,0 +1,190 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeperf
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// triadBytesPerElement are the bytes the triad kernel moves per element,
+	// like STREAM: two reads and a write of a float64.
+	triadBytesPerElement = 3 * 8
+	// cacheLineSize is larger than the cache lines of x86 and Arm CPUs, so
+	// that the cache line of the ping-pong kernel isn't shared.
+	cacheLineSize = 128
+)
+
+// computeSink keeps the compiler from optimizing the compute kernel away.
+var computeSink atomic.Uint64
+
+// triad is the triad kernel of STREAM, a = b + scalar*c.
+func triad(a, b, c []float64, scalar float64) {
+	b = b[:len(a)]
+	c = c[:len(a)]
+	for i := range a {
+		a[i] = b[i] + scalar*c[i]
+	}
+}
+
+// memoryBandwidth runs the triad kernel on threads goroutines at once, each on
+// its own arrays of elems float64, and returns the best bandwidth of the
+// rounds in GB/s. The arrays are allocated by the goroutines, so that they are
+// local to the NUMA node the goroutines run on.
+func memoryBandwidth(threads, elems, rounds int) float64 {
+	arrays := make([][3][]float64, threads)
+	var wg sync.WaitGroup
+	for i := range arrays {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range arrays[i] {
+				arrays[i][j] = make([]float64, elems)
+				for k := range arrays[i][j] {
+					arrays[i][j][k] = float64(j + 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var best float64
+	for r := 0; r < rounds; r++ {
+		start := time.Now()
+		for i := range arrays {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				triad(arrays[i][0], arrays[i][1], arrays[i][2], 3)
+			}()
+		}
+		wg.Wait()
+		bytes := float64(threads * elems * triadBytesPerElement)
+		best = max(best, bytes/time.Since(start).Seconds()/1e9)
+	}
+	return best
+}
+
+// xorshift runs steps steps of the xorshift64 generator, a chain of dependent
+// integer operations which measures the throughput of a core.
+func xorshift(x uint64, steps int) uint64 {
+	for i := 0; i < steps; i++ {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return x
+}
+
+// coreCompute runs the compute kernel on threads goroutines at once, each
+// locked to a thread, and returns the throughput of each of them in millions
+// of xorshift64 steps per second.
+func coreCompute(threads, steps int) []float64 {
+	throughputs := make([]float64, threads)
+	var wg sync.WaitGroup
+	for i := range throughputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			start := time.Now()
+			computeSink.Add(xorshift(uint64(i+1), steps))
+			throughputs[i] = float64(steps) / time.Since(start).Seconds() / 1e6
+		}()
+	}
+	wg.Wait()
+	return throughputs
+}
+
+// pingPongLatency bounces a cache line between a thread pinned to cpuA and a
+// thread pinned to cpuB for rounds round trips, and returns the one way
+// latency in nanoseconds. The threads are terminated once done, since they
+// keep their CPU affinity.
+func pingPongLatency(pin func(cpu int) error, cpuA, cpuB, rounds int) (float64, error) {
+	var line struct {
+		_ [cacheLineSize]byte
+		v atomic.Int64
+		_ [cacheLineSize - 8]byte
+	}
+	pinned := make(chan error, 2)
+	start := make(chan bool, 2)
+	var done sync.WaitGroup
+	side := func(cpu int, first int64) {
+		defer done.Done()
+		// The goroutine exits without unlocking the thread, which terminates
+		// it.
+		runtime.LockOSThread()
+		pinned <- pin(cpu)
+		if !<-start {
+			return
+		}
+		for i := first; i < 2*int64(rounds); i += 2 {
+			for line.v.Load() != i {
+			}
+			line.v.Store(i + 1)
+		}
+	}
+	done.Add(2)
+	go side(cpuA, 0)
+	go side(cpuB, 1)
+	var err error
+	for i := 0; i < 2; i++ {
+		if pinErr := <-pinned; pinErr != nil {
+			err = pinErr
+		}
+	}
+	begin := time.Now()
+	start <- err == nil
+	start <- err == nil
+	done.Wait()
+	if err != nil {
+		return 0, err
+	}
+	return float64(time.Since(begin).Nanoseconds()) / float64(2*rounds), nil
+}
+
+// parseKernelList parses a list of CPUs or NUMA nodes from the kernel, like
+// 0-4,6.
+func parseKernelList(list string) ([]int, error) {
+	var items []int
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, nil
+	}
+	for _, item := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		i0, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("malformed list %q", list)
+		}
+		i1 := i0
+		if isRange {
+			if i1, err = strconv.Atoi(last); err != nil || i1 < i0 {
+				return nil, fmt.Errorf("malformed list %q", list)
+			}
+		}
+		for i := i0; i <= i1; i++ {
+			items = append(items, i)
+		}
+	}
+	return items, nil
+}
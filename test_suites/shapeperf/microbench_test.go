@@ -0,0 +1,88 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeperf
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTriad(t *testing.T) {
+	a := make([]float64, 3)
+	triad(a, []float64{1, 2, 3}, []float64{4, 5, 6}, 3)
+	if diff := cmp.Diff([]float64{13, 17, 21}, a); diff != "" {
+		t.Errorf("triad() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if bandwidth := memoryBandwidth(2, 1024, 2); bandwidth <= 0 {
+		t.Errorf("memoryBandwidth() = %f, want a positive bandwidth", bandwidth)
+	}
+}
+
+func TestXorshift(t *testing.T) {
+	// The first output of xorshift64 with the triple 13, 7, 17 seeded with 1.
+	if got, want := xorshift(1, 1), uint64(1082269761); got != want {
+		t.Errorf("xorshift(1, 1) = %d, want %d", got, want)
+	}
+	throughputs := coreCompute(2, 1000)
+	if len(throughputs) != 2 || throughputs[0] <= 0 || throughputs[1] <= 0 {
+		t.Errorf("coreCompute(2) = %v, want 2 positive throughputs", throughputs)
+	}
+}
+
+func TestPingPongLatency(t *testing.T) {
+	var pinned atomic.Int32
+	pin := func(int) error {
+		pinned.Add(1)
+		return nil
+	}
+	latency, err := pingPongLatency(pin, 0, 0, 100)
+	if err != nil || latency <= 0 {
+		t.Errorf("pingPongLatency() = %f, %v, want a positive latency", latency, err)
+	}
+	if got := pinned.Load(); got != 2 {
+		t.Errorf("pingPongLatency() pinned %d threads, want 2", got)
+	}
+
+	failing := func(int) error { return errors.New("invalid argument") }
+	if _, err := pingPongLatency(failing, 0, 1, 100); err == nil {
+		t.Errorf("pingPongLatency() with a failing pin = nil, want error")
+	}
+}
+
+func TestParseKernelList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{list: "0-3,6\n", want: []int{0, 1, 2, 3, 6}},
+		{list: "1", want: []int{1}},
+		{list: "", want: nil},
+		{list: "3-1", wantErr: true},
+		{list: "0-a", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseKernelList(tc.list)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseKernelList(%q) = %v, want error: %v", tc.list, err, tc.wantErr)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("parseKernelList(%q) returned unexpected diff (-want +got):\n%s", tc.list, diff)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shapeperf runs memory bandwidth, compute and NUMA latency
+// microbenchmarks on the largest VM shape in a family, to catch image
+// regressions like wrong CPU frequency governors or NUMA balancing settings.
+package shapeperf
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/shapeutils"
+)
+
+// Name is the name of the test package. It must match the directory name.
+var Name = "shapeperf"
+
+var testFilter = flag.String("shapeperf_test_filter", ".*", "regexp filter for shapeperf test cases, only cases with a matching family name will be run")
+
+// The metrics of the microbenchmarks, whose targets are in
+// utils/perftargets/targets.yaml.
+const (
+	memoryBandwidthMetric = "memory_bandwidth"
+	coreComputeMetric     = "core_compute"
+	numaLatencyMetric     = "numa_latency"
+)
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests the memory bandwidth, compute throughput and NUMA latency of the image on supported machine shapes.",
+		// Like shapevalidation, the test uses so much capacity that images
+		// are tested serially.
+		ExclusiveProject: true,
+	})
+}
+
+// TestSetup sets up the test workflow.
+func TestSetup(t *imagetest.TestWorkflow) error {
+	filter, err := regexp.Compile(*testFilter)
+	if err != nil {
+		return fmt.Errorf("invalid shapeperf test filter: %v", err)
+	}
+	// Resolve the targets of all the shapes before creating any resource, so
+	// that shapes without a target fail the setup.
+	type testCase struct {
+		shape                        *shapeutils.Shape
+		memoryBandwidth, coreCompute float64
+		// numaLatency is only set for shapes with several NUMA nodes.
+		numaLatency float64
+	}
+	var testCases []testCase
+	var targetErrs []error
+	families := shapeutils.Families(t.Image)
+	for _, family := range slices.Sorted(maps.Keys(families)) {
+		shape := families[family]
+		if !filter.MatchString(family) || !shape.Supports(t.Image) {
+			continue
+		}
+		c := testCase{shape: shape}
+		query := perftargets.Query{MachineType: shape.Name, VCPUs: int64(shape.CPU)}
+		var errs []error
+		for _, target := range []struct {
+			metric string
+			value  *float64
+			upper  bool
+		}{
+			{metric: memoryBandwidthMetric, value: &c.memoryBandwidth},
+			{metric: coreComputeMetric, value: &c.coreCompute},
+			{metric: numaLatencyMetric, value: &c.numaLatency, upper: true},
+		} {
+			if target.metric == numaLatencyMetric && shape.Numa < 2 {
+				continue
+			}
+			query.Metric = target.metric
+			tgt, err := perftargets.Lookup(query)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if target.upper {
+				*target.value = tgt.Maximum()
+			} else {
+				*target.value = tgt.Minimum()
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			targetErrs = append(targetErrs, fmt.Errorf("shape %s: %w", shape.Name, err))
+			continue
+		}
+		testCases = append(testCases, c)
+	}
+	if err := errors.Join(targetErrs...); err != nil {
+		return fmt.Errorf("getting perf targets: %w", err)
+	}
+
+	for _, c := range testCases {
+		vm, err := shapeutils.CreateVM(t, c.shape)
+		if err != nil {
+			return err
+		}
+		vm.AddMetadata("expected_memory_bandwidth", fmt.Sprint(c.memoryBandwidth))
+		vm.AddMetadata("expected_core_compute", fmt.Sprint(c.coreCompute))
+		tests := "TestMemoryBandwidth|TestCoreCompute"
+		if c.numaLatency > 0 {
+			vm.AddMetadata("expected_numa_latency", fmt.Sprint(c.numaLatency))
+			tests += "|TestNUMALatency"
+		}
+		vm.RunTests(tests)
+	}
+	return nil
+}
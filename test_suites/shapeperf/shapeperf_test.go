@@ -0,0 +1,119 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeperf
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+const (
+	// triadElements are the float64 of each array of the triad kernel, 32 MB,
+	// much larger than the share of the last level cache of a vCPU.
+	triadElements = 4 << 20
+	triadRounds   = 10
+	// computeSteps take about a second on current CPUs.
+	computeSteps = 1 << 28
+	// pingPongRounds are the round trips of the cache line between two CPUs.
+	pingPongRounds = 100000
+)
+
+// expectedValue returns the target of a metric, set by the setup in the
+// attribute.
+func expectedValue(t *testing.T, attribute string) float64 {
+	t.Helper()
+	value, err := utils.GetMetadata(utils.Context(t), "instance", "attributes", attribute)
+	if err != nil {
+		t.Fatalf("could not get %s from metadata: %v", attribute, err)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("could not parse float from %s", value)
+	}
+	return f
+}
+
+// metricLabels returns the labels of the recorded metrics, which tell apart
+// the machine types of the test VMs.
+func metricLabels(t *testing.T) map[string]string {
+	t.Helper()
+	labels := make(map[string]string)
+	if machineType, err := utils.GetMetadata(utils.Context(t), "instance", "machine-type"); err == nil {
+		labels["machine_type"] = machineType[strings.LastIndex(machineType, "/")+1:]
+	}
+	return labels
+}
+
+func TestMemoryBandwidth(t *testing.T) {
+	expected := expectedValue(t, "expected_memory_bandwidth")
+	utils.WriteArtifact(t, "settings.txt", []byte(perfSettingsReport()))
+	bandwidth := memoryBandwidth(runtime.NumCPU(), triadElements, triadRounds)
+	utils.RecordMetric(t, memoryBandwidthMetric, bandwidth, "GBps", metricLabels(t))
+	if bandwidth < expected {
+		t.Errorf("got %f GB/s memory bandwidth, want at least %f GB/s", bandwidth, expected)
+	}
+}
+
+func TestCoreCompute(t *testing.T) {
+	expected := expectedValue(t, "expected_core_compute")
+	utils.WriteArtifact(t, "settings.txt", []byte(perfSettingsReport()))
+	// The throughput of each core is a sample.
+	summary := utils.RecordSamples(t, coreComputeMetric, coreCompute(runtime.NumCPU(), computeSteps), "Mops/s", metricLabels(t))
+	// A single slow core is likely a noisy neighbor, while a wrong frequency
+	// governor or disabled turbo slows down all of them.
+	if summary.Median < expected {
+		t.Errorf("got a median of %f million operations per second per core, want at least %f", summary.Median, expected)
+	}
+}
+
+func TestNUMALatency(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("NUMA latency is only measured on Linux")
+	}
+	expected := expectedValue(t, "expected_numa_latency")
+	nodes, err := numaNodeCPUs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) < 2 {
+		t.Fatalf("got %d NUMA nodes, want at least 2", len(nodes))
+	}
+	// Measure the latency from the first CPU of the first node to the first
+	// CPU of each node, and to another CPU of the first node for reference.
+	for node, cpus := range nodes {
+		from, to := nodes[0][0], cpus[0]
+		if node == 0 {
+			if len(cpus) < 2 {
+				continue
+			}
+			to = cpus[1]
+		}
+		latency, err := pingPongLatency(pinToCPU, from, to, pingPongRounds)
+		if err != nil {
+			t.Fatalf("could not measure the latency from CPU %d to CPU %d: %v", from, to, err)
+		}
+		labels := metricLabels(t)
+		labels["from_node"] = "0"
+		labels["to_node"] = strconv.Itoa(node)
+		utils.RecordMetric(t, numaLatencyMetric, latency, "ns", labels)
+		if node > 0 && latency > expected {
+			t.Errorf("got %f ns latency from NUMA node 0 to node %d, want at most %f ns", latency, node, expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeperf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// perfSettings are the files of the kernel settings which affect the
+// microbenchmarks, like the CPU frequency governor and NUMA balancing.
+var perfSettings = []string{
+	"/sys/devices/system/cpu/cpu0/cpufreq/scaling_governor",
+	"/sys/devices/system/cpu/intel_pstate/no_turbo",
+	"/sys/devices/system/cpu/cpufreq/boost",
+	"/sys/kernel/mm/transparent_hugepage/enabled",
+	"/sys/kernel/mm/transparent_hugepage/defrag",
+	"/proc/sys/vm/nr_hugepages",
+	"/proc/sys/kernel/numa_balancing",
+}
+
+// pinToCPU sets the affinity of the calling thread to the CPU.
+func pinToCPU(cpu int) error {
+	var set unix.CPUSet
+	set.Set(cpu)
+	return unix.SchedSetaffinity(0, &set)
+}
+
+// numaNodeCPUs returns the CPUs of each online NUMA node.
+func numaNodeCPUs() ([][]int, error) {
+	online, err := os.ReadFile("/sys/devices/system/node/online")
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := parseKernelList(string(online))
+	if err != nil {
+		return nil, err
+	}
+	var cpus [][]int
+	for _, node := range nodes {
+		list, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", node))
+		if err != nil {
+			return nil, err
+		}
+		nodeCPUs, err := parseKernelList(string(list))
+		if err != nil {
+			return nil, err
+		}
+		cpus = append(cpus, nodeCPUs)
+	}
+	return cpus, nil
+}
+
+// perfSettingsReport returns the value of each of the kernel settings, or why
+// it couldn't be read, like on VMs without cpufreq.
+func perfSettingsReport() string {
+	var report strings.Builder
+	for _, path := range perfSettings {
+		value, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(&report, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&report, "%s: %s\n", path, strings.TrimSpace(string(value)))
+	}
+	return report.String()
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeperf
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+// pinToCPU isn't implemented on Windows, whose processor groups make CPU
+// numbers ambiguous on VMs with more than 64 vCPUs.
+func pinToCPU(cpu int) error {
+	return fmt.Errorf("pinning threads to CPUs is not implemented on windows")
+}
+
+// numaNodeCPUs isn't implemented on Windows, see pinToCPU.
+func numaNodeCPUs() ([][]int, error) {
+	return nil, fmt.Errorf("listing the CPUs of NUMA nodes is not implemented on windows")
+}
+
+// perfSettingsReport returns the active power plan, which sets the processor
+// performance state.
+func perfSettingsReport() string {
+	out, err := utils.RunPowershellCmd("powercfg /getactivescheme")
+	if err != nil {
+		return fmt.Sprintf("powercfg /getactivescheme: %v %s", err, out.Stderr)
+	}
+	return out.Stdout
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sharedvpc tests test VMs attached to a Shared VPC network of another
+// project.
+package sharedvpc
+
+import (
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+)
+
+// Name is the name of the test package. It must match the directory name.
+var Name = "sharedvpc"
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests VMs attached to the Shared VPC network set by the shared_vpc flags.",
+	})
+}
+
+// TestSetup sets up the test workflow.
+func TestSetup(t *imagetest.TestWorkflow) error {
+	if network, _ := t.SharedVPC(); network == nil {
+		t.Skip("Test suite sharedvpc requires -shared_vpc_host_project, -shared_vpc_network and -shared_vpc_subnetwork")
+		return nil
+	}
+	// Test VMs are attached to the Shared VPC by default.
+	vm, err := t.CreateTestVM("sharedvpc")
+	if err != nil {
+		return err
+	}
+	vm.RunTests("TestSharedVPCAttachment")
+	return nil
+}
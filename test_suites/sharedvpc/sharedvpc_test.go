@@ -0,0 +1,57 @@
+// Copyright 2026 Google LLC
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//     https://www.apache.org/licenses/LICENSE-2.0
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharedvpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+// TestSharedVPCAttachment tests that the primary NIC of the VM is attached to
+// a network of another project, and configured with its address.
+func TestSharedVPCAttachment(t *testing.T) {
+	ctx := utils.Context(t)
+	project, err := utils.GetMetadata(ctx, "project", "numeric-project-id")
+	if err != nil {
+		t.Fatalf("Failed to get the project number: %v", err)
+	}
+	// The network is reported as projects/<project number>/networks/<name>.
+	network, err := utils.GetMetadata(ctx, "instance", "network-interfaces", "0", "network")
+	if err != nil {
+		t.Fatalf("Failed to get the network of the primary NIC: %v", err)
+	}
+	parts := strings.Split(network, "/")
+	if len(parts) != 4 || parts[0] != "projects" {
+		t.Fatalf("Network of the primary NIC is %q, want projects/<project>/networks/<name>", network)
+	}
+	if parts[1] == project {
+		t.Errorf("Primary NIC is attached to network %s of the VM project, want a network of the Shared VPC host project", network)
+	}
+
+	ip, err := utils.GetMetadata(ctx, "instance", "network-interfaces", "0", "ip")
+	if err != nil {
+		t.Fatalf("Failed to get the address of the primary NIC: %v", err)
+	}
+	iface, err := utils.GetInterface(ctx, 0)
+	if err != nil {
+		t.Fatalf("Failed to get the primary NIC: %v", err)
+	}
+	got, err := utils.ParseInterfaceIPv4(iface)
+	if err != nil {
+		t.Fatalf("Failed to get the address of %s: %v", iface.Name, err)
+	}
+	if got.String() != ip {
+		t.Errorf("Primary NIC %s has address %s, want %s from the Shared VPC subnetwork", iface.Name, got, ip)
+	}
+}
@@ -42,6 +42,10 @@ const (
 	clientStartupScriptURL = "startupscripts/remote_auth_client_setup.ps1"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests SQL Server images."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	if utils.HasFeature(t.Image, "WINDOWS") && utils.IsWindowsSQLImage(t.Image.Name) {
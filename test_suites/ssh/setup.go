@@ -17,7 +17,6 @@ package ssh
 
 import (
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
@@ -30,6 +29,10 @@ const (
 	user4 = "test-user4"
 )
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests SSH access to VMs."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	// adds the private key to the t.wf.Sources
@@ -61,7 +64,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	vm.AddMetadata("enable-windows-ssh", "true")
 	vm.AddMetadata("sysprep-specialize-script-cmd", "googet -noconfirm=true install google-compute-engine-ssh")
 	runTests := "TestSSHInstanceKey|TestHostKeysAreUnique|TestMatchingKeysInGuestAttributes"
-	if !utils.IsWindowsImage(t.Image.Name) {
+	if !t.GuestOS.IsWindows() {
 		// Windows does not remove the local users.
 		runTests += "|TestSSHServiceStartsAfterGuestAgent|TestDeleteLocalUser|TestDeleteUserDefault"
 	}
@@ -84,7 +87,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 	vm4.AddMetadata("enable-windows-ssh", "true")
 	vm4.AddMetadata("sysprep-specialize-script-cmd", "googet -noconfirm=true install google-compute-engine-ssh")
 	server2Tests := "TestSSHChangeKey"
-	if !utils.IsWindowsImage(t.Image.Name) {
+	if !t.GuestOS.IsWindows() {
 		// Windows does not remove the local users.
 		server2Tests += "|TestSwitchDefaultConfig"
 	}
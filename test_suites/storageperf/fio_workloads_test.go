@@ -0,0 +1,44 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageperf
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+// TestFIOWorkloads runs the workloads of the workload matrix of the test case
+// other than the default ones, which have their own tests.
+func TestFIOWorkloads(t *testing.T) {
+	workloads, err := getWorkloads(utils.Context(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	for _, w := range workloads {
+		if slices.ContainsFunc(defaultWorkloads, func(d fioWorkload) bool { return d.Name == w.Name }) {
+			continue
+		}
+		ran = true
+		t.Run(w.Name, func(t *testing.T) {
+			runWorkload(t, w)
+		})
+	}
+	if !ran {
+		t.Skip("the workload matrix has no other workloads than the default ones")
+	}
+}
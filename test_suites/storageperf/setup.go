@@ -17,13 +17,17 @@ package storageperf
 
 import (
 	"embed"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
 	daisy "github.com/GoogleCloudPlatform/compute-daisy"
 	"google.golang.org/api/compute/v1"
 )
@@ -34,7 +38,11 @@ var Name = "storageperf"
 //go:embed startupscripts/*
 var scripts embed.FS
 
-var testFilter = flag.String("storageperf_test_filter", ".*", "regexp filter for storageperf test cases, only cases with a matching name will be run")
+var (
+	testFilter = flag.String("storageperf_test_filter", ".*", "regexp filter for storageperf test cases, only cases with a matching name will be run")
+	repeat     = flag.Int("storageperf_repeat", 1, "number of samples of each FIO workload, run one after the other on the same VMs. Multiplies the workflow timeout")
+	statistic  = flag.String("storageperf_statistic", string(utils.SelectMedian), "sample the thresholds of the workloads apply to (worst|best|median): worst checks the lowest sample against a minimum like IOPS and the highest sample against a maximum like p99 latency, best does the opposite")
+)
 
 type storagePerfTest struct {
 	name             string
@@ -46,6 +54,9 @@ type storagePerfTest struct {
 	minCPUPlatform   string
 	zone             string
 	requiredFeatures []string
+	// workloads is the workload matrix of the test case, defaultWorkloads
+	// when unset.
+	workloads []fioWorkload
 }
 
 const (
@@ -93,6 +104,7 @@ var storagePerfTestConfig = []storagePerfTest{
 		diskType:         "lssd",
 		cpuMetric:        "C3_CPUS",
 		requiredFeatures: []string{"GVNIC"},
+		workloads:        withLatencyWorkloads(),
 	},
 	{
 		name:             "c3-hde",
@@ -111,6 +123,7 @@ var storagePerfTestConfig = []storagePerfTest{
 		diskType:         imagetest.PdBalanced,
 		cpuMetric:        "C3_CPUS",
 		requiredFeatures: []string{"GVNIC"},
+		workloads:        withLatencyWorkloads(),
 	},
 	{
 		name:             "c4-hdb",
@@ -121,6 +134,7 @@ var storagePerfTestConfig = []storagePerfTest{
 		diskType:         imagetest.HyperdiskBalanced,
 		cpuMetric:        "CPUS",
 		requiredFeatures: []string{"GVNIC"},
+		workloads:        withLatencyWorkloads(),
 	},
 	{
 		name:             "c4-hde",
@@ -178,24 +192,80 @@ var storagePerfTestConfig = []storagePerfTest{
 	},
 }
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests disk performance against expected IOPS and bandwidth."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	filter, err := regexp.Compile(*testFilter)
 	if err != nil {
 		return fmt.Errorf("invalid test case filter: %v", err)
 	}
-	testVMs := []*imagetest.TestVM{}
+	if *repeat < 1 {
+		return fmt.Errorf("invalid -storageperf_repeat %d, want at least 1", *repeat)
+	}
+	// The samples are taken one after the other on the same VMs.
+	if err := t.ScaleTimeout(*repeat); err != nil {
+		return err
+	}
+	if _, err := utils.ParseSampleSelection(*statistic); err != nil {
+		return fmt.Errorf("invalid -storageperf_statistic: %v", err)
+	}
+	// Resolve the targets of all the test cases before creating any resource,
+	// so that machine and disk types without a target fail the setup.
+	type testCase struct {
+		storagePerfTest
+		machine      *compute.MachineType
+		randReadIOPS float64
+	}
+	var testCases []testCase
+	var targetErrs []error
 	for _, tc := range storagePerfTestConfig {
 		if skipTest(tc, t.Image) || !filter.MatchString(tc.name) {
 			continue
 		}
+		z := tc.zone
+		if z == "" {
+			z = t.Zone.Name
+		}
+		mt, err := t.Client.GetMachineType(t.Project.Name, z, tc.machineType)
+		if err != nil {
+			return fmt.Errorf("could not find machinetype %v", err)
+		}
+		c := testCase{storagePerfTest: tc, machine: mt}
+		if c.workloads == nil {
+			c.workloads = defaultWorkloads
+		}
+		if err := validateWorkloads(c.workloads); err != nil {
+			return fmt.Errorf("test case %s: %v", tc.name, err)
+		}
+		query := perftargets.Query{MachineType: tc.machineType, VCPUs: mt.GuestCpus, DiskType: tc.diskType}
+		if c.workloads, err = workloadThresholds(c.workloads, query); err != nil {
+			targetErrs = append(targetErrs, fmt.Errorf("test case %s: %w", tc.name, err))
+			continue
+		}
+		// the disk is sized for the random read IOPS target, if any.
+		query.Metric = "rand_read_iops"
+		if target, err := perftargets.Lookup(query); err == nil {
+			c.randReadIOPS = target.Target
+		} else if !errors.Is(err, perftargets.ErrNoTarget) {
+			return err
+		}
+		testCases = append(testCases, c)
+	}
+	if err := errors.Join(targetErrs...); err != nil {
+		return fmt.Errorf("expected performance not found: %w", err)
+	}
 
+	testVMs := []*imagetest.TestVM{}
+	for _, tc := range testCases {
 		region := tc.zone
 		if len(region) > 2 {
 			region = region[:len(region)-2]
 		}
 
-		mountdiskSizeGB := getRequiredDiskSize(tc.machineType, tc.diskType)
+		mountdiskSizeGB := getRequiredDiskSize(tc.diskType, tc.randReadIOPS)
 		// disk sizes must be different for disk identification
 		if bootdiskSizeGB == mountdiskSizeGB {
 			mountdiskSizeGB++
@@ -209,15 +279,7 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 			}
 			if tc.cpuMetric != "" {
 				quota := &daisy.QuotaAvailable{Metric: tc.cpuMetric, Region: region}
-				z := tc.zone
-				if z == "" {
-					z = t.Zone.Name
-				}
-				mt, err := t.Client.GetMachineType(t.Project.Name, z, tc.machineType)
-				if err != nil {
-					return fmt.Errorf("could not find machinetype %v", err)
-				}
-				quota.Units = float64(mt.GuestCpus)
+				quota.Units = float64(tc.machine.GuestCpus)
 				if err := t.WaitForVMQuota(quota); err != nil {
 					return err
 				}
@@ -240,27 +302,15 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		// set the disk type: hyperdisk has different testing parameters from https://cloud.google.com/compute/docs/disks/benchmark-hyperdisk-performance
 		vm.AddMetadata(diskTypeAttribute, tc.diskType)
 		vm.AddMetadata(diskSizeGBAttribute, fmt.Sprintf("%d", mountdiskSizeGB))
-		// set the expected performance values
-		var vmPerformanceTargets PerformanceTargets
-		var foundKey bool = false
-		if tc.diskType == imagetest.HyperdiskExtreme {
-			vmPerformanceTargets, foundKey = hyperdiskExtremeIOPSMap[tc.machineType]
-		} else if tc.diskType == imagetest.HyperdiskBalanced {
-			vmPerformanceTargets, foundKey = hyperdiskBalancedIOPSMap[tc.machineType]
-		} else if tc.diskType == imagetest.HyperdiskThroughput {
-			vmPerformanceTargets, foundKey = hyperdiskThroughputIOPSMap[tc.machineType]
-		} else if tc.diskType == imagetest.PdBalanced {
-			vmPerformanceTargets, foundKey = pdbalanceIOPSMap[tc.machineType]
-		} else if tc.diskType == "lssd" {
-			vmPerformanceTargets, foundKey = lssdIOPSMap[tc.machineType]
-		}
-		if !foundKey {
-			return fmt.Errorf("expected performance for machine type %s and disk type %s not found", tc.machineType, tc.diskType)
+		// set the workload matrix, with the expected performance values
+		workloadsJSON, err := json.Marshal(tc.workloads)
+		if err != nil {
+			return err
 		}
-		vm.AddMetadata(randReadAttribute, fmt.Sprintf("%f", vmPerformanceTargets.randReadIOPS))
-		vm.AddMetadata(randWriteAttribute, fmt.Sprintf("%f", vmPerformanceTargets.randWriteIOPS))
-		vm.AddMetadata(seqReadAttribute, fmt.Sprintf("%f", vmPerformanceTargets.seqReadBW))
-		vm.AddMetadata(seqWriteAttribute, fmt.Sprintf("%f", vmPerformanceTargets.seqWriteBW))
+		vm.AddMetadata(workloadsAttribute, string(workloadsJSON))
+		// set the number of samples of each workload, and the sample the thresholds apply to
+		vm.AddMetadata(repeatAttribute, fmt.Sprint(*repeat))
+		vm.AddMetadata(statisticAttribute, *statistic)
 		// for now, only use the startup script on windows because the linux startup script to install fio can take a while, leading to race conditions.
 		if utils.HasFeature(t.Image, "WINDOWS") {
 			windowsStartup, err := scripts.ReadFile(windowsInstallFioScriptURL)
@@ -272,11 +322,47 @@ func TestSetup(t *imagetest.TestWorkflow) error {
 		testVMs = append(testVMs, vm)
 	}
 	for _, vm := range testVMs {
-		vm.RunTests("TestRandomReadIOPS|TestSequentialReadIOPS|TestRandomWriteIOPS|TestSequentialWriteIOPS")
+		vm.RunTests("TestRandomReadIOPS|TestSequentialReadIOPS|TestRandomWriteIOPS|TestSequentialWriteIOPS|TestFIOWorkloads")
 	}
 	return nil
 }
 
+// workloadThresholds returns the workloads with the thresholds of their metrics
+// which have a performance target for the query. Latencies must stay below
+// their maximum, other metrics reach their minimum. It fails if a required
+// metric has no target.
+func workloadThresholds(workloads []fioWorkload, query perftargets.Query) ([]fioWorkload, error) {
+	var withThresholds []fioWorkload
+	var errs []error
+	for _, w := range workloads {
+		w.Thresholds = nil
+		units := w.metricUnits()
+		metrics := make([]string, 0, len(units))
+		for metric := range units {
+			metrics = append(metrics, metric)
+		}
+		slices.Sort(metrics)
+		for _, metric := range metrics {
+			query.Metric = metric
+			target, err := perftargets.Lookup(query)
+			if errors.Is(err, perftargets.ErrNoTarget) && !slices.Contains(w.Required, metric) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			threshold := fioThreshold{Metric: metric, Min: target.Minimum()}
+			if units[metric] == microsecondUnit {
+				threshold = fioThreshold{Metric: metric, Max: target.Maximum()}
+			}
+			w.Thresholds = append(w.Thresholds, threshold)
+		}
+		withThresholds = append(withThresholds, w)
+	}
+	return withThresholds, errors.Join(errs...)
+}
+
 // Due to compatability issues or other one off errors, some combinations of machine types and images must be skipped. This function returns true for those combinations.
 func skipTest(tc storagePerfTest, image *compute.Image) bool {
 	if image.Architecture != tc.arch {
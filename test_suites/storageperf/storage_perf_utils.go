@@ -22,6 +22,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -30,17 +31,7 @@ import (
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
-// PerformanceTargets is a structure which stores the expected iops for each operation. This is used as a value in a map from machine type to performance targets
-type PerformanceTargets struct {
-	randReadIOPS  float64
-	randWriteIOPS float64
-	seqReadBW     float64
-	seqWriteBW    float64
-}
-
 const (
-	// iopsErrorMargin allows for a small difference between iops found in the test and the iops value listed in public documentation.
-	iopsErrorMargin = 0.85
 	bootdiskSizeGB  = 50
 	bytesInMB       = 1048576
 	mountDiskName   = "hyperdisk"
@@ -57,138 +48,14 @@ const (
 	randWrite = "randwrite"
 	seqRead   = "read"
 	seqWrite  = "write"
-	// Guest Attribute constants for storing the expected iops and disk type
-	diskTypeAttribute  = "diskType"
-	randReadAttribute  = "randRead"
-	randWriteAttribute = "randWrite"
-	seqReadAttribute   = "seqRead"
-	seqWriteAttribute  = "seqWrite"
+	// Guest Attribute constants for storing the disk type
+	diskTypeAttribute = "diskType"
 	// disk size varies due to performance limits per GB being different for disk types
 	diskSizeGBAttribute = "diskSizeGB"
 	// this excludes the filename=$TEST_DIR and filesize=$SIZE_IN_GB fields, which should be manually added to the string
-	fillDiskCommonOptions   = "--name=fill_disk --direct=1 --verify=0 --randrepeat=0 --bs=128K --iodepth=64 --rw=randwrite --iodepth_batch_submit=64  --iodepth_batch_complete_max=64"
-	commonFIORandOptions    = "--name=write_iops_test --filesize=500G --numjobs=1 --time_based --runtime=1m --ramp_time=2s --direct=1 --verify=0 --bs=4K --iodepth=256 --randrepeat=0 --iodepth_batch_submit=256  --iodepth_batch_complete_max=256 --output-format=json"
-	commonFIOSeqOptions     = "--name=write_bandwidth_test --filesize=500G --time_based --ramp_time=2s --runtime=1m --direct=1 --verify=0 --randrepeat=0 --numjobs=1 --offset_increment=500G --bs=1M --iodepth=64 --iodepth_batch_submit=64 --iodepth_batch_complete_max=64 --output-format=json"
-	hyperdiskFIORandOptions = "--numjobs=8 --size=500G --time_based --runtime=5m --ramp_time=10s --direct=1 --verify=0 --bs=4K --iodepth=256 --iodepth_batch_submit=256 --iodepth_batch_complete_max=256 --group_reporting --output-format=json"
-	hyperdiskFIOSeqOptions  = "--numjobs=8 --size=500G --time_based --runtime=5m --ramp_time=10s --direct=1 --verify=0 --bs=1M --iodepth=64 --iodepth_batch_submit=64 --iodepth_batch_complete_max=64 --offset_increment=20G --group_reporting --output-format=json"
-	lssdFIORandOptions      = "--name=write_iops_test --numjobs=8 --size=500G --time_based --runtime=5m --randrepeat=0 --invalidate=1 --ramp_time=10s --direct=1 --verify=0 --verify_fatal=0 --bs=4K --iodepth=256 --iodepth_batch_submit=256 --iodepth_batch_complete_max=256 --group_reporting --output-format=json"
-	lssdFIOSeqOptions       = "--name=write_bandwidth_test --numjobs=8 --size=500G --time_based --runtime=5m --randrepeat=0 --invalidate=1 --ramp_time=10s --direct=1 --verify=0 --verify_fatal=0 --bs=1M --iodepth=64 --iodepth_batch_submit=64 --iodepth_batch_complete_max=64 --offset_increment=20G --group_reporting --output-format=json"
+	fillDiskCommonOptions = "--name=fill_disk --direct=1 --verify=0 --randrepeat=0 --bs=128K --iodepth=64 --rw=randwrite --iodepth_batch_submit=64  --iodepth_batch_complete_max=64"
 )
 
-// map the machine type to performance targets
-var lssdIOPSMap = map[string]PerformanceTargets{
-	"c3-standard-88-lssd": {
-		randReadIOPS:  1600000.0,
-		randWriteIOPS: 800000.0,
-		seqReadBW:     6240.0,
-		seqWriteBW:    3120.0,
-	},
-	"c3d-standard-180": {
-		randReadIOPS:  1600000.0,
-		randWriteIOPS: 800000.0,
-		seqReadBW:     6240.0,
-		seqWriteBW:    3120.0,
-	},
-}
-
-var hyperdiskExtremeIOPSMap = map[string]PerformanceTargets{
-	"c3-standard-88": {
-		randReadIOPS:  350000.0,
-		randWriteIOPS: 350000.0,
-		seqReadBW:     5000.0,
-		seqWriteBW:    5000.0,
-	},
-	"c3d-standard-180": {
-		randReadIOPS:  350000.0,
-		randWriteIOPS: 350000.0,
-		seqReadBW:     5000.0,
-		seqWriteBW:    5000.0,
-	},
-	"n2-standard-80": {
-		randReadIOPS:  160000.0,
-		randWriteIOPS: 160000.0,
-		seqReadBW:     5000.0,
-		seqWriteBW:    5000.0,
-	},
-	"c4-standard-192": {
-		randReadIOPS:  1000000.0,
-		randWriteIOPS: 1000000.0,
-		seqReadBW:     10000.0,
-		seqWriteBW:    10000.0,
-	},
-}
-
-var hyperdiskBalancedIOPSMap = map[string]PerformanceTargets{
-	"n4-standard-48": {
-		randReadIOPS:  160000.0,
-		randWriteIOPS: 160000.0,
-		seqReadBW:     2400.0,
-		seqWriteBW:    2400.0,
-	},
-	"n4-standard-64": {
-		randReadIOPS:  160000.0,
-		randWriteIOPS: 160000.0,
-		seqReadBW:     3000.0,
-		seqWriteBW:    3000.0,
-	},
-	"c4-standard-192": {
-		randReadIOPS:  320000.0,
-		randWriteIOPS: 320000.0,
-		seqReadBW:     10000.0,
-		seqWriteBW:    10000.0,
-	},
-}
-
-var hyperdiskThroughputIOPSMap = map[string]PerformanceTargets{
-	"c4-standard-192": {
-		randReadIOPS:  320000.0, // TODO
-		randWriteIOPS: 320000.0, // TODO
-		seqReadBW:     10000.0,
-		seqWriteBW:    10000.0,
-	},
-}
-
-// See https://cloud.google.com/compute/docs/disks/performance#pd-balanced_7
-var pdbalanceIOPSMap = map[string]PerformanceTargets{
-	"c3-standard-88": {
-		randReadIOPS:  80000.0,
-		randWriteIOPS: 80000.0,
-		seqReadBW:     1200.0,
-		seqWriteBW:    1200.0,
-	},
-	"c3d-standard-180": {
-		randReadIOPS:  80000.0,
-		randWriteIOPS: 80000.0,
-		seqReadBW:     1200.0,
-		seqWriteBW:    1200.0,
-	},
-	"n2d-standard-64": {
-		randReadIOPS:  80000.0,
-		randWriteIOPS: 80000.0,
-		seqReadBW:     1200.0,
-		seqWriteBW:    1200.0,
-	},
-	// this machine type should use Intel Skylake
-	"n1-standard-64": {
-		randReadIOPS:  80000.0,
-		randWriteIOPS: 80000.0,
-		seqReadBW:     1200.0,
-		seqWriteBW:    1200.0,
-	},
-	"h3-standard-88": {
-		randReadIOPS:  15000.0,
-		randWriteIOPS: 15000.0,
-		seqReadBW:     240.0,
-		seqWriteBW:    240.0,
-	},
-	"t2a-standard-48": {
-		randReadIOPS:  80000.0,
-		randWriteIOPS: 80000.0,
-		seqReadBW:     1800.0,
-		seqWriteBW:    1800.0,
-	},
-}
-
 // The mount disk size should be large enough that size*iopsPerGB is equal to the iops performance target
 // https://cloud.google.com/compute/docs/disks/performance#iops_limits_for_zonal
 // https://cloud.google.com/compute/docs/disks/hyperdisks#iops_for
@@ -216,8 +83,27 @@ type FIOStatistics struct {
 	// Bandwidth should be able to convert to an int64
 	Bandwidth json.Number `json:"bw,omitempty"`
 	// IOPS should be able to convert to a float64
-	IOPS json.Number    `json:iops,omitempty"`
-	X    map[string]any `json:"-"`
+	IOPS json.Number `json:"iops,omitempty"`
+	// CompletionLatencyNs is the completion latency in nanoseconds, and
+	// CompletionLatencyUs the one in microseconds of older fio versions.
+	CompletionLatencyNs FIOLatency     `json:"clat_ns,omitempty"`
+	CompletionLatencyUs FIOLatency     `json:"clat,omitempty"`
+	X                   map[string]any `json:"-"`
+}
+
+// FIOLatency gives the percentiles of a latency, keyed like "99.000000".
+type FIOLatency struct {
+	Percentile map[string]float64 `json:"percentile,omitempty"`
+}
+
+// completionLatencyUs returns the percentile of the completion latency in
+// microseconds.
+func (s FIOStatistics) completionLatencyUs(percentile string) (float64, bool) {
+	if ns, ok := s.CompletionLatencyNs.Percentile[percentile]; ok {
+		return ns / 1000, true
+	}
+	us, ok := s.CompletionLatencyUs.Percentile[percentile]
+	return us, ok
 }
 
 // installFioWindows copies the fio.exe file onto the VM instance.
@@ -339,29 +225,6 @@ func collectLSSDs(ctx context.Context) (string, error) {
 	return diskPath, nil
 }
 
-// diskClass should be one of "pd", "hyperdisk", "lssd"
-func getFIOOptions(mode, diskClass string) string {
-	switch diskClass {
-	case "hyperdisk":
-		if mode == randRead || mode == randWrite {
-			return hyperdiskFIORandOptions + " --rw=" + mode
-		}
-		return hyperdiskFIOSeqOptions + " --rw=" + mode
-	case "lssd":
-		if mode == randRead || mode == randWrite {
-			return lssdFIORandOptions + " --rw=" + mode
-		}
-		return lssdFIOSeqOptions + " --rw=" + mode
-	case "pd":
-		fallthrough
-	default:
-		if mode == randRead || mode == randWrite {
-			return commonFIORandOptions + " --rw=" + mode
-		}
-		return commonFIOSeqOptions + " --rw=" + mode
-	}
-}
-
 // check if a known zypper backend error is found
 func checkZypperTransientError(err error, stdout, stderr string) error {
 	exitErr, foundErr := err.(*exec.ExitError)
@@ -393,6 +256,54 @@ func getDiskClass(ctx context.Context) string {
 	return "pd"
 }
 
+// getWorkloads gets the workload matrix of the test case.
+func getWorkloads(ctx context.Context) ([]fioWorkload, error) {
+	workloadsJSON, err := utils.GetMetadata(ctx, "instance", "attributes", workloadsAttribute)
+	if err != nil {
+		return nil, fmt.Errorf("could not get metadata attribute %s: err %v", workloadsAttribute, err)
+	}
+	var workloads []fioWorkload
+	if err := json.Unmarshal([]byte(workloadsJSON), &workloads); err != nil {
+		return nil, fmt.Errorf("could not parse metadata attribute %s: err %v", workloadsAttribute, err)
+	}
+	return workloads, nil
+}
+
+// getSampling returns the number of samples of each workload, and the
+// selection of the samples the thresholds apply to.
+func getSampling(ctx context.Context) (int, utils.SampleSelection, error) {
+	repeatStr, err := utils.GetMetadata(ctx, "instance", "attributes", repeatAttribute)
+	if err != nil {
+		return 0, "", fmt.Errorf("could not get metadata attribute %s: err %v", repeatAttribute, err)
+	}
+	repeat, err := strconv.Atoi(repeatStr)
+	if err != nil || repeat < 1 {
+		return 0, "", fmt.Errorf("invalid metadata attribute %s %q, want a positive integer", repeatAttribute, repeatStr)
+	}
+	statisticStr, err := utils.GetMetadata(ctx, "instance", "attributes", statisticAttribute)
+	if err != nil {
+		return 0, "", fmt.Errorf("could not get metadata attribute %s: err %v", statisticAttribute, err)
+	}
+	sel, err := utils.ParseSampleSelection(statisticStr)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid metadata attribute %s: %v", statisticAttribute, err)
+	}
+	return repeat, sel, nil
+}
+
+// metricLabels returns the labels of the recorded performance metrics, which
+// tell apart the machine and disk types of the test VMs.
+func metricLabels(ctx context.Context) map[string]string {
+	labels := make(map[string]string)
+	if machineType, err := utils.GetMetadata(ctx, "instance", "machine-type"); err == nil {
+		labels["machine_type"] = machineType[strings.LastIndex(machineType, "/")+1:]
+	}
+	if diskType, err := utils.GetMetadata(ctx, "instance", "attributes", diskTypeAttribute); err == nil {
+		labels["disk_type"] = diskType
+	}
+	return labels
+}
+
 // function to get num numa nodes
 // TODO: implement this for windows hyperdisk
 func getNumNumaNodes() (int, error) {
@@ -494,10 +405,10 @@ func installFioAndFillDisk(symlinkRealPath, diskClass string, t *testing.T) erro
 	return nil
 }
 
-func runFIOLinux(t *testing.T, mode string) ([]byte, error) {
+func runFIOLinux(t *testing.T, w fioWorkload) ([]byte, error) {
 	ctx := utils.Context(t)
 	diskClass := getDiskClass(ctx)
-	options := getFIOOptions(mode, diskClass)
+	options := fioOptions(w, diskClass)
 
 	var diskPath string
 	if diskClass == "lssd" {
@@ -516,15 +427,16 @@ func runFIOLinux(t *testing.T, mode string) ([]byte, error) {
 			return []byte{}, err
 		}
 	}
-	// ubuntu 16.04 has a different option name due to an old fio version
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		return []byte{}, fmt.Errorf("couldn't get image from metadata")
+		return []byte{}, fmt.Errorf("couldn't get guest OS: %v", err)
 	}
-	if strings.Contains(image, "ubuntu-pro-1604") {
-		options = strings.Replace(options, "iodepth_batch_complete_max", "iodepth_batch_complete", 1)
+	isUbuntu := guestOS.Distro == utils.DistroUbuntu
+	// ubuntu 16.04 has a different option name due to an old fio version
+	if isUbuntu && guestOS.Version == "16.04" {
+		options = strings.ReplaceAll(options, "iodepth_batch_complete_max", "iodepth_batch_complete")
 	}
-	if utils.IsUbuntu(image) && (strings.Contains(image, "1804") || strings.Contains(image, "1604")) {
+	if isUbuntu && (guestOS.Version == "18.04" || guestOS.Version == "16.04") {
 		err := installPkgLinux("libnuma-dev")
 		if err != nil {
 			return nil, err
@@ -545,6 +457,8 @@ func runFIOLinux(t *testing.T, mode string) ([]byte, error) {
 			t.Fatalf("failed to get hyperdisk additional options: error %v", err)
 		}
 		options += hyperdiskAdditionalOptions
+	} else {
+		options += " --name=" + w.Name
 	}
 	randCmd := exec.Command(fioCmdNameLinux, strings.Fields(options)...)
 	IOPSJson, err := randCmd.CombinedOutput()
@@ -554,7 +468,7 @@ func runFIOLinux(t *testing.T, mode string) ([]byte, error) {
 	return IOPSJson, nil
 }
 
-func runFIOWindows(t *testing.T, mode string) ([]byte, error) {
+func runFIOWindows(t *testing.T, w fioWorkload) ([]byte, error) {
 	IOPSFile := "C:\\fio-iops.txt"
 	ctx := utils.Context(t)
 	// TODO: hyperdisk testing is not yet implemented for windows
@@ -562,7 +476,7 @@ func runFIOWindows(t *testing.T, mode string) ([]byte, error) {
 	if diskClass == "hyperdisk" {
 		diskClass = "pd"
 	}
-	fiopOptions := getFIOOptions(mode, diskClass)
+	fiopOptions := fioOptions(w, diskClass)
 	diskPath := `\\.\PhysicalDrive1`
 	if diskClass == "lssd" {
 		var err error
@@ -571,7 +485,7 @@ func runFIOWindows(t *testing.T, mode string) ([]byte, error) {
 			return nil, err
 		}
 	}
-	fioOptionsWindows := " -ArgumentList \"" + fiopOptions + " --output=" + IOPSFile + " --filename=" + diskPath + " --ioengine=windowsaio" + " --thread\"" + " -wait"
+	fioOptionsWindows := " -ArgumentList \"" + fiopOptions + " --output=" + IOPSFile + " --filename=" + diskPath + " --ioengine=windowsaio" + " --thread" + " --name=" + w.Name + "\"" + " -wait"
 	// fioWindowsLocalPath is defined within storage_perf_utils.go
 	if procStatus, err := utils.RunPowershellCmd("Start-Process " + fioWindowsLocalPath + fioOptionsWindows); err != nil {
 		return []byte{}, fmt.Errorf("fio.exe returned with error: %v %s %s", err, procStatus.Stdout, procStatus.Stderr)
@@ -586,31 +500,88 @@ func runFIOWindows(t *testing.T, mode string) ([]byte, error) {
 
 // get the minimum mount disk size required to reach the iops target.
 // default to 3500GB if this calculation fails.
-func getRequiredDiskSize(machineType, diskType string) int64 {
+func getRequiredDiskSize(diskType string, randReadIOPS float64) int64 {
 	if diskType == "lssd" {
 		return 0
 	}
 	// mount disks should always be at least 3500GB, as a testing convention.
 	var minimumDiskSizeGB int64 = 3500
-	var iopsTargetStruct PerformanceTargets
-	var iopsTargetFound bool
-	if diskType == imagetest.PdBalanced {
-		iopsTargetStruct, iopsTargetFound = pdbalanceIOPSMap[machineType]
-
-	} else if diskType == imagetest.HyperdiskExtreme {
-		iopsTargetStruct, iopsTargetFound = hyperdiskExtremeIOPSMap[machineType]
-	} else if diskType == imagetest.HyperdiskBalanced {
-		iopsTargetStruct, iopsTargetFound = hyperdiskBalancedIOPSMap[machineType]
-	} else if diskType == imagetest.HyperdiskThroughput {
-		iopsTargetStruct, iopsTargetFound = hyperdiskThroughputIOPSMap[machineType]
-	}
-
-	iopsPerGB, diskTypeFound := iopsPerGBMap[diskType]
-	if iopsTargetFound && diskTypeFound {
-		calculatedDiskSizeGB := int64(iopsTargetStruct.randReadIOPS / float64(iopsPerGB))
+	if iopsPerGB, diskTypeFound := iopsPerGBMap[diskType]; diskTypeFound {
+		calculatedDiskSizeGB := int64(randReadIOPS / float64(iopsPerGB))
 		if calculatedDiskSizeGB > minimumDiskSizeGB {
 			return calculatedDiskSizeGB
 		}
 	}
 	return minimumDiskSizeGB
 }
+
+// runWorkload runs the workload with FIO repeat times, records the metrics of
+// every sample and checks the selected sample of each metric against the
+// thresholds of the workload, the worst sample being the lowest against a
+// minimum and the highest against a maximum.
+func runWorkload(t *testing.T, w fioWorkload) {
+	repeat, sel, err := getSampling(utils.Context(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples [][]fioMetric
+	for i := 0; i < repeat; i++ {
+		var output []byte
+		if runtime.GOOS == "windows" {
+			if output, err = runFIOWindows(t, w); err != nil {
+				t.Fatalf("windows fio %s failed with error: %v. If testing locally, check the guidance at storageperf/startupscripts/install_fio.ps1", w.Name, err)
+			}
+		} else {
+			if output, err = runFIOLinux(t, w); err != nil {
+				t.Fatalf("linux fio %s failed with error: %v", w.Name, err)
+			}
+		}
+		metrics, err := parseFIOResult(w, output)
+		if err != nil {
+			t.Fatalf("could not parse fio %s result of sample %d: %v", w.Name, i, err)
+		}
+		samples = append(samples, metrics)
+	}
+
+	labels := metricLabels(utils.Context(t))
+	var metrics []fioSummary
+	for _, m := range samples[0] {
+		var values []float64
+		for _, sample := range samples {
+			i := slices.IndexFunc(sample, func(s fioMetric) bool { return s.name == m.name })
+			if i >= 0 {
+				values = append(values, sample[i].value)
+			}
+		}
+		summary := utils.RecordSamples(t, m.name, values, m.unit, labels)
+		metrics = append(metrics, fioSummary{name: m.name, summary: summary, unit: m.unit})
+	}
+
+	// suppress the error because the vm name is only for printing out test results, and does not affect test behavior
+	machineName, _ := utils.GetInstanceName(utils.Context(t))
+	sampling := ""
+	if repeat > 1 {
+		sampling = fmt.Sprintf(" (%s of %d samples)", sel, repeat)
+	}
+	errs := checkThresholds(w, metrics, sel)
+	for _, err := range errs {
+		t.Errorf("performance for vm %s%s: %v", machineName, sampling, err)
+	}
+	if len(errs) == 0 {
+		t.Logf("%s test pass for vm %s%s with thresholds %+v", w.Name, machineName, sampling, w.Thresholds)
+	}
+}
+
+// runNamedWorkload runs the workload of the matrix of the test case with the
+// name, and skips the test if the matrix doesn't have it.
+func runNamedWorkload(t *testing.T, name string) {
+	workloads, err := getWorkloads(utils.Context(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(workloads, func(w fioWorkload) bool { return w.Name == name })
+	if i < 0 {
+		t.Skipf("the workload matrix has no %s workload", name)
+	}
+	runWorkload(t, workloads[i])
+}
@@ -0,0 +1,303 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageperf
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+const (
+	// constants for the mixed modes of running the test
+	randReadWrite = "randrw"
+	seqReadWrite  = "rw"
+	// units of the recorded metrics
+	iopsUnit        = "IOPS"
+	mbpsUnit        = "MBps"
+	microsecondUnit = "us"
+	// workloadsAttribute is the guest attribute storing the workload matrix of the test case as JSON.
+	workloadsAttribute = "fioWorkloads"
+	// repeatAttribute is the guest attribute storing the number of samples of each workload.
+	repeatAttribute = "fioRepeat"
+	// statisticAttribute is the guest attribute storing the selection of the samples the thresholds apply to.
+	statisticAttribute = "fioStatistic"
+)
+
+// fioWorkload is a FIO workload of the workload matrix of a test case.
+type fioWorkload struct {
+	// Name prefixes the names of the metrics of the workload, like
+	// rand_read_iops.
+	Name string `json:"name"`
+	// RW is the I/O pattern of FIO: randread, randwrite, read, write, or the
+	// mixed randrw and rw.
+	RW        string `json:"rw"`
+	BlockSize string `json:"bs"`
+	IODepth   int    `json:"iodepth"`
+	// NumJobs is 1 on PD and 8 on other disk classes when unset.
+	NumJobs int `json:"numjobs,omitempty"`
+	// ReadPercent is the percentage of reads of the mixed I/O patterns.
+	ReadPercent int `json:"rwmixread,omitempty"`
+	// Runtime is a FIO duration, 1m on PD and 5m on other disk classes when
+	// unset.
+	Runtime string `json:"runtime,omitempty"`
+	// Required are the metrics of the workload which must have a performance
+	// target, like rand_read_iops.
+	Required []string `json:"-"`
+	// Thresholds are set by the setup from the performance targets of the
+	// metrics of the workload.
+	Thresholds []fioThreshold `json:"thresholds,omitempty"`
+}
+
+// fioThreshold is the range a metric of a workload must be in, from its
+// performance target. Latencies have a maximum, other metrics a minimum.
+type fioThreshold struct {
+	Metric string  `json:"metric"`
+	Min    float64 `json:"min,omitempty"`
+	Max    float64 `json:"max,omitempty"`
+}
+
+// fioMetric is a metric measured by a workload.
+type fioMetric struct {
+	name  string
+	value float64
+	unit  string
+}
+
+// fioSummary summarizes the samples of a metric measured by a workload.
+type fioSummary struct {
+	name    string
+	summary utils.SampleSummary
+	unit    string
+}
+
+// The default workloads of the test cases, whose names match the metrics of
+// the performance targets.
+var defaultWorkloads = []fioWorkload{
+	{Name: "rand_read", RW: randRead, BlockSize: "4K", IODepth: 256, Required: []string{"rand_read_iops"}},
+	{Name: "rand_write", RW: randWrite, BlockSize: "4K", IODepth: 256, Required: []string{"rand_write_iops"}},
+	{Name: "seq_read", RW: seqRead, BlockSize: "1M", IODepth: 64, Required: []string{"seq_read_bandwidth"}},
+	{Name: "seq_write", RW: seqWrite, BlockSize: "1M", IODepth: 64, Required: []string{"seq_write_bandwidth"}},
+}
+
+// latencyWorkloads measure the latencies of database-like workloads: a
+// mixed workload of small blocks, and reads at a queue depth of 1.
+var latencyWorkloads = []fioWorkload{
+	{Name: "oltp", RW: randReadWrite, BlockSize: "16K", IODepth: 32, NumJobs: 4, ReadPercent: 70, Runtime: "2m"},
+	{Name: "rand_read_qd1", RW: randRead, BlockSize: "4K", IODepth: 1, NumJobs: 1, Runtime: "1m"},
+}
+
+// withLatencyWorkloads returns the default workloads followed by the latency
+// workloads.
+func withLatencyWorkloads() []fioWorkload {
+	return slices.Concat(defaultWorkloads, latencyWorkloads)
+}
+
+// measures are the measures of each direction of a workload, and their units.
+var measures = []struct {
+	name, unit string
+	// percentile is the completion latency percentile of latency measures.
+	percentile string
+}{
+	{name: "iops", unit: iopsUnit},
+	{name: "bandwidth", unit: mbpsUnit},
+	{name: "p50_latency", unit: microsecondUnit, percentile: "50.000000"},
+	{name: "p99_latency", unit: microsecondUnit, percentile: "99.000000"},
+	{name: "p999_latency", unit: microsecondUnit, percentile: "99.900000"},
+}
+
+var workloadNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func (w fioWorkload) mixed() bool {
+	return w.RW == randReadWrite || w.RW == seqReadWrite
+}
+
+func (w fioWorkload) sequential() bool {
+	return w.RW == seqRead || w.RW == seqWrite || w.RW == seqReadWrite
+}
+
+// directions returns the directions measured by the workload, read and write.
+func (w fioWorkload) directions() []string {
+	switch w.RW {
+	case randRead, seqRead:
+		return []string{"read"}
+	case randWrite, seqWrite:
+		return []string{"write"}
+	}
+	return []string{"read", "write"}
+}
+
+// metricName returns the name of the metric of the measure in the direction.
+// The metrics of mixed workloads include the direction, like oltp_read_iops.
+func (w fioWorkload) metricName(direction, measure string) string {
+	if w.mixed() {
+		return w.Name + "_" + direction + "_" + measure
+	}
+	return w.Name + "_" + measure
+}
+
+// metricUnits returns the names of the metrics of the workload and their units.
+func (w fioWorkload) metricUnits() map[string]string {
+	units := make(map[string]string)
+	for _, d := range w.directions() {
+		for _, m := range measures {
+			units[w.metricName(d, m.name)] = m.unit
+		}
+	}
+	return units
+}
+
+// validateWorkloads checks that the workloads of a matrix are valid FIO
+// workloads with distinct names.
+func validateWorkloads(workloads []fioWorkload) error {
+	names := make(map[string]bool)
+	for _, w := range workloads {
+		if !workloadNameRegex.MatchString(w.Name) {
+			return fmt.Errorf("invalid workload name %q", w.Name)
+		}
+		if names[w.Name] {
+			return fmt.Errorf("duplicate workload name %q", w.Name)
+		}
+		names[w.Name] = true
+		switch w.RW {
+		case randRead, randWrite, seqRead, seqWrite, randReadWrite, seqReadWrite:
+		default:
+			return fmt.Errorf("workload %s: invalid rw %q", w.Name, w.RW)
+		}
+		if w.BlockSize == "" || w.IODepth <= 0 || w.NumJobs < 0 {
+			return fmt.Errorf("workload %s: the block size and a positive iodepth are required", w.Name)
+		}
+		if w.mixed() != (w.ReadPercent > 0 && w.ReadPercent < 100) {
+			return fmt.Errorf("workload %s: mixed workloads, and only them, need a read percentage between 1 and 99, got %d", w.Name, w.ReadPercent)
+		}
+		units := w.metricUnits()
+		for _, metric := range w.Required {
+			if _, ok := units[metric]; !ok {
+				return fmt.Errorf("workload %s: required metric %s is not measured by the workload", w.Name, metric)
+			}
+		}
+	}
+	return nil
+}
+
+// fioOptions returns the FIO options of the workload on the disk class,
+// without the job name, file name and I/O engine.
+func fioOptions(w fioWorkload, diskClass string) string {
+	numJobs, runtime, rampTime := 8, "5m", "10s"
+	if diskClass != "hyperdisk" && diskClass != "lssd" {
+		numJobs, runtime, rampTime = 1, "1m", "2s"
+	}
+	if w.NumJobs > 0 {
+		numJobs = w.NumJobs
+	}
+	if w.Runtime != "" {
+		runtime = w.Runtime
+	}
+	options := fmt.Sprintf("--rw=%s --bs=%s --iodepth=%d --iodepth_batch_submit=%d --iodepth_batch_complete_max=%d --numjobs=%d --time_based --runtime=%s --ramp_time=%s --direct=1 --verify=0 --randrepeat=0 --percentile_list=50:99:99.9 --output-format=json",
+		w.RW, w.BlockSize, w.IODepth, w.IODepth, w.IODepth, numJobs, runtime, rampTime)
+	if w.mixed() {
+		options += " --rwmixread=" + strconv.Itoa(w.ReadPercent)
+	}
+	switch diskClass {
+	case "hyperdisk":
+		options += " --size=500G --group_reporting"
+	case "lssd":
+		options += " --size=500G --invalidate=1 --verify_fatal=0 --group_reporting"
+	default:
+		options += " --filesize=500G"
+	}
+	if w.sequential() {
+		if diskClass == "hyperdisk" || diskClass == "lssd" {
+			options += " --offset_increment=20G"
+		} else {
+			options += " --offset_increment=500G"
+		}
+	}
+	return options
+}
+
+// parseFIOResult returns the metrics of the workload from the JSON output of
+// FIO. IOPS and bandwidths are summed over the jobs, and latencies are the
+// highest of the jobs.
+func parseFIOResult(w fioWorkload, output []byte) ([]fioMetric, error) {
+	var fioOut FIOOutput
+	if err := json.Unmarshal(output, &fioOut); err != nil {
+		return nil, fmt.Errorf("fio output %s could not be unmarshalled with error: %v", string(output), err)
+	}
+	if len(fioOut.Jobs) == 0 {
+		return nil, fmt.Errorf("fio output %s has no jobs", string(output))
+	}
+	var metrics []fioMetric
+	for _, d := range w.directions() {
+		values := make(map[string]float64)
+		for _, job := range fioOut.Jobs {
+			stats := job.ReadResult
+			if d == "write" {
+				stats = job.WriteResult
+			}
+			iops, err := stats.IOPS.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("iops json number %s was not a float: %v", stats.IOPS.String(), err)
+			}
+			values["iops"] += iops
+			bandwidth, err := stats.Bandwidth.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("bandwidth units per second %s was not a number: %v", stats.Bandwidth.String(), err)
+			}
+			values["bandwidth"] += bandwidth * fioBWToBytes / bytesInMB
+			for _, m := range measures {
+				if m.percentile == "" {
+					continue
+				}
+				latency, ok := stats.completionLatencyUs(m.percentile)
+				if !ok {
+					return nil, fmt.Errorf("fio output has no %s completion latency percentile for %s", m.percentile, d)
+				}
+				values[m.name] = max(values[m.name], latency)
+			}
+		}
+		for _, m := range measures {
+			metrics = append(metrics, fioMetric{name: w.metricName(d, m.name), value: values[m.name], unit: m.unit})
+		}
+	}
+	return metrics, nil
+}
+
+// checkThresholds returns the errors of the metrics outside the thresholds of
+// the workload. The selection picks the sample checked against each bound of
+// a threshold, so that the worst sample is the lowest against a minimum and
+// the highest against a maximum.
+func checkThresholds(w fioWorkload, metrics []fioSummary, sel utils.SampleSelection) []error {
+	var errs []error
+	for _, threshold := range w.Thresholds {
+		i := slices.IndexFunc(metrics, func(m fioSummary) bool { return m.name == threshold.Metric })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("metric %s of the threshold was not measured", threshold.Metric))
+			continue
+		}
+		m := metrics[i]
+		if value := m.summary.Value(sel.Statistic(true)); threshold.Min > 0 && value < threshold.Min {
+			errs = append(errs, fmt.Errorf("%s was too low: got %f %s, want at least %f", m.name, value, m.unit, threshold.Min))
+		}
+		if value := m.summary.Value(sel.Statistic(false)); threshold.Max > 0 && value > threshold.Max {
+			errs = append(errs, fmt.Errorf("%s was too high: got %f %s, want at most %f", m.name, value, m.unit, threshold.Max))
+		}
+	}
+	return errs
+}
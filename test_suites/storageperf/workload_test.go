@@ -0,0 +1,268 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageperf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const fioMixedOutput = `{
+  "fio version": "fio-3.33",
+  "jobs": [
+    {
+      "jobname": "oltp",
+      "read": {
+        "bw": 102400,
+        "iops": 6400.5,
+        "clat_ns": {"percentile": {"50.000000": 250000, "99.000000": 1200000, "99.900000": 3000000}}
+      },
+      "write": {
+        "bw": 51200,
+        "iops": 3200.25,
+        "clat_ns": {"percentile": {"50.000000": 400000, "99.000000": 2000000, "99.900000": 5000000}}
+      }
+    },
+    {
+      "jobname": "oltp",
+      "read": {
+        "bw": 102400,
+        "iops": 6400.5,
+        "clat_ns": {"percentile": {"50.000000": 260000, "99.000000": 1100000, "99.900000": 3500000}}
+      },
+      "write": {
+        "bw": 51200,
+        "iops": 3200.25,
+        "clat_ns": {"percentile": {"50.000000": 390000, "99.000000": 2100000, "99.900000": 4000000}}
+      }
+    }
+  ]
+}`
+
+// fioOldOutput is the output of fio versions before 3, with latencies in
+// microseconds.
+const fioOldOutput = `{
+  "jobs": [
+    {
+      "read": {
+        "bw": 1048576,
+        "iops": 1024,
+        "clat": {"percentile": {"50.000000": 900, "99.000000": 1500, "99.900000": 2200}}
+      }
+    }
+  ]
+}`
+
+func TestValidateWorkloads(t *testing.T) {
+	tests := []struct {
+		name      string
+		workloads []fioWorkload
+		wantErr   bool
+	}{
+		{name: "default", workloads: defaultWorkloads},
+		{name: "latency", workloads: withLatencyWorkloads()},
+		{name: "duplicate", workloads: []fioWorkload{defaultWorkloads[0], defaultWorkloads[0]}, wantErr: true},
+		{name: "invalid name", workloads: []fioWorkload{{Name: "Rand Read", RW: randRead, BlockSize: "4K", IODepth: 1}}, wantErr: true},
+		{name: "invalid rw", workloads: []fioWorkload{{Name: "trim", RW: "trim", BlockSize: "4K", IODepth: 1}}, wantErr: true},
+		{name: "no iodepth", workloads: []fioWorkload{{Name: "r", RW: randRead, BlockSize: "4K"}}, wantErr: true},
+		{name: "mixed without read percent", workloads: []fioWorkload{{Name: "m", RW: randReadWrite, BlockSize: "4K", IODepth: 1}}, wantErr: true},
+		{name: "read percent of a read", workloads: []fioWorkload{{Name: "r", RW: randRead, BlockSize: "4K", IODepth: 1, ReadPercent: 50}}, wantErr: true},
+		{name: "unknown required metric", workloads: []fioWorkload{{Name: "r", RW: randRead, BlockSize: "4K", IODepth: 1, Required: []string{"r_write_iops"}}}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateWorkloads(tc.workloads); (err != nil) != tc.wantErr {
+				t.Errorf("validateWorkloads() = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFIOOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		workload  fioWorkload
+		diskClass string
+		want      []string
+		dontWant  []string
+	}{
+		{
+			name:      "pd random",
+			workload:  defaultWorkloads[0],
+			diskClass: "pd",
+			want:      []string{"--rw=randread", "--bs=4K", "--iodepth=256", "--iodepth_batch_complete_max=256", "--numjobs=1", "--runtime=1m", "--filesize=500G", "--percentile_list=50:99:99.9"},
+			dontWant:  []string{"--offset_increment", "--group_reporting", "--rwmixread"},
+		},
+		{
+			name:      "hyperdisk sequential",
+			workload:  defaultWorkloads[3],
+			diskClass: "hyperdisk",
+			want:      []string{"--rw=write", "--bs=1M", "--numjobs=8", "--runtime=5m", "--ramp_time=10s", "--group_reporting", "--offset_increment=20G"},
+		},
+		{
+			name:      "lssd mixed",
+			workload:  latencyWorkloads[0],
+			diskClass: "lssd",
+			want:      []string{"--rw=randrw", "--rwmixread=70", "--bs=16K", "--numjobs=4", "--runtime=2m", "--invalidate=1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			options := strings.Fields(fioOptions(tc.workload, tc.diskClass))
+			for _, want := range tc.want {
+				if !strings.Contains(strings.Join(options, " "), want) {
+					t.Errorf("fioOptions(%s, %s) = %v, want it to contain %s", tc.workload.Name, tc.diskClass, options, want)
+				}
+			}
+			for _, dontWant := range tc.dontWant {
+				if strings.Contains(strings.Join(options, " "), dontWant) {
+					t.Errorf("fioOptions(%s, %s) = %v, want it not to contain %s", tc.workload.Name, tc.diskClass, options, dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFIOResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		workload fioWorkload
+		output   string
+		want     []fioMetric
+		wantErr  bool
+	}{
+		{
+			name:     "mixed",
+			workload: latencyWorkloads[0],
+			output:   fioMixedOutput,
+			want: []fioMetric{
+				{name: "oltp_read_iops", value: 12801, unit: iopsUnit},
+				{name: "oltp_read_bandwidth", value: 200, unit: mbpsUnit},
+				{name: "oltp_read_p50_latency", value: 260, unit: microsecondUnit},
+				{name: "oltp_read_p99_latency", value: 1200, unit: microsecondUnit},
+				{name: "oltp_read_p999_latency", value: 3500, unit: microsecondUnit},
+				{name: "oltp_write_iops", value: 6400.5, unit: iopsUnit},
+				{name: "oltp_write_bandwidth", value: 100, unit: mbpsUnit},
+				{name: "oltp_write_p50_latency", value: 400, unit: microsecondUnit},
+				{name: "oltp_write_p99_latency", value: 2100, unit: microsecondUnit},
+				{name: "oltp_write_p999_latency", value: 5000, unit: microsecondUnit},
+			},
+		},
+		{
+			name:     "old fio",
+			workload: defaultWorkloads[2],
+			output:   fioOldOutput,
+			want: []fioMetric{
+				{name: "seq_read_iops", value: 1024, unit: iopsUnit},
+				{name: "seq_read_bandwidth", value: 1024, unit: mbpsUnit},
+				{name: "seq_read_p50_latency", value: 900, unit: microsecondUnit},
+				{name: "seq_read_p99_latency", value: 1500, unit: microsecondUnit},
+				{name: "seq_read_p999_latency", value: 2200, unit: microsecondUnit},
+			},
+		},
+		{
+			name:     "missing percentiles",
+			workload: defaultWorkloads[0],
+			output:   `{"jobs": [{"read": {"bw": 1, "iops": 1}}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "no jobs",
+			workload: defaultWorkloads[0],
+			output:   `{"jobs": []}`,
+			wantErr:  true,
+		},
+		{
+			name:     "not json",
+			workload: defaultWorkloads[0],
+			output:   "fio: pid=0, err=2/file:filesetup.c:174, func=open, error=No such file or directory",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFIOResult(tc.workload, []byte(tc.output))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseFIOResult() = %v, want error: %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(fioMetric{}), cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("parseFIOResult() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	metrics := []fioSummary{
+		{name: "oltp_read_iops", summary: utils.SummarizeSamples([]float64{8000, 10000, 12000}), unit: iopsUnit},
+		{name: "oltp_read_p99_latency", summary: utils.SummarizeSamples([]float64{1000, 1500, 2500}), unit: microsecondUnit},
+	}
+	tests := []struct {
+		name       string
+		thresholds []fioThreshold
+		sel        utils.SampleSelection
+		wantErrs   int
+	}{
+		{name: "met", thresholds: []fioThreshold{{Metric: "oltp_read_iops", Min: 9000}, {Metric: "oltp_read_p99_latency", Max: 2000}}, sel: utils.SelectMedian},
+		{name: "low iops", thresholds: []fioThreshold{{Metric: "oltp_read_iops", Min: 11000}}, sel: utils.SelectMedian, wantErrs: 1},
+		{name: "high latency", thresholds: []fioThreshold{{Metric: "oltp_read_p99_latency", Max: 1200}}, sel: utils.SelectMedian, wantErrs: 1},
+		{name: "unmeasured", thresholds: []fioThreshold{{Metric: "oltp_write_iops", Min: 1}}, sel: utils.SelectMedian, wantErrs: 1},
+		{name: "worst iops is the lowest", thresholds: []fioThreshold{{Metric: "oltp_read_iops", Min: 9000}}, sel: utils.SelectWorst, wantErrs: 1},
+		{name: "worst latency is the highest", thresholds: []fioThreshold{{Metric: "oltp_read_p99_latency", Max: 2000}}, sel: utils.SelectWorst, wantErrs: 1},
+		{name: "best iops is the highest", thresholds: []fioThreshold{{Metric: "oltp_read_iops", Min: 11000}}, sel: utils.SelectBest},
+		{name: "best latency is the lowest", thresholds: []fioThreshold{{Metric: "oltp_read_p99_latency", Max: 1200}}, sel: utils.SelectBest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := fioWorkload{Name: "oltp", Thresholds: tc.thresholds}
+			if errs := checkThresholds(w, metrics, tc.sel); len(errs) != tc.wantErrs {
+				t.Errorf("checkThresholds() = %v, want %d errors", errs, tc.wantErrs)
+			}
+		})
+	}
+}
+
+func TestWorkloadThresholds(t *testing.T) {
+	query := perftargets.Query{MachineType: "c3-standard-88", VCPUs: 88, DiskType: "pd-balanced"}
+	workloads, err := workloadThresholds(withLatencyWorkloads(), query)
+	if err != nil {
+		t.Fatalf("workloadThresholds() failed: %v", err)
+	}
+	got := make(map[string][]fioThreshold)
+	for _, w := range workloads {
+		got[w.Name] = w.Thresholds
+	}
+	want := map[string][]fioThreshold{
+		"rand_read":     {{Metric: "rand_read_iops", Min: 80000 * perftargets.DefaultTolerance}},
+		"rand_write":    {{Metric: "rand_write_iops", Min: 80000 * perftargets.DefaultTolerance}},
+		"seq_read":      {{Metric: "seq_read_bandwidth", Min: 1200 * perftargets.DefaultTolerance}},
+		"seq_write":     {{Metric: "seq_write_bandwidth", Min: 1200 * perftargets.DefaultTolerance}},
+		"oltp":          nil,
+		"rand_read_qd1": nil,
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("workloadThresholds() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	query.DiskType = "pd-standard"
+	if _, err := workloadThresholds(defaultWorkloads, query); err == nil {
+		t.Errorf("workloadThresholds() for a disk type without targets = nil, want error")
+	}
+}
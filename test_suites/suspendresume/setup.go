@@ -16,43 +16,27 @@
 package suspendresume
 
 import (
-	"strings"
-
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
 	"github.com/GoogleCloudPlatform/compute-daisy"
 	"google.golang.org/api/compute/v1"
 )
 
-var (
-	// Name is the name of the test package. It must match the directory name.
-	Name = "suspendresume"
-	// unsupportedImages is a list of images which do not support suspend resume. These strings will be match against the name field.
-	unsupportedImages = []string{
-		"windows-server-2025",
-		"windows-2025-dc",
-		// TODO(b/360913029): Remove skips for windows once b/356460144 is fixed.
-		"windows-11-24h2",
-		"windows-11-25h2",
-		"windows-server-2012-r2",
-		"rhel-8-2-sap",
-		"rhel-8-1-sap",
-		"debian-10",
-		"ubuntu-pro-1804-bionic-arm64",
-		"sles-15-sp7", // TODO(b/428736040): Investigate why suspend is failing on SLES 15 SP7
-	}
-)
+// Name is the name of the test package. It must match the directory name.
+var Name = "suspendresume"
+
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description:   "Tests suspending and resuming VMs.",
+		Architectures: []string{"X86_64"},
+		ExcludedImages: []imagetest.ImageExclusion{
+			{Regexp: "windows-server-2025|windows-2025-dc", Reason: "Suspend is not supported on Windows Server 2025."},
+			{Regexp: "rhel-8-[12]-sap|debian-10|ubuntu-pro-1804-bionic-arm64", Reason: "Suspend is not supported on these images."},
+		},
+	})
+}
 
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if t.Image.Architecture == "ARM64" {
-		return nil
-	}
-
-	for _, match := range unsupportedImages {
-		if strings.Contains(t.Image.Name, match) {
-			return nil
-		}
-	}
 	suspend := &daisy.Instance{}
 	suspend.Scopes = append(suspend.Scopes, "https://www.googleapis.com/auth/cloud-platform")
 	suspendvm, err := t.CreateTestVMMultipleDisks([]*compute.Disk{{Name: "suspend"}}, suspend)
@@ -50,14 +50,16 @@ func init() {
 	machineTypeCounter = rand.Intn(len(machineTypes))
 }
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests that things work after VM spec changes.",
+		// No ARM64-supporting machine types support LSSDs.
+		Architectures: []string{"X86_64"},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	// Skip ARM64 images, since no ARM64-supporting machine types support LSSDs.
-	if t.Image.Architecture == "ARM64" {
-		t.Skip("vmspec not supported on ARM images")
-		return nil
-	}
-
 	// Create new networks and subnetworks for multinic.
 	network1, err := t.CreateNetwork("test-network", false)
 	if err != nil {
@@ -101,11 +101,11 @@ func TestPing(t *testing.T) {
 	}
 	command := "ping"
 
-	image, err := utils.GetMetadata(ctx, "instance", "image")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Fatalf("could not determine image: %v", err)
+		t.Fatalf("could not determine guest OS: %v", err)
 	}
-	if utils.IsCOS(image) {
+	if guestOS.Distro == utils.DistroCOS {
 		command = "toolbox"
 		baseArgs = append([]string{"ping"}, baseArgs...)
 	}
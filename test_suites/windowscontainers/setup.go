@@ -23,6 +23,10 @@ import (
 // Name is the name of the test package. It must match the directory name.
 var Name = "windowscontainers"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{Description: "Tests containers on Windows images."})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
 	if utils.HasFeature(t.Image, "WINDOWS") {
@@ -25,14 +25,18 @@ var Name = "winrm"
 
 const user = "test-user"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests WinRM access to Windows VMs.",
+		OSFamilies:  []string{imagetest.OSFamilyWindows},
+		ExcludedImages: []imagetest.ImageExclusion{
+			{Regexp: "windows-(7|8|10|11)-", Reason: "WinRM is not tested on Windows client images."},
+		},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if utils.IsWindowsClient(t.Image.Name) {
-		t.Skip("Skipping winrm test for Windows Client Images.")
-	}
-	if !utils.HasFeature(t.Image, "WINDOWS") {
-		return nil
-	}
 	passwd := utils.ValidWindowsPassword(14)
 
 	vm, err := t.CreateTestVM("client")
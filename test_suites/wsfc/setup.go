@@ -18,7 +18,6 @@ package wsfc
 
 import (
 	"github.com/GoogleCloudPlatform/cloud-image-tests"
-	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
 )
 
 // Name is the name of the test package. It must match the directory name.
@@ -26,12 +25,15 @@ var Name = "wsfc"
 
 const wsfcAgentPort = "59998"
 
+func init() {
+	imagetest.Register(Name, TestSetup, imagetest.SuiteMetadata{
+		Description: "Tests Windows Server Failover Clustering.",
+		OSFamilies:  []string{imagetest.OSFamilyWindows},
+	})
+}
+
 // TestSetup sets up the test workflow.
 func TestSetup(t *imagetest.TestWorkflow) error {
-	if !utils.HasFeature(t.Image, "WINDOWS") {
-		t.Skip("Skipping wsfc test for non-Windows images.")
-	}
-
 	vm, err := t.CreateTestVM("wsfc")
 	if err != nil {
 		return err
@@ -18,7 +18,10 @@
 package imagetest
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -33,6 +36,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/cleanerupper"
 	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/exceptions"
 	daisy "github.com/GoogleCloudPlatform/compute-daisy"
 	daisycompute "github.com/GoogleCloudPlatform/compute-daisy/compute"
 	"github.com/jstemmer/go-junit-report/v2/junit"
@@ -104,6 +108,20 @@ type TestWorkflowOpts struct {
 	// true, the zone from the command line will be enforced if the test suite
 	// does specify a zone. If false, the hardcoded zone will be used.
 	ArgZoneOverride bool
+	// SharedVPCHostProject is the Shared VPC host project of the network to
+	// attach test VMs to. If set, SharedVPCNetwork and SharedVPCSubnetwork are
+	// required, and test VMs without a custom network use the Shared VPC
+	// subnetwork instead of the default network of the test project.
+	SharedVPCHostProject string
+	// SharedVPCNetwork is the name of the network in SharedVPCHostProject.
+	SharedVPCNetwork string
+	// SharedVPCSubnetwork is the name of the subnetwork in SharedVPCNetwork. It
+	// must be in the region of the test zone.
+	SharedVPCSubnetwork string
+	// Sample is the index, from 1, of the repeated run of the test suite on the
+	// image when the manager samples metrics. Results of samples after the
+	// first are named after the sample. 0 is the same as 1.
+	Sample int
 }
 
 // TestWorkflow defines a test workflow which creates at least one test VM.
@@ -115,8 +133,14 @@ type TestWorkflow struct {
 	Image *compute.Image
 	// ImageBeta is the image under test using Beta API
 	ImageBeta *computeBeta.Image
+	// GuestOS is the guest OS of the image under test. Prefer it to matching
+	// the image name.
+	GuestOS utils.GuestOS
 	// ImageURL will be the partial URL of a GCE image.
 	ImageURL string
+	// Sample is the index of the repeated run of the test suite on the image,
+	// see TestWorkflowOpts.Sample.
+	Sample int
 	// MachineType is the machine type to be used for the test. This can be overridden by individual test suites.
 	MachineType *compute.MachineType
 	Project     *compute.Project
@@ -125,6 +149,7 @@ type TestWorkflow struct {
 	GCSPath           string
 	skipped           bool
 	skippedMessage    string
+	failedMessage     string
 	testExcludeFilter string
 	wf                *daisy.Workflow
 	// Global counter for all daisy steps on all VMs. This is an interim solution in order to prevent step-name collisions.
@@ -144,6 +169,27 @@ type TestWorkflow struct {
 	// the call entirely. Default false because only the loadbalancer suite
 	// creates LB resources today.
 	CreatesLoadBalancers bool
+	// networkProfiles are the network profiles created by NetworkProfile, keyed
+	// by profile name.
+	networkProfiles map[string]*NetworkProfile
+	// sharedVPCNetwork and sharedVPCSubnetwork are the Shared VPC resources
+	// test VMs are attached to by default, set from TestWorkflowOpts.
+	sharedVPCNetwork    *Network
+	sharedVPCSubnetwork *Subnetwork
+	// sharedVPCHostProjects are the Shared VPC host projects used by this
+	// workflow, which are checked for leftover firewall rules on cleanup.
+	sharedVPCHostProjects []string
+	// createsImages indicates that the workflow captures images from test VMs,
+	// which are checked for leftovers on cleanup.
+	createsImages bool
+	// createsSnapshots and createsMachineImages indicate that the workflow
+	// creates snapshots and machine images of test VMs, which are checked for
+	// leftovers on cleanup.
+	createsSnapshots     bool
+	createsMachineImages bool
+	// managedInstanceGroups are the managed instance groups created by
+	// CreateManagedInstanceGroup, whose members report test results too.
+	managedInstanceGroups []*ManagedInstanceGroup
 	// argZoneOverride is whether to override the zone from the command line. If
 	// true, the zone from the command line will be enforced if the test suite
 	// does specify a zone. If false, the hardcoded zone will be used.
@@ -164,22 +210,41 @@ func (t *TestWorkflow) setInstanceTestMetadata(instance *daisy.Instance, suffix
 	instance.Metadata["_test_properties_url"] = fmt.Sprintf("${OUTSPATH}/properties/%s.txt", instance.Name)
 	instance.Metadata["_test_package_name"] = fmt.Sprintf("image_test%s", suffix)
 	instance.Metadata["_test_results_url"] = fmt.Sprintf("${OUTSPATH}/%s.txt", name)
+	instance.Metadata["_test_metrics_url"] = fmt.Sprintf("${OUTSPATH}/metrics/%s.json", name)
+	instance.Metadata["_test_artifacts_url"] = fmt.Sprintf("${OUTSPATH}/artifacts/%s/", name)
 	instance.Metadata["_test_suite_name"] = getTestSuiteName(t)
 	instance.Metadata["_compute_endpoint"] = t.wf.ComputeEndpoint
 	instance.Metadata["_cit_timeout"] = t.wf.DefaultTimeout
 	instance.Metadata["_exclude_discrete_tests"] = t.testExcludeFilter
+	instance.Metadata[exceptions.MetadataKey] = exceptions.FileData()
+	instance.Metadata[utils.GuestOSMetadataKey] = t.GuestOS.Metadata()
 	instance.Metadata["enable-guest-attributes"] = "TRUE" // enable guest attributes by default.
 }
 
 // addNewVMStep adds an entirely new addVM step, separate from the step used and
 // modified by the `appendCreateVMStep` function.
 func (t *TestWorkflow) addNewVMStep(disks []*compute.Disk, instanceParams *daisy.Instance) (*daisy.Step, *daisy.Instance, error) {
-	stepSuffix := fmt.Sprintf("%d", t.counter)
 	if len(disks) == 0 || disks[0].Name == "" {
 		return nil, nil, fmt.Errorf("failed to create VM from empty boot disk")
 	}
+
+	instance := instanceParams
+	if instance == nil {
+		instance = &daisy.Instance{}
+	}
+	for _, disk := range disks {
+		currentDisk := &compute.AttachedDisk{Source: disk.Name, AutoDelete: true}
+		instance.Disks = append(instance.Disks, currentDisk)
+	}
+
 	// The boot disk is the first disk, and the VM name comes from that
-	name := disks[0].Name
+	return t.addNewVMStepForInstance(disks[0].Name, instance)
+}
+
+// addNewVMStepForInstance is like addNewVMStep, but uses the disks already set
+// on the instance, if any.
+func (t *TestWorkflow) addNewVMStepForInstance(name string, instance *daisy.Instance) (*daisy.Step, *daisy.Instance, error) {
+	stepSuffix := fmt.Sprintf("%d", t.counter)
 	if strings.Contains(name, "-") {
 		return nil, nil, fmt.Errorf("dashes are disallowed in testworkflow vm names: %s", name)
 	}
@@ -190,20 +255,10 @@ func (t *TestWorkflow) addNewVMStep(disks []*compute.Disk, instanceParams *daisy
 		suffix = ".exe"
 	}
 
-	instance := instanceParams
-	if instance == nil {
-		instance = &daisy.Instance{}
-	}
-
 	instance.StartupScript = fmt.Sprintf("wrapper%s", suffix)
 	instance.Name = name
 	instance.Scopes = append(instance.Scopes, "https://www.googleapis.com/auth/devstorage.read_write")
 
-	for _, disk := range disks {
-		currentDisk := &compute.AttachedDisk{Source: disk.Name, AutoDelete: true}
-		instance.Disks = append(instance.Disks, currentDisk)
-	}
-
 	if instance.Metadata == nil {
 		instance.Metadata = make(map[string]string)
 	}
@@ -337,11 +392,15 @@ func (t *TestWorkflow) appendCreateVMStepBeta(disks []*compute.Disk, instance *d
 	instance.Metadata["_test_vmname"] = name
 	instance.Metadata["_test_package_url"] = "${SOURCESPATH}/testpackage"
 	instance.Metadata["_test_results_url"] = fmt.Sprintf("${OUTSPATH}/%s.txt", name)
+	instance.Metadata["_test_metrics_url"] = fmt.Sprintf("${OUTSPATH}/metrics/%s.json", name)
+	instance.Metadata["_test_artifacts_url"] = fmt.Sprintf("${OUTSPATH}/artifacts/%s/", name)
 	instance.Metadata["_test_properties_url"] = fmt.Sprintf("${OUTSPATH}/properties/%s.txt", name)
 	instance.Metadata["_test_suite_name"] = getTestSuiteName(t)
 	instance.Metadata["_test_package_name"] = fmt.Sprintf("image_test%s", suffix)
 	instance.Metadata["_compute_endpoint"] = t.wf.ComputeEndpoint
 	instance.Metadata["_exclude_discrete_tests"] = t.testExcludeFilter
+	instance.Metadata[exceptions.MetadataKey] = exceptions.FileData()
+	instance.Metadata[utils.GuestOSMetadataKey] = t.GuestOS.Metadata()
 	instance.Metadata["enable-guest-attributes"] = "TRUE" // enable guest attributes by default.
 
 	createInstances := &daisy.CreateInstances{}
@@ -440,7 +499,7 @@ func (t *TestWorkflow) appendCreateImageStep(stepname string, image *compute.Ima
 		if err != nil {
 			return nil, err
 		}
-		createImagesStep.CreateImages.Images = []*daisy.Image{createImages}
+		createImagesStep.CreateImages = &daisy.CreateImages{Images: []*daisy.Image{createImages}}
 	}
 	return createImagesStep, nil
 }
@@ -507,6 +566,37 @@ func (t *TestWorkflow) addWaitStep(stepname, vmname string) (*daisy.Step, error)
 	return waitStep, nil
 }
 
+// addWaitBootedStep waits for the wrapper of the VM to start, which it signals
+// on the serial port before running the tests. The wait step depends on the
+// step creating the VM, so it also runs after every step the VM waits for.
+func (t *TestWorkflow) addWaitBootedStep(stepname, vmname string) (*daisy.Step, error) {
+	createVMStep, err := t.getCreateStepForVM(vmname)
+	if err != nil {
+		return nil, err
+	}
+
+	serialOutput := &daisy.SerialOutput{}
+	serialOutput.Port = 1
+	serialOutput.SuccessMatch = bootedMatch
+
+	instanceSignal := &daisy.InstanceSignal{}
+	instanceSignal.Name = vmname
+	instanceSignal.Stopped = false
+	instanceSignal.SerialOutput = serialOutput
+	instanceSignal.Interval = "2s"
+
+	waitStep, err := t.wf.NewStep("wait-booted-" + stepname)
+	if err != nil {
+		return nil, err
+	}
+	waitStep.WaitForInstancesSignal = &daisy.WaitForInstancesSignal{instanceSignal}
+	if err := t.wf.AddDependency(waitStep, createVMStep); err != nil {
+		return nil, err
+	}
+
+	return waitStep, nil
+}
+
 // after guest attributes for instance wait step matching are implemented, this step will wait for a different guest attribute key than addWaitStep
 func (t *TestWorkflow) addWaitRebootGAStep(stepname, vmname string) (*daisy.Step, error) {
 	serialOutput := &daisy.SerialOutput{}
@@ -654,6 +744,9 @@ func finalizeWorkflows(ctx context.Context, tests []*TestWorkflow, gcsPrefix, lo
 
 		// $GCS_PATH/2021-04-20T11:44:08-07:00/image_validation/debian-10
 		twf.GCSPath = fmt.Sprintf("%s/%s/%s", gcsPrefix, twf.Name, twf.Image.Name)
+		if twf.Sample > 1 {
+			twf.GCSPath += fmt.Sprintf("/sample%d", twf.Sample)
+		}
 		twf.wf.GCSPath = twf.GCSPath
 
 		// Process quota steps and associated creation steps.
@@ -690,10 +783,9 @@ func finalizeWorkflows(ctx context.Context, tests []*TestWorkflow, gcsPrefix, lo
 			}
 		}
 
-		// Assume amd64 when arch is not set.
-		arch := "amd64"
-		if twf.Image.Architecture == "ARM64" {
-			arch = "arm64"
+		twf.attachSharedVPC()
+		if err := twf.finalizeManagedInstanceGroups(); err != nil {
+			return err
 		}
 
 		createDisksStep, createDisksOk := twf.wf.Steps[createDisksStepName]
@@ -740,16 +832,12 @@ func finalizeWorkflows(ctx context.Context, tests []*TestWorkflow, gcsPrefix, lo
 			}
 		}
 
-		if utils.HasFeature(twf.Image, "WINDOWS") {
-			archBits := "64"
-			if strings.Contains(twf.ImageURL, "x86") {
-				archBits = "32"
-			}
-			twf.wf.Sources["testpackage"] = fmt.Sprintf("%s/%s%s.exe", localPath, twf.Name, archBits)
-			twf.wf.Sources["wrapper.exe"] = fmt.Sprintf("%s/%s%s.exe", localPath, testWrapperPathWindows, archBits)
+		testPackage, wrapper, goos, _ := twf.TestBinaries()
+		twf.wf.Sources["testpackage"] = fmt.Sprintf("%s/%s", localPath, testPackage)
+		if goos == "windows" {
+			twf.wf.Sources["wrapper.exe"] = fmt.Sprintf("%s/%s", localPath, wrapper)
 		} else {
-			twf.wf.Sources["testpackage"] = fmt.Sprintf("%s/%s.%s.test", localPath, twf.Name, arch)
-			twf.wf.Sources["wrapper"] = fmt.Sprintf("%s%s.%s", localPath, testWrapperPath, arch)
+			twf.wf.Sources["wrapper"] = fmt.Sprintf("%s/%s", localPath, wrapper)
 		}
 
 		// add a final copy-objects step which copies the daisy-outs-path directory to twf.gcsPath + /outs
@@ -783,31 +871,96 @@ type testResult struct {
 	workflowSuccess bool
 	err             error
 	results         []string
+	metrics         []utils.Metric
+}
+
+// testVMOutput is where a test VM uploads its test results and metrics.
+type testVMOutput struct {
+	name       string
+	resultsURL string
+	metricsURL string
+}
+
+// testVMOutputs returns the outputs of every test VM of the workflow. Most test
+// VMs are created by the create-vms step, but test VMs which must be created
+// after other steps, like VMs booting from derived images, snapshots or machine
+// images, are created by their own create-vms-N steps. Outputs shared by
+// several VMs, like those of derivative VMs, are returned once.
+func (t *TestWorkflow) testVMOutputs() []testVMOutput {
+	var stepnames []string
+	for stepname, step := range t.wf.Steps {
+		if step.CreateInstances != nil {
+			stepnames = append(stepnames, stepname)
+		}
+	}
+	sort.Strings(stepnames)
+
+	var outputs []testVMOutput
+	seen := make(map[string]bool)
+	add := func(name string, metadata map[string]string) {
+		resultsURL := metadata["_test_results_url"]
+		if resultsURL == "" || seen[resultsURL] {
+			return
+		}
+		seen[resultsURL] = true
+		outputs = append(outputs, testVMOutput{name: name, resultsURL: resultsURL, metricsURL: metadata["_test_metrics_url"]})
+	}
+	for _, stepname := range stepnames {
+		createInstances := t.wf.Steps[stepname].CreateInstances
+		for _, vm := range createInstances.Instances {
+			add(vm.Name, vm.Metadata)
+		}
+		for _, vm := range createInstances.InstancesBeta {
+			add(vm.Name, vm.Metadata)
+		}
+	}
+	return outputs
 }
 
 func getTestResults(ctx context.Context, ts *TestWorkflow) ([]string, error) {
 	var results []string
-	createVMsStep, ok := ts.wf.Steps[createVMsStepName]
-	if ok {
-		for _, vm := range createVMsStep.CreateInstances.Instances {
-			out, err := utils.DownloadGCSObject(ctx, client, vm.Metadata["_test_results_url"])
-			if err != nil {
-				return nil, fmt.Errorf("failed to get results for test %s vm %s: %v", ts.Name, vm.Name, err)
-			}
-			results = append(results, string(out))
+	for _, vm := range ts.testVMOutputs() {
+		out, err := utils.DownloadGCSObject(ctx, client, vm.resultsURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get results for test %s vm %s: %v", ts.Name, vm.name, err)
 		}
-		for _, vm := range createVMsStep.CreateInstances.InstancesBeta {
-			out, err := utils.DownloadGCSObject(ctx, client, vm.Metadata["_test_results_url"])
-			if err != nil {
-				return nil, fmt.Errorf("failed to get results for test %s vm %s: %v", ts.Name, vm.Name, err)
-			}
-			results = append(results, string(out))
+		results = append(results, string(out))
+	}
+	for _, m := range ts.managedInstanceGroups {
+		out, err := m.results(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get results for test %s: %v", ts.Name, err)
 		}
+		results = append(results, out...)
 	}
 
 	return results, nil
 }
 
+// getTestMetrics gets the metrics recorded by the tests of the test VMs with
+// utils.RecordMetric. VMs which recorded no metric have no metrics file.
+func getTestMetrics(ctx context.Context, ts *TestWorkflow) ([]utils.Metric, error) {
+	var metrics []utils.Metric
+	for _, vm := range ts.testVMOutputs() {
+		if vm.metricsURL == "" {
+			continue
+		}
+		out, err := utils.DownloadGCSObject(ctx, client, vm.metricsURL)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, err := utils.ReadMetrics(bytes.NewReader(out))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metrics %s: %v", vm.metricsURL, err)
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
 // NewTestWorkflow returns a new TestWorkflow.
 func NewTestWorkflow(opts *TestWorkflowOpts, setupFunc func(*TestWorkflow) error) (*TestWorkflow, error) {
 	if len(opts.Zones) == 0 && opts.Zone != "" {
@@ -818,6 +971,7 @@ func NewTestWorkflow(opts *TestWorkflowOpts, setupFunc func(*TestWorkflow) error
 	t.counter = 0
 	t.Name = opts.Name
 	t.ImageURL = opts.Image
+	t.Sample = opts.Sample
 	t.Client = opts.Client
 	t.testExcludeFilter = opts.ExcludeFilter
 	t.argZoneOverride = opts.ArgZoneOverride
@@ -844,6 +998,12 @@ func NewTestWorkflow(opts *TestWorkflowOpts, setupFunc func(*TestWorkflow) error
 	if err != nil {
 		return nil, err
 	}
+	if opts.SharedVPCHostProject != "" {
+		t.sharedVPCNetwork, t.sharedVPCSubnetwork, err = t.SharedVPCNetwork(opts.SharedVPCHostProject, opts.SharedVPCNetwork, opts.SharedVPCSubnetwork)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Initializing Image inside the TestWorkflow
 	split := strings.Split(opts.Image, "/")
@@ -862,6 +1022,7 @@ func NewTestWorkflow(opts *TestWorkflowOpts, setupFunc func(*TestWorkflow) error
 	if err != nil {
 		return nil, err
 	}
+	t.GuestOS = utils.GuestOSFromImage(t.Image)
 	if t.Image.Architecture == "ARM64" {
 		t.MachineType, err = t.Client.GetMachineType(t.Project.Name, t.Zone.Name, opts.ARM64Shape)
 	} else {
@@ -885,6 +1046,26 @@ func NewTestWorkflow(opts *TestWorkflowOpts, setupFunc func(*TestWorkflow) error
 	return t, nil
 }
 
+// TestBinaries returns the names of the test package and wrapper binaries run
+// on the image of the test workflow, in the local path, and the GOOS and
+// GOARCH they are built for.
+func (t *TestWorkflow) TestBinaries() (testPackage, wrapper, goos, goarch string) {
+	if utils.HasFeature(t.Image, "WINDOWS") {
+		archBits := "64"
+		goarch = "amd64"
+		if strings.Contains(t.ImageURL, "x86") {
+			archBits, goarch = "32", "386"
+		}
+		return fmt.Sprintf("%s%s.exe", t.Name, archBits), fmt.Sprintf("%s%s.exe", strings.TrimPrefix(testWrapperPathWindows, "/"), archBits), "windows", goarch
+	}
+	// Assume amd64 when arch is not set.
+	goarch = "amd64"
+	if t.Image.Architecture == "ARM64" {
+		goarch = "arm64"
+	}
+	return fmt.Sprintf("%s.%s.test", t.Name, goarch), fmt.Sprintf("%s.%s", strings.TrimPrefix(testWrapperPath, "/"), goarch), "linux", goarch
+}
+
 // PrintTests prints all test workflows.
 func PrintTests(ctx context.Context, storageClient *storage.Client, testWorkflows []*TestWorkflow, project, gcsPath, localPath string) {
 	gcsPrefix, err := getGCSPrefix(ctx, storageClient, project, gcsPath)
@@ -1097,6 +1278,10 @@ func runTestWorkflow(ctx context.Context, metrics *testMetrics, test *TestWorkfl
 		res.err = fmt.Errorf("test suite was skipped with message: %q", res.testWorkflow.SkippedMessage())
 		return res
 	}
+	if test.failedMessage != "" {
+		res.err = fmt.Errorf("test suite failed without running: %s", test.failedMessage)
+		return res
+	}
 
 	defer func() {
 		cleanupTestWorkflowProgress(res.testWorkflow, metrics)
@@ -1121,6 +1306,10 @@ func runTestWorkflow(ctx context.Context, metrics *testMetrics, test *TestWorkfl
 	}
 	res.results = results
 	res.workflowSuccess = true
+	// Metrics are reported on a best effort basis, they don't fail the tests.
+	if res.metrics, err = getTestMetrics(ctx, test); err != nil {
+		log.Printf("failed to get metrics of test %s/%s: %v", test.Name, test.Image.Name, err)
+	}
 
 	return res
 }
@@ -1192,14 +1381,46 @@ func cleanTestWorkflow(test *TestWorkflow) (totalCleaned []string, totalErrs []e
 	cleaned, errs = cleanerupper.CleanDisks(c, test.wf.Project, policy, false)
 	totalCleaned = append(totalCleaned, cleaned...)
 	totalErrs = append(totalErrs, errs...)
-	if test.CreatesLoadBalancers {
+	// Managed instance groups are cleaned up with the load balancer resources,
+	// as they can be load balancer backends.
+	if len(test.managedInstanceGroups) > 0 {
+		service, err := newComputeService(context.Background(), test.wf.ComputeEndpoint)
+		if err != nil {
+			totalErrs = append(totalErrs, fmt.Errorf("failed to create compute service: %v", err))
+		} else {
+			c.Compute = service
+		}
+	}
+	if test.CreatesLoadBalancers || c.Compute != nil {
 		cleaned, errs = cleanerupper.CleanLoadBalancerResources(c, test.wf.Project, policy, regions, false)
 		totalCleaned = append(totalCleaned, cleaned...)
 		totalErrs = append(totalErrs, errs...)
 	}
+	if test.createsImages {
+		cleaned, errs = cleanerupper.CleanImages(c, test.wf.Project, policy, false)
+		totalCleaned = append(totalCleaned, cleaned...)
+		totalErrs = append(totalErrs, errs...)
+	}
+	if test.createsMachineImages {
+		cleaned, errs = cleanerupper.CleanMachineImages(c, test.wf.Project, policy, false)
+		totalCleaned = append(totalCleaned, cleaned...)
+		totalErrs = append(totalErrs, errs...)
+	}
+	if test.createsSnapshots {
+		cleaned, errs = cleanerupper.CleanSnapshots(c, test.wf.Project, policy, false)
+		totalCleaned = append(totalCleaned, cleaned...)
+		totalErrs = append(totalErrs, errs...)
+	}
 	cleaned, errs = cleanerupper.CleanNetworks(c, test.wf.Project, policy, false)
 	totalCleaned = append(totalCleaned, cleaned...)
 	totalErrs = append(totalErrs, errs...)
+	// Networks in Shared VPC host projects are not owned by the workflow, only
+	// the firewall rules created on them are.
+	for _, hostProject := range test.sharedVPCHostProjects {
+		cleaned, errs = cleanerupper.CleanFirewallRules(c, hostProject, policy, false)
+		totalCleaned = append(totalCleaned, cleaned...)
+		totalErrs = append(totalErrs, errs...)
+	}
 
 	return
 }
@@ -1304,6 +1525,11 @@ func parseResult(res testResult, localPath string) junit.Testsuite {
 		ret.AddProperty("image_family", res.testWorkflow.Image.Family)
 		ret.AddProperty("image", res.testWorkflow.Image.SelfLink)
 		ret.AddProperty("project", res.testWorkflow.Project.Name)
+		for _, m := range res.metrics {
+			if b, err := json.Marshal(m); err == nil {
+				ret.AddProperty(metricProperty, string(b))
+			}
+		}
 	default:
 		var status string
 		if res.err != nil {
@@ -1330,25 +1556,68 @@ func parseResult(res testResult, localPath string) junit.Testsuite {
 	return ret
 }
 
+// metricProperty is the name of the junit properties of the metrics recorded
+// by the tests, whose values are utils.Metric in JSON.
+const metricProperty = "metric"
+
+// ResultMetrics returns the metrics recorded by the tests of a test suite
+// result with utils.RecordMetric.
+func ResultMetrics(ts junit.Testsuite) []utils.Metric {
+	if ts.Properties == nil {
+		return nil
+	}
+	var metrics []utils.Metric
+	for _, p := range *ts.Properties {
+		if p.Name != metricProperty {
+			continue
+		}
+		var m utils.Metric
+		if err := json.Unmarshal([]byte(p.Value), &m); err == nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// ResultName returns the name of the junit test suite of the results of the
+// test workflow, like imageboot-debian-12.
+func (t *TestWorkflow) ResultName() string {
+	return getTestSuiteName(t)
+}
+
 func getTestSuiteName(testWorkflow *TestWorkflow) string {
 	// Use ImageURL instead of the name or family to display results the same way
 	// as the user entered them.
-	return fmt.Sprintf("%s-%s", testWorkflow.Name, strings.Split(testWorkflow.ImageURL, "/")[len(strings.Split(testWorkflow.ImageURL, "/"))-1])
+	name := fmt.Sprintf("%s-%s", testWorkflow.Name, strings.Split(testWorkflow.ImageURL, "/")[len(strings.Split(testWorkflow.ImageURL, "/"))-1])
+	if testWorkflow.Sample > 1 {
+		name += fmt.Sprintf("-sample%d", testWorkflow.Sample)
+	}
+	return name
 }
 
 func getTestsBySuiteName(name, localPath string) []string {
-	b, err := ioutil.ReadFile(fmt.Sprintf("%s/%s_tests.txt", localPath, name))
+	res, err := SuiteTests(name, localPath)
 	if err != nil {
 		log.Fatalf("unable to parse tests list: %v", err)
 		return []string{} // NOT nil
 	}
+	return res
+}
+
+// SuiteTests returns the names of the tests of the named suite, from the
+// <suite>_tests.txt list written to localPath by local_build.sh.
+func SuiteTests(name, localPath string) ([]string, error) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("%s/%s_tests.txt", localPath, name))
+	if err != nil {
+		return nil, err
+	}
 	var res []string
 	for _, testname := range strings.Split(string(b), "\n") {
 		if strings.HasPrefix(testname, "Test") {
 			res = append(res, testname)
 		}
 	}
-	return res
+	return res, nil
 }
 
 func (t *TestWorkflow) getLastStepForVM(vmname string) (*daisy.Step, error) {
@@ -1377,6 +1646,26 @@ func (t *TestWorkflow) getLastStepForVM(vmname string) (*daisy.Step, error) {
 	return t.wf.Steps[step], nil
 }
 
+// getCreateStepForVM returns the step creating the VM.
+func (t *TestWorkflow) getCreateStepForVM(vmname string) (*daisy.Step, error) {
+	for _, step := range t.wf.Steps {
+		if step.CreateInstances == nil {
+			continue
+		}
+		for _, vm := range step.CreateInstances.Instances {
+			if vm.Name == vmname {
+				return step, nil
+			}
+		}
+		for _, vm := range step.CreateInstances.InstancesBeta {
+			if vm.Name == vmname {
+				return step, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no step creates vm %s", vmname)
+}
+
 func (t *TestWorkflow) skipWindowsStagingKMS(isWindows bool, instance *daisy.Instance) {
 	if isWindows && t.IsComputeStaging() {
 		if instance.Metadata == nil {
@@ -17,6 +17,8 @@ package imagetest
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
@@ -528,6 +530,31 @@ func TestAppendCreateDisksStep(t *testing.T) {
 	}
 }
 
+func TestAppendCreateImageStep(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	step, err := twf.appendCreateImageStep("create-image", &compute.Image{Name: "image1", SourceDisk: "disk1"})
+	if err != nil {
+		t.Fatalf("failed to add create image step to test workflow: %v", err)
+	}
+	if step.CreateImages == nil || len(step.CreateImages.Images) != 1 {
+		t.Fatal("CreateImages step is malformed")
+	}
+	step2, err := twf.appendCreateImageStep("create-image", &compute.Image{Name: "image2", SourceDisk: "disk2"})
+	if err != nil {
+		t.Fatalf("failed to append to create image step: %v", err)
+	}
+	if step2 != step {
+		t.Fatal("CreateImages step was not appended")
+	}
+	images := step.CreateImages.Images
+	if len(images) != 2 {
+		t.Fatalf("CreateImages step has %d images, want 2", len(images))
+	}
+	if images[1].Name != "image2" || images[1].SourceDisk != "disk2" {
+		t.Errorf("CreateImages step is malformed, got image %s from disk %s", images[1].Name, images[1].SourceDisk)
+	}
+}
+
 func TestAppendCreateVMStep(t *testing.T) {
 	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
 	if twf.wf == nil {
@@ -1055,3 +1082,128 @@ func TestRecreateTestWorkflow(t *testing.T) {
 		t.Errorf("recreated name = %q, want %q", recreated.Name, twf.Name)
 	}
 }
+
+func TestSuiteTests(t *testing.T) {
+	dir := t.TempDir()
+	list := "TestA\nTestB\nBenchmarkC\nok  \tsuite\t0.001s\n"
+	if err := os.WriteFile(filepath.Join(dir, "suite_tests.txt"), []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SuiteTests("suite", dir)
+	if err != nil {
+		t.Fatalf("SuiteTests() = %v, want nil", err)
+	}
+	if diff := cmp.Diff([]string{"TestA", "TestB"}, got); diff != "" {
+		t.Errorf("SuiteTests() returned unexpected tests (-want +got):\n%s", diff)
+	}
+	if _, err := SuiteTests("missing", dir); err == nil {
+		t.Errorf("SuiteTests() for a missing list = nil, want error")
+	}
+}
+
+func TestTestBinaries(t *testing.T) {
+	tests := []struct {
+		image                              *compute.Image
+		imageURL                           string
+		testPackage, wrapper, goos, goarch string
+	}{
+		{
+			image:       &compute.Image{},
+			imageURL:    "projects/debian-cloud/global/images/family/debian-12",
+			testPackage: "suite.amd64.test", wrapper: "wrapper.amd64", goos: "linux", goarch: "amd64",
+		},
+		{
+			image:       &compute.Image{Architecture: "ARM64"},
+			imageURL:    "projects/debian-cloud/global/images/family/debian-12-arm64",
+			testPackage: "suite.arm64.test", wrapper: "wrapper.arm64", goos: "linux", goarch: "arm64",
+		},
+		{
+			image:       &compute.Image{GuestOsFeatures: []*compute.GuestOsFeature{{Type: "WINDOWS"}}},
+			imageURL:    "projects/windows-cloud/global/images/family/windows-2022",
+			testPackage: "suite64.exe", wrapper: "wrapp64.exe", goos: "windows", goarch: "amd64",
+		},
+		{
+			image:       &compute.Image{GuestOsFeatures: []*compute.GuestOsFeature{{Type: "WINDOWS"}}},
+			imageURL:    "projects/windows-cloud/global/images/family/windows-10-x86",
+			testPackage: "suite32.exe", wrapper: "wrapp32.exe", goos: "windows", goarch: "386",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.imageURL, func(t *testing.T) {
+			twf := NewTestWorkflowForUnitTest("suite", tc.imageURL, "30m")
+			twf.Image = tc.image
+			testPackage, wrapper, goos, goarch := twf.TestBinaries()
+			if testPackage != tc.testPackage || wrapper != tc.wrapper || goos != tc.goos || goarch != tc.goarch {
+				t.Errorf("TestBinaries() = %s, %s, %s, %s, want %s, %s, %s, %s", testPackage, wrapper, goos, goarch, tc.testPackage, tc.wrapper, tc.goos, tc.goarch)
+			}
+		})
+	}
+}
+
+func TestResultName(t *testing.T) {
+	for sample, want := range map[int]string{0: "imageboot-debian-12", 1: "imageboot-debian-12", 3: "imageboot-debian-12-sample3"} {
+		twf := NewTestWorkflowForUnitTest("imageboot", "projects/debian-cloud/global/images/family/debian-12", "30m")
+		twf.Sample = sample
+		if got := twf.ResultName(); got != want {
+			t.Errorf("ResultName() for sample %d = %q, want %q", sample, got, want)
+		}
+	}
+}
+
+func TestParseResultMetrics(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "imageboot_tests.txt"), []byte("TestBootTime\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	twf := NewTestWorkflowForUnitTest("imageboot", "projects/debian-cloud/global/images/family/debian-12", "30m")
+	metrics := []utils.Metric{
+		{Test: "TestBootTime", Name: "boot_time", Value: 21, Unit: "s"},
+		{Test: "TestBootTime", Name: "kernel_time", Value: 3.5, Unit: "s", Labels: map[string]string{"stage": "kernel"}},
+	}
+	res := testResult{testWorkflow: twf, workflowSuccess: true, metrics: metrics}
+	ts := parseResult(res, dir)
+	if diff := cmp.Diff(metrics, ResultMetrics(ts)); diff != "" {
+		t.Errorf("ResultMetrics(parseResult()) returned unexpected metrics (-want +got):\n%s", diff)
+	}
+}
+
+// TestTestVMOutputsDerivedImage tests that the results and metrics of test VMs
+// booting from a derived image, which have their own create VM steps, are
+// collected along with those of the other test VMs.
+func TestTestVMOutputsDerivedImage(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	image, err := tvm.CreateDerivedImage("derived")
+	if err != nil {
+		t.Fatalf("failed to create derived image: %v", err)
+	}
+	if _, err := image.CreateTestVM("derived1"); err != nil {
+		t.Fatalf("failed to create derived vm: %v", err)
+	}
+	want := []testVMOutput{
+		{name: "vm", resultsURL: "${OUTSPATH}/vm.txt", metricsURL: "${OUTSPATH}/metrics/vm.json"},
+		{name: "derived1", resultsURL: "${OUTSPATH}/derived1.txt", metricsURL: "${OUTSPATH}/metrics/derived1.json"},
+	}
+	if diff := cmp.Diff(want, twf.testVMOutputs(), cmp.AllowUnexported(testVMOutput{})); diff != "" {
+		t.Errorf("testVMOutputs() returned unexpected outputs (-want +got):\n%s", diff)
+	}
+}
+
+// TestTestVMOutputsDerivativeVM tests that the outputs shared by a derivative
+// VM and its source VM are collected once.
+func TestTestVMOutputsDerivativeVM(t *testing.T) {
+	twf := NewTestWorkflowForUnitTest("name", "image", "30m")
+	tvm, err := twf.CreateTestVM("vm")
+	if err != nil {
+		t.Fatalf("failed to create test vm: %v", err)
+	}
+	if _, err := tvm.CreateDerivativeVM("vm"); err != nil {
+		t.Fatalf("failed to create derivative vm: %v", err)
+	}
+	if got := twf.testVMOutputs(); len(got) != 1 || got[0].resultsURL != "${OUTSPATH}/vm.txt" {
+		t.Errorf("testVMOutputs() = %+v, want the outputs of vm once", got)
+	}
+}
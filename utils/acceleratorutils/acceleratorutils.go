@@ -181,12 +181,12 @@ func InstallIbVerbsUtils(ctx context.Context, t *testing.T) {
 // IsRockyLinux checks if the current OS is Rocky Linux.
 func IsRockyLinux(ctx context.Context, t *testing.T) bool {
 	t.Helper()
-	content, err := os.ReadFile("/etc/os-release")
+	guestOS, err := utils.GetGuestOS()
 	if err != nil {
-		t.Logf("Could not read /etc/os-release: %v, defaulting IsRockyLinux to false", err)
+		t.Logf("Could not get guest OS: %v, defaulting IsRockyLinux to false", err)
 		return false
 	}
-	return utils.IsRocky(string(content))
+	return guestOS.Distro == utils.DistroRocky
 }
 
 // SetupRDMAPerftestLibrary clones and builds the perftest library from
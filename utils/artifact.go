@@ -0,0 +1,54 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ArtifactsDirEnv is the environment variable the wrapper sets to the directory
+// whose files it uploads with the test results.
+const ArtifactsDirEnv = "CIT_ARTIFACTS_DIR"
+
+var artifactNameReplacer = strings.NewReplacer("/", "_", "\\", "_", " ", "_")
+
+// artifactName returns the name of the file of an artifact of the test, like
+// TestBootProfile-blame.txt.
+func artifactName(test, name string) string {
+	return artifactNameReplacer.Replace(test) + "-" + artifactNameReplacer.Replace(name)
+}
+
+// WriteArtifact saves data, like a log or a profile which helps investigating
+// failures, as an artifact of the test. The wrapper uploads the artifacts of a
+// VM to the artifacts directory of the VM in the outputs of the workflow. When
+// the artifacts directory isn't set, like when the test runs outside of a test
+// VM, the data is logged instead.
+func WriteArtifact(t *testing.T, name string, data []byte) {
+	t.Helper()
+	dir := os.Getenv(ArtifactsDirEnv)
+	if dir == "" {
+		t.Logf("artifact %s:\n%s", name, data)
+		return
+	}
+	path := filepath.Join(dir, artifactName(t.Name(), name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Logf("failed to write artifact %s: %v", name, err)
+		return
+	}
+	t.Logf("artifact %s written to %s", name, path)
+}
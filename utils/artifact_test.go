@@ -0,0 +1,44 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteArtifact(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ArtifactsDirEnv, dir)
+	t.Run("sub test", func(t *testing.T) {
+		WriteArtifact(t, "blame.txt", []byte("5.000s cloud-init.service\n"))
+	})
+
+	path := filepath.Join(dir, "TestWriteArtifact_sub_test-blame.txt")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) = %v, want nil", path, err)
+	}
+	if string(got) != "5.000s cloud-init.service\n" {
+		t.Errorf("artifact %s = %q, want %q", path, got, "5.000s cloud-init.service\n")
+	}
+}
+
+func TestArtifactName(t *testing.T) {
+	if got, want := artifactName("TestBootProfile/linux", "../blame.txt"), "TestBootProfile_linux-.._blame.txt"; got != want {
+		t.Errorf("artifactName() = %q, want %q", got, want)
+	}
+}
@@ -94,16 +94,47 @@ type Exception struct {
 	// Match is the regex to match the image name. This is ignored when using
 	// MatchAll.
 	Match string
+	// Versions is a range expression of the versions of the OS for the
+	// exception, like ">=9.2,<10" or "22.04". See ParseVersionRange. It takes
+	// precedence over Version and Type.
+	Versions string
 	// Version is the version of the OS for the exception. For example, "Debian 11"
 	// has the version 11. "Ubuntu 22.04 LTS" has the version 2204.
 	//
 	// A version of 0 means that the exception applies to all versions of the OS.
+	//
+	// Deprecated: Version is the first number in the image name, which has no
+	// minor version for most distributions. Use Versions.
 	Version int
 	// Type is the type of exception. This is used to determine how to compare the
 	// image version with the threshold version. If unspecified, the default is Equal.
+	//
+	// Deprecated: Use Versions.
 	Type ExceptionType
 }
 
+// allVersions returns whether the exception applies to all versions of the OS.
+func (e Exception) allVersions() bool {
+	return e.Versions == "" && e.Version == 0
+}
+
+// matchesVersion returns whether the version of the image matches the
+// exception.
+func (e Exception) matchesVersion(image string) bool {
+	if e.Versions != "" {
+		r, err := ParseVersionRange(e.Versions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse version range: %v\n", err)
+			return false
+		}
+		return r.ContainsImage(image)
+	}
+	if e.Version == 0 {
+		return true
+	}
+	return checkException(parseVersion(image), e)
+}
+
 // MatchAll returns true if the image matches all of the exceptions.
 //
 // image is the full name of the image.
@@ -130,17 +161,15 @@ func MatchAll(image string, base string, exceptions ...Exception) bool {
 		return true
 	}
 
-	version := parseVersion(image)
-
 	// Check if the version is within the range of the exceptions.
 	for _, exception := range exceptions {
 		// If the version is 0, then the exception applies to all versions.
-		if exception.Version == 0 {
+		if exception.allVersions() {
 			return true
 		}
 
 		// Check if the version matches the exception.
-		if !checkException(version, exception) {
+		if !exception.matchesVersion(image) {
 			return false
 		}
 	}
@@ -157,8 +186,6 @@ func HasMatch(image string, exceptions []Exception) bool {
 		return false
 	}
 
-	version := parseVersion(image)
-
 	// Check if the version is within the range of the exceptions.
 	for _, exception := range exceptions {
 		// Compile the regex.
@@ -173,12 +200,8 @@ func HasMatch(image string, exceptions []Exception) bool {
 		if !regex.MatchString(image) {
 			continue
 		}
-		if exception.Version == 0 {
-			return true
-		}
-
 		// Check if the version is within the range of the exception.
-		if checkException(version, exception) {
+		if exception.matchesVersion(image) {
 			return true
 		}
 
@@ -186,7 +209,8 @@ func HasMatch(image string, exceptions []Exception) bool {
 	return false
 }
 
-// parseVersion parses the version from the image name.
+// parseVersion parses the version from the image name, for the deprecated
+// Version of exceptions.
 func parseVersion(image string) int {
 	image = filepath.Base(image)
 	imageParts := strings.Split(image, "-")
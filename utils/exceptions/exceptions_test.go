@@ -114,6 +114,22 @@ func TestHasMatch(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name:  "version-range-success",
+			image: "rhel-9-0-sap-ha-v20260101",
+			exceptions: []Exception{
+				Exception{Match: ImageRHELSAP, Versions: "8.6 || 9.0"},
+			},
+			want: true,
+		},
+		{
+			name:  "version-range-failure",
+			image: "rhel-9-4-sap-ha-v20260101",
+			exceptions: []Exception{
+				Exception{Match: ImageRHELSAP, Versions: "8.6 || 9.0"},
+			},
+			want: false,
+		},
 		{
 			name:       "no-exceptions",
 			image:      "windows-2019",
@@ -0,0 +1,331 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exceptions
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// fileVersion is the version of the exceptions file format.
+const fileVersion = 1
+
+// dateLayout is the layout of the expiry dates in the exceptions file.
+const dateLayout = "2006-01-02"
+
+// MetadataKey is the metadata key of test VMs holding the contents of the
+// exceptions file of FileFlag, which is empty if the flag is not set.
+const MetadataKey = "_cit_exceptions"
+
+//go:embed exceptions.yaml
+var defaultFile []byte
+
+var (
+	// FileFlag is the flag to specify an exceptions file whose exceptions
+	// take precedence over the default exceptions.
+	FileFlag = flag.String("exceptions_file", "", "path of an exceptions file, in the format of utils/exceptions/exceptions.yaml, whose exceptions take precedence over the default exceptions")
+
+	loadDefault sync.Once
+	defaultList *List
+	defaultErr  error
+	fileData    []byte
+
+	loadGuest sync.Once
+	guestList *List
+	guestErr  error
+)
+
+// Entry is an exception in the exceptions file, which skips a test suite or a
+// test on the matching images until it expires. Feature exceptions instead
+// disable a feature of the test workflow, like IPv6, for every suite.
+type Entry struct {
+	// Suite is the name of the test suite. It is empty for feature exceptions.
+	Suite string `yaml:"suite,omitempty"`
+	// Feature is the name of the test workflow feature the images do not
+	// support, like ipv6. It is empty for suite and test exceptions.
+	Feature string `yaml:"feature,omitempty"`
+	// Test is a regex matching the names of the excepted tests. The whole
+	// suite is excepted if it is empty.
+	Test string `yaml:"test,omitempty"`
+	// Image is a regex matching the names of the excepted images.
+	Image string `yaml:"image"`
+	// Versions is a range expression of the versions of the excepted images,
+	// like ">=9.2,<10". See ParseVersionRange. All versions are excepted if it
+	// is empty.
+	Versions string `yaml:"versions,omitempty"`
+	// Reason is why the images are excepted.
+	Reason string `yaml:"reason"`
+	// Tracking is a link to the bug tracking the removal of the exception.
+	Tracking string `yaml:"tracking"`
+	// Expires is the date, formatted as YYYY-MM-DD, from which the exception
+	// no longer skips and matching tests fail instead.
+	Expires string `yaml:"expires"`
+
+	test     *regexp.Regexp
+	image    *regexp.Regexp
+	versions *VersionRange
+	expires  time.Time
+}
+
+// String returns the skip message of the exception.
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s (%s, expires %s)", e.Reason, e.Tracking, e.Expires)
+}
+
+// Expired returns whether the exception has expired at the given time.
+func (e *Entry) Expired(now time.Time) bool {
+	return !now.Before(e.expires)
+}
+
+// matchesImage returns whether the exception applies to the image name.
+func (e *Entry) matchesImage(image string) bool {
+	image = filepath.Base(image)
+	if !e.image.MatchString(image) {
+		return false
+	}
+	return e.versions == nil || e.versions.ContainsImage(image)
+}
+
+// List is a list of exceptions loaded from an exceptions file.
+type List struct {
+	Version    int      `yaml:"version"`
+	Exceptions []*Entry `yaml:"exceptions"`
+}
+
+// Load parses and validates an exceptions file.
+func Load(data []byte) (*List, error) {
+	l := &List{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse exceptions file: %v", err)
+	}
+	if l.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported exceptions file version %d, want %d", l.Version, fileVersion)
+	}
+	for i, e := range l.Exceptions {
+		if (e.Suite == "") == (e.Feature == "") {
+			return nil, fmt.Errorf("exception %d must have one of a suite or a feature", i)
+		}
+		if e.Feature != "" && e.Test != "" {
+			return nil, fmt.Errorf("exception %d of feature %s cannot have a test", i, e.Feature)
+		}
+		if e.Image == "" || e.Reason == "" || e.Tracking == "" || e.Expires == "" {
+			return nil, fmt.Errorf("exception %d must have an image, reason, tracking link and expiry date", i)
+		}
+		var err error
+		if e.image, err = regexp.Compile(e.Image); err != nil {
+			return nil, fmt.Errorf("exception %d has an invalid image regex: %v", i, err)
+		}
+		if e.Test != "" {
+			if e.test, err = regexp.Compile("^(?:" + e.Test + ")$"); err != nil {
+				return nil, fmt.Errorf("exception %d has an invalid test regex: %v", i, err)
+			}
+		}
+		if e.Versions != "" {
+			r, err := ParseVersionRange(e.Versions)
+			if err != nil {
+				return nil, fmt.Errorf("exception %d has an invalid version range: %v", i, err)
+			}
+			e.versions = &r
+		}
+		if e.expires, err = time.Parse(dateLayout, e.Expires); err != nil {
+			return nil, fmt.Errorf("exception %d has an invalid expiry date: %v", i, err)
+		}
+	}
+	return l, nil
+}
+
+// merge returns the exceptions of the file data, if any, followed by the
+// default exceptions of the exceptions.yaml file of this package, so that
+// Match finds them first.
+func merge(data []byte) (*List, error) {
+	l, err := Load(defaultFile)
+	if err != nil || len(data) == 0 {
+		return l, err
+	}
+	o, err := Load(data)
+	if err != nil {
+		return nil, err
+	}
+	o.Exceptions = append(o.Exceptions, l.Exceptions...)
+	return o, nil
+}
+
+// Default returns the exceptions of the file of FileFlag, if set, followed by
+// the exceptions of the exceptions.yaml file of this package.
+func Default() (*List, error) {
+	loadDefault.Do(func() {
+		if *FileFlag != "" {
+			if fileData, defaultErr = os.ReadFile(*FileFlag); defaultErr != nil {
+				return
+			}
+		}
+		defaultList, defaultErr = merge(fileData)
+	})
+	return defaultList, defaultErr
+}
+
+// FileData returns the contents of the exceptions file of FileFlag, which is
+// passed to test VMs in the MetadataKey attribute. It is empty if the flag is
+// not set, or if Default fails to read the file.
+func FileData() string {
+	Default()
+	return string(fileData)
+}
+
+// guestDefault is like Default on test VMs, where the exceptions file of
+// FileFlag is read from the MetadataKey attribute.
+func guestDefault(ctx context.Context) (*List, error) {
+	loadGuest.Do(func() {
+		data, err := utils.GetMetadata(ctx, "instance", "attributes", MetadataKey)
+		if err != nil && !errors.Is(err, utils.ErrMDSEntryNotFound) {
+			guestErr = fmt.Errorf("failed to read the %s metadata: %v", MetadataKey, err)
+			return
+		}
+		guestList, guestErr = merge([]byte(data))
+	})
+	return guestList, guestErr
+}
+
+// Match returns the exception for the test of the suite on the image, or nil
+// if there is none. The test is empty to match exceptions of the whole suite.
+// Match returns the exception with an error if it has expired, so that the
+// skip becomes a failure.
+func (l *List) Match(suite, test, image string, now time.Time) (*Entry, error) {
+	for _, e := range l.Exceptions {
+		if e.Feature != "" || e.Suite != suite || !e.matchesImage(image) {
+			continue
+		}
+		if (e.test == nil) != (test == "") || (e.test != nil && !e.test.MatchString(test)) {
+			continue
+		}
+		if e.Expired(now) {
+			return e, fmt.Errorf("exception for %s on %s expired on %s, fix %s or extend the exception: %s", suite, image, e.Expires, e.Tracking, e.Reason)
+		}
+		return e, nil
+	}
+	return nil, nil
+}
+
+// MatchFeature returns the exception disabling the feature on the image, or
+// nil if there is none. Like Match, it returns the exception with an error if
+// it has expired, in which case the feature is no longer disabled.
+func (l *List) MatchFeature(feature, image string, now time.Time) (*Entry, error) {
+	for _, e := range l.Exceptions {
+		if e.Feature != feature || !e.matchesImage(image) {
+			continue
+		}
+		if e.Expired(now) {
+			return e, fmt.Errorf("exception for %s on %s expired on %s, fix %s or extend the exception: %s", feature, image, e.Expires, e.Tracking, e.Reason)
+		}
+		return e, nil
+	}
+	return nil, nil
+}
+
+// Report returns the expired exceptions, and the exceptions which match none
+// of the images. Only the exceptions of the given suites, and the feature
+// exceptions, can be unused, since other suites are not run on the images.
+func (l *List) Report(images, suites []string, now time.Time) (expired, unused []*Entry) {
+	for _, e := range l.Exceptions {
+		if e.Expired(now) {
+			expired = append(expired, e)
+		}
+		if e.Suite != "" && !slices.Contains(suites, e.Suite) {
+			continue
+		}
+		used := false
+		for _, image := range images {
+			if e.matchesImage(image) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, e)
+		}
+	}
+	return expired, unused
+}
+
+// MatchDefault is like Match on the Default exceptions, at the current time.
+func MatchDefault(suite, test, image string) (*Entry, error) {
+	l, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return l.Match(suite, test, image, time.Now())
+}
+
+// MatchDefaultFeature is like MatchFeature on the Default exceptions, at the
+// current time.
+func MatchDefaultFeature(feature, image string) (*Entry, error) {
+	l, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return l.MatchFeature(feature, image, time.Now())
+}
+
+// matchTest is like Match for the running test on the exceptions of the test
+// VM, at the current time.
+func matchTest(t *testing.T, suite, image string) (*Entry, error) {
+	t.Helper()
+	l, err := guestDefault(utils.Context(t))
+	if err != nil {
+		return nil, err
+	}
+	return l.Match(suite, t.Name(), image, time.Now())
+}
+
+// MatchTest returns the exception for the running test on the image from the
+// exceptions of the test VM, or nil if there is none, for tests which check
+// less rather than skip on the excepted images. If the exception has expired,
+// MatchTest fails the test and returns nil, so that the test checks
+// everything.
+func MatchTest(t *testing.T, suite, image string) *Entry {
+	t.Helper()
+	e, err := matchTest(t, suite, image)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	return e
+}
+
+// SkipTest skips the running test if the exceptions of the test VM have an
+// exception for it on the image, and fails it if the exception has expired.
+func SkipTest(t *testing.T, suite, image string) {
+	t.Helper()
+	e, err := matchTest(t, suite, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != nil {
+		t.Skip(e.String())
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exceptions
+
+import (
+	"testing"
+	"time"
+)
+
+const testFile = `
+version: 1
+exceptions:
+  - suite: suite
+    image: debian
+    versions: ">=11,<13"
+    reason: broken
+    tracking: b/1
+    expires: 2026-06-01
+  - suite: suite
+    test: TestA|TestB
+    image: rhel-9
+    reason: flaky
+    tracking: b/2
+    expires: 2026-01-01
+  - suite: other
+    image: windows
+    reason: unsupported
+    tracking: b/3
+    expires: 2026-06-01
+  - feature: ipv6
+    image: rhel-.*sap
+    versions: 8.6 || 9.0
+    reason: no ipv6
+    tracking: b/4
+    expires: 2026-06-01
+  - suite: unselected
+    image: windows
+    reason: unsupported
+    tracking: b/5
+    expires: 2026-06-01
+`
+
+func TestDefault(t *testing.T) {
+	if _, err := Default(); err != nil {
+		t.Errorf("Default() failed: %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	defaults, err := merge(nil)
+	if err != nil {
+		t.Fatalf("merge(nil) failed: %v", err)
+	}
+	override := `
+version: 1
+exceptions:
+  - suite: hotattach
+    image: sles
+    reason: overridden
+    tracking: b/6
+    expires: 2026-06-01
+`
+	l, err := merge([]byte(override))
+	if err != nil {
+		t.Fatalf("merge(%q) failed: %v", override, err)
+	}
+	if got, want := len(l.Exceptions), len(defaults.Exceptions)+1; got != want {
+		t.Errorf("merge(%q) has %d exceptions, want %d", override, got, want)
+	}
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	e, err := l.Match("hotattach", "", "sles-15-sp5-v20260101", now)
+	if err != nil || e == nil || e.Reason != "overridden" {
+		t.Errorf("Match(hotattach, sles-15-sp5) = %v, %v, want the overriding exception", e, err)
+	}
+	if _, err := merge([]byte("version: 2")); err == nil {
+		t.Error("merge(version: 2) succeeded, want an error")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: testFile},
+		{name: "version", data: "version: 2", wantErr: true},
+		{name: "missing suite", data: "version: 1\nexceptions:\n  - {image: i, reason: r, tracking: b/1, expires: 2026-01-01}", wantErr: true},
+		{name: "suite and feature", data: "version: 1\nexceptions:\n  - {suite: s, feature: ipv6, image: i, reason: r, tracking: b/1, expires: 2026-01-01}", wantErr: true},
+		{name: "feature test", data: "version: 1\nexceptions:\n  - {feature: ipv6, test: TestA, image: i, reason: r, tracking: b/1, expires: 2026-01-01}", wantErr: true},
+		{name: "missing tracking", data: "version: 1\nexceptions:\n  - {suite: s, image: i, reason: r, expires: 2026-01-01}", wantErr: true},
+		{name: "invalid image", data: "version: 1\nexceptions:\n  - {suite: s, image: '(', reason: r, tracking: b/1, expires: 2026-01-01}", wantErr: true},
+		{name: "invalid versions", data: "version: 1\nexceptions:\n  - {suite: s, image: i, versions: '>=x', reason: r, tracking: b/1, expires: 2026-01-01}", wantErr: true},
+		{name: "invalid expiry", data: "version: 1\nexceptions:\n  - {suite: s, image: i, reason: r, tracking: b/1, expires: soon}", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load([]byte(tc.data)); (err != nil) != tc.wantErr {
+				t.Errorf("Load() = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestListMatch(t *testing.T) {
+	l, err := Load([]byte(testFile))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		suite, test string
+		image       string
+		wantMatch   bool
+		wantErr     bool
+	}{
+		{name: "suite", suite: "suite", image: "projects/debian-cloud/global/images/debian-12-bookworm-v20260101", wantMatch: true},
+		{name: "version out of range", suite: "suite", image: "debian-13-trixie-v20260101"},
+		{name: "other suite", suite: "other", image: "debian-12-bookworm-v20260101"},
+		{name: "suite exception does not match tests", suite: "suite", test: "TestA", image: "debian-12-bookworm-v20260101"},
+		{name: "test exception does not match suite", suite: "suite", image: "rhel-9-v20260101"},
+		{name: "test", suite: "suite", test: "TestB", image: "rhel-9-v20260101", wantMatch: true, wantErr: true},
+		{name: "other test", suite: "suite", test: "TestAB", image: "rhel-9-v20260101"},
+		{name: "feature exception does not match suites", suite: "", image: "rhel-9-0-sap-ha-v20260101"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := l.Match(tc.suite, tc.test, tc.image, now)
+			if (e != nil) != tc.wantMatch {
+				t.Errorf("Match(%s, %s, %s) = %v, want match: %v", tc.suite, tc.test, tc.image, e, tc.wantMatch)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Match(%s, %s, %s) = %v, want expired: %v", tc.suite, tc.test, tc.image, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestListMatchFeature(t *testing.T) {
+	l, err := Load([]byte(testFile))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	tests := []struct {
+		name      string
+		feature   string
+		image     string
+		now       time.Time
+		wantMatch bool
+		wantErr   bool
+	}{
+		{name: "feature", feature: "ipv6", image: "rhel-9-0-sap-ha-v20260101", now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), wantMatch: true},
+		{name: "version out of range", feature: "ipv6", image: "rhel-9-4-sap-ha-v20260101", now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "other feature", feature: "other", image: "rhel-9-0-sap-ha-v20260101", now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "suite exception does not match features", feature: "ipv6", image: "debian-12-bookworm-v20260101", now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "expired", feature: "ipv6", image: "rhel-8-6-sap-ha-v20260101", now: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), wantMatch: true, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := l.MatchFeature(tc.feature, tc.image, tc.now)
+			if (e != nil) != tc.wantMatch {
+				t.Errorf("MatchFeature(%s, %s) = %v, want match: %v", tc.feature, tc.image, e, tc.wantMatch)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("MatchFeature(%s, %s) = %v, want expired: %v", tc.feature, tc.image, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestListReport(t *testing.T) {
+	l, err := Load([]byte(testFile))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	expired, unused := l.Report([]string{"debian-12-bookworm-v20260101", "rhel-9-v20260101"}, []string{"suite", "other"}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if len(expired) != 1 || expired[0].Tracking != "b/2" {
+		t.Errorf("Report() expired = %v, want b/2", expired)
+	}
+	// The exception of the unselected suite is not unused.
+	if len(unused) != 2 || unused[0].Tracking != "b/3" || unused[1].Tracking != "b/4" {
+		t.Errorf("Report() unused = %v, want b/3 and b/4", unused)
+	}
+}
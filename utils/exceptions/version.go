@@ -0,0 +1,192 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exceptions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+)
+
+// Version is the version of the OS of an image, like 9.4 for rhel-9-4-sap-ha,
+// 22.04 for ubuntu-2204-lts or 15.5 for sles-15-sp5. Components missing from
+// the image name are 0.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// String returns the version formatted as major.minor.patch.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// compare compares the first precision components of the versions, returning
+// -1, 0 or 1 if v is lower than, equal to or greater than o.
+func (v Version) compare(o Version, precision int) int {
+	a := []int{v.Major, v.Minor, v.Patch}
+	b := []int{o.Major, o.Minor, o.Patch}
+	for i := 0; i < precision; i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// VersionFromImage parses the version of the OS from the name of an image or
+// image family, as resolved by utils.ImageVersion. It returns false if the
+// name has no version.
+func VersionFromImage(image string) (Version, bool) {
+	v, _, err := ParseVersion(utils.ImageVersion(image))
+	if err != nil {
+		return Version{}, false
+	}
+	return v, true
+}
+
+// ParseVersion parses a version formatted as major[.minor[.patch]], returning
+// the version and the number of components it has.
+func ParseVersion(s string) (Version, int, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("version %q has more than 3 components", s)
+	}
+	var v Version
+	components := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("version %q is not valid", s)
+		}
+		*components[i] = n
+	}
+	return v, len(parts), nil
+}
+
+type versionConstraint struct {
+	op      string
+	version Version
+	// precision is the number of components of the version compared, so that
+	// =9 matches 9.4 and <10 matches 9.4.
+	precision int
+}
+
+func (c versionConstraint) matches(v Version) bool {
+	cmp := v.compare(c.version, c.precision)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// VersionRange is a set of versions, parsed by ParseVersionRange.
+type VersionRange struct {
+	expr string
+	// alternatives are the constraints of each alternative of the range. A
+	// version is in the range if it matches all the constraints of one
+	// alternative.
+	alternatives [][]versionConstraint
+}
+
+// versionOps are the comparison operators of version constraints, longest
+// first so that >= is not parsed as >.
+var versionOps = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseVersionRange parses a range expression. Constraints are an operator,
+// one of =, !=, >, >=, < and <=, followed by a version. Constraints separated
+// by commas must all match, and alternatives are separated by ||. A version
+// without an operator must be equal. Only the components given in a constraint
+// are compared. For example:
+//
+//	>=9.2,<10
+//	8.6 || 9.0
+//	>=22.04
+func ParseVersionRange(expr string) (VersionRange, error) {
+	r := VersionRange{expr: expr}
+	for _, alternative := range strings.Split(expr, "||") {
+		var constraints []versionConstraint
+		for _, s := range strings.Split(alternative, ",") {
+			s = strings.TrimSpace(s)
+			c := versionConstraint{op: "="}
+			for _, op := range versionOps {
+				if strings.HasPrefix(s, op) {
+					c.op = op
+					s = strings.TrimSpace(strings.TrimPrefix(s, op))
+					break
+				}
+			}
+			var err error
+			if c.version, c.precision, err = ParseVersion(s); err != nil {
+				return VersionRange{}, fmt.Errorf("invalid version range %q: %v", expr, err)
+			}
+			constraints = append(constraints, c)
+		}
+		r.alternatives = append(r.alternatives, constraints)
+	}
+	return r, nil
+}
+
+// Contains returns whether the version is in the range.
+func (r VersionRange) Contains(v Version) bool {
+	for _, constraints := range r.alternatives {
+		matches := true
+		for _, c := range constraints {
+			if !c.matches(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsImage returns whether the version of the image is in the range. It
+// returns false if the image name has no version.
+func (r VersionRange) ContainsImage(image string) bool {
+	v, ok := VersionFromImage(image)
+	return ok && r.Contains(v)
+}
+
+// ContainsGuestOS returns whether the version of the guest OS is in the range.
+// It returns false if the version of the guest OS is unknown.
+func (r VersionRange) ContainsGuestOS(g utils.GuestOS) bool {
+	v, _, err := ParseVersion(g.Version)
+	return err == nil && r.Contains(v)
+}
+
+// String returns the range expression.
+func (r VersionRange) String() string {
+	return r.expr
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exceptions
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestVersionFromImage(t *testing.T) {
+	tests := []struct {
+		image  string
+		want   Version
+		wantOK bool
+	}{
+		{image: "rhel-9-4-sap-ha-v20260101", want: Version{Major: 9, Minor: 4}, wantOK: true},
+		{image: "rhel-8-10-sap-ha", want: Version{Major: 8, Minor: 10}, wantOK: true},
+		{image: "rhel-9-v20260101", want: Version{Major: 9}, wantOK: true},
+		{image: "projects/rocky-linux-cloud/global/images/family/rocky-linux-8-optimized-gcp", want: Version{Major: 8}, wantOK: true},
+		{image: "debian-12-bookworm-arm64-v20260101", want: Version{Major: 12}, wantOK: true},
+		{image: "ubuntu-2204-jammy-v20260101", want: Version{Major: 22, Minor: 4}, wantOK: true},
+		{image: "ubuntu-minimal-2404-lts-arm64", want: Version{Major: 24, Minor: 4}, wantOK: true},
+		{image: "sles-15-sp5-sap-v20260101", want: Version{Major: 15, Minor: 5}, wantOK: true},
+		{image: "sles-16-0-x86-64-v20260101", want: Version{Major: 16}, wantOK: true},
+		{image: "opensuse-leap-15-6-v20260101", want: Version{Major: 15, Minor: 6}, wantOK: true},
+		{image: "cos-stable-117-18613-0-79", want: Version{Major: 117, Minor: 18613}, wantOK: true},
+		{image: "windows-server-2022-dc-v20260101", want: Version{Major: 2022}, wantOK: true},
+		{image: "sql-2019-web-windows-2022-dc-v20260101", want: Version{Major: 2022}, wantOK: true},
+		{image: "my-custom-image"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			got, ok := VersionFromImage(tc.image)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("VersionFromImage(%q) = %v, %v, want %v, %v", tc.image, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		expr    string
+		image   string
+		want    bool
+		wantErr bool
+	}{
+		{expr: ">=9.2,<10", image: "rhel-9-4-sap-ha", want: true},
+		{expr: ">=9.2,<10", image: "rhel-9-0-sap-ha"},
+		{expr: ">=9.2,<10", image: "rhel-10-0"},
+		{expr: "8.6 || 9.0", image: "rhel-8-6-sap-ha", want: true},
+		{expr: "8.6 || 9.0", image: "rhel-9-0-sap-byos", want: true},
+		{expr: "8.6 || 9.0", image: "rhel-9-2-sap-ha"},
+		{expr: "9", image: "rhel-9-4", want: true},
+		{expr: ">9", image: "rhel-9-4"},
+		{expr: "<=9", image: "rhel-9-4", want: true},
+		{expr: "!=15.5", image: "sles-15-sp6", want: true},
+		{expr: ">=22.04", image: "ubuntu-2404-noble-amd64", want: true},
+		{expr: "== 20.04", image: "ubuntu-2204-jammy"},
+		{expr: ">=1", image: "my-custom-image"},
+		{expr: ">=9.x", wantErr: true},
+		{expr: "1.2.3.4", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr+" "+tc.image, func(t *testing.T) {
+			r, err := ParseVersionRange(tc.expr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseVersionRange(%q) = %v, want error: %v", tc.expr, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := r.ContainsImage(tc.image); got != tc.want {
+				t.Errorf("ParseVersionRange(%q).ContainsImage(%q) = %v, want %v", tc.expr, tc.image, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVersionRangeContainsGuestOS(t *testing.T) {
+	r, err := ParseVersionRange("15.5 || 15.6")
+	if err != nil {
+		t.Fatalf("ParseVersionRange() failed: %v", err)
+	}
+	tests := []struct {
+		guestOS utils.GuestOS
+		want    bool
+	}{
+		{guestOS: utils.GuestOSFromImage(&compute.Image{Name: "sles-15-sp5-v20260101"}), want: true},
+		{guestOS: utils.GuestOS{Distro: utils.DistroSLES, Version: "15.6"}, want: true},
+		{guestOS: utils.GuestOS{Distro: utils.DistroSLES, Version: "15.7"}},
+		{guestOS: utils.GuestOS{Distro: utils.DistroSLES}},
+	}
+	for _, tc := range tests {
+		if got := r.ContainsGuestOS(tc.guestOS); got != tc.want {
+			t.Errorf("ContainsGuestOS(%v) = %v, want %v", tc.guestOS, got, tc.want)
+		}
+	}
+}
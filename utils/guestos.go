@@ -0,0 +1,368 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// Distributions of a GuestOS. They are the IDs of the distributions in
+// /etc/os-release.
+const (
+	DistroAlmaLinux = "almalinux"
+	DistroCentOS    = "centos"
+	DistroCOS       = "cos"
+	DistroDebian    = "debian"
+	DistroFedora    = "fedora"
+	DistroOpenSUSE  = "opensuse-leap"
+	DistroOracle    = "ol"
+	DistroRHEL      = "rhel"
+	DistroRocky     = "rocky"
+	DistroSLES      = "sles"
+	DistroUbuntu    = "ubuntu"
+	DistroWindows   = "windows"
+)
+
+// Variants of a GuestOS.
+const (
+	VariantAccelerator = "accelerator"
+	VariantBYOS        = "byos"
+	VariantEUS         = "eus"
+	VariantGuestAgent  = "guest-agent"
+	VariantLVM         = "lvm"
+	VariantMinimal     = "minimal"
+	VariantSAP         = "sap"
+)
+
+// GuestOSMetadataKey is the metadata key of test VMs holding the guest OS of
+// the image under test, as resolved by the test workflow, in JSON.
+const GuestOSMetadataKey = "_cit_guest_os"
+
+// distroPrefixes maps distributions to the prefix of the names of their image
+// families, images and licenses.
+var distroPrefixes = []struct {
+	distro string
+	prefix *regexp.Regexp
+}{
+	{DistroAlmaLinux, regexp.MustCompile(`^almalinux`)},
+	{DistroCentOS, regexp.MustCompile(`^centos`)},
+	{DistroCOS, regexp.MustCompile(`^cos\b`)},
+	{DistroDebian, regexp.MustCompile(`^debian`)},
+	{DistroFedora, regexp.MustCompile(`^fedora`)},
+	{DistroOpenSUSE, regexp.MustCompile(`^opensuse(-leap)?`)},
+	{DistroOracle, regexp.MustCompile(`^oracle-linux`)},
+	{DistroRHEL, regexp.MustCompile(`^rhel`)},
+	{DistroRocky, regexp.MustCompile(`^rocky-linux`)},
+	{DistroSLES, regexp.MustCompile(`^sles`)},
+	{DistroUbuntu, regexp.MustCompile(`^ubuntu`)},
+	{DistroWindows, regexp.MustCompile(`^(sql-.*-)?windows`)},
+}
+
+// imageVersionFormats are the naming conventions of the versions in the names
+// of images, image families and licenses, tried in order. The submatches are
+// the major, minor and patch versions.
+var imageVersionFormats = []*regexp.Regexp{
+	// ubuntu-2204-lts, ubuntu-minimal-2404-lts-arm64, ubuntu-pro-fips-2004.
+	regexp.MustCompile(`^ubuntu-(?:[a-z]+-)*(\d{2})(\d{2})\b`),
+	// sles-15-sp5, sles-12-sp5-sap, sles-16-0.
+	regexp.MustCompile(`^sles-(\d+)(?:-(?:sp)?(\d{1,2})\b)?`),
+	// cos-117-18613-0-79, cos-stable-117-18613-0-79, cos-arm64-beta-121-18867-0-4.
+	regexp.MustCompile(`^cos-(?:[a-z][a-z0-9]*-)*(\d+)(?:-(\d+)(?:-(\d+))?)?`),
+	// windows-server-2022-dc, windows-11-24h2-x64, sql-2022-web-windows-2022-dc.
+	regexp.MustCompile(`(?:^|-)windows-(?:[a-z]+-)*(\d+)\b`),
+	// rhel-9-4-sap-ha, rocky-linux-8-10, debian-12-bookworm, opensuse-leap-15-6.
+	regexp.MustCompile(`^[a-z]+(?:-[a-z]+)*-(\d+)(?:-(\d{1,2})\b)?(?:-(\d{1,2})\b)?`),
+}
+
+// variantTokens maps the dash separated tokens of image families, images and
+// licenses to variants.
+var variantTokens = map[string]string{
+	"accelerator": VariantAccelerator,
+	"nvidia":      VariantAccelerator,
+	"byos":        VariantBYOS,
+	"eus":         VariantEUS,
+	"lvm":         VariantLVM,
+	"minimal":     VariantMinimal,
+	"sap":         VariantSAP,
+}
+
+// GuestOS identifies the operating system of an image or of a running guest.
+// Unlike the Is* functions on image names, it also identifies images with
+// custom names derived from public images.
+type GuestOS struct {
+	// Distro is the distribution, one of the Distro constants, or empty if it
+	// is unknown.
+	Distro string `json:"distro,omitempty"`
+	// Version is the version of the distribution, like 12, 9.4, 15.5, 22.04
+	// or 2022. It is as precise as the source it is resolved from, see
+	// ImageVersion.
+	Version string `json:"version,omitempty"`
+	// Variants are the variants of the distribution, the Variant constants,
+	// sorted.
+	Variants []string `json:"variants,omitempty"`
+	// Architecture is the architecture of the image, X86_64 or ARM64.
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// GuestOSFromImage resolves the guest OS of an image. The distribution is
+// resolved from the licenses of the image, which are kept by derived images,
+// then from its family and name. The version is resolved from the family, the
+// name and the licenses, in this order.
+func GuestOSFromImage(image *compute.Image) GuestOS {
+	var licenses []string
+	for _, l := range image.Licenses {
+		licenses = append(licenses, l[strings.LastIndex(l, "/")+1:])
+	}
+	g := GuestOS{Architecture: image.Architecture}
+	for _, name := range append(slices.Clone(licenses), image.Family, image.Name) {
+		if g.Distro = distroOf(name); g.Distro != "" {
+			break
+		}
+	}
+	if g.Distro == "" && HasFeature(image, "WINDOWS") {
+		g.Distro = DistroWindows
+	}
+	for _, name := range append([]string{image.Family, image.Name}, licenses...) {
+		if distroOf(name) != g.Distro {
+			continue
+		}
+		if g.Version = ImageVersion(name); g.Version != "" {
+			break
+		}
+	}
+	// The project of a license identifies variants too, like rhel-byos-cloud.
+	for _, name := range append(append(licenses, image.Licenses...), image.Family, image.Name) {
+		for _, token := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '/' }) {
+			if v, ok := variantTokens[token]; ok && !slices.Contains(g.Variants, v) {
+				g.Variants = append(g.Variants, v)
+			}
+		}
+	}
+	// Images built with a release of the guest agent keep the licenses of the
+	// image they are derived from, only their name identifies them.
+	if strings.Contains(image.Name, "guest-agent") {
+		g.Variants = append(g.Variants, VariantGuestAgent)
+	}
+	slices.Sort(g.Variants)
+	return g
+}
+
+func distroOf(name string) string {
+	for _, d := range distroPrefixes {
+		if d.prefix.MatchString(name) {
+			return d.distro
+		}
+	}
+	return ""
+}
+
+// ImageVersion returns the version of the OS in the name of an image, image
+// family or license, following the naming convention of its distribution,
+// like 9.4 for rhel-9-4-sap-ha, 22.04 for ubuntu-2204-lts or 15.5 for
+// sles-15-sp5. It returns an empty string if the name has no version.
+func ImageVersion(name string) string {
+	name = name[strings.LastIndex(name, "/")+1:]
+	for _, format := range imageVersionFormats {
+		m := format.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		var components []string
+		for _, c := range m[1:] {
+			if c == "" {
+				break
+			}
+			components = append(components, c)
+		}
+		return strings.Join(components, ".")
+	}
+	return ""
+}
+
+// parseOSRelease resolves the guest OS from the content of /etc/os-release.
+func parseOSRelease(content string) GuestOS {
+	g := GuestOS{Architecture: runtimeArchitecture()}
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			// SLES for SAP has its own ID.
+			if value == "sles_sap" {
+				g.Distro = DistroSLES
+				g.Variants = append(g.Variants, VariantSAP)
+			} else {
+				g.Distro = value
+			}
+		case "VERSION_ID":
+			g.Version = value
+		}
+	}
+	return g
+}
+
+var windowsVersionRe = regexp.MustCompile(`^Windows (?:Server )?(\d+)`)
+
+// parseWindowsVersion resolves the guest OS from the ProductName and
+// CurrentBuildNumber in the CurrentVersion registry key.
+func parseWindowsVersion(productName, buildNumber string) GuestOS {
+	g := GuestOS{Distro: DistroWindows, Architecture: runtimeArchitecture()}
+	if m := windowsVersionRe.FindStringSubmatch(productName); m != nil {
+		g.Version = m[1]
+	}
+	// Windows 11 still reports Windows 10 as its product name.
+	if build, err := strconv.Atoi(buildNumber); err == nil && g.Version == "10" && build >= 22000 {
+		g.Version = "11"
+	}
+	return g
+}
+
+func runtimeArchitecture() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "X86_64"
+	case "arm64":
+		return "ARM64"
+	default:
+		return strings.ToUpper(runtime.GOARCH)
+	}
+}
+
+var (
+	loadImageGuestOS sync.Once
+	imageGuestOS     GuestOS
+	imageGuestOSErr  error
+)
+
+// getImageGuestOS returns the guest OS of the image of the running test VM,
+// from its GuestOSMetadataKey attribute.
+func getImageGuestOS() (GuestOS, error) {
+	loadImageGuestOS.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		value, err := GetMetadata(ctx, "instance", "attributes", GuestOSMetadataKey)
+		if err != nil {
+			imageGuestOSErr = fmt.Errorf("could not read the %s metadata: %v", GuestOSMetadataKey, err)
+			return
+		}
+		imageGuestOS, imageGuestOSErr = ParseGuestOSMetadata(value)
+	})
+	return imageGuestOS, imageGuestOSErr
+}
+
+// ParseGuestOSMetadata parses the guest OS of the GuestOSMetadataKey attribute.
+func ParseGuestOSMetadata(value string) (GuestOS, error) {
+	var g GuestOS
+	if err := json.Unmarshal([]byte(value), &g); err != nil {
+		return GuestOS{}, fmt.Errorf("invalid guest OS %q: %v", value, err)
+	}
+	return g, nil
+}
+
+// Metadata returns the guest OS as the value of the GuestOSMetadataKey
+// attribute.
+func (g GuestOS) Metadata() string {
+	data, err := json.Marshal(g)
+	if err != nil {
+		// A GuestOS only has strings, which always marshal.
+		panic(err)
+	}
+	return string(data)
+}
+
+// withImage completes the guest OS of a running guest with the guest OS of
+// its image: the distribution and version when the guest does not report
+// them, and the variants which only the image identifies, like SAP, BYOS, EUS
+// and LVM.
+func (g GuestOS) withImage(image GuestOS) GuestOS {
+	if g.Distro == "" {
+		g.Distro, g.Version = image.Distro, image.Version
+	}
+	if g.Version == "" && g.Distro == image.Distro {
+		g.Version = image.Version
+	}
+	for _, v := range image.Variants {
+		if !slices.Contains(g.Variants, v) {
+			g.Variants = append(g.Variants, v)
+		}
+	}
+	slices.Sort(g.Variants)
+	return g
+}
+
+// IsWindows returns whether the guest OS is Windows.
+func (g GuestOS) IsWindows() bool {
+	return g.Distro == DistroWindows
+}
+
+// IsLinux returns whether the guest OS is Linux, which includes unknown
+// distributions other than Windows.
+func (g GuestOS) IsLinux() bool {
+	return !g.IsWindows()
+}
+
+// IsEL returns whether the guest OS is an Enterprise Linux distribution.
+func (g GuestOS) IsEL() bool {
+	switch g.Distro {
+	case DistroAlmaLinux, DistroCentOS, DistroOracle, DistroRHEL, DistroRocky:
+		return true
+	}
+	return false
+}
+
+// IsSUSE returns whether the guest OS is a SUSE distribution.
+func (g GuestOS) IsSUSE() bool {
+	return g.Distro == DistroSLES || g.Distro == DistroOpenSUSE
+}
+
+// HasVariant returns whether the guest OS is the given variant.
+func (g GuestOS) HasVariant(variant string) bool {
+	return slices.Contains(g.Variants, variant)
+}
+
+// MajorVersion returns the major version of the guest OS.
+func (g GuestOS) MajorVersion() string {
+	major, _, _ := strings.Cut(g.Version, ".")
+	return major
+}
+
+// MinorVersion returns the minor version of the guest OS, or an empty string
+// if it is unknown.
+func (g GuestOS) MinorVersion() string {
+	_, minor, _ := strings.Cut(g.Version, ".")
+	return minor
+}
+
+// String returns a description of the guest OS, like "rhel 9.4 sap X86_64".
+func (g GuestOS) String() string {
+	fields := []string{g.Distro, g.Version}
+	fields = append(fields, g.Variants...)
+	fields = append(fields, g.Architecture)
+	return strings.Join(slices.DeleteFunc(fields, func(s string) bool { return s == "" }), " ")
+}
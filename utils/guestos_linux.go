@@ -0,0 +1,42 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// GetGuestOS resolves the guest OS of the running guest from /etc/os-release,
+// completed with the variants of the guest OS of its image, which the test
+// workflow sets in the GuestOSMetadataKey attribute. Accelerator guests are
+// also identified by the presence of nvidia-smi.
+func GetGuestOS() (GuestOS, error) {
+	content, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return GuestOS{}, fmt.Errorf("could not read /etc/os-release: %v", err)
+	}
+	g := parseOSRelease(string(content))
+	if CheckLinuxCmdExists("nvidia-smi") {
+		g.Variants = append(g.Variants, VariantAccelerator)
+	}
+	image, err := getImageGuestOS()
+	if err != nil {
+		return GuestOS{}, err
+	}
+	return g.withImage(image), nil
+}
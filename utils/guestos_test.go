@@ -0,0 +1,238 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestGuestOSFromImage(t *testing.T) {
+	tests := []struct {
+		name  string
+		image *compute.Image
+		want  GuestOS
+	}{
+		{
+			name: "debian",
+			image: &compute.Image{
+				Name:         "debian-12-bookworm-v20260101",
+				Family:       "debian-12",
+				Architecture: "X86_64",
+				Licenses:     []string{"https://www.googleapis.com/compute/v1/projects/debian-cloud/global/licenses/debian-12-bookworm"},
+			},
+			want: GuestOS{Distro: DistroDebian, Version: "12", Architecture: "X86_64"},
+		},
+		{
+			name: "rhel sap",
+			image: &compute.Image{
+				Name:     "rhel-9-4-sap-v20260101",
+				Family:   "rhel-9-4-sap-ha",
+				Licenses: []string{"https://www.googleapis.com/compute/v1/projects/rhel-sap-cloud/global/licenses/rhel-9-sap"},
+			},
+			want: GuestOS{Distro: DistroRHEL, Version: "9.4", Variants: []string{VariantSAP}},
+		},
+		{
+			name:  "sles",
+			image: &compute.Image{Name: "sles-15-sp5-sap-v20260101", Family: "sles-15-sp5-sap"},
+			want:  GuestOS{Distro: DistroSLES, Version: "15.5", Variants: []string{VariantSAP}},
+		},
+		{
+			name: "guest agent build",
+			image: &compute.Image{
+				Name:     "debian-12-guest-agent-stable-v20260101",
+				Licenses: []string{"https://www.googleapis.com/compute/v1/projects/debian-cloud/global/licenses/debian-12-bookworm"},
+			},
+			want: GuestOS{Distro: DistroDebian, Version: "12", Variants: []string{VariantGuestAgent}},
+		},
+		{
+			name: "custom name derived from rhel byos",
+			image: &compute.Image{
+				Name:     "golden-image-v3",
+				Licenses: []string{"https://www.googleapis.com/compute/v1/projects/rhel-byos-cloud/global/licenses/rhel-8-byos"},
+			},
+			want: GuestOS{Distro: DistroRHEL, Version: "8", Variants: []string{VariantBYOS}},
+		},
+		{
+			name:  "name containing cos",
+			image: &compute.Image{Name: "rocky-linux-9-optimized-gcp-nvidia-latest-v20260101", Family: "rocky-linux-9-optimized-gcp-nvidia-latest"},
+			want:  GuestOS{Distro: DistroRocky, Version: "9", Variants: []string{VariantAccelerator}},
+		},
+		{
+			name:  "cos",
+			image: &compute.Image{Name: "cos-117-18613-0-79", Family: "cos-117-lts"},
+			want:  GuestOS{Distro: DistroCOS, Version: "117"},
+		},
+		{
+			name:  "ubuntu minimal",
+			image: &compute.Image{Name: "ubuntu-minimal-2404-noble-amd64-v20260101", Family: "ubuntu-minimal-2404-lts-amd64"},
+			want:  GuestOS{Distro: DistroUbuntu, Version: "24.04", Variants: []string{VariantMinimal}},
+		},
+		{
+			name:  "sql server",
+			image: &compute.Image{Name: "sql-2022-standard-windows-2022-dc-v20260101", Family: "sql-std-2022-win-2022"},
+			want:  GuestOS{Distro: DistroWindows, Version: "2022"},
+		},
+		{
+			name: "windows by feature",
+			image: &compute.Image{
+				Name:            "my-windows-build",
+				GuestOsFeatures: []*compute.GuestOsFeature{{Type: "WINDOWS"}},
+			},
+			want: GuestOS{Distro: DistroWindows},
+		},
+		{
+			name:  "unknown",
+			image: &compute.Image{Name: "my-image"},
+			want:  GuestOS{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GuestOSFromImage(tc.image)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GuestOSFromImage(%s) returned unexpected guest OS (-want +got):\n%s", tc.image.Name, diff)
+			}
+		})
+	}
+}
+
+func TestImageVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "rhel-9-4-sap-ha-v20260101", want: "9.4"},
+		{name: "rhel-8-10-sap-ha", want: "8.10"},
+		{name: "projects/rocky-linux-cloud/global/images/family/rocky-linux-8-optimized-gcp", want: "8"},
+		{name: "ubuntu-pro-2204-lts", want: "22.04"},
+		{name: "sles-15-sp5-sap-v20260101", want: "15.5"},
+		{name: "sles-16-0-x86-64-v20260101", want: "16.0"},
+		{name: "cos-stable-117-18613-0-79", want: "117.18613.0"},
+		{name: "sql-2019-web-windows-2022-dc-v20260101", want: "2022"},
+		{name: "my-custom-image"},
+	}
+	for _, tc := range tests {
+		if got := ImageVersion(tc.name); got != tc.want {
+			t.Errorf("ImageVersion(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantDistro   string
+		wantVersion  string
+		wantVariants []string
+	}{
+		{
+			name:        "rhel",
+			content:     "NAME=\"Red Hat Enterprise Linux\"\nID=\"rhel\"\nID_LIKE=\"fedora\"\nVERSION_ID=\"9.4\"\n",
+			wantDistro:  DistroRHEL,
+			wantVersion: "9.4",
+		},
+		{
+			name:         "sles for sap",
+			content:      "NAME=\"SLES_SAP\"\nVERSION_ID=\"15.5\"\nID=\"sles_sap\"\nID_LIKE=\"suse\"\n",
+			wantDistro:   DistroSLES,
+			wantVersion:  "15.5",
+			wantVariants: []string{VariantSAP},
+		},
+		{
+			name:        "debian",
+			content:     "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nVERSION_ID=\"12\"\nID=debian\n",
+			wantDistro:  DistroDebian,
+			wantVersion: "12",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseOSRelease(tc.content)
+			if got.Distro != tc.wantDistro || got.Version != tc.wantVersion || !cmp.Equal(got.Variants, tc.wantVariants) {
+				t.Errorf("parseOSRelease() = %+v, want distro %q version %q variants %v", got, tc.wantDistro, tc.wantVersion, tc.wantVariants)
+			}
+		})
+	}
+}
+
+func TestParseWindowsVersion(t *testing.T) {
+	tests := []struct {
+		productName string
+		buildNumber string
+		want        string
+	}{
+		{productName: "Windows Server 2022 Datacenter", buildNumber: "20348", want: "2022"},
+		{productName: "Windows 10 Pro", buildNumber: "19045", want: "10"},
+		{productName: "Windows 10 Enterprise", buildNumber: "22631", want: "11"},
+	}
+	for _, tc := range tests {
+		if got := parseWindowsVersion(tc.productName, tc.buildNumber); got.Version != tc.want || !got.IsWindows() {
+			t.Errorf("parseWindowsVersion(%q, %q) = %+v, want windows version %s", tc.productName, tc.buildNumber, got, tc.want)
+		}
+	}
+}
+
+func TestGuestOSMetadata(t *testing.T) {
+	want := GuestOS{Distro: DistroRHEL, Version: "9.4", Variants: []string{VariantEUS, VariantSAP}, Architecture: "X86_64"}
+	got, err := ParseGuestOSMetadata(want.Metadata())
+	if err != nil {
+		t.Fatalf("ParseGuestOSMetadata(%q) failed: %v", want.Metadata(), err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseGuestOSMetadata(%q) returned unexpected guest OS (-want +got):\n%s", want.Metadata(), diff)
+	}
+	if _, err := ParseGuestOSMetadata("rhel"); err == nil {
+		t.Error("ParseGuestOSMetadata(rhel) succeeded, want an error")
+	}
+}
+
+func TestGuestOSWithImage(t *testing.T) {
+	tests := []struct {
+		name  string
+		guest GuestOS
+		image GuestOS
+		want  GuestOS
+	}{
+		{
+			name:  "variants of the image",
+			guest: GuestOS{Distro: DistroRHEL, Version: "9.4", Architecture: "X86_64"},
+			image: GuestOS{Distro: DistroRHEL, Version: "9", Variants: []string{VariantSAP}},
+			want:  GuestOS{Distro: DistroRHEL, Version: "9.4", Variants: []string{VariantSAP}, Architecture: "X86_64"},
+		},
+		{
+			name:  "variants of the guest and image",
+			guest: GuestOS{Distro: DistroSLES, Version: "15.5", Variants: []string{VariantSAP}},
+			image: GuestOS{Distro: DistroSLES, Version: "15.5", Variants: []string{VariantBYOS, VariantSAP}},
+			want:  GuestOS{Distro: DistroSLES, Version: "15.5", Variants: []string{VariantBYOS, VariantSAP}},
+		},
+		{
+			name:  "unknown guest",
+			guest: GuestOS{Architecture: "ARM64"},
+			image: GuestOS{Distro: DistroDebian, Version: "12"},
+			want:  GuestOS{Distro: DistroDebian, Version: "12", Architecture: "ARM64"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.guest.withImage(tc.image)); diff != "" {
+				t.Errorf("withImage() returned unexpected guest OS (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
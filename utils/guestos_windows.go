@@ -0,0 +1,41 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GetGuestOS resolves the guest OS of the running guest from the
+// CurrentVersion registry key, completed with the variants of the guest OS of
+// its image, which the test workflow sets in the GuestOSMetadataKey attribute.
+func GetGuestOS() (GuestOS, error) {
+	status, err := RunPowershellCmd(`$v = Get-ItemProperty 'HKLM:\SOFTWARE\Microsoft\Windows NT\CurrentVersion'; Write-Output "$($v.ProductName)|$($v.CurrentBuildNumber)"`)
+	if err != nil {
+		return GuestOS{}, fmt.Errorf("failed to read the CurrentVersion registry key: %v", err)
+	}
+	productName, buildNumber, ok := strings.Cut(strings.TrimSpace(status.Stdout), "|")
+	if !ok {
+		return GuestOS{}, fmt.Errorf("unexpected CurrentVersion registry key values %q", status.Stdout)
+	}
+	image, err := getImageGuestOS()
+	if err != nil {
+		return GuestOS{}, err
+	}
+	return parseWindowsVersion(productName, buildNumber).withImage(image), nil
+}
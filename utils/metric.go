@@ -0,0 +1,125 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// MetricsFileEnv is the environment variable the wrapper sets to the file
+// RecordMetric appends the metrics of the test binary to.
+const MetricsFileEnv = "CIT_METRICS_FILE"
+
+var metricsFileMu sync.Mutex
+
+// Metric is a value measured by a test, like a boot time or a throughput.
+type Metric struct {
+	// Test is the name of the test which measured the value.
+	Test  string  `json:"test"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	// Unit is the unit of the value, like s or Gbps.
+	Unit string `json:"unit,omitempty"`
+	// Labels tell apart the values of a metric measured by a test, like the
+	// interface a throughput was measured on.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Key returns the name of the metric with its labels, like
+// throughput{interface=0}, which is unique within a test.
+func (m Metric) Key() string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	var labels []string
+	for k, v := range m.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	return m.Name + "{" + strings.Join(labels, ",") + "}"
+}
+
+// LowerIsBetter returns whether lower values of the metric are better, which is
+// the case of durations, like boot times and latencies, and of retransmit
+// counts. Higher values are better for other units, like throughputs and IOPS.
+func (m Metric) LowerIsBetter() bool {
+	switch m.Unit {
+	case "ns", "us", "ms", "s", "min", "retransmits":
+		return true
+	}
+	return false
+}
+
+// RecordMetric records a value measured by the test. The value is logged, and
+// appended to the metrics file the wrapper uploads with the test results, so
+// that the manager reports it in the junit properties of the suite and in its
+// metrics output. Labels may be nil.
+func RecordMetric(t *testing.T, name string, value float64, unit string, labels map[string]string) {
+	t.Helper()
+	m := Metric{Test: t.Name(), Name: name, Value: value, Unit: unit, Labels: labels}
+	t.Logf("metric %s = %g %s", m.Key(), value, unit)
+	path := os.Getenv(MetricsFileEnv)
+	if path == "" {
+		return
+	}
+	if err := appendMetric(path, m); err != nil {
+		t.Logf("failed to record metric %s: %v", m.Key(), err)
+	}
+}
+
+func appendMetric(path string, m Metric) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	metricsFileMu.Lock()
+	defer metricsFileMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadMetrics reads the metrics written by RecordMetric, one JSON object per
+// line.
+func ReadMetrics(r io.Reader) ([]Metric, error) {
+	var metrics []Metric
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		var m Metric
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			return nil, fmt.Errorf("invalid metric %q: %v", line, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, s.Err()
+}
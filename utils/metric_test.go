@@ -0,0 +1,77 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordMetric(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	t.Setenv(MetricsFileEnv, path)
+	RecordMetric(t, "boot_time", 21, "s", nil)
+	RecordMetric(t, "throughput", 98.5, "Gbps", map[string]string{"interface": "1"})
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("os.Open(%q) = %v, want nil", path, err)
+	}
+	defer f.Close()
+	got, err := ReadMetrics(f)
+	if err != nil {
+		t.Fatalf("ReadMetrics() = %v, want nil", err)
+	}
+	want := []Metric{
+		{Test: "TestRecordMetric", Name: "boot_time", Value: 21, Unit: "s"},
+		{Test: "TestRecordMetric", Name: "throughput", Value: 98.5, Unit: "Gbps", Labels: map[string]string{"interface": "1"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadMetrics() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadMetricsInvalid(t *testing.T) {
+	if _, err := ReadMetrics(strings.NewReader("{\"test\":\"TestBootTime\"}\nboot_time=21\n")); err == nil {
+		t.Errorf("ReadMetrics() = nil, want error")
+	}
+}
+
+func TestMetricKey(t *testing.T) {
+	tests := []struct {
+		metric Metric
+		want   string
+	}{
+		{metric: Metric{Name: "boot_time"}, want: "boot_time"},
+		{metric: Metric{Name: "throughput", Labels: map[string]string{"interface": "0", "direction": "rx"}}, want: "throughput{direction=rx,interface=0}"},
+	}
+	for _, tc := range tests {
+		if got := tc.metric.Key(); got != tc.want {
+			t.Errorf("%+v.Key() = %q, want %q", tc.metric, got, tc.want)
+		}
+	}
+}
+
+func TestMetricLowerIsBetter(t *testing.T) {
+	for unit, want := range map[string]bool{"s": true, "ms": true, "Gbps": false, "retransmits": true, "pps": false, "IOPS": false, "": false} {
+		if got := (Metric{Unit: unit}).LowerIsBetter(); got != want {
+			t.Errorf("Metric{Unit: %q}.LowerIsBetter() = %v, want %v", unit, got, want)
+		}
+	}
+}
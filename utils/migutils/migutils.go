@@ -0,0 +1,589 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migutils manages the managed instance groups of test VMs created by
+// imagetest.ManagedInstanceGroup. Daisy cannot create instance templates or
+// managed instance groups, so the group is created by the wrapper on a
+// controller test VM, and tests on the controller VM use this package to act
+// on the group (autohealing, rolling updates, ...).
+package migutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	// SpecMetadataKey is the metadata key on the controller VM holding the JSON
+	// encoded Spec of the managed instance group.
+	SpecMetadataKey = "_cit_mig_spec"
+
+	pollInterval = 10 * time.Second
+)
+
+// stateDir is where the current instance template version of groups is
+// recorded. The wrapper and the tests on the controller VM run in separate
+// processes, so the wrapper reads the version recorded by rolling updates
+// to delete every template version.
+var stateDir = os.TempDir()
+
+// Spec describes a managed instance group of test VMs. The group is created
+// in the zone of the controller VM, or in its region for regional groups.
+type Spec struct {
+	// Name is the name of the managed instance group.
+	Name string `json:"name"`
+	// Regional indicates a regional rather than a zonal group.
+	Regional bool `json:"regional,omitempty"`
+	// Size is the target size of the group.
+	Size int64 `json:"size"`
+	// BaseInstanceName is the prefix of the names of the group members.
+	BaseInstanceName string `json:"baseInstanceName"`
+	// Template is the instance template of the group members.
+	Template *compute.InstanceTemplate `json:"template"`
+	// HealthCheck is the health check used for autohealing, if any.
+	HealthCheck *compute.HealthCheck `json:"healthCheck,omitempty"`
+	// AutoHealingInitialDelaySec is the time members have to boot before
+	// autohealing starts checking them.
+	AutoHealingInitialDelaySec int64 `json:"autoHealingInitialDelaySec,omitempty"`
+	// StatefulDisks are the device names of disks preserved when members are
+	// recreated.
+	StatefulDisks []string `json:"statefulDisks,omitempty"`
+	// ResultsURL is the gs:// prefix members upload their test results to.
+	ResultsURL string `json:"resultsURL"`
+}
+
+// Group is a managed instance group created from a Spec.
+type Group struct {
+	Spec    *Spec
+	project string
+	zone    string
+	region  string
+	service *compute.Service
+	// templateVersion is incremented on each rolling update to name the new
+	// instance template, and recorded in the versionFile.
+	templateVersion int
+}
+
+// GetSpec returns the managed instance group Spec from the metadata of the
+// controller VM. It returns an error if the VM is not a controller VM.
+func GetSpec(ctx context.Context) (*Spec, error) {
+	data, err := utils.GetMetadata(ctx, "instance", "attributes", SpecMetadataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata %s: %v", SpecMetadataKey, err)
+	}
+	spec := &Spec{}
+	if err := json.Unmarshal([]byte(data), spec); err != nil {
+		return nil, fmt.Errorf("failed to parse managed instance group spec: %v", err)
+	}
+	return spec, nil
+}
+
+// NewGroup returns the Group for spec, in the project and zone or region of
+// the calling VM. The group is not created.
+func NewGroup(ctx context.Context, spec *Spec) (*Group, error) {
+	project, zone, err := utils.GetProjectZone(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var opts []option.ClientOption
+	if endpoint, err := utils.GetMetadata(ctx, "instance", "attributes", "_compute_endpoint"); err == nil && endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	service, err := compute.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compute service: %v", err)
+	}
+	g := &Group{Spec: spec, project: project, service: service}
+	if spec.Regional {
+		g.region = zone[:strings.LastIndex(zone, "-")]
+	} else {
+		g.zone = zone
+	}
+	if err := g.loadTemplateVersion(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Get returns the Group of the controller VM, for use by tests running on the
+// controller VM once the wrapper has created the group.
+func Get(ctx context.Context) (*Group, error) {
+	spec, err := GetSpec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewGroup(ctx, spec)
+}
+
+// Create creates the health check, instance template and managed instance
+// group, and waits for the group to be stable.
+func (g *Group) Create(ctx context.Context) error {
+	var autoHealing []*compute.InstanceGroupManagerAutoHealingPolicy
+	if g.Spec.HealthCheck != nil {
+		op, err := g.service.HealthChecks.Insert(g.project, g.Spec.HealthCheck).Context(ctx).Do()
+		if err := g.wait(ctx, op, err); err != nil {
+			return fmt.Errorf("failed to create health check %s: %v", g.Spec.HealthCheck.Name, err)
+		}
+		autoHealing = append(autoHealing, &compute.InstanceGroupManagerAutoHealingPolicy{
+			HealthCheck:     fmt.Sprintf("projects/%s/global/healthChecks/%s", g.project, g.Spec.HealthCheck.Name),
+			InitialDelaySec: g.Spec.AutoHealingInitialDelaySec,
+		})
+	}
+	if err := g.inheritNetwork(ctx); err != nil {
+		return err
+	}
+	op, err := g.service.InstanceTemplates.Insert(g.project, g.Spec.Template).Context(ctx).Do()
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to create instance template %s: %v", g.Spec.Template.Name, err)
+	}
+
+	igm := &compute.InstanceGroupManager{
+		Name:                g.Spec.Name,
+		BaseInstanceName:    g.Spec.BaseInstanceName,
+		InstanceTemplate:    g.templateURL(g.Spec.Template.Name),
+		TargetSize:          g.Spec.Size,
+		AutoHealingPolicies: autoHealing,
+	}
+	if len(g.Spec.StatefulDisks) > 0 {
+		disks := make(map[string]compute.StatefulPolicyPreservedStateDiskDevice)
+		for _, d := range g.Spec.StatefulDisks {
+			disks[d] = compute.StatefulPolicyPreservedStateDiskDevice{AutoDelete: "ON_PERMANENT_INSTANCE_DELETION"}
+		}
+		igm.StatefulPolicy = &compute.StatefulPolicy{PreservedState: &compute.StatefulPolicyPreservedState{Disks: disks}}
+	}
+	if g.region != "" {
+		op, err = g.service.RegionInstanceGroupManagers.Insert(g.project, g.region, igm).Context(ctx).Do()
+	} else {
+		op, err = g.service.InstanceGroupManagers.Insert(g.project, g.zone, igm).Context(ctx).Do()
+	}
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to create managed instance group %s: %v", g.Spec.Name, err)
+	}
+	return g.WaitForStable(ctx)
+}
+
+// Delete deletes the managed instance group and its members, every instance
+// template created for it and the health check. It attempts every deletion
+// and returns the first error.
+func (g *Group) Delete(ctx context.Context) error {
+	var errs []error
+	// Rolling updates may have been done by another Group of the same spec.
+	if err := g.loadTemplateVersion(); err != nil {
+		errs = append(errs, err)
+	}
+	var op *compute.Operation
+	var err error
+	if g.region != "" {
+		op, err = g.service.RegionInstanceGroupManagers.Delete(g.project, g.region, g.Spec.Name).Context(ctx).Do()
+	} else {
+		op, err = g.service.InstanceGroupManagers.Delete(g.project, g.zone, g.Spec.Name).Context(ctx).Do()
+	}
+	if err := g.wait(ctx, op, err); err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete managed instance group %s: %v", g.Spec.Name, err))
+	}
+	for v := g.templateVersion; v >= 0; v-- {
+		name := g.templateName(v)
+		op, err := g.service.InstanceTemplates.Delete(g.project, name).Context(ctx).Do()
+		if err := g.wait(ctx, op, err); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete instance template %s: %v", name, err))
+		}
+	}
+	if g.Spec.HealthCheck != nil {
+		op, err := g.service.HealthChecks.Delete(g.project, g.Spec.HealthCheck.Name).Context(ctx).Do()
+		if err := g.wait(ctx, op, err); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete health check %s: %v", g.Spec.HealthCheck.Name, err))
+		}
+	}
+	if err := os.Remove(g.versionFile()); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("failed to remove %s: %v", g.versionFile(), err))
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// WaitForStable waits for the managed instance group to have no pending
+// actions on its members.
+func (g *Group) WaitForStable(ctx context.Context) error {
+	for {
+		var igm *compute.InstanceGroupManager
+		var err error
+		if g.region != "" {
+			igm, err = g.service.RegionInstanceGroupManagers.Get(g.project, g.region, g.Spec.Name).Context(ctx).Do()
+		} else {
+			igm, err = g.service.InstanceGroupManagers.Get(g.project, g.zone, g.Spec.Name).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get managed instance group %s: %v", g.Spec.Name, err)
+		}
+		if igm.Status != nil && igm.Status.IsStable {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("managed instance group %s did not become stable: %v", g.Spec.Name, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// ListInstances returns the current members of the managed instance group.
+func (g *Group) ListInstances(ctx context.Context) ([]*compute.ManagedInstance, error) {
+	var instances []*compute.ManagedInstance
+	var err error
+	if g.region != "" {
+		err = g.service.RegionInstanceGroupManagers.ListManagedInstances(g.project, g.region, g.Spec.Name).Pages(ctx, func(resp *compute.RegionInstanceGroupManagersListInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
+	} else {
+		err = g.service.InstanceGroupManagers.ListManagedInstances(g.project, g.zone, g.Spec.Name).Pages(ctx, func(resp *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances of managed instance group %s: %v", g.Spec.Name, err)
+	}
+	return instances, nil
+}
+
+// WaitForHealthy waits for every member of the managed instance group to be
+// running and reported healthy by the autohealing health check.
+func (g *Group) WaitForHealthy(ctx context.Context) error {
+	for {
+		instances, err := g.ListInstances(ctx)
+		if err != nil {
+			return err
+		}
+		var unhealthy []string
+		for _, mi := range instances {
+			if mi.InstanceStatus != "RUNNING" || !isHealthy(mi) {
+				unhealthy = append(unhealthy, path.Base(mi.Instance))
+			}
+		}
+		if len(instances) > 0 && len(unhealthy) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("members %v of managed instance group %s did not become healthy: %v", unhealthy, g.Spec.Name, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// StopInstance stops the given member of the managed instance group,
+// identified by its URL, outside of the group, as a failure of the member
+// would.
+func (g *Group) StopInstance(ctx context.Context, instance string) error {
+	name := path.Base(instance)
+	op, err := g.service.Instances.Stop(g.project, zoneFromURL(instance), name).Context(ctx).Do()
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to stop instance %s: %v", name, err)
+	}
+	return nil
+}
+
+// RecreateInstances recreates the given members of the managed instance group,
+// identified by their URLs, and waits for the group to be stable. Stateful
+// disks are preserved.
+func (g *Group) RecreateInstances(ctx context.Context, instances []string) error {
+	var op *compute.Operation
+	var err error
+	if g.region != "" {
+		op, err = g.service.RegionInstanceGroupManagers.RecreateInstances(g.project, g.region, g.Spec.Name, &compute.RegionInstanceGroupManagersRecreateRequest{Instances: instances}).Context(ctx).Do()
+	} else {
+		op, err = g.service.InstanceGroupManagers.RecreateInstances(g.project, g.zone, g.Spec.Name, &compute.InstanceGroupManagersRecreateInstancesRequest{Instances: instances}).Context(ctx).Do()
+	}
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to recreate instances of managed instance group %s: %v", g.Spec.Name, err)
+	}
+	return g.WaitForStable(ctx)
+}
+
+// RollingUpdate creates a new version of the instance template, with metadata
+// updated by the given items, and proactively replaces every member of the
+// managed instance group with it. Members keep their names, so that their test
+// results replace the results of the previous version.
+func (g *Group) RollingUpdate(ctx context.Context, metadata map[string]string) error {
+	template := *g.Spec.Template
+	properties := *template.Properties
+	properties.Metadata = &compute.Metadata{}
+	for _, item := range g.Spec.Template.Properties.Metadata.Items {
+		if _, ok := metadata[item.Key]; !ok {
+			properties.Metadata.Items = append(properties.Metadata.Items, item)
+		}
+	}
+	for k, v := range metadata {
+		v := v
+		properties.Metadata.Items = append(properties.Metadata.Items, &compute.MetadataItems{Key: k, Value: &v})
+	}
+	template.Properties = &properties
+	template.Name = g.templateName(g.templateVersion + 1)
+	op, err := g.service.InstanceTemplates.Insert(g.project, &template).Context(ctx).Do()
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to create instance template %s: %v", template.Name, err)
+	}
+	g.templateVersion++
+	if err := g.recordTemplateVersion(); err != nil {
+		return err
+	}
+
+	maxUnavailable, err := g.maxUnavailable(ctx)
+	if err != nil {
+		return err
+	}
+	// Members are recreated with their names, which doesn't allow a surge.
+	igm := &compute.InstanceGroupManager{
+		Versions: []*compute.InstanceGroupManagerVersion{{InstanceTemplate: g.templateURL(template.Name)}},
+		UpdatePolicy: &compute.InstanceGroupManagerUpdatePolicy{
+			Type:              "PROACTIVE",
+			MinimalAction:     "REPLACE",
+			ReplacementMethod: "RECREATE",
+			MaxSurge:          &compute.FixedOrPercent{Fixed: 0, ForceSendFields: []string{"Fixed"}},
+			MaxUnavailable:    &compute.FixedOrPercent{Fixed: maxUnavailable},
+		},
+	}
+	if g.region != "" {
+		op, err = g.service.RegionInstanceGroupManagers.Patch(g.project, g.region, g.Spec.Name, igm).Context(ctx).Do()
+	} else {
+		op, err = g.service.InstanceGroupManagers.Patch(g.project, g.zone, g.Spec.Name, igm).Context(ctx).Do()
+	}
+	if err := g.wait(ctx, op, err); err != nil {
+		return fmt.Errorf("failed to update managed instance group %s: %v", g.Spec.Name, err)
+	}
+	return g.WaitForStable(ctx)
+}
+
+// WaitForResults waits for every current member of the managed instance group
+// to upload test results written after it was created, so that results of
+// members replaced by autohealing or updates are not mistaken for results of
+// the current members.
+func (g *Group) WaitForResults(ctx context.Context, client *storage.Client) error {
+	u, err := url.Parse(g.Spec.ResultsURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse results url %q: %v", g.Spec.ResultsURL, err)
+	}
+	bucket := client.Bucket(u.Host)
+	prefix := strings.TrimPrefix(u.Path, "/")
+	for {
+		pending, err := g.pendingResults(ctx, bucket, prefix)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("members %v of managed instance group %s did not upload results: %v", pending, g.Spec.Name, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (g *Group) pendingResults(ctx context.Context, bucket *storage.BucketHandle, prefix string) ([]string, error) {
+	updated := make(map[string]time.Time)
+	it := bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list results of managed instance group %s: %v", g.Spec.Name, err)
+		}
+		updated[strings.TrimSuffix(path.Base(attrs.Name), ".txt")] = attrs.Updated
+	}
+
+	instances, err := g.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, mi := range instances {
+		name := path.Base(mi.Instance)
+		instance, err := g.service.Instances.Get(g.project, zoneFromURL(mi.Instance), name).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get instance %s: %v", name, err)
+		}
+		created, err := time.Parse(time.RFC3339, instance.CreationTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse creation time of instance %s: %v", name, err)
+		}
+		if t, ok := updated[name]; !ok || t.Before(created) {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// inheritNetwork attaches the members to the network and subnetwork of the
+// first NIC of the controller VM. Daisy generates the names of the networks it
+// creates, so they are only known once the controller VM is running.
+func (g *Group) inheritNetwork(ctx context.Context) error {
+	name, err := utils.GetInstanceName(ctx)
+	if err != nil {
+		return err
+	}
+	_, zone, err := utils.GetProjectZone(ctx)
+	if err != nil {
+		return err
+	}
+	controller, err := g.service.Instances.Get(g.project, zone, name).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to get controller instance %s: %v", name, err)
+	}
+	if len(controller.NetworkInterfaces) == 0 {
+		return fmt.Errorf("controller instance %s has no network interface", name)
+	}
+	nic := controller.NetworkInterfaces[0]
+	g.Spec.Template.Properties.NetworkInterfaces = []*compute.NetworkInterface{{
+		Network:       nic.Network,
+		Subnetwork:    nic.Subnetwork,
+		AccessConfigs: []*compute.AccessConfig{{Type: "ONE_TO_ONE_NAT"}},
+	}}
+	return nil
+}
+
+// maxUnavailable returns the number of members which may be replaced at once
+// during rolling updates. Regional groups need at least one per zone.
+func (g *Group) maxUnavailable(ctx context.Context) (int64, error) {
+	if g.region == "" {
+		return 1, nil
+	}
+	igm, err := g.service.RegionInstanceGroupManagers.Get(g.project, g.region, g.Spec.Name).Context(ctx).Do()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get managed instance group %s: %v", g.Spec.Name, err)
+	}
+	if igm.DistributionPolicy == nil || len(igm.DistributionPolicy.Zones) == 0 {
+		return 1, nil
+	}
+	return int64(len(igm.DistributionPolicy.Zones)), nil
+}
+
+// TemplateName returns the name of the current instance template of the
+// group, which members have once rolling updates are done.
+func (g *Group) TemplateName() string {
+	return g.templateName(g.templateVersion)
+}
+
+// versionFile returns the file recording the current instance template
+// version of the group.
+func (g *Group) versionFile() string {
+	return filepath.Join(stateDir, fmt.Sprintf("cit-mig-%s-template-version", g.Spec.Name))
+}
+
+// loadTemplateVersion reads the instance template version recorded by rolling
+// updates, if any.
+func (g *Group) loadTemplateVersion() error {
+	data, err := os.ReadFile(g.versionFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read template version of managed instance group %s: %v", g.Spec.Name, err)
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("failed to parse template version of managed instance group %s: %v", g.Spec.Name, err)
+	}
+	if v > g.templateVersion {
+		g.templateVersion = v
+	}
+	return nil
+}
+
+// recordTemplateVersion records the current instance template version.
+func (g *Group) recordTemplateVersion() error {
+	if err := os.WriteFile(g.versionFile(), []byte(strconv.Itoa(g.templateVersion)), 0644); err != nil {
+		return fmt.Errorf("failed to record template version of managed instance group %s: %v", g.Spec.Name, err)
+	}
+	return nil
+}
+
+func (g *Group) templateName(version int) string {
+	if version == 0 {
+		return g.Spec.Template.Name
+	}
+	return fmt.Sprintf("v%d-%s", version, g.Spec.Template.Name)
+}
+
+func (g *Group) templateURL(name string) string {
+	return fmt.Sprintf("projects/%s/global/instanceTemplates/%s", g.project, name)
+}
+
+// wait waits for the operation to be done, and returns its error if any.
+func (g *Group) wait(ctx context.Context, op *compute.Operation, err error) error {
+	if err != nil {
+		return err
+	}
+	for op.Status != "DONE" {
+		switch {
+		case op.Zone != "":
+			op, err = g.service.ZoneOperations.Wait(g.project, path.Base(op.Zone), op.Name).Context(ctx).Do()
+		case op.Region != "":
+			op, err = g.service.RegionOperations.Wait(g.project, path.Base(op.Region), op.Name).Context(ctx).Do()
+		default:
+			op, err = g.service.GlobalOperations.Wait(g.project, op.Name).Context(ctx).Do()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("operation %s failed: %s", op.Name, op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+// isHealthy returns whether the health checks of the member report it healthy.
+func isHealthy(mi *compute.ManagedInstance) bool {
+	if len(mi.InstanceHealth) == 0 {
+		return false
+	}
+	for _, h := range mi.InstanceHealth {
+		if h.DetailedHealthState != "HEALTHY" {
+			return false
+		}
+	}
+	return true
+}
+
+// zoneFromURL returns the zone of a zonal resource URL.
+func zoneFromURL(resource string) string {
+	parts := strings.Split(resource, "/")
+	for i, p := range parts {
+		if p == "zones" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migutils
+
+import (
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// TestTemplateVersion tests that a Group of the same spec, like the one of the
+// wrapper, finds the templates created by the rolling updates of another.
+func TestTemplateVersion(t *testing.T) {
+	stateDir = t.TempDir()
+	spec := &Spec{Name: "mig-abc", Template: &compute.InstanceTemplate{Name: "mig-abc"}}
+
+	updated := &Group{Spec: spec}
+	if got := updated.TemplateName(); got != "mig-abc" {
+		t.Errorf("TemplateName() = %q, want %q", got, "mig-abc")
+	}
+	updated.templateVersion = 2
+	if err := updated.recordTemplateVersion(); err != nil {
+		t.Fatalf("recordTemplateVersion() = %v, want nil", err)
+	}
+
+	wrapper := &Group{Spec: spec}
+	if err := wrapper.loadTemplateVersion(); err != nil {
+		t.Fatalf("loadTemplateVersion() = %v, want nil", err)
+	}
+	if got := wrapper.TemplateName(); got != "v2-mig-abc" {
+		t.Errorf("TemplateName() = %q, want %q", got, "v2-mig-abc")
+	}
+
+	other := &Group{Spec: &Spec{Name: "other", Template: &compute.InstanceTemplate{Name: "other"}}}
+	if err := other.loadTemplateVersion(); err != nil {
+		t.Fatalf("loadTemplateVersion() = %v, want nil", err)
+	}
+	if other.templateVersion != 0 {
+		t.Errorf("templateVersion = %d, want 0 without a recorded version", other.templateVersion)
+	}
+}
+
+// TestIsHealthy tests that members are healthy only when every health check
+// reports them healthy.
+func TestIsHealthy(t *testing.T) {
+	tests := []struct {
+		name   string
+		health []*compute.ManagedInstanceInstanceHealth
+		want   bool
+	}{
+		{name: "no health check", want: false},
+		{name: "healthy", health: []*compute.ManagedInstanceInstanceHealth{{DetailedHealthState: "HEALTHY"}}, want: true},
+		{name: "unknown", health: []*compute.ManagedInstanceInstanceHealth{{DetailedHealthState: "UNKNOWN"}}, want: false},
+		{name: "one unhealthy", health: []*compute.ManagedInstanceInstanceHealth{{DetailedHealthState: "HEALTHY"}, {DetailedHealthState: "UNHEALTHY"}}, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isHealthy(&compute.ManagedInstance{InstanceHealth: tc.health}); got != tc.want {
+				t.Errorf("isHealthy() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
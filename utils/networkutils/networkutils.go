@@ -265,7 +265,10 @@ func daisyNetworkForMRDMANIC(index int, project string, zone string, isMetal boo
 	}
 }
 
-func daisyNetworkForNIC(nicType string, index int, project string, zone string, isMetal bool) (*daisy.Network, error) {
+// DaisyNetworkForNIC returns the daisy network for the NIC at the index. RDMA
+// NICs need networks with the network profile of their zone, and all MRDMA NICs
+// share a single network.
+func DaisyNetworkForNIC(nicType string, index int, project string, zone string, isMetal bool) (*daisy.Network, error) {
 	switch nicType {
 	case NICTypeVIRTIONET:
 		return daisyNetworkForGeneralPurposeNIC(index), nil
@@ -331,8 +334,15 @@ func daisySubnet(daisyNetwork *daisy.Network, index int, zone string) (*daisy.Su
 	}, nil
 }
 
-func accessConfigsForNIC(nicType string) []*compute.AccessConfig {
-	if nicType == NICTypeIRDMA || nicType == NICTypeMRDMA {
+// IsRDMANIC returns whether the NIC type is an RDMA NIC type.
+func IsRDMANIC(nicType string) bool {
+	return nicType == NICTypeIRDMA || nicType == NICTypeMRDMA
+}
+
+// AccessConfigsForNIC returns the access configs of a NIC of the type. RDMA
+// NICs have no external access.
+func AccessConfigsForNIC(nicType string) []*compute.AccessConfig {
+	if IsRDMANIC(nicType) {
 		return []*compute.AccessConfig{}
 	}
 	return []*compute.AccessConfig{&compute.AccessConfig{
@@ -359,7 +369,7 @@ func CreateMachineWithNetworks(t *imagetest.TestWorkflow, o *CreateMachineWithNe
 	createdSubnetworks := make(map[string]bool)
 
 	for nicIndex, nicType := range o.NicTypes {
-		daisyNetwork, err := daisyNetworkForNIC(nicType, nicIndex, o.Project, o.Zone, imagetest.IsMetal(o.MachineType))
+		daisyNetwork, err := DaisyNetworkForNIC(nicType, nicIndex, o.Project, o.Zone, imagetest.IsMetal(o.MachineType))
 		if err != nil {
 			return nil, fmt.Errorf("building daisy network: %w", err)
 		}
@@ -391,7 +401,7 @@ func CreateMachineWithNetworks(t *imagetest.TestWorkflow, o *CreateMachineWithNe
 			Subnetwork:     daisySub.Name,
 			StackType:      daisySub.StackType,
 			Ipv6AccessType: daisySub.Ipv6AccessType,
-			AccessConfigs:  accessConfigsForNIC(nicType),
+			AccessConfigs:  AccessConfigsForNIC(nicType),
 		})
 	}
 
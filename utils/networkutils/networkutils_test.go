@@ -521,12 +521,12 @@ func TestDaisyNetworkForNIC(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := daisyNetworkForNIC(tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal)
+			got, err := DaisyNetworkForNIC(tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal)
 			if err != nil {
-				t.Fatalf("daisyNetworkForNIC(%q, %d, %q, %q, %t) failed: %v", tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal, err)
+				t.Fatalf("DaisyNetworkForNIC(%q, %d, %q, %q, %t) failed: %v", tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal, err)
 			}
 			if diff := diffDaisy(got, tc.want); diff != "" {
-				t.Errorf("daisyNetworkForNIC(%q, %d, %q, %q, %t) = doesn't match expectations: diff (-got +want):\n%s", tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal, diff)
+				t.Errorf("DaisyNetworkForNIC(%q, %d, %q, %q, %t) = doesn't match expectations: diff (-got +want):\n%s", tc.nicType, tc.index, tc.project, tc.zone, tc.isMetal, diff)
 			}
 		})
 	}
@@ -799,3 +799,11 @@ func TestCompareKernelVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestIsRDMANIC(t *testing.T) {
+	for nicType, want := range map[string]bool{NICTypeGVNIC: false, NICTypeVIRTIONET: false, NICTypeIDPF: false, NICTypeIRDMA: true, NICTypeMRDMA: true} {
+		if got := IsRDMANIC(nicType); got != want {
+			t.Errorf("IsRDMANIC(%q) = %v, want %v", nicType, got, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package perftargets provides the performance targets of the networkperf,
+// storageperf and shapeperf test suites, loaded from a targets file.
+package perftargets
+
+import (
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileVersion is the version of the targets file format.
+const fileVersion = 1
+
+// DefaultTolerance is the fraction of a target the measured value must reach
+// when the target doesn't set a tolerance.
+const DefaultTolerance = 0.85
+
+// ErrNoTarget is returned by Lookup when no target matches the configuration.
+var ErrNoTarget = errors.New("no performance target")
+
+//go:embed targets.yaml
+var defaultFile []byte
+
+var (
+	// FileFlag is the flag to specify a targets file whose targets take
+	// precedence over the default targets.
+	FileFlag = flag.String("perf_targets_file", "", "path of a performance targets file, in the format of utils/perftargets/targets.yaml, whose targets take precedence over the default targets of the networkperf, storageperf and shapeperf suites")
+
+	loadTargets sync.Once
+	targetLists []*List
+	targetsErr  error
+)
+
+// Target is the expected value of a metric on the matching test
+// configurations.
+type Target struct {
+	// MachineType is a regex matching the whole names of the machine types.
+	MachineType string `yaml:"machine_type"`
+	// MinVCPUs is the number of vCPUs from which the target applies to the
+	// matching machine types. The target of a machine type is the one with
+	// the largest MinVCPUs at or below its vCPU count.
+	MinVCPUs int64 `yaml:"min_vcpus,omitempty"`
+	// DiskType is the disk type of the target, like pd-balanced. Targets
+	// without a disk type match all disk types, and so on for Tier and
+	// NICType.
+	DiskType string `yaml:"disk_type,omitempty"`
+	// Tier is the network tier of the target, DEFAULT or TIER_1.
+	Tier string `yaml:"tier,omitempty"`
+	// NICType is the NIC type of the target, like GVNIC.
+	NICType string `yaml:"nic_type,omitempty"`
+	// Metric is the name of the metric recorded by the test, like
+	// throughput.
+	Metric string `yaml:"metric"`
+	// Target is the expected value of the metric.
+	Target float64 `yaml:"target"`
+	// Tolerance is the fraction of the target the measured value must reach.
+	// It is DefaultTolerance when unset.
+	Tolerance float64 `yaml:"tolerance,omitempty"`
+
+	machineType *regexp.Regexp
+}
+
+// Minimum returns the lowest measured value meeting the target.
+func (t *Target) Minimum() float64 {
+	return t.Target * t.Tolerance
+}
+
+// Maximum returns the highest measured value meeting the target of a metric
+// for which lower values are better, like a latency.
+func (t *Target) Maximum() float64 {
+	return t.Target / t.Tolerance
+}
+
+// Query is a test configuration to look up the target of a metric for.
+type Query struct {
+	MachineType string
+	// VCPUs is the vCPU count of the machine type.
+	VCPUs    int64
+	DiskType string
+	Tier     string
+	NICType  string
+	Metric   string
+}
+
+// String returns a description of the configuration for error messages.
+func (q Query) String() string {
+	s := []string{fmt.Sprintf("machine type %s (%d vCPUs)", q.MachineType, q.VCPUs)}
+	if q.DiskType != "" {
+		s = append(s, "disk type "+q.DiskType)
+	}
+	if q.Tier != "" {
+		s = append(s, "network tier "+q.Tier)
+	}
+	if q.NICType != "" {
+		s = append(s, "NIC type "+q.NICType)
+	}
+	return fmt.Sprintf("%s on %s", q.Metric, strings.Join(s, ", "))
+}
+
+func (t *Target) matches(q Query) bool {
+	return t.Metric == q.Metric && t.machineType.MatchString(q.MachineType) && t.MinVCPUs <= q.VCPUs &&
+		(t.DiskType == "" || t.DiskType == q.DiskType) &&
+		(t.Tier == "" || t.Tier == q.Tier) &&
+		(t.NICType == "" || t.NICType == q.NICType)
+}
+
+// List is a list of targets loaded from a targets file.
+type List struct {
+	Version int       `yaml:"version"`
+	Targets []*Target `yaml:"targets"`
+}
+
+// Load parses and validates a targets file.
+func Load(data []byte) (*List, error) {
+	l := &List{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse targets file: %v", err)
+	}
+	if l.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported targets file version %d, want %d", l.Version, fileVersion)
+	}
+	for i, t := range l.Targets {
+		if t.MachineType == "" || t.Metric == "" {
+			return nil, fmt.Errorf("target %d must have a machine type and a metric", i)
+		}
+		if t.Target <= 0 {
+			return nil, fmt.Errorf("target %d must have a positive target, got %g", i, t.Target)
+		}
+		if t.Tolerance < 0 || t.Tolerance > 1 {
+			return nil, fmt.Errorf("target %d must have a tolerance between 0 and 1, got %g", i, t.Tolerance)
+		}
+		if t.Tolerance == 0 {
+			t.Tolerance = DefaultTolerance
+		}
+		if t.MinVCPUs < 0 {
+			return nil, fmt.Errorf("target %d has a negative min_vcpus", i)
+		}
+		var err error
+		if t.machineType, err = regexp.Compile("^(?:" + t.MachineType + ")$"); err != nil {
+			return nil, fmt.Errorf("target %d has an invalid machine type regex: %v", i, err)
+		}
+	}
+	return l, nil
+}
+
+// LoadFile is like Load on the file at path.
+func LoadFile(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l, err := Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Lookup returns the target of the list for the configuration, or nil if there
+// is none. Of the matching targets, it returns the first one with the largest
+// MinVCPUs.
+func (l *List) Lookup(q Query) *Target {
+	var found *Target
+	for _, t := range l.Targets {
+		if t.matches(q) && (found == nil || t.MinVCPUs > found.MinVCPUs) {
+			found = t
+		}
+	}
+	return found
+}
+
+// lists returns the targets file of FileFlag, if set, and the default targets
+// of the targets.yaml file of this package, in order of precedence.
+func lists() ([]*List, error) {
+	loadTargets.Do(func() {
+		if *FileFlag != "" {
+			l, err := LoadFile(*FileFlag)
+			if err != nil {
+				targetsErr = err
+				return
+			}
+			targetLists = append(targetLists, l)
+		}
+		l, err := Load(defaultFile)
+		if err != nil {
+			targetsErr = err
+			return
+		}
+		targetLists = append(targetLists, l)
+	})
+	return targetLists, targetsErr
+}
+
+// Lookup returns the target for the configuration from the file of FileFlag,
+// or the default targets if that file has none.
+func Lookup(q Query) (*Target, error) {
+	ls, err := lists()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range ls {
+		if t := l.Lookup(q); t != nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s", ErrNoTarget, q)
+}
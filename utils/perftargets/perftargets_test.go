@@ -0,0 +1,150 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package perftargets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testFile = `
+version: 1
+targets:
+  - {machine_type: 'n2-(standard|highmem)-\d+', min_vcpus: 2, tier: DEFAULT, metric: throughput, target: 10}
+  - {machine_type: 'n2-(standard|highmem)-\d+', min_vcpus: 32, tier: DEFAULT, metric: throughput, target: 32}
+  - {machine_type: 'n2-standard-\d+', min_vcpus: 32, tier: TIER_1, metric: throughput, target: 50, tolerance: 0.9}
+  - {machine_type: c3-standard-88, disk_type: pd-balanced, metric: rand_read_iops, target: 80000}
+`
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: testFile},
+		{name: "default", data: string(defaultFile)},
+		{name: "version", data: "version: 2", wantErr: true},
+		{name: "missing metric", data: "version: 1\ntargets:\n  - {machine_type: n2-standard-2, target: 10}", wantErr: true},
+		{name: "invalid machine type", data: "version: 1\ntargets:\n  - {machine_type: '(', metric: throughput, target: 10}", wantErr: true},
+		{name: "zero target", data: "version: 1\ntargets:\n  - {machine_type: n2-standard-2, metric: throughput, target: 0}", wantErr: true},
+		{name: "invalid tolerance", data: "version: 1\ntargets:\n  - {machine_type: n2-standard-2, metric: throughput, target: 10, tolerance: 1.5}", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load([]byte(tc.data)); (err != nil) != tc.wantErr {
+				t.Errorf("Load() = %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestListLookup(t *testing.T) {
+	l, err := Load([]byte(testFile))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	tests := []struct {
+		name          string
+		query         Query
+		wantTarget    float64
+		wantTolerance float64
+	}{
+		{name: "breakpoint", query: Query{MachineType: "n2-standard-32", VCPUs: 32, Tier: "DEFAULT", Metric: "throughput"}, wantTarget: 32, wantTolerance: DefaultTolerance},
+		{name: "between breakpoints", query: Query{MachineType: "n2-highmem-16", VCPUs: 16, Tier: "DEFAULT", Metric: "throughput"}, wantTarget: 10, wantTolerance: DefaultTolerance},
+		{name: "tier", query: Query{MachineType: "n2-standard-48", VCPUs: 48, Tier: "TIER_1", Metric: "throughput"}, wantTarget: 50, wantTolerance: 0.9},
+		{name: "below breakpoints", query: Query{MachineType: "n2-standard-16", VCPUs: 16, Tier: "TIER_1", Metric: "throughput"}},
+		{name: "unknown machine type", query: Query{MachineType: "z9-standard-2", VCPUs: 2, Tier: "DEFAULT", Metric: "throughput"}},
+		{name: "disk type", query: Query{MachineType: "c3-standard-88", VCPUs: 88, DiskType: "pd-balanced", Metric: "rand_read_iops"}, wantTarget: 80000, wantTolerance: DefaultTolerance},
+		{name: "other disk type", query: Query{MachineType: "c3-standard-88", VCPUs: 88, DiskType: "lssd", Metric: "rand_read_iops"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := l.Lookup(tc.query)
+			if tc.wantTarget == 0 {
+				if got != nil {
+					t.Errorf("Lookup(%v) = %+v, want nil", tc.query, got)
+				}
+				return
+			}
+			if got == nil || got.Target != tc.wantTarget || got.Tolerance != tc.wantTolerance {
+				t.Errorf("Lookup(%v) = %+v, want target %g with tolerance %g", tc.query, got, tc.wantTarget, tc.wantTolerance)
+			}
+		})
+	}
+}
+
+func TestDefaultTargets(t *testing.T) {
+	l, err := Load(defaultFile)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	tests := []struct {
+		query Query
+		want  float64
+	}{
+		{query: Query{MachineType: "n2-standard-2", VCPUs: 2, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 10},
+		{query: Query{MachineType: "n2d-standard-48", VCPUs: 48, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 32},
+		{query: Query{MachineType: "e2-standard-2", VCPUs: 2, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 4},
+		{query: Query{MachineType: "t2a-standard-1", VCPUs: 1, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 10},
+		{query: Query{MachineType: "c3-standard-8", VCPUs: 8, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 23},
+		{query: Query{MachineType: "a3-ultragpu-8g", VCPUs: 224, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"}, want: 200},
+		{query: Query{MachineType: "c4-standard-192", VCPUs: 192, Tier: "TIER_1", NICType: "GVNIC", Metric: "throughput"}, want: 200},
+		{query: Query{MachineType: "n2-standard-48", VCPUs: 48, Tier: "TIER_1", NICType: "GVNIC", Metric: "throughput"}, want: 50},
+		{query: Query{MachineType: "n2-standard-2", VCPUs: 2, Tier: "DEFAULT", NICType: "VIRTIO_NET", Metric: "throughput"}, want: 10},
+		{query: Query{MachineType: "a3-ultragpu-8g", VCPUs: 224, Tier: "DEFAULT", NICType: "MRDMA", Metric: "throughput"}, want: 400},
+		{query: Query{MachineType: "c3-highcpu-192-metal", VCPUs: 192, Tier: "TIER_1", NICType: "IDPF", Metric: "throughput"}, want: 200},
+		{query: Query{MachineType: "c3-standard-88-lssd", VCPUs: 88, DiskType: "lssd", Metric: "rand_write_iops"}, want: 800000},
+		{query: Query{MachineType: "h3-standard-88", VCPUs: 88, DiskType: "pd-balanced", Metric: "seq_read_bandwidth"}, want: 240},
+	}
+	for _, tc := range tests {
+		if got := l.Lookup(tc.query); got == nil || got.Target != tc.want {
+			t.Errorf("Lookup(%v) = %+v, want target %g", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestLookupOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.yaml")
+	override := "version: 1\ntargets:\n  - {machine_type: 'n2-standard-\\d+', tier: DEFAULT, metric: throughput, target: 5, tolerance: 0.5}\n"
+	if err := os.WriteFile(path, []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+	*FileFlag = path
+	t.Cleanup(func() { *FileFlag = "" })
+
+	got, err := Lookup(Query{MachineType: "n2-standard-32", VCPUs: 32, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"})
+	if err != nil {
+		t.Fatalf("Lookup() failed: %v", err)
+	}
+	if got.Minimum() != 2.5 {
+		t.Errorf("Lookup().Minimum() = %g, want 2.5 from the override", got.Minimum())
+	}
+	if got.Maximum() != 10 {
+		t.Errorf("Lookup().Maximum() = %g, want 10 from the override", got.Maximum())
+	}
+	got, err = Lookup(Query{MachineType: "n2d-standard-48", VCPUs: 48, Tier: "DEFAULT", NICType: "GVNIC", Metric: "throughput"})
+	if err != nil {
+		t.Fatalf("Lookup() failed: %v", err)
+	}
+	if got.Target != 32 {
+		t.Errorf("Lookup().Target = %g, want 32 from the defaults", got.Target)
+	}
+	if _, err := Lookup(Query{MachineType: "z9-standard-2", VCPUs: 2, Tier: "DEFAULT", Metric: "throughput"}); !errors.Is(err, ErrNoTarget) {
+		t.Errorf("Lookup() for an unknown machine type = %v, want ErrNoTarget", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// SampleStatistic is a statistic of the repeated samples of a measurement.
+type SampleStatistic string
+
+const (
+	// SampleMedian is the middle sample.
+	SampleMedian SampleStatistic = "median"
+	// SampleMin is the lowest sample.
+	SampleMin SampleStatistic = "min"
+	// SampleMax is the highest sample.
+	SampleMax SampleStatistic = "max"
+)
+
+// SampleSelection selects which of the repeated samples of a measurement a
+// performance threshold applies to, relative to the direction of the
+// threshold.
+type SampleSelection string
+
+const (
+	// SelectMedian selects the median, which is robust to a single noisy
+	// sample, and the default.
+	SelectMedian SampleSelection = "median"
+	// SelectWorst selects the sample furthest from the threshold, the lowest
+	// for a minimum like a throughput and the highest for a maximum like a
+	// latency.
+	SelectWorst SampleSelection = "worst"
+	// SelectBest selects the sample closest to the threshold, the highest for
+	// a minimum and the lowest for a maximum.
+	SelectBest SampleSelection = "best"
+)
+
+// ParseSampleSelection parses a selection of the samples, worst, best or
+// median.
+func ParseSampleSelection(s string) (SampleSelection, error) {
+	switch sel := SampleSelection(s); sel {
+	case SelectMedian, SelectWorst, SelectBest:
+		return sel, nil
+	}
+	return "", fmt.Errorf("unknown sample selection %q, want worst, best or median", s)
+}
+
+// Statistic returns the statistic of the samples the selection picks for a
+// threshold which is a minimum when atLeast is true, and a maximum otherwise.
+func (sel SampleSelection) Statistic(atLeast bool) SampleStatistic {
+	switch sel {
+	case SelectWorst:
+		if atLeast {
+			return SampleMin
+		}
+		return SampleMax
+	case SelectBest:
+		if atLeast {
+			return SampleMax
+		}
+		return SampleMin
+	}
+	return SampleMedian
+}
+
+// SampleSummary summarizes the repeated samples of a measurement.
+type SampleSummary struct {
+	Count  int
+	Median float64
+	Min    float64
+	Max    float64
+}
+
+// SummarizeSamples returns the summary of the samples. The median of an even
+// number of samples is the mean of the two middle ones.
+func SummarizeSamples(samples []float64) SampleSummary {
+	if len(samples) == 0 {
+		return SampleSummary{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return SampleSummary{Count: n, Median: median, Min: sorted[0], Max: sorted[n-1]}
+}
+
+// Value returns the statistic of the samples.
+func (s SampleSummary) Value(stat SampleStatistic) float64 {
+	switch stat {
+	case SampleMin:
+		return s.Min
+	case SampleMax:
+		return s.Max
+	}
+	return s.Median
+}
+
+// RecordSamples records the repeated samples of a measurement with
+// RecordMetric, each with a sample label holding its index, followed by their
+// median, min and max with a statistic label, and returns their summary. A
+// single sample is recorded without additional labels, like a measurement
+// which isn't repeated, so that its history is kept. The metrics history of the
+// manager ignores the sample label, so samples are checked together.
+func RecordSamples(t *testing.T, name string, samples []float64, unit string, labels map[string]string) SampleSummary {
+	t.Helper()
+	summary := SummarizeSamples(samples)
+	if len(samples) == 1 {
+		RecordMetric(t, name, samples[0], unit, labels)
+		return summary
+	}
+	withLabel := func(k, v string) map[string]string {
+		l := maps.Clone(labels)
+		if l == nil {
+			l = make(map[string]string)
+		}
+		l[k] = v
+		return l
+	}
+	for i, sample := range samples {
+		RecordMetric(t, name, sample, unit, withLabel("sample", strconv.Itoa(i)))
+	}
+	for _, stat := range []SampleStatistic{SampleMedian, SampleMin, SampleMax} {
+		RecordMetric(t, name, summary.Value(stat), unit, withLabel("statistic", string(stat)))
+	}
+	return summary
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarizeSamples(t *testing.T) {
+	tests := []struct {
+		samples []float64
+		want    SampleSummary
+	}{
+		{samples: nil, want: SampleSummary{}},
+		{samples: []float64{7}, want: SampleSummary{Count: 1, Median: 7, Min: 7, Max: 7}},
+		{samples: []float64{9.5, 3, 8}, want: SampleSummary{Count: 3, Median: 8, Min: 3, Max: 9.5}},
+		{samples: []float64{4, 1, 10, 2}, want: SampleSummary{Count: 4, Median: 3, Min: 1, Max: 10}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, SummarizeSamples(tc.samples)); diff != "" {
+			t.Errorf("SummarizeSamples(%v) returned unexpected diff (-want +got):\n%s", tc.samples, diff)
+		}
+	}
+
+	summary := SummarizeSamples([]float64{9.5, 3, 8})
+	for stat, want := range map[SampleStatistic]float64{SampleMedian: 8, SampleMin: 3, SampleMax: 9.5} {
+		if got := summary.Value(stat); got != want {
+			t.Errorf("SampleSummary.Value(%s) = %g, want %g", stat, got, want)
+		}
+	}
+}
+
+func TestParseSampleSelection(t *testing.T) {
+	for _, s := range []string{"worst", "best", "median"} {
+		if got, err := ParseSampleSelection(s); err != nil || string(got) != s {
+			t.Errorf("ParseSampleSelection(%q) = %q, %v, want %q, nil", s, got, err, s)
+		}
+	}
+	for _, s := range []string{"", "min", "max", "WORST"} {
+		if _, err := ParseSampleSelection(s); err == nil {
+			t.Errorf("ParseSampleSelection(%q) = nil, want error", s)
+		}
+	}
+}
+
+func TestSampleSelectionStatistic(t *testing.T) {
+	tests := []struct {
+		sel     SampleSelection
+		atLeast bool
+		want    SampleStatistic
+	}{
+		{sel: SelectWorst, atLeast: true, want: SampleMin},
+		{sel: SelectWorst, atLeast: false, want: SampleMax},
+		{sel: SelectBest, atLeast: true, want: SampleMax},
+		{sel: SelectBest, atLeast: false, want: SampleMin},
+		{sel: SelectMedian, atLeast: true, want: SampleMedian},
+		{sel: SelectMedian, atLeast: false, want: SampleMedian},
+	}
+	for _, tc := range tests {
+		if got := tc.sel.Statistic(tc.atLeast); got != tc.want {
+			t.Errorf("SampleSelection(%q).Statistic(%t) = %q, want %q", tc.sel, tc.atLeast, got, tc.want)
+		}
+	}
+}
+
+func TestRecordSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	t.Setenv(MetricsFileEnv, path)
+	labels := map[string]string{"interface": "0"}
+	RecordSamples(t, "boot_time", []float64{21}, "s", nil)
+	summary := RecordSamples(t, "throughput", []float64{9, 10}, "Gbps", labels)
+	if summary.Median != 9.5 {
+		t.Errorf("RecordSamples() median = %g, want 9.5", summary.Median)
+	}
+	if len(labels) != 1 {
+		t.Errorf("RecordSamples() modified the labels to %v", labels)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("os.Open(%q) = %v, want nil", path, err)
+	}
+	defer f.Close()
+	metrics, err := ReadMetrics(f)
+	if err != nil {
+		t.Fatalf("ReadMetrics() = %v, want nil", err)
+	}
+	var got []string
+	for _, m := range metrics {
+		got = append(got, m.Key())
+	}
+	want := []string{
+		"boot_time",
+		"throughput{interface=0,sample=0}",
+		"throughput{interface=0,sample=1}",
+		"throughput{interface=0,statistic=median}",
+		"throughput{interface=0,statistic=min}",
+		"throughput{interface=0,statistic=max}",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RecordSamples() recorded unexpected metrics (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shapeutils contains the largest VM shape of each machine family,
+// tested by the shapevalidation and shapeperf test suites.
+package shapeutils
+
+import (
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests"
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils"
+	daisy "github.com/GoogleCloudPlatform/compute-daisy"
+	"google.golang.org/api/compute/v1"
+)
+
+// Shape is the largest VM shape of a machine family.
+type Shape struct {
+	Name string // Full shape name
+	// TODO use the compute API to fetch cpu and memory amounts instead of
+	// hardcoding a list.
+	CPU             int                   // Expected number of vCPUs
+	Mem             uint64                // Expected memory in GB
+	Numa            uint8                 // Expected number of vNUMA nodes
+	Disks           []*compute.Disk       // Disk configuration for created instances
+	Zone            string                // If set, force the VM to run in this zone
+	RequireFeatures []string              // Features necessary for testing this shape
+	ExcludeFeatures []string              // Features which prevent testing this shape
+	Exceptions      []*regexp.Regexp      // Regexp matches for image names to exempt
+	Quota           *daisy.QuotaAvailable // Quota necessary to run the test
+}
+
+// X86Shapes are the largest x86 shapes of the machine families, by family
+// name.
+var X86Shapes = map[string]*Shape{
+	"C3": {
+		Name:            "c3-highmem-176",
+		CPU:             176,
+		Mem:             1408,
+		Numa:            4,
+		Disks:           []*compute.Disk{{Name: "C3", Type: imagetest.PdBalanced}},
+		RequireFeatures: []string{"GVNIC"},
+		Quota:           &daisy.QuotaAvailable{Metric: "C3_CPUS", Units: 176},
+	},
+	"C3D": {
+		Name:            "c3d-highmem-360",
+		CPU:             360,
+		Mem:             2880,
+		Numa:            2,
+		Disks:           []*compute.Disk{{Name: "C3D", Type: imagetest.PdBalanced, Zone: "us-east4-c"}},
+		Zone:            "us-east4-c",
+		RequireFeatures: []string{"GVNIC"},
+		ExcludeFeatures: []string{"WINDOWS"},
+		Exceptions:      []*regexp.Regexp{regexp.MustCompile("(rhel|centos|almalinux|rocky-linux)-7")},
+		Quota:           &daisy.QuotaAvailable{Metric: "CPUS", Units: 176, Region: "us-east4"}, // No public C3D metric yet
+	},
+	"E2": {
+		Name:  "e2-standard-32",
+		CPU:   32,
+		Mem:   128,
+		Numa:  1,
+		Disks: []*compute.Disk{{Name: "E2", Type: imagetest.PdStandard}},
+		Quota: &daisy.QuotaAvailable{Metric: "E2_CPUS", Units: 32},
+	},
+	"N4": {
+		Name:            "n4-highmem-80",
+		CPU:             80,
+		Mem:             640,
+		Numa:            1,
+		Zone:            "us-east4-b",
+		Disks:           []*compute.Disk{{Name: "N4", Type: imagetest.HyperdiskBalanced, Zone: "us-east4-b"}},
+		Quota:           &daisy.QuotaAvailable{Metric: "CPUS", Units: 80, Region: "us-east4"},
+		RequireFeatures: []string{"GVNIC"},
+	},
+	"C4": {
+		Name:            "c4-highmem-192",
+		CPU:             192,
+		Mem:             1488,
+		Numa:            2,
+		Zone:            "us-east4-a",
+		Disks:           []*compute.Disk{{Name: "C4", Type: imagetest.HyperdiskBalanced, Zone: "us-east4-a"}},
+		Quota:           &daisy.QuotaAvailable{Metric: "CPUS", Units: 192, Region: "us-east4"},
+		RequireFeatures: []string{"GVNIC"},
+	},
+	"N2": {
+		Name:  "n2-highmem-128",
+		CPU:   128,
+		Mem:   864,
+		Numa:  2,
+		Disks: []*compute.Disk{{Name: "N2", Type: imagetest.PdStandard}},
+		Quota: &daisy.QuotaAvailable{Metric: "N2_CPUS", Units: 128},
+	},
+	"N2D": {
+		Name:  "n2d-standard-224",
+		CPU:   224,
+		Mem:   896,
+		Numa:  2,
+		Disks: []*compute.Disk{{Name: "N2D", Type: imagetest.PdStandard}},
+		Quota: &daisy.QuotaAvailable{Metric: "N2D_CPUS", Units: 224},
+	},
+	"T2D": {
+		Name:  "t2d-standard-60",
+		CPU:   60,
+		Mem:   240,
+		Numa:  1,
+		Disks: []*compute.Disk{{Name: "T2D", Type: imagetest.PdStandard}},
+		Quota: &daisy.QuotaAvailable{Metric: "T2D_CPUS", Units: 60},
+	},
+	"N1": {
+		Name:  "n1-highmem-96",
+		CPU:   96,
+		Mem:   624,
+		Numa:  2,
+		Disks: []*compute.Disk{{Name: "N1", Type: imagetest.PdStandard}},
+		Quota: &daisy.QuotaAvailable{Metric: "CPUS", Units: 96},
+	},
+}
+
+// ARMShapes are the largest Arm shapes of the machine families, by family
+// name.
+var ARMShapes = map[string]*Shape{
+	"T2A": {
+		Name:  "t2a-standard-48",
+		CPU:   48,
+		Mem:   192,
+		Numa:  1,
+		Disks: []*compute.Disk{{Name: "T2A", Type: imagetest.PdStandard, Zone: "europe-west4-a"}},
+		Zone:  "europe-west4-a",
+		Quota: &daisy.QuotaAvailable{Metric: "T2A_CPUS", Units: 48, Region: "europe-west4"},
+	},
+}
+
+// Families returns the shapes of the architecture of the image, by family
+// name.
+func Families(image *compute.Image) map[string]*Shape {
+	if image.Architecture == "ARM64" {
+		return ARMShapes
+	}
+	return X86Shapes
+}
+
+// Supports returns whether the image can be tested on the shape, from the
+// features of the image and the exempted image names of the shape.
+func (s *Shape) Supports(image *compute.Image) bool {
+	for _, feat := range s.RequireFeatures {
+		if !utils.HasFeature(image, feat) {
+			return false
+		}
+	}
+	for _, feat := range s.ExcludeFeatures {
+		if utils.HasFeature(image, feat) {
+			return false
+		}
+	}
+	for _, r := range s.Exceptions {
+		if r.MatchString(image.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateVM waits for the quota of the shape, then creates a test VM of the
+// shape with its disks.
+func CreateVM(t *imagetest.TestWorkflow, s *Shape) (*imagetest.TestVM, error) {
+	if s.Quota != nil {
+		// Copy the quota, the wait step adds other quotas of the same metric to
+		// it.
+		qa := *s.Quota
+		if err := t.WaitForVMQuota(&qa); err != nil {
+			return nil, err
+		}
+	}
+	vm, err := t.CreateTestVMMultipleDisks(s.Disks, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Zone != "" {
+		vm.ForceZone(s.Zone)
+	}
+	vm.ForceMachineType(s.Name)
+	return vm, nil
+}
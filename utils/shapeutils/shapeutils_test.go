@@ -0,0 +1,78 @@
+// Copyright 2026 Google LLC.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shapeutils
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloud-image-tests/utils/perftargets"
+	"google.golang.org/api/compute/v1"
+)
+
+func image(name, arch string, features ...string) *compute.Image {
+	img := &compute.Image{Name: name, Architecture: arch}
+	for _, f := range features {
+		img.GuestOsFeatures = append(img.GuestOsFeatures, &compute.GuestOsFeature{Type: f})
+	}
+	return img
+}
+
+func TestFamilies(t *testing.T) {
+	if _, ok := Families(image("debian-12-arm64", "ARM64"))["T2A"]; !ok {
+		t.Errorf("Families() of an ARM64 image doesn't have T2A")
+	}
+	if _, ok := Families(image("debian-12", "X86_64"))["N2"]; !ok {
+		t.Errorf("Families() of an X86_64 image doesn't have N2")
+	}
+}
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		family string
+		image  *compute.Image
+		want   bool
+	}{
+		{family: "N2", image: image("debian-12", "X86_64"), want: true},
+		{family: "C3", image: image("debian-12", "X86_64"), want: false},
+		{family: "C3", image: image("debian-12", "X86_64", "GVNIC"), want: true},
+		{family: "C3D", image: image("windows-server-2022", "X86_64", "GVNIC", "WINDOWS"), want: false},
+		{family: "C3D", image: image("rhel-7-v20260101", "X86_64", "GVNIC"), want: false},
+		{family: "C3D", image: image("rhel-9-v20260101", "X86_64", "GVNIC"), want: true},
+	}
+	for _, tc := range tests {
+		if got := X86Shapes[tc.family].Supports(tc.image); got != tc.want {
+			t.Errorf("%s.Supports(%s) = %v, want %v", tc.family, tc.image.Name, got, tc.want)
+		}
+	}
+}
+
+// TestShapeTargets checks that every shape has the targets of shapeperf, which
+// fails at setup otherwise.
+func TestShapeTargets(t *testing.T) {
+	for _, shapes := range []map[string]*Shape{X86Shapes, ARMShapes} {
+		for family, s := range shapes {
+			metrics := []string{"memory_bandwidth", "core_compute"}
+			if s.Numa > 1 {
+				metrics = append(metrics, "numa_latency")
+			}
+			for _, metric := range metrics {
+				q := perftargets.Query{MachineType: s.Name, VCPUs: int64(s.CPU), Metric: metric}
+				if _, err := perftargets.Lookup(q); err != nil {
+					t.Errorf("%s: perftargets.Lookup(%v) = %v, want nil", family, q, err)
+				}
+			}
+		}
+	}
+}
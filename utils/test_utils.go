@@ -56,6 +56,10 @@ const (
 	GuestAttributeTestKey = "test-complete"
 	// FirstBootGAKey is the key for guest attribute in the daisy "wait for instance" step in the case where it is the first boot, and we still want to wait for results from a subsequent reboot.
 	FirstBootGAKey = "first-boot-key"
+	// GuestFlushMetadataKey is the instance metadata key which tells the wrapper
+	// to generalize the guest after the tests finish, so that an image can be
+	// captured from its boot disk.
+	GuestFlushMetadataKey = "_cit_guest_flush"
 	// corePluginWaitTimeSeconds is the time in seconds to wait for the core plugin
 	// to restart.
 	corePluginWaitTimeSeconds = 15
@@ -417,6 +421,9 @@ func IsAccelerator(image string) bool {
 }
 
 // IsAlmaLinux returns true if the image is AlmaLinux.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsAlmaLinux(image string) bool {
 	return strings.Contains(image, "almalinux")
 }
@@ -432,21 +439,33 @@ func IsBYOS(image string) bool {
 }
 
 // IsCOS returns true if the image is cos.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsCOS(image string) bool {
 	return strings.Contains(image, "cos")
 }
 
 // IsCentOS returns true if the image is CentOS.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsCentOS(image string) bool {
 	return strings.Contains(image, "centos")
 }
 
 // IsDebian returns true if the image is Debian.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsDebian(image string) bool {
 	return strings.Contains(image, "debian")
 }
 
 // IsEL returns true if the image is an EL image.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsEL(image string) bool {
 	if IsCentOS(image) || IsRHEL(image) || IsRocky(image) || IsAlmaLinux(image) || IsOracle(image) {
 		return true
@@ -455,21 +474,33 @@ func IsEL(image string) bool {
 }
 
 // IsFedora returns true if the image is Fedora Linux.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsFedora(image string) bool {
 	return strings.Contains(image, "fedora")
 }
 
 // IsOpenSUSE returns true if the image is OpenSUSE.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsOpenSUSE(image string) bool {
 	return strings.Contains(image, "opensuse")
 }
 
 // IsOracle returns true if the image is Oracle.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsOracle(image string) bool {
 	return strings.Contains(image, "oracle")
 }
 
 // IsRHEL returns true if the image is RHEL.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsRHEL(image string) bool {
 	return strings.Contains(image, "rhel")
 }
@@ -485,6 +516,9 @@ func IsRHELLVM(image string) bool {
 }
 
 // IsRocky returns true if the image is Rocky.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsRocky(image string) bool {
 	return strings.Contains(image, "rocky")
 }
@@ -495,21 +529,33 @@ func IsSAP(image string) bool {
 }
 
 // IsSLES returns true if the image is SLES.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsSLES(image string) bool {
 	return strings.Contains(image, "sles")
 }
 
 // IsSUSE returns true if the image is SUSE.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsSUSE(image string) bool {
 	return strings.Contains(image, "suse")
 }
 
 // IsUbuntu returns true if the image is Ubuntu.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsUbuntu(image string) bool {
 	return strings.Contains(image, "ubuntu")
 }
 
 // IsWindowsImage returns true if the image is Windows.
+//
+// Deprecated: this matches the image name. Use TestWorkflow.GuestOS in suite
+// setup and GetGuestOS in tests.
 func IsWindowsImage(image string) bool {
 	return strings.Contains(image, "windows")
 }